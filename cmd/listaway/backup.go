@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jeffrpowell/listaway/internal/backup"
+	"github.com/jeffrpowell/listaway/internal/database"
+)
+
+func runBackupCommand(ctx context.Context, repo *database.Repository, args []string) {
+	if len(args) != 1 {
+		printUsage()
+		os.Exit(2)
+	}
+	path := args[0]
+
+	archive, err := repo.Backup(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Write beside the destination and rename, so a failed backup never clobbers a good one
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := backup.Write(tmp, archive); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		log.Fatal(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		log.Fatal(err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		log.Fatal(err)
+	}
+	fmt.Printf("Backed up %s to %s\n", summarize(archive), path)
+}
+
+func runRestoreCommand(ctx context.Context, repo *database.Repository, args []string) {
+	if len(args) != 1 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	archive, err := backup.Read(file)
+	file.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Backup from %s contains %s\n", archive.CreatedAt.Format("2006-01-02 15:04:05 MST"), summarize(archive))
+
+	fmt.Println("Running database migrations")
+	if err := repo.MigrateUp(ctx); err != nil {
+		log.Fatal(err)
+	}
+	if err := repo.Restore(ctx, archive); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Restore complete")
+}
+
+func summarize(archive backup.Archive) string {
+	return fmt.Sprintf("%d group(s), %d user(s), %d list(s), %d item(s), %d claim(s) and %d collection(s)",
+		len(archive.Groups), len(archive.Users), len(archive.Lists), len(archive.Items), len(archive.ItemClaims), len(archive.Collections))
+}
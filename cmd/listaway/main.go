@@ -1,15 +1,85 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
-	_ "github.com/jeffrpowell/listaway/internal/database" //blank import to run init()
-	_ "github.com/jeffrpowell/listaway/internal/handlers" //blank import to run init()
+	"github.com/jeffrpowell/listaway/internal/database"
+	"github.com/jeffrpowell/listaway/internal/handlers"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/linkpreview"
+	"github.com/jeffrpowell/listaway/internal/storage"
+	"github.com/jeffrpowell/listaway/web"
 )
 
+// linkPreviewWorkers is how many item URLs are fetched for previews at once
+const linkPreviewWorkers = 4
+
 func main() {
+	command := ""
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	switch command {
+	case "", "migrate", "backup", "restore":
+	default:
+		printUsage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	fmt.Printf("Attempting database connection: %s\n", constants.DB_CONNECTION_STRING)
+	pool, err := database.OpenPool(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	repo := database.NewRepository(pool)
+	defer repo.Close()
+
+	switch command {
+	case "migrate":
+		runMigrateCommand(ctx, repo, os.Args[2:])
+		return
+	case "backup":
+		runBackupCommand(ctx, repo, os.Args[2:])
+		return
+	case "restore":
+		runRestoreCommand(ctx, repo, os.Args[2:])
+		return
+	}
+
+	fmt.Println("Running database migrations")
+	if err := repo.MigrateUp(ctx); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Database initialized successfully")
+	constants.ADMIN_EXISTS = repo.AdminUserExists(ctx)
+	constants.SESSION_STORE = database.NewSessionStore(repo, helper.ClientIP, constants.SESSION_KEY)
+	handlers.SetRepository(repo)
+	attachments, err := storage.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	repo.SetAttachmentStore(attachments)
+	web.SetAttachmentStore(attachments)
+	if constants.LINK_PREVIEWS_ENABLED {
+		previews := linkpreview.NewWorker(linkpreview.New(), repo)
+		previews.Start(ctx, linkPreviewWorkers)
+		repo.OnItemURLSaved(previews.Enqueue)
+	}
+	retentionDays, err := trashRetentionDays()
+	if err != nil {
+		log.Fatal(err)
+	}
+	web.SetTrashRetention(retentionDays)
+	if retentionDays > 0 {
+		startTrashPurge(ctx, repo, retentionDays)
+	}
+
 	fmt.Println("####################################")
 	fmt.Println("#             LISTAWAY             #")
 	fmt.Println("####################################")
@@ -20,3 +90,13 @@ func main() {
 	}
 	http.ListenAndServe(":"+constants.PORT, constants.ROUTER)
 }
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  listaway                      run the server (applies pending migrations first)")
+	fmt.Fprintln(os.Stderr, "  listaway migrate status       list migrations and whether they have been applied")
+	fmt.Fprintln(os.Stderr, "  listaway migrate up           apply all pending migrations")
+	fmt.Fprintln(os.Stderr, "  listaway migrate down [n]     revert the last n applied migrations (default 1)")
+	fmt.Fprintln(os.Stderr, "  listaway backup <file>        write every user, group, list, item and collection to a backup file")
+	fmt.Fprintln(os.Stderr, "  listaway restore <file>       load a backup file into an empty database")
+}
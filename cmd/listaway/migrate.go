@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/jeffrpowell/listaway/internal/database"
+)
+
+func runMigrateCommand(ctx context.Context, repo *database.Repository, args []string) {
+	if len(args) == 0 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := repo.GetMigrationStatus(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Printf("%04d_%-30s applied %s\n", s.Version, s.Name, s.AppliedAt.Time.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("%04d_%-30s pending\n", s.Version, s.Name)
+			}
+		}
+	case "up":
+		if err := repo.MigrateUp(ctx); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Database is up to date")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of migrations to revert: %s", args[1])
+			}
+		}
+		if err := repo.MigrateDown(ctx, steps); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Reverted %d migration(s)\n", steps)
+	default:
+		printUsage()
+		os.Exit(2)
+	}
+}
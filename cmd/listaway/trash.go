@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/database"
+)
+
+// trashPurgeInterval is how often the trash is checked for things past their retention
+const trashPurgeInterval = time.Hour
+
+// trashRetentionDays parses TRASH_RETENTION_DAYS; 0 turns off the scheduled purge
+func trashRetentionDays() (int, error) {
+	days, err := strconv.Atoi(constants.TRASH_RETENTION_DAYS)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", constants.ENV_TRASH_RETENTION_DAYS, constants.TRASH_RETENTION_DAYS)
+	}
+	return days, nil
+}
+
+// startTrashPurge deletes for good whatever has been in the trash longer than days, now and then
+// every trashPurgeInterval until ctx is cancelled
+func startTrashPurge(ctx context.Context, repo *database.Repository, days int) {
+	retention := time.Duration(days) * 24 * time.Hour
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			purged, err := repo.PurgeTrash(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Print(err)
+			} else if purged > 0 {
+				log.Printf("purged %d lists, items and collections from the trash", purged)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,428 @@
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/storage"
+)
+
+// FormatName marks a file as a Listaway backup
+const FormatName = "listaway-backup"
+
+// Version is the archive layout written by this build. Read accepts any version up to this one.
+//
+//	1: initial layout
+//	2: items carry their position
+//	3: item sections
+//	4: item completion and each list's completed display
+//	5: item quantities and contributions
+//	6: item prices and list budgets
+//	7: item attachments
+//	8: list and item tags
+//	9: trash
+//	10: list templates
+//	11: group two-factor policy
+const Version = 11
+
+// Archive is a whole instance: every group, user, list, section, item, claim, contribution, attachment, collection and list template.
+// Ids are the ones from the source database and only serve to link rows within the archive;
+// a restore gives every row a new id.
+type Archive struct {
+	Format        string        `json:"format"`
+	Version       int           `json:"version"`
+	SchemaVersion int           `json:"schemaVersion"` // latest migration applied to the source database
+	CreatedAt     time.Time     `json:"createdAt"`
+	Groups        []Group       `json:"groups"`
+	Users         []User        `json:"users"`
+	Lists         []List        `json:"lists"`
+	ItemSections  []ItemSection `json:"itemSections"`
+	Items         []Item        `json:"items"`
+	ItemClaims    []ItemClaim   `json:"itemClaims"`
+	// Absent from archives before version 5
+	ItemContributions []ItemContribution `json:"itemContributions"`
+	// Absent from archives before version 7
+	ItemAttachments []ItemAttachment `json:"itemAttachments"`
+	Collections     []Collection     `json:"collections"`
+	CollectionLists []CollectionList `json:"collectionLists"`
+	// Absent from archives before version 10
+	Templates     []Template     `json:"templates"`
+	TemplateItems []TemplateItem `json:"templateItems"`
+}
+
+// Group is a user group and its group_settings row
+type Group struct {
+	Id             int  `json:"id"`
+	SharingEnabled bool `json:"sharingEnabled"`
+	// Absent from archives before version 11
+	TwoFactorRequired bool `json:"twoFactorRequired"`
+}
+
+type User struct {
+	Id            int64   `json:"id"`
+	GroupId       int     `json:"groupId"`
+	Email         string  `json:"email"`
+	Name          *string `json:"name"`
+	PasswordHash  string  `json:"passwordHash"`
+	Admin         bool    `json:"admin"`
+	InstanceAdmin bool    `json:"instanceAdmin"`
+	OIDCProvider  *string `json:"oidcProvider"`
+	OIDCSubject   *string `json:"oidcSubject"`
+	OIDCEmail     *string `json:"oidcEmail"`
+}
+
+type List struct {
+	Id             int64   `json:"id"`
+	UserId         int64   `json:"userId"`
+	Name           string  `json:"name"`
+	Description    *string `json:"description"`
+	ShareCode      *string `json:"shareCode"`
+	ShareWithGroup bool    `json:"shareWithGroup"`
+	GroupCanEdit   bool    `json:"groupCanEdit"`
+	Claimable      bool    `json:"claimable"`
+	// Absent from archives before version 4; restore then uses the default
+	CompletedDisplay string `json:"completedDisplay"`
+	// Absent from archives before version 6; in minor units of BudgetCurrency
+	Budget         *int64  `json:"budget"`
+	BudgetCurrency *string `json:"budgetCurrency"`
+	// Absent from archives before version 8
+	Tags []string `json:"tags"`
+	// Set while the list is in its owner's trash; absent from archives before version 9
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+type ItemSection struct {
+	Id       int64  `json:"id"`
+	ListId   int64  `json:"listId"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+type Item struct {
+	Id          int64      `json:"id"`
+	ListId      int64      `json:"listId"`
+	Name        string     `json:"name"`
+	URL         *string    `json:"url"`
+	Notes       *string    `json:"notes"`
+	Priority    *int64     `json:"priority"`
+	Position    int        `json:"position"` // absent from version 1 archives; restore then keeps id order
+	SectionId   *int64     `json:"sectionId"`
+	CompletedAt *time.Time `json:"completedAt"`
+	CompletedBy *int64     `json:"completedBy"` // a user in the archive; null once that user is gone
+	// Absent from archives before version 5; restore then uses 1 wanted, none fulfilled
+	Quantity  int `json:"quantity"`
+	Fulfilled int `json:"fulfilled"`
+	// Absent from archives before version 6; in minor units of Currency
+	Price    *int64  `json:"price"`
+	Currency *string `json:"currency"`
+	// Absent from archives before version 8
+	Tags []string `json:"tags"`
+	// Set while the item is in the trash; absent from archives before version 9
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+type ItemClaim struct {
+	ItemId       int64     `json:"itemId"`
+	ClaimerName  *string   `json:"claimerName"`
+	ClaimerEmail *string   `json:"claimerEmail"`
+	ClaimToken   string    `json:"claimToken"`
+	ClaimedAt    time.Time `json:"claimedAt"`
+}
+
+type ItemContribution struct {
+	ItemId            int64     `json:"itemId"`
+	ContributorName   *string   `json:"contributorName"`
+	Quantity          int       `json:"quantity"`
+	ContributionToken string    `json:"contributionToken"`
+	ContributedAt     time.Time `json:"contributedAt"`
+}
+
+// ItemAttachment records an uploaded image. The files themselves stay in the attachment store under
+// their keys and are not part of the archive; restore expects the same store, or a copy of it.
+type ItemAttachment struct {
+	ItemId       int64     `json:"itemId"`
+	StorageKey   string    `json:"storageKey"`
+	ThumbnailKey string    `json:"thumbnailKey"`
+	ContentType  string    `json:"contentType"`
+	Filename     *string   `json:"filename"`
+	SizeBytes    int64     `json:"sizeBytes"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	UploadedAt   time.Time `json:"uploadedAt"`
+}
+
+type Collection struct {
+	Id          int64   `json:"id"`
+	UserId      int64   `json:"userId"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	ShareCode   *string `json:"shareCode"`
+	// Set while the collection is in its owner's trash; absent from archives before version 9
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+type CollectionList struct {
+	CollectionId int64 `json:"collectionId"`
+	ListId       int64 `json:"listId"`
+}
+
+// Template is a group's list template
+type Template struct {
+	Id          int64     `json:"id"`
+	GroupId     int       `json:"groupId"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type TemplateItem struct {
+	TemplateId int64   `json:"templateId"`
+	Name       string  `json:"name"`
+	URL        *string `json:"url"`
+	Notes      *string `json:"notes"`
+	Priority   *int64  `json:"priority"`
+	Quantity   int     `json:"quantity"`
+	// In minor units of Currency
+	Price    *int64  `json:"price"`
+	Currency *string `json:"currency"`
+	Position int     `json:"position"`
+}
+
+// Write stores the archive as gzipped JSON
+func Write(w io.Writer, archive Archive) error {
+	zw := gzip.NewWriter(w)
+	encoder := json.NewEncoder(zw)
+	encoder.SetIndent("", " ")
+	if err := encoder.Encode(archive); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// Read loads an archive written by Write and checks that it is internally consistent
+func Read(r io.Reader) (Archive, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return Archive{}, fmt.Errorf("not a Listaway backup: %v", err)
+	}
+	defer zr.Close()
+
+	var archive Archive
+	decoder := json.NewDecoder(zr)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&archive); err != nil {
+		return Archive{}, fmt.Errorf("could not read backup: %v", err)
+	}
+	if archive.Format != FormatName {
+		return Archive{}, errors.New("not a Listaway backup")
+	}
+	if archive.Version < 1 || archive.Version > Version {
+		return Archive{}, fmt.Errorf("backup version %d is not supported by this version of Listaway (supports up to %d)", archive.Version, Version)
+	}
+	if err := archive.Validate(); err != nil {
+		return Archive{}, err
+	}
+	return archive, nil
+}
+
+// Validate checks that ids are unique, every reference points at a row in the archive,
+// and the values the database keeps unique are unique
+func (a Archive) Validate() error {
+	groups := make(map[int]bool, len(a.Groups))
+	for _, g := range a.Groups {
+		if groups[g.Id] {
+			return fmt.Errorf("group %d appears more than once", g.Id)
+		}
+		groups[g.Id] = true
+	}
+
+	users := make(map[int64]bool, len(a.Users))
+	emails := make(map[string]bool, len(a.Users))
+	oidcLinks := make(map[[2]string]bool)
+	for _, u := range a.Users {
+		if users[u.Id] {
+			return fmt.Errorf("user %d appears more than once", u.Id)
+		}
+		users[u.Id] = true
+		if emails[u.Email] {
+			return fmt.Errorf("email %q belongs to more than one user", u.Email)
+		}
+		emails[u.Email] = true
+		if !groups[u.GroupId] {
+			return fmt.Errorf("user %d belongs to group %d, which is not in the backup", u.Id, u.GroupId)
+		}
+		if u.OIDCProvider != nil && u.OIDCSubject != nil {
+			link := [2]string{*u.OIDCProvider, *u.OIDCSubject}
+			if oidcLinks[link] {
+				return fmt.Errorf("OIDC account %s/%s is linked to more than one user", link[0], link[1])
+			}
+			oidcLinks[link] = true
+		}
+	}
+
+	lists := make(map[int64]bool, len(a.Lists))
+	listShareCodes := make(map[string]bool)
+	for _, l := range a.Lists {
+		if lists[l.Id] {
+			return fmt.Errorf("list %d appears more than once", l.Id)
+		}
+		lists[l.Id] = true
+		if !users[l.UserId] {
+			return fmt.Errorf("list %d belongs to user %d, who is not in the backup", l.Id, l.UserId)
+		}
+		if l.ShareCode != nil {
+			if listShareCodes[*l.ShareCode] {
+				return fmt.Errorf("share code %q is used by more than one list", *l.ShareCode)
+			}
+			listShareCodes[*l.ShareCode] = true
+		}
+		if l.Budget != nil && (*l.Budget < 0 || !validCurrency(l.BudgetCurrency)) {
+			return fmt.Errorf("list %d has a negative budget or one without a valid currency", l.Id)
+		}
+	}
+
+	sectionLists := make(map[int64]int64, len(a.ItemSections))
+	for _, s := range a.ItemSections {
+		if _, ok := sectionLists[s.Id]; ok {
+			return fmt.Errorf("section %d appears more than once", s.Id)
+		}
+		sectionLists[s.Id] = s.ListId
+		if !lists[s.ListId] {
+			return fmt.Errorf("section %d belongs to list %d, which is not in the backup", s.Id, s.ListId)
+		}
+	}
+
+	items := make(map[int64]bool, len(a.Items))
+	for _, i := range a.Items {
+		if items[i.Id] {
+			return fmt.Errorf("item %d appears more than once", i.Id)
+		}
+		items[i.Id] = true
+		if !lists[i.ListId] {
+			return fmt.Errorf("item %d belongs to list %d, which is not in the backup", i.Id, i.ListId)
+		}
+		if i.SectionId != nil {
+			if listId, ok := sectionLists[*i.SectionId]; !ok || listId != i.ListId {
+				return fmt.Errorf("item %d is in section %d, which is not a section of its list in the backup", i.Id, *i.SectionId)
+			}
+		}
+		if i.CompletedBy != nil && !users[*i.CompletedBy] {
+			return fmt.Errorf("item %d was completed by user %d, who is not in the backup", i.Id, *i.CompletedBy)
+		}
+		if i.Price != nil && (*i.Price < 0 || !validCurrency(i.Currency)) {
+			return fmt.Errorf("item %d has a negative price or one without a valid currency", i.Id)
+		}
+	}
+
+	claimed := make(map[int64]bool, len(a.ItemClaims))
+	for _, c := range a.ItemClaims {
+		if claimed[c.ItemId] {
+			return fmt.Errorf("item %d is claimed more than once", c.ItemId)
+		}
+		claimed[c.ItemId] = true
+		if !items[c.ItemId] {
+			return fmt.Errorf("claim on item %d, which is not in the backup", c.ItemId)
+		}
+	}
+
+	for _, c := range a.ItemContributions {
+		if !items[c.ItemId] {
+			return fmt.Errorf("contribution to item %d, which is not in the backup", c.ItemId)
+		}
+		if c.Quantity < 1 {
+			return fmt.Errorf("contribution to item %d has quantity %d", c.ItemId, c.Quantity)
+		}
+	}
+
+	attachmentKeys := make(map[string]bool, 2*len(a.ItemAttachments))
+	for _, at := range a.ItemAttachments {
+		if !items[at.ItemId] {
+			return fmt.Errorf("attachment on item %d, which is not in the backup", at.ItemId)
+		}
+		for _, key := range []string{at.StorageKey, at.ThumbnailKey} {
+			if !storage.ValidKey(key) {
+				return fmt.Errorf("attachment on item %d has invalid storage key %q", at.ItemId, key)
+			}
+			if attachmentKeys[key] {
+				return fmt.Errorf("storage key %q is used by more than one attachment", key)
+			}
+			attachmentKeys[key] = true
+		}
+	}
+
+	collections := make(map[int64]bool, len(a.Collections))
+	collectionShareCodes := make(map[string]bool)
+	for _, c := range a.Collections {
+		if collections[c.Id] {
+			return fmt.Errorf("collection %d appears more than once", c.Id)
+		}
+		collections[c.Id] = true
+		if !users[c.UserId] {
+			return fmt.Errorf("collection %d belongs to user %d, who is not in the backup", c.Id, c.UserId)
+		}
+		if c.ShareCode != nil {
+			if collectionShareCodes[*c.ShareCode] {
+				return fmt.Errorf("share code %q is used by more than one collection", *c.ShareCode)
+			}
+			collectionShareCodes[*c.ShareCode] = true
+		}
+	}
+
+	memberships := make(map[CollectionList]bool, len(a.CollectionLists))
+	for _, cl := range a.CollectionLists {
+		if memberships[cl] {
+			return fmt.Errorf("list %d is in collection %d more than once", cl.ListId, cl.CollectionId)
+		}
+		memberships[cl] = true
+		if !collections[cl.CollectionId] || !lists[cl.ListId] {
+			return fmt.Errorf("collection %d includes list %d, but one of them is not in the backup", cl.CollectionId, cl.ListId)
+		}
+	}
+
+	templates := make(map[int64]bool, len(a.Templates))
+	templateNames := make(map[int]map[string]bool)
+	for _, t := range a.Templates {
+		if templates[t.Id] {
+			return fmt.Errorf("template %d appears more than once", t.Id)
+		}
+		templates[t.Id] = true
+		if !groups[t.GroupId] {
+			return fmt.Errorf("template %d belongs to group %d, which is not in the backup", t.Id, t.GroupId)
+		}
+		if templateNames[t.GroupId] == nil {
+			templateNames[t.GroupId] = make(map[string]bool)
+		}
+		if templateNames[t.GroupId][t.Name] {
+			return fmt.Errorf("group %d has more than one template named %q", t.GroupId, t.Name)
+		}
+		templateNames[t.GroupId][t.Name] = true
+	}
+
+	for _, ti := range a.TemplateItems {
+		if !templates[ti.TemplateId] {
+			return fmt.Errorf("template item %q belongs to template %d, which is not in the backup", ti.Name, ti.TemplateId)
+		}
+		if ti.Price != nil && (*ti.Price < 0 || !validCurrency(ti.Currency)) {
+			return fmt.Errorf("template item %q has a negative price or one without a valid currency", ti.Name)
+		}
+	}
+	return nil
+}
+
+// validCurrency reports whether code is a three-letter upper-case currency code, as the database stores them
+func validCurrency(code *string) bool {
+	if code == nil || len(*code) != 3 {
+		return false
+	}
+	for _, c := range *code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
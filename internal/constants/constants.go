@@ -2,7 +2,6 @@ package constants
 
 import (
 	"fmt"
-	"net/http"
 	"os"
 
 	"github.com/gorilla/mux"
@@ -18,6 +17,12 @@ const (
 	ENV_POSTGRES_HOST     string = "POSTGRES_HOST"
 	ENV_POSTGRES_DB       string = "POSTGRES_DB"
 
+	// Database connection pool configuration
+	ENV_POSTGRES_MAX_OPEN_CONNS     string = "POSTGRES_MAX_OPEN_CONNS"     // maximum open connections, 0 for unlimited
+	ENV_POSTGRES_MAX_IDLE_CONNS     string = "POSTGRES_MAX_IDLE_CONNS"     // maximum idle connections kept in the pool
+	ENV_POSTGRES_CONN_MAX_LIFETIME  string = "POSTGRES_CONN_MAX_LIFETIME"  // Go duration, e.g. 30m; 0 to reuse forever
+	ENV_POSTGRES_CONN_MAX_IDLE_TIME string = "POSTGRES_CONN_MAX_IDLE_TIME" // Go duration, e.g. 5m; 0 to never close for idleness
+
 	// SMTP configuration for password reset emails
 	ENV_SMTP_HOST     string = "SMTP_HOST"
 	ENV_SMTP_PORT     string = "SMTP_PORT"
@@ -34,6 +39,22 @@ const (
 	ENV_OIDC_CLIENT_SECRET  string = "OIDC_CLIENT_SECRET"  // OAuth2 client secret
 	ENV_OIDC_REDIRECT_URL   string = "OIDC_REDIRECT_URL"   // OAuth2 redirect URL
 	ENV_OIDC_SCOPES         string = "OIDC_SCOPES"         // OAuth2 scopes (space-separated)
+
+	ENV_LINK_PREVIEWS string = "LINK_PREVIEWS" // false to never fetch item URLs for previews
+
+	ENV_TRASH_RETENTION_DAYS string = "TRASH_RETENTION_DAYS" // days deleted lists, items and collections are kept; 0 keeps them until the trash is emptied
+
+	ENV_TRUST_PROXY string = "TRUST_PROXY" // true to take client IPs from X-Forwarded-For, when behind a reverse proxy
+
+	// Item image storage
+	ENV_ATTACHMENT_STORAGE   string = "ATTACHMENT_STORAGE"   // local or s3
+	ENV_ATTACHMENT_DIR       string = "ATTACHMENT_DIR"       // directory for local storage
+	ENV_S3_ENDPOINT          string = "S3_ENDPOINT"          // e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	ENV_S3_REGION            string = "S3_REGION"            // signing region
+	ENV_S3_BUCKET            string = "S3_BUCKET"            // bucket to store images in
+	ENV_S3_ACCESS_KEY_ID     string = "S3_ACCESS_KEY_ID"     // access key
+	ENV_S3_SECRET_ACCESS_KEY string = "S3_SECRET_ACCESS_KEY" // secret key
+	ENV_S3_PATH_STYLE        string = "S3_PATH_STYLE"        // true to address the bucket in the path, as MinIO expects
 )
 
 // Database consts
@@ -45,14 +66,38 @@ const (
 	DB_TABLE_LIST            string = "listaway.list"
 	DB_TABLE_USER            string = "listaway.user"
 	DB_TABLE_ITEM            string = "listaway.item"
+	DB_TABLE_ITEM_CLAIM      string = "listaway.item_claim"
+	DB_TABLE_ITEM_SECTION    string = "listaway.item_section"
+	DB_TABLE_CONTRIBUTION    string = "listaway.item_contribution"
+	DB_TABLE_API_TOKEN       string = "listaway.api_token"
 	DB_TABLE_RESET           string = "listaway.reset_tokens"
 	DB_TABLE_COLLECTION      string = "listaway.collection"
 	DB_TABLE_COLLECTION_LIST string = "listaway.collection_list"
 	DB_TABLE_GROUP_SETTINGS  string = "listaway.group_settings"
+	DB_TABLE_LINK_PREVIEW    string = "listaway.link_preview"
+	DB_TABLE_ATTACHMENT      string = "listaway.item_attachment"
+	DB_TABLE_REVISION        string = "listaway.revision"
+	DB_TABLE_AUDIT_LOG       string = "listaway.audit_log"
+	DB_TABLE_TEMPLATE        string = "listaway.list_template"
+	DB_TABLE_TEMPLATE_ITEM   string = "listaway.list_template_item"
+	DB_TABLE_SESSION         string = "listaway.session"
+	DB_TABLE_TWO_FACTOR      string = "listaway.user_two_factor"
+	DB_TABLE_RECOVERY_CODE   string = "listaway.two_factor_recovery_code"
+	DB_TABLE_PASSKEY         string = "listaway.passkey"
+	DB_TABLE_MIGRATIONS      string = "listaway.schema_migrations"
+	DB_MIGRATION_LOCK_ID     int64  = 4_817_273_001 // arbitrary key for pg_advisory_lock, shared by all replicas
 )
 
 var DB_CONNECTION_STRING string = getDbConnectionString()
 
+// Database connection pool configuration with defaults
+var (
+	DB_MAX_OPEN_CONNS     string = loadEnvWithDefault(ENV_POSTGRES_MAX_OPEN_CONNS, "25")
+	DB_MAX_IDLE_CONNS     string = loadEnvWithDefault(ENV_POSTGRES_MAX_IDLE_CONNS, "25")
+	DB_CONN_MAX_LIFETIME  string = loadEnvWithDefault(ENV_POSTGRES_CONN_MAX_LIFETIME, "30m")
+	DB_CONN_MAX_IDLE_TIME string = loadEnvWithDefault(ENV_POSTGRES_CONN_MAX_IDLE_TIME, "5m")
+)
+
 // SMTP configuration with defaults
 var (
 	SMTP_HOST     string = loadEnvWithDefault(ENV_SMTP_HOST, "")
@@ -74,23 +119,87 @@ var (
 	OIDC_SCOPES         string = loadEnvWithDefault(ENV_OIDC_SCOPES, "openid profile email")
 )
 
+// Fetching item URLs for link previews is on unless turned off
+var LINK_PREVIEWS_ENABLED bool = loadEnvWithDefault(ENV_LINK_PREVIEWS, "true") != "false"
+
+// Deleted things are purged from the trash after this many days
+var TRASH_RETENTION_DAYS string = loadEnvWithDefault(ENV_TRASH_RETENTION_DAYS, "30")
+
+// Forwarding headers are only believed when a reverse proxy is known to set them
+var TRUST_PROXY bool = loadEnvWithDefault(ENV_TRUST_PROXY, "false") == "true"
+
+// Item image storage with defaults
+var (
+	ATTACHMENT_STORAGE   string = loadEnvWithDefault(ENV_ATTACHMENT_STORAGE, "local")
+	ATTACHMENT_DIR       string = loadEnvWithDefault(ENV_ATTACHMENT_DIR, "attachments")
+	S3_ENDPOINT          string = loadEnvWithDefault(ENV_S3_ENDPOINT, "")
+	S3_REGION            string = loadEnvWithDefault(ENV_S3_REGION, "us-east-1")
+	S3_BUCKET            string = loadEnvWithDefault(ENV_S3_BUCKET, "")
+	S3_ACCESS_KEY_ID     string = loadEnvWithDefault(ENV_S3_ACCESS_KEY_ID, "")
+	S3_SECRET_ACCESS_KEY string = loadEnvWithDefault(ENV_S3_SECRET_ACCESS_KEY, "")
+	S3_PATH_STYLE        string = loadEnvWithDefault(ENV_S3_PATH_STYLE, "false")
+)
+
 // Handler consts
 const (
 	COOKIE_NAME_SESSION    string = "session"
 	SHARED_LIST_PATH       string = "sharedlist"
 	SHARED_COLLECTION_PATH string = "sharedcollection"
+	API_V1_PATH            string = "/api/v1"
+	ATTACHMENT_PATH        string = "attachments"
 	defaultPort            string = "8080"
 )
 
 var PORT string = loadEnvWithDefault(ENV_PORT, defaultPort)
 
 var (
-	authKey                            = []byte(os.Getenv(ENV_AUTH_KEY))
-	COOKIE_STORE *sessions.CookieStore = sessions.NewCookieStore(authKey)
-	ROUTER       *mux.Router           = mux.NewRouter()
-	ADMIN_EXISTS                       = false
+	SESSION_KEY   = []byte(os.Getenv(ENV_AUTH_KEY)) // signs the session cookie
+	SESSION_STORE sessions.Store                    // set by main once the database is open
+	ROUTER        *mux.Router                       = mux.NewRouter()
+	ADMIN_EXISTS                                    = false
+)
+
+const SESSION_MAX_AGE int = 86400 * 7 // 7 days
+
+// Personal access token consts
+const (
+	API_TOKEN_PREFIX string = "law_" // makes tokens easy to spot in configs and secret scanners
+	API_SCOPE_READ   string = "read"
+	API_SCOPE_WRITE  string = "write"
 )
 
+// Two-factor authentication consts
+const (
+	TWO_FACTOR_ISSUER         string = "Listaway" // how authenticator apps label the account
+	TWO_FACTOR_RECOVERY_CODES int    = 10         // recovery codes handed out at a time
+)
+
+// Passkey consts
+const (
+	PASSKEY_RP_NAME         string = "Listaway" // how authenticators label the site
+	PASSKEY_MAX_PER_USER    int    = 20
+	PASSKEY_MAX_NAME_LENGTH int    = 64
+)
+
+// How a list shows its completed items
+const (
+	COMPLETED_DISPLAY_STRIKE  string = "strike"  // in place, struck through
+	COMPLETED_DISPLAY_HIDE    string = "hide"    // left out of share links; the list page shows them on request
+	COMPLETED_DISPLAY_ARCHIVE string = "archive" // moved under a Completed heading at the end of the list
+)
+
+var COMPLETED_DISPLAYS = []string{COMPLETED_DISPLAY_STRIKE, COMPLETED_DISPLAY_HIDE, COMPLETED_DISPLAY_ARCHIVE}
+
+// Orders the items JSON can be sorted in
+const (
+	ITEM_SORT_POSITION string = "position" // the list's custom order
+	ITEM_SORT_NAME     string = "name"
+	ITEM_SORT_PRIORITY string = "priority"
+	ITEM_SORT_PRICE    string = "price" // grouped by currency; unpriced items last
+)
+
+var ITEM_SORTS = []string{ITEM_SORT_POSITION, ITEM_SORT_NAME, ITEM_SORT_PRIORITY, ITEM_SORT_PRICE}
+
 // Random consts
 const (
 	DefaultN                  = 8
@@ -98,18 +207,9 @@ const (
 	charSetUnambiguousLower   = "abcdefghjklmnpqrtuvwyxz"
 	charSetUnambiguousNumeric = "2346789"
 	CHARSET_UNAMBIGUOUS       = charSetUnambiguousUpper + charSetUnambiguousLower + charSetUnambiguousNumeric
+	CHARSET_RECOVERY_CODE     = charSetUnambiguousUpper + charSetUnambiguousNumeric // read aloud or typed from paper, so one case only
 )
 
-func init() {
-	maxAge := 86400 * 7 // 7 days
-
-	COOKIE_STORE.MaxAge(maxAge)
-	COOKIE_STORE.Options.Path = "/"
-	COOKIE_STORE.Options.HttpOnly = true
-	COOKIE_STORE.Options.Secure = false
-	COOKIE_STORE.Options.SameSite = http.SameSiteLaxMode
-}
-
 func loadEnvWithDefault(key string, defaultValue string) string {
 	val := os.Getenv(key)
 	if val == "" {
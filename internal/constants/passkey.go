@@ -0,0 +1,14 @@
+package constants
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Passkey is a WebAuthn credential a user registered, as listed on the passkeys page
+type Passkey struct {
+	Id         int          `json:"id"`
+	Name       string       `json:"name"` // Starts as the browser it was added from; the user can rename it
+	CreatedAt  time.Time    `json:"createdAt"`
+	LastUsedAt sql.NullTime `json:"lastUsedAt"`
+}
@@ -0,0 +1,90 @@
+package constants
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Prices and budgets are stored as whole numbers of the currency's minor unit (cents for USD,
+// yen for JPY) next to an ISO 4217 code, so totals are exact.
+
+// currencyExponents lists the currencies whose minor unit isn't a hundredth
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// maxAmountDigits keeps amounts, and sums of many of them, well inside an int64
+const maxAmountDigits = 13
+
+// CurrencyExponent is how many decimal places the currency's amounts have
+func CurrencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// NormalizeCurrency upper-cases a currency code, returning false unless it is three letters
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", false
+		}
+	}
+	return code, true
+}
+
+// FormatAmount renders minor units as a decimal, e.g. 1250 USD as "12.50"
+func FormatAmount(amount int64, currency string) string {
+	exponent := CurrencyExponent(currency)
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// FormatPrice renders minor units with their currency, e.g. "12.50 USD"
+func FormatPrice(amount int64, currency string) string {
+	return FormatAmount(amount, currency) + " " + currency
+}
+
+// ParseAmount reads a decimal such as "12.5" or "1,299.99" as minor units of currency.
+// Negative amounts and more decimal places than the currency has are rejected.
+func ParseAmount(text string, currency string) (int64, error) {
+	text = strings.ReplaceAll(strings.TrimSpace(text), ",", "")
+	whole, fraction, _ := strings.Cut(text, ".")
+	exponent := CurrencyExponent(currency)
+	if whole == "" && fraction == "" {
+		return 0, errors.New("amount is required")
+	}
+	if len(fraction) > exponent {
+		return 0, fmt.Errorf("%s amounts have at most %d decimal places", currency, exponent)
+	}
+	digits := whole + fraction + strings.Repeat("0", exponent-len(fraction))
+	if len(strings.TrimLeft(digits, "0")) > maxAmountDigits {
+		return 0, errors.New("amount is too large")
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("%q is not an amount", text)
+		}
+	}
+	return strconv.ParseInt(digits, 10, 64)
+}
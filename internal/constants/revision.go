@@ -0,0 +1,170 @@
+package constants
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// What a revision did; reverting one records the opposite
+const (
+	REVISION_CREATE  string = "create"
+	REVISION_UPDATE  string = "update"
+	REVISION_DELETE  string = "delete"  // moved to the trash
+	REVISION_RESTORE string = "restore" // brought back from the trash
+)
+
+// MaxRevisions is how many of a list's most recent revisions its history shows
+const MaxRevisions = 200
+
+// Revision is one recorded change to a list's details or to one of its items
+type Revision struct {
+	Id        uint64
+	ListId    uint64
+	ItemId    sql.NullInt64  // null for changes to the list itself
+	ActorName sql.NullString // null once the user who made the change is gone
+	Action    string
+	Subject   string // the item's or list's name at the time
+	Changes   []FieldChange
+	CreatedAt time.Time
+	// Whether the change can still be undone; an item since deleted for good can't be brought back, say
+	Revertible bool
+}
+
+// FieldChange is one field a revision changed, as text ready to show. Before is empty for fields
+// that were first set, After for fields that were cleared.
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// ItemSnapshot is what a revision records of an item: the fields an editor can change
+type ItemSnapshot struct {
+	Name      string   `json:"name"`
+	URL       string   `json:"url,omitempty"`
+	Notes     string   `json:"notes,omitempty"`
+	Priority  *int64   `json:"priority,omitempty"`
+	SectionId *int64   `json:"sectionId,omitempty"`
+	Section   string   `json:"section,omitempty"` // the section's name, for showing the change
+	Quantity  int      `json:"quantity"`
+	Fulfilled int      `json:"fulfilled"`
+	Price     *int64   `json:"price,omitempty"`
+	Currency  string   `json:"currency,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// ListSnapshot is what a revision records of a list's own details
+type ListSnapshot struct {
+	Name           string `json:"name"`
+	Description    string `json:"description,omitempty"`
+	ShareWithGroup bool   `json:"shareWithGroup"`
+	GroupCanEdit   bool   `json:"groupCanEdit"`
+}
+
+// Insert turns the snapshot back into the item it describes, in listId
+func (s ItemSnapshot) Insert(listId uint64) ItemInsert {
+	item := ItemInsert{
+		Name:      s.Name,
+		ListId:    listId,
+		URL:       sql.NullString{String: s.URL, Valid: s.URL != ""},
+		Notes:     sql.NullString{String: s.Notes, Valid: s.Notes != ""},
+		Quantity:  s.Quantity,
+		Fulfilled: s.Fulfilled,
+		Currency:  sql.NullString{String: s.Currency, Valid: s.Currency != ""},
+		Tags:      s.Tags,
+	}
+	if s.Priority != nil {
+		item.Priority = sql.NullInt64{Int64: *s.Priority, Valid: true}
+	}
+	if s.SectionId != nil {
+		item.SectionId = sql.NullInt64{Int64: *s.SectionId, Valid: true}
+	}
+	if s.Price != nil {
+		item.Price = sql.NullInt64{Int64: *s.Price, Valid: true}
+	}
+	return item
+}
+
+// Params turns the snapshot back into the list details it describes
+func (s ListSnapshot) Params() ListPostParams {
+	return ListPostParams{Name: s.Name, Description: s.Description, ShareWithGroup: s.ShareWithGroup, GroupCanEdit: s.GroupCanEdit}
+}
+
+// DiffItems lists the fields that differ between two snapshots of an item; before is nil for a
+// created item and after for a deleted one
+func DiffItems(before *ItemSnapshot, after *ItemSnapshot) []FieldChange {
+	return diffFields(itemFields(before), itemFields(after))
+}
+
+// DiffLists lists the fields that differ between two snapshots of a list's details
+func DiffLists(before *ListSnapshot, after *ListSnapshot) []FieldChange {
+	return diffFields(listFields(before), listFields(after))
+}
+
+// itemFields renders a snapshot as field name and value pairs, always the same fields in the same order
+func itemFields(s *ItemSnapshot) [][2]string {
+	if s == nil {
+		s = &ItemSnapshot{}
+	}
+	price := ""
+	if s.Price != nil {
+		price = FormatPrice(*s.Price, s.Currency)
+	}
+	return [][2]string{
+		{"Name", s.Name},
+		{"URL", s.URL},
+		{"Notes", s.Notes},
+		{"Priority", formatOptionalInt(s.Priority)},
+		{"Section", s.Section},
+		{"Quantity", formatCount(s.Quantity)},
+		{"Fulfilled", formatCount(s.Fulfilled)},
+		{"Price", price},
+		{"Tags", strings.Join(s.Tags, ", ")},
+	}
+}
+
+func listFields(s *ListSnapshot) [][2]string {
+	if s == nil {
+		s = &ListSnapshot{}
+	}
+	return [][2]string{
+		{"Name", s.Name},
+		{"Description", s.Description},
+		{"Shared with group", formatYesNo(s.ShareWithGroup)},
+		{"Group can edit", formatYesNo(s.GroupCanEdit)},
+	}
+}
+
+func diffFields(before [][2]string, after [][2]string) []FieldChange {
+	var changes []FieldChange
+	for i := range before {
+		if before[i][1] != after[i][1] {
+			changes = append(changes, FieldChange{Field: before[i][0], Before: before[i][1], After: after[i][1]})
+		}
+	}
+	return changes
+}
+
+func formatOptionalInt(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+// formatCount leaves zero empty, so a created item doesn't list "Fulfilled: 0"
+func formatCount(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func formatYesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
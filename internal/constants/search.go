@@ -0,0 +1,41 @@
+package constants
+
+import "database/sql"
+
+// MaxSearchResults caps how many lists, items and collections a search returns, each
+const MaxSearchResults = 50
+
+// SearchResults are what a full-text search found among what the user can view, best matches first
+type SearchResults struct {
+	Lists       []SearchList
+	Items       []SearchItem
+	Collections []Collection
+}
+
+// SearchList is a list found by search: one of the user's own, or one shared with their group
+type SearchList struct {
+	Id          uint64
+	Name        string
+	Description sql.NullString
+	Tags        []string
+	OwnerId     uint64
+	OwnerName   string
+	Owned       bool // whether the searching user owns the list
+}
+
+// SearchItem is an item found by search, with the list it is on
+type SearchItem struct {
+	Id       uint64
+	Name     string
+	URL      sql.NullString
+	Notes    sql.NullString
+	Tags     []string
+	ListId   uint64
+	ListName string
+	Owned    bool // whether the searching user owns the list
+}
+
+// Empty reports whether the search found nothing at all
+func (s SearchResults) Empty() bool {
+	return len(s.Lists) == 0 && len(s.Items) == 0 && len(s.Collections) == 0
+}
@@ -0,0 +1,50 @@
+package constants
+
+import (
+	"strings"
+	"time"
+)
+
+// Session is one signed-in browser, as listed on the sessions page
+type Session struct {
+	Id         uint64
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	Current    bool // Whether this is the session viewing the page
+}
+
+// Device names the browser and operating system from the user agent, e.g. "Firefox on Windows"
+func (s Session) Device() string {
+	ua := s.UserAgent
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"), strings.Contains(ua, "FxiOS/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	}
+	// Check the mobile systems first: their user agents also mention Linux or Mac OS X
+	switch {
+	case strings.Contains(ua, "Android"):
+		return browser + " on Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		return browser + " on iOS"
+	case strings.Contains(ua, "Windows"):
+		return browser + " on Windows"
+	case strings.Contains(ua, "Mac OS X"):
+		return browser + " on macOS"
+	case strings.Contains(ua, "CrOS"):
+		return browser + " on ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		return browser + " on Linux"
+	}
+	return browser
+}
@@ -0,0 +1,73 @@
+package constants
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tags are free-form labels on lists and items. They are stored lowercased with their
+// whitespace collapsed, so "Kitchen " and "kitchen" are the same tag.
+
+const (
+	// MaxTags caps how many tags one list or item can carry
+	MaxTags = 20
+	// MaxTagLength caps a tag's length, in characters
+	MaxTagLength = 32
+)
+
+// Tag is one entry of a user's tag index: how many of their lists and items carry it
+type Tag struct {
+	Name      string `json:"name"`
+	ListCount int    `json:"listCount"`
+	ItemCount int    `json:"itemCount"`
+}
+
+// TaggedItem is an item found by tag across several lists, with the list it is on
+type TaggedItem struct {
+	Item
+	ListId   uint64 `json:"listId"`
+	ListName string `json:"listName"`
+}
+
+// NormalizeTag lowercases a tag and collapses its whitespace; an empty result means no tag
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// NormalizeTags normalizes each tag, dropping blanks and repeats while keeping the first-seen order.
+// It returns an error if a tag is too long or has a comma, or if there are more than MaxTags.
+// The result is never nil, so it always stores as an empty array rather than NULL.
+func NormalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tags can be at most %d characters", MaxTagLength)
+		}
+		if strings.Contains(tag, ",") {
+			return nil, errors.New("tags can't contain commas")
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > MaxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", MaxTags)
+	}
+	return result, nil
+}
+
+// ParseTags reads comma-separated tags as typed into a form, e.g. "kitchen, Gifts"
+func ParseTags(text string) ([]string, error) {
+	return NormalizeTags(strings.Split(text, ","))
+}
+
+// TagsText renders tags back into the comma-separated form ParseTags reads
+func TagsText(tags []string) string {
+	return strings.Join(tags, ", ")
+}
@@ -0,0 +1,17 @@
+package constants
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ListTemplate is a starting point for new lists that a group's admins offer everyone in the group.
+// It keeps its own copy of the items it was made with.
+type ListTemplate struct {
+	Id          uint64
+	GroupId     int
+	Name        string
+	Description sql.NullString
+	ItemCount   int
+	CreatedAt   time.Time
+}
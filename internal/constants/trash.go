@@ -0,0 +1,37 @@
+package constants
+
+import "time"
+
+// Trash is what a user has deleted and can still restore, most recently deleted first.
+// Items only appear on their own while their list is live; those of a trashed list come back with it.
+type Trash struct {
+	Lists       []TrashedList
+	Items       []TrashedItem
+	Collections []TrashedCollection
+}
+
+type TrashedList struct {
+	Id        uint64
+	Name      string
+	ItemCount int // items that come back with the list
+	DeletedAt time.Time
+}
+
+type TrashedItem struct {
+	Id        uint64
+	Name      string
+	ListId    uint64
+	ListName  string
+	DeletedAt time.Time
+}
+
+type TrashedCollection struct {
+	Id        uint64
+	Name      string
+	DeletedAt time.Time
+}
+
+// Empty reports whether there is nothing in the trash
+func (t Trash) Empty() bool {
+	return len(t.Lists) == 0 && len(t.Items) == 0 && len(t.Collections) == 0
+}
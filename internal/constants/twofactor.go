@@ -0,0 +1,18 @@
+package constants
+
+import "database/sql"
+
+// TwoFactorStatus is where a user stands with two-factor authentication
+type TwoFactorStatus struct {
+	Enabled           bool
+	EnabledAt         sql.NullTime
+	RecoveryCodesLeft int
+	Required          bool // Whether the user's group requires it
+}
+
+// TwoFactorSetup is what an authenticator app needs to add the account
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`    // otpauth:// URI the QR code encodes
+	QRCode string `json:"qrCode"` // PNG data URL of the QR code
+}
@@ -1,6 +1,11 @@
 package constants
 
-import "database/sql"
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"unicode"
+)
 
 type UserRead struct {
 	Id            uint64
@@ -21,36 +26,220 @@ type UserRegister struct {
 }
 
 type List struct {
-	Id             uint64
-	Name           string
-	Description    sql.NullString
-	ShareCode      sql.NullString
-	ShareWithGroup bool
-	GroupCanEdit   bool
-	ItemCount      int // Number of items in the list
+	Id               uint64
+	Name             string
+	Description      sql.NullString
+	ShareCode        sql.NullString
+	ShareWithGroup   bool
+	GroupCanEdit     bool
+	Claimable        bool           // Whether share link visitors can claim items
+	CompletedDisplay string         // One of the COMPLETED_DISPLAY consts
+	ItemCount        int            // Number of items in the list
+	OutstandingCount int            // Items not yet completed and short of their quantity
+	Budget           sql.NullInt64  // In minor units of BudgetCurrency
+	BudgetCurrency   sql.NullString // ISO 4217 code, set whenever Budget is
+	Tags             []string       // normalized, see NormalizeTags
+}
+
+// BudgetText renders the budget with its currency, or an empty string if the list has none
+func (l List) BudgetText() string {
+	if !l.Budget.Valid {
+		return ""
+	}
+	return FormatPrice(l.Budget.Int64, l.BudgetCurrency.String)
 }
 
 type ListPostParams struct {
-	Name           string `json:"name"`
+	Name           string `json:"name" openapi:"required,nonempty"`
 	Description    string `json:"description"`
 	ShareWithGroup bool   `json:"shareWithGroup"`
 	GroupCanEdit   bool   `json:"groupCanEdit"`
 }
 
+// ListCopy says how to copy a list into a new one
+type ListCopy struct {
+	Name          string
+	CopySharing   bool // keep the group sharing and gift claiming settings
+	SkipCompleted bool // leave out checked-off items, as a share link that hides them does
+}
+
 type ItemInsert struct {
-	Name     string
-	ListId   uint64
-	URL      sql.NullString
-	Notes    sql.NullString
-	Priority sql.NullInt64
+	Name      string
+	ListId    uint64
+	URL       sql.NullString
+	Notes     sql.NullString
+	Priority  sql.NullInt64
+	SectionId sql.NullInt64
+	Quantity  int            // how many are wanted, at least 1
+	Fulfilled int            // how many editors have marked as already covered
+	Price     sql.NullInt64  // per unit, in minor units of Currency
+	Currency  sql.NullString // ISO 4217 code, set whenever Price is
+	Tags      []string       // normalized, see NormalizeTags
 }
 
 type Item struct {
-	Id       uint64         `json:"id"`
-	Name     string         `json:"name"`
-	URL      sql.NullString `json:"url"`
-	Priority sql.NullInt64  `json:"priority"`
-	Notes    sql.NullString `json:"notes"`
+	Id        uint64         `json:"id"`
+	Name      string         `json:"name"`
+	URL       sql.NullString `json:"url"`
+	Priority  sql.NullInt64  `json:"priority"`
+	Notes     sql.NullString `json:"notes"`
+	Position  int            `json:"position"` // 1-based place in the list's custom order
+	SectionId sql.NullInt64  `json:"sectionId"`
+	// Checked off in checklist mode; CompletedByName is only filled in by GetListItems
+	CompletedAt     sql.NullTime     `json:"completedAt"`
+	CompletedBy     sql.NullInt64    `json:"completedBy"`
+	CompletedByName sql.NullString   `json:"completedByName"`
+	Quantity        int              `json:"quantity"`    // how many are wanted, at least 1
+	Fulfilled       int              `json:"fulfilled"`   // how many editors have marked as already covered
+	Contributed     int              `json:"contributed"` // how many share link visitors have pledged
+	Price           sql.NullInt64    `json:"price"`       // per unit, in minor units of Currency
+	Currency        sql.NullString   `json:"currency"`    // ISO 4217 code, set whenever Price is
+	Preview         *LinkPreview     `json:"preview"`     // what the URL's page says about itself, once fetched; only filled in by GetListItems
+	Attachments     []ItemAttachment `json:"attachments"` // uploaded images, oldest first
+	Tags            []string         `json:"tags"`
+}
+
+// PriceText renders the unit price with its currency, or an empty string if the item has none
+func (i Item) PriceText() string {
+	if !i.Price.Valid {
+		return ""
+	}
+	return FormatPrice(i.Price.Int64, i.Currency.String)
+}
+
+// PreviewDetails are the parts of the item's link preview worth showing beside it: the page's title,
+// unless the item is already named after it, and its price and availability
+func (i Item) PreviewDetails() []string {
+	if i.Preview == nil {
+		return nil
+	}
+	var details []string
+	if i.Preview.Title.Valid && !strings.EqualFold(i.Preview.Title.String, strings.TrimSpace(i.Name)) {
+		details = append(details, i.Preview.Title.String)
+	}
+	if i.Preview.Price.Valid {
+		details = append(details, i.Preview.PriceText())
+	}
+	if i.Preview.Availability.Valid {
+		details = append(details, i.Preview.AvailabilityText())
+	}
+	return details
+}
+
+// Completed reports whether the item has been checked off
+func (i Item) Completed() bool {
+	return i.CompletedAt.Valid
+}
+
+// StillNeeded is how many of the item nobody has covered or pledged yet
+func (i Item) StillNeeded() int {
+	return max(i.Quantity-i.Fulfilled-i.Contributed, 0)
+}
+
+// LinkPreview is the product data found on an item URL's page
+type LinkPreview struct {
+	URL          string         `json:"url"`
+	Title        sql.NullString `json:"title"`
+	ImageURL     sql.NullString `json:"imageUrl"`
+	Price        sql.NullInt64  `json:"price"`        // in minor units of Currency
+	Currency     sql.NullString `json:"currency"`     // ISO 4217 code, set whenever Price is
+	Availability sql.NullString `json:"availability"` // a schema.org ItemAvailability term, e.g. InStock
+	FetchedAt    time.Time      `json:"fetchedAt"`
+}
+
+// Empty reports whether the page had nothing worth showing
+func (p LinkPreview) Empty() bool {
+	return !p.Title.Valid && !p.ImageURL.Valid && !p.Price.Valid && !p.Availability.Valid
+}
+
+// PriceText renders the page's price with its currency, or an empty string if it has none
+func (p LinkPreview) PriceText() string {
+	if !p.Price.Valid {
+		return ""
+	}
+	return FormatPrice(p.Price.Int64, p.Currency.String)
+}
+
+// AvailabilityText spells out the availability term, e.g. InStock as "In stock"
+func (p LinkPreview) AvailabilityText() string {
+	var b strings.Builder
+	for i, c := range p.Availability.String {
+		if i > 0 && unicode.IsUpper(c) {
+			b.WriteRune(' ')
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// ItemAttachmentInsert is an uploaded image, already checked, with the thumbnail made from it
+type ItemAttachmentInsert struct {
+	Filename    sql.NullString
+	ContentType string // detected from the data
+	Extension   string // for the storage key, e.g. "jpg"
+	Width       int
+	Height      int
+	Data        []byte
+	Thumbnail   []byte // a JPEG
+}
+
+// ItemAttachment is an image uploaded to an item. The file and its thumbnail live in the attachment
+// store; the keys are random, so knowing one is what grants access to it.
+type ItemAttachment struct {
+	Id           uint64         `json:"id"`
+	ItemId       uint64         `json:"itemId"`
+	StorageKey   string         `json:"storageKey"`   // served at URL()
+	ThumbnailKey string         `json:"thumbnailKey"` // served at ThumbnailURL()
+	ContentType  string         `json:"contentType"`
+	Filename     sql.NullString `json:"filename"` // as uploaded, for display only
+	SizeBytes    int64          `json:"sizeBytes"`
+	Width        int            `json:"width"`
+	Height       int            `json:"height"`
+	UploadedAt   time.Time      `json:"uploadedAt"`
+}
+
+// URL is where the full image is served
+func (a ItemAttachment) URL() string {
+	return "/" + ATTACHMENT_PATH + "/" + a.StorageKey
+}
+
+// ThumbnailURL is where the thumbnail is served
+func (a ItemAttachment) ThumbnailURL() string {
+	return "/" + ATTACHMENT_PATH + "/" + a.ThumbnailKey
+}
+
+// PriceTotal sums price × quantity over the priced items of a list or collection in one currency
+type PriceTotal struct {
+	Currency    string `json:"currency"`
+	Items       int    `json:"items"`       // priced items counted
+	Total       int64  `json:"total"`       // every priced item, in minor units
+	StillNeeded int64  `json:"stillNeeded"` // only what nobody has covered or pledged yet, ignoring completed items
+}
+
+// ItemContribution records a share link visitor pledging part of an item's quantity
+type ItemContribution struct {
+	Id              uint64
+	ItemId          uint64
+	ContributorName sql.NullString
+	Quantity        int
+	ContributedAt   time.Time
+}
+
+// ItemSection is a named heading within a list that items can be grouped under
+type ItemSection struct {
+	Id       uint64 `json:"id"`
+	ListId   uint64 `json:"listId"`
+	Name     string `json:"name"`
+	Position int    `json:"position"` // 1-based place among the list's sections
+}
+
+// ItemClaim records that a share link visitor has reserved an item
+type ItemClaim struct {
+	ItemId       uint64
+	ClaimerName  sql.NullString
+	ClaimerEmail sql.NullString
+	ClaimedAt    time.Time
 }
 
 type Collection struct {
@@ -61,7 +250,7 @@ type Collection struct {
 }
 
 type CollectionPostParams struct {
-	Name        string `json:"name"`
+	Name        string `json:"name" openapi:"required,nonempty"`
 	Description string `json:"description"`
 }
 
@@ -73,6 +262,7 @@ type ListSharedWithGroup struct {
 	OwnerId      uint64
 	OwnerName    string
 	GroupCanEdit bool
+	Tags         []string
 }
 
 // ListWithAuthor extends List with author information for collection displays
@@ -82,7 +272,29 @@ type ListWithAuthor struct {
 	Description sql.NullString
 	ShareCode   sql.NullString
 	ItemCount   int
-	AuthorId    uint64
-	AuthorName  string
-	CanEdit     bool // Whether the current user can edit this list
+	// Items not yet completed and short of their quantity
+	OutstandingCount int
+	AuthorId         uint64
+	AuthorName       string
+	CanEdit          bool // Whether the current user can edit this list
+	// How the list shows completed items; only filled in by GetCollectionLists
+	CompletedDisplay string
+	Tags             []string
+}
+
+// MigrationStatus describes one embedded schema migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt sql.NullTime
+}
+
+// ApiToken describes a personal access token without its secret, which is only ever shown once at creation
+type ApiToken struct {
+	Id         uint64
+	Name       string
+	Scope      string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
 }
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	_ "github.com/lib/pq"
+)
+
+// CreateApiToken issues a new personal access token for a user.
+// Only a hash is stored, so the returned token can never be retrieved again.
+func (repo *Repository) CreateApiToken(ctx context.Context, userId int, name string, scope string) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := constants.API_TOKEN_PREFIX + hex.EncodeToString(tokenBytes)
+
+	_, err := repo.db.ExecContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_API_TOKEN+` (userid, name, token_hash, scope, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userId, name, hashApiToken(token), scope, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetApiTokens lists a user's personal access tokens, newest first
+func (repo *Repository) GetApiTokens(ctx context.Context, userId int) ([]constants.ApiToken, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, name, scope, created_at, last_used_at FROM "+constants.DB_TABLE_API_TOKEN+" WHERE userid = $1 ORDER BY created_at DESC", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []constants.ApiToken
+	for rows.Next() {
+		var t constants.ApiToken
+		err := rows.Scan(&t.Id, &t.Name, &t.Scope, &t.CreatedAt, &t.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// DeleteApiToken revokes one of a user's tokens and returns its name. Returns false if the user has no such token.
+func (repo *Repository) DeleteApiToken(ctx context.Context, userId int, tokenId int) (string, bool, error) {
+	var name string
+	err := repo.db.QueryRowContext(ctx, "DELETE FROM "+constants.DB_TABLE_API_TOKEN+" WHERE id = $1 AND userid = $2 RETURNING name", tokenId, userId).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return name, true, nil
+}
+
+// AuthenticateApiToken resolves a presented token to its owner and scope, recording when it was last used.
+// Returns a userId of -1 if the token is unknown.
+func (repo *Repository) AuthenticateApiToken(ctx context.Context, token string) (int, string, error) {
+	var userId int
+	var scope string
+	err := repo.db.QueryRowContext(ctx, `
+		UPDATE `+constants.DB_TABLE_API_TOKEN+` SET last_used_at = $1
+		WHERE token_hash = $2
+		RETURNING userid, scope
+	`, time.Now(), hashApiToken(token)).Scan(&userId, &scope)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, "", nil
+		}
+		return -1, "", err
+	}
+	return userId, scope, nil
+}
+
+// Tokens are 256 bits of randomness, so a fast unsalted hash is sufficient and allows lookup by hash
+func hashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
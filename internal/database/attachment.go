@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/storage"
+	_ "github.com/lib/pq"
+)
+
+// MaxAttachmentsPerItem caps how many images one item can carry
+const MaxAttachmentsPerItem = 10
+
+// ErrTooManyAttachments is returned by AddItemAttachment when the item already has MaxAttachmentsPerItem images
+var ErrTooManyAttachments = errors.New("item already has the maximum number of images")
+
+// SetAttachmentStore sets where attachment files are kept. It must be set before the server starts.
+func (repo *Repository) SetAttachmentStore(store storage.Store) {
+	repo.attachments = store
+}
+
+const attachmentColumns = "a.id, a.itemid, a.storage_key, a.thumbnail_key, a.content_type, a.filename, a.size_bytes, a.width, a.height, a.uploaded_at"
+
+func scanAttachment(rows *sql.Rows) (constants.ItemAttachment, error) {
+	var a constants.ItemAttachment
+	err := rows.Scan(&a.Id, &a.ItemId, &a.StorageKey, &a.ThumbnailKey, &a.ContentType, &a.Filename, &a.SizeBytes, &a.Width, &a.Height, &a.UploadedAt)
+	return a, err
+}
+
+// attachmentKey makes a random storage key such as 3f9c…e1.jpg
+func attachmentKey(suffix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + suffix, nil
+}
+
+// AddItemAttachment stores an image and its thumbnail and records them against an item. The files
+// are written first and removed again if the row can't be saved, so a row never points at nothing.
+func (repo *Repository) AddItemAttachment(ctx context.Context, itemId int, upload constants.ItemAttachmentInsert) (constants.ItemAttachment, error) {
+	if repo.attachments == nil {
+		return constants.ItemAttachment{}, errors.New("no attachment store configured")
+	}
+	count, err := repo.CountItemAttachments(ctx, itemId)
+	if err != nil {
+		return constants.ItemAttachment{}, err
+	}
+	if count >= MaxAttachmentsPerItem {
+		return constants.ItemAttachment{}, ErrTooManyAttachments
+	}
+
+	storageKey, err := attachmentKey("." + upload.Extension)
+	if err != nil {
+		return constants.ItemAttachment{}, err
+	}
+	thumbnailKey, err := attachmentKey(".thumb.jpg")
+	if err != nil {
+		return constants.ItemAttachment{}, err
+	}
+	if err := repo.attachments.Put(ctx, storageKey, upload.Data, upload.ContentType); err != nil {
+		return constants.ItemAttachment{}, err
+	}
+	if err := repo.attachments.Put(ctx, thumbnailKey, upload.Thumbnail, "image/jpeg"); err != nil {
+		repo.removeAttachmentFiles(storageKey)
+		return constants.ItemAttachment{}, err
+	}
+
+	attachment := constants.ItemAttachment{
+		ItemId:       uint64(itemId),
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+		ContentType:  upload.ContentType,
+		Filename:     upload.Filename,
+		SizeBytes:    int64(len(upload.Data)),
+		Width:        upload.Width,
+		Height:       upload.Height,
+	}
+	err = repo.db.QueryRowContext(ctx, `INSERT INTO `+constants.DB_TABLE_ATTACHMENT+` (itemid, storage_key, thumbnail_key, content_type, filename, size_bytes, width, height, uploaded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW()) RETURNING id, uploaded_at`,
+		itemId, storageKey, thumbnailKey, attachment.ContentType, attachment.Filename, attachment.SizeBytes, attachment.Width, attachment.Height).Scan(&attachment.Id, &attachment.UploadedAt)
+	if err != nil {
+		repo.removeAttachmentFiles(storageKey, thumbnailKey)
+		return constants.ItemAttachment{}, err
+	}
+	return attachment, nil
+}
+
+func (repo *Repository) CountItemAttachments(ctx context.Context, itemId int) (int, error) {
+	var count int
+	err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+constants.DB_TABLE_ATTACHMENT+" WHERE itemid = $1", itemId).Scan(&count)
+	return count, err
+}
+
+// GetItemAttachments returns an item's images, oldest first
+func (repo *Repository) GetItemAttachments(ctx context.Context, itemId int) ([]constants.ItemAttachment, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT "+attachmentColumns+" FROM "+constants.DB_TABLE_ATTACHMENT+" a WHERE a.itemid = $1 ORDER BY a.id", itemId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []constants.ItemAttachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// getListAttachments returns the images of every item in a list, keyed by item id
+func (repo *Repository) getListAttachments(ctx context.Context, listId int) (map[uint64][]constants.ItemAttachment, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM `+constants.DB_TABLE_ATTACHMENT+` a
+		JOIN `+constants.DB_TABLE_ITEM+` i ON i.id = a.itemid
+		WHERE i.listid = $1
+		ORDER BY a.id`, listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make(map[uint64][]constants.ItemAttachment)
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments[a.ItemId] = append(attachments[a.ItemId], a)
+	}
+	return attachments, rows.Err()
+}
+
+// DeleteItemAttachment removes one of an item's images and its files. It returns false if the item has no such image.
+func (repo *Repository) DeleteItemAttachment(ctx context.Context, itemId int, attachmentId int) (bool, error) {
+	var storageKey, thumbnailKey string
+	err := repo.db.QueryRowContext(ctx, "DELETE FROM "+constants.DB_TABLE_ATTACHMENT+" WHERE id = $1 AND itemid = $2 RETURNING storage_key, thumbnail_key", attachmentId, itemId).Scan(&storageKey, &thumbnailKey)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	repo.removeAttachmentFiles(storageKey, thumbnailKey)
+	return true, nil
+}
+
+// queryer is what deleteAttachmentRows needs from either the pool or a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// deleteAttachmentRows deletes the attachment rows matched by where (a condition on itemid) and returns
+// the keys of their files, to be removed with removeAttachmentFiles once the deletion has committed
+func deleteAttachmentRows(ctx context.Context, q queryer, where string, args ...any) ([]string, error) {
+	rows, err := q.QueryContext(ctx, "DELETE FROM "+constants.DB_TABLE_ATTACHMENT+" WHERE "+where+" RETURNING storage_key, thumbnail_key", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var storageKey, thumbnailKey string
+		if err := rows.Scan(&storageKey, &thumbnailKey); err != nil {
+			return nil, err
+		}
+		keys = append(keys, storageKey, thumbnailKey)
+	}
+	return keys, rows.Err()
+}
+
+// removeAttachmentFiles deletes files from the attachment store. Its rows are already gone, so a
+// failure only leaves an unreachable file behind; it is logged rather than returned.
+func (repo *Repository) removeAttachmentFiles(keys ...string) {
+	if repo.attachments == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := repo.attachments.Delete(context.Background(), key); err != nil {
+			log.Printf("could not remove attachment %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/storage"
+)
+
+// attachmentKeys are an image and its thumbnail, as stored for one attachment
+var attachmentKeys = []string{"0a1b2c3d.png", "0a1b2c3d.thumb.jpg"}
+
+// newAttachmentRepo returns a repository on a mock database whose attachment store, a local
+// directory, already holds attachmentKeys
+func newAttachmentRepo(t *testing.T) (*Repository, sqlmock.Sqlmock, string) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range attachmentKeys {
+		if err := store.Put(context.Background(), key, []byte("image"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := NewRepository(db)
+	repo.SetAttachmentStore(store)
+	return repo, mock, dir
+}
+
+// deleteFrom matches a DELETE from table, and not from a table whose name starts with table's
+func deleteFrom(table string) string {
+	return "DELETE FROM " + regexp.QuoteMeta(table) + " WHERE"
+}
+
+// expectItemPurge expects purgeItems' statements, the attachment rows giving up attachmentKeys
+func expectItemPurge(mock sqlmock.Sqlmock, itemsPurged int64) {
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_ITEM_CLAIM)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_CONTRIBUTION)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_REVISION)).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(deleteFrom(constants.DB_TABLE_ATTACHMENT) + ".* RETURNING storage_key, thumbnail_key").
+		WillReturnRows(sqlmock.NewRows([]string{"storage_key", "thumbnail_key"}).AddRow(attachmentKeys[0], attachmentKeys[1]))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_ITEM)).WillReturnResult(sqlmock.NewResult(0, itemsPurged))
+}
+
+func assertStored(t *testing.T, dir string, want bool) {
+	t.Helper()
+	for _, key := range attachmentKeys {
+		_, err := os.Stat(filepath.Join(dir, key))
+		if want && err != nil {
+			t.Errorf("%s was removed: %v", key, err)
+		}
+		if !want && !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s is still stored", key)
+		}
+	}
+}
+
+func TestDeleteItemKeepsAttachmentsInTrash(t *testing.T) {
+	repo, mock, dir := newAttachmentRepo(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT i.listid, i.name").WithArgs(5).WillReturnRows(
+		sqlmock.NewRows([]string{"listid", "name", "url", "notes", "priority", "sectionid", "section", "quantity", "fulfilled", "price", "currency", "tags"}).
+			AddRow(3, "Mug", nil, nil, nil, nil, nil, 1, 0, nil, nil, "{}"))
+	mock.ExpectExec("UPDATE listaway.item SET deleted_at = \\$1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO " + regexp.QuoteMeta(constants.DB_TABLE_REVISION)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.DeleteItem(context.Background(), 1, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	// The item can still be restored, so its images stay until it is purged
+	assertStored(t, dir, true)
+}
+
+func TestDeleteListKeepsAttachmentsInTrash(t *testing.T) {
+	repo, mock, dir := newAttachmentRepo(t)
+	mock.ExpectExec("UPDATE listaway.list SET deleted_at = \\$1").WithArgs(sqlmock.AnyArg(), 3, "Kitchen").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	deleted, err := repo.DeleteList(context.Background(), 3, "Kitchen")
+	if err != nil || !deleted {
+		t.Fatalf("DeleteList: %v, %v", deleted, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	assertStored(t, dir, true)
+}
+
+func TestPurgeTrashedItemRemovesAttachments(t *testing.T) {
+	repo, mock, dir := newAttachmentRepo(t)
+	mock.ExpectBegin()
+	expectItemPurge(mock, 1)
+	mock.ExpectCommit()
+
+	purged, err := repo.PurgeTrashedItem(context.Background(), 1, 5)
+	if err != nil || !purged {
+		t.Fatalf("PurgeTrashedItem: %v, %v", purged, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	assertStored(t, dir, false)
+}
+
+func TestPurgeTrashedListRemovesAttachments(t *testing.T) {
+	repo, mock, dir := newAttachmentRepo(t)
+	mock.ExpectBegin()
+	expectItemPurge(mock, 4)
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_ITEM_SECTION)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_COLLECTION_LIST)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_REVISION)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_LIST)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	purged, err := repo.PurgeTrashedList(context.Background(), 1, 3)
+	if err != nil || !purged {
+		t.Fatalf("PurgeTrashedList: %v, %v", purged, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	assertStored(t, dir, false)
+}
+
+func TestFailedPurgeKeepsAttachments(t *testing.T) {
+	repo, mock, dir := newAttachmentRepo(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_ITEM_CLAIM)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_CONTRIBUTION)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_REVISION)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(deleteFrom(constants.DB_TABLE_ATTACHMENT)).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_key", "thumbnail_key"}).AddRow(attachmentKeys[0], attachmentKeys[1]))
+	mock.ExpectExec(deleteFrom(constants.DB_TABLE_ITEM)).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	if _, err := repo.PurgeTrashedItem(context.Background(), 1, 5); err == nil {
+		t.Fatal("PurgeTrashedItem succeeded")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	// The rows are rolled back, so they still point at the files
+	assertStored(t, dir, true)
+}
+
+func TestDeleteItemAttachmentRemovesFiles(t *testing.T) {
+	repo, mock, dir := newAttachmentRepo(t)
+	mock.ExpectQuery(deleteFrom(constants.DB_TABLE_ATTACHMENT)).WithArgs(9, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_key", "thumbnail_key"}).AddRow(attachmentKeys[0], attachmentKeys[1]))
+
+	deleted, err := repo.DeleteItemAttachment(context.Background(), 5, 9)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteItemAttachment: %v, %v", deleted, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	assertStored(t, dir, false)
+}
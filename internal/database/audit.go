@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// RecordAudit appends an event to the audit log, stamped with the current time
+func (repo *Repository) RecordAudit(ctx context.Context, event constants.AuditEvent) error {
+	_, err := repo.db.ExecContext(ctx, "INSERT INTO "+constants.DB_TABLE_AUDIT_LOG+" (actorid, actor, targetid, target, groupid, action, detail, ip, user_agent, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		event.ActorId, event.Actor, event.TargetId, event.Target, event.GroupId, event.Action,
+		sql.NullString{String: event.Detail, Valid: event.Detail != ""},
+		sql.NullString{String: event.IP, Valid: event.IP != ""},
+		sql.NullString{String: event.UserAgent, Valid: event.UserAgent != ""},
+		time.Now())
+	return err
+}
+
+// GetAuditEvents returns the audit log events matching filter, newest first
+func (repo *Repository) GetAuditEvents(ctx context.Context, filter constants.AuditFilter) ([]constants.AuditEvent, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT id, actorid, actor, targetid, target, groupid, action, COALESCE(detail, ''), COALESCE(ip, ''), COALESCE(user_agent, ''), created_at
+		FROM `+constants.DB_TABLE_AUDIT_LOG+`
+		WHERE ($1::bigint IS NULL OR groupid = $1)
+		  AND ($2 = '' OR action = $2)
+		  AND ($3 = '' OR STRPOS(LOWER(actor), LOWER($3)) > 0 OR STRPOS(LOWER(target), LOWER($3)) > 0)
+		  AND ($4::timestamp IS NULL OR created_at >= $4)
+		  AND ($5::timestamp IS NULL OR created_at < $5)
+		ORDER BY id DESC
+		LIMIT $6`,
+		filter.GroupId, filter.Action, filter.User, optionalTime(filter.From), optionalTime(filter.Until),
+		sql.NullInt64{Int64: int64(filter.Limit), Valid: filter.Limit > 0})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []constants.AuditEvent
+	for rows.Next() {
+		var e constants.AuditEvent
+		err := rows.Scan(&e.Id, &e.ActorId, &e.Actor, &e.TargetId, &e.Target, &e.GroupId, &e.Action, &e.Detail, &e.IP, &e.UserAgent, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// optionalTime is SQL NULL for the zero time
+func optionalTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,496 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/backup"
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/lib/pq"
+)
+
+// backupTables are the tables a backup covers. API tokens, password reset tokens, sessions,
+// two-factor secrets and passkeys are credentials tied to the old instance and are deliberately
+// left out. Link previews are only a cache of other sites' pages and are fetched again as items
+// are saved. Attachments are backed up as rows only; their files stay in the attachment store.
+// Revision history is left out because reverting a change puts back the section ids its snapshots
+// hold, and a restore gives sections new ids. The audit log stays behind as the record of the old
+// instance, whose events name users by ids a restore rewrites. A restored instance starts both afresh.
+var backupTables = []string{
+	constants.DB_TABLE_GROUP_SETTINGS,
+	constants.DB_TABLE_USER,
+	constants.DB_TABLE_LIST,
+	constants.DB_TABLE_ITEM_SECTION,
+	constants.DB_TABLE_ITEM,
+	constants.DB_TABLE_ITEM_CLAIM,
+	constants.DB_TABLE_CONTRIBUTION,
+	constants.DB_TABLE_ATTACHMENT,
+	constants.DB_TABLE_COLLECTION,
+	constants.DB_TABLE_COLLECTION_LIST,
+	constants.DB_TABLE_TEMPLATE,
+	constants.DB_TABLE_TEMPLATE_ITEM,
+}
+
+// Backup reads the whole instance from a single consistent snapshot.
+// Rows left behind by a deleted parent (an item whose list is gone, say) are not included.
+func (repo *Repository) Backup(ctx context.Context) (backup.Archive, error) {
+	tx, err := repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return backup.Archive{}, err
+	}
+	defer tx.Rollback()
+
+	archive := backup.Archive{Format: backup.FormatName, Version: backup.Version, CreatedAt: time.Now().UTC()}
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM "+constants.DB_TABLE_MIGRATIONS).Scan(&archive.SchemaVersion); err != nil {
+		return backup.Archive{}, err
+	}
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		return backup.Archive{}, err
+	}
+	if archive.SchemaVersion < latest {
+		return backup.Archive{}, fmt.Errorf("the database has pending migrations (at version %d of %d); run `listaway migrate up` first", archive.SchemaVersion, latest)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT u.groupid, COALESCE(gs.group_sharing_enabled, false), COALESCE(gs.two_factor_required, false)
+		FROM (SELECT DISTINCT groupid FROM `+constants.DB_TABLE_USER+`) u
+		LEFT JOIN `+constants.DB_TABLE_GROUP_SETTINGS+` gs ON gs.groupid = u.groupid
+		ORDER BY u.groupid`,
+		func(rows *sql.Rows) error {
+			var g backup.Group
+			err := rows.Scan(&g.Id, &g.SharingEnabled, &g.TwoFactorRequired)
+			archive.Groups = append(archive.Groups, g)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading groups: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT id, groupid, email, name, passwordhash, admin, instanceadmin, oidc_provider, oidc_subject, oidc_email
+		FROM `+constants.DB_TABLE_USER+` ORDER BY id`,
+		func(rows *sql.Rows) error {
+			var u backup.User
+			err := rows.Scan(&u.Id, &u.GroupId, &u.Email, &u.Name, &u.PasswordHash, &u.Admin, &u.InstanceAdmin, &u.OIDCProvider, &u.OIDCSubject, &u.OIDCEmail)
+			archive.Users = append(archive.Users, u)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading users: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT l.id, l.userid, l.name, l.description, l.sharecode, l.share_with_group, l.group_can_edit, l.claimable, l.completed_display, l.budget, l.budget_currency, l.tags, l.deleted_at
+		FROM `+constants.DB_TABLE_LIST+` l
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = l.userid
+		ORDER BY l.id`,
+		func(rows *sql.Rows) error {
+			var l backup.List
+			err := rows.Scan(&l.Id, &l.UserId, &l.Name, &l.Description, &l.ShareCode, &l.ShareWithGroup, &l.GroupCanEdit, &l.Claimable, &l.CompletedDisplay, &l.Budget, &l.BudgetCurrency, pq.Array(&l.Tags), &l.DeletedAt)
+			archive.Lists = append(archive.Lists, l)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading lists: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT s.id, s.listid, s.name, s.position
+		FROM `+constants.DB_TABLE_ITEM_SECTION+` s
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = s.listid
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = l.userid
+		ORDER BY s.id`,
+		func(rows *sql.Rows) error {
+			var s backup.ItemSection
+			err := rows.Scan(&s.Id, &s.ListId, &s.Name, &s.Position)
+			archive.ItemSections = append(archive.ItemSections, s)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading item sections: %v", err)
+	}
+
+	// An item pointing at a section of another list, or one that is gone, is backed up outside any section.
+	// Likewise an item completed by a since-deleted user keeps its completion time but loses who completed it.
+	err = queryEach(ctx, tx, `
+		SELECT i.id, i.listid, i.name, i.url, i.notes, i.priority, i.position, s.id, i.completed_at, cu.id, i.quantity, i.fulfilled, i.price, i.currency, i.tags, i.deleted_at
+		FROM `+constants.DB_TABLE_ITEM+` i
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = i.listid
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = l.userid
+		LEFT JOIN `+constants.DB_TABLE_ITEM_SECTION+` s ON s.id = i.sectionid AND s.listid = i.listid
+		LEFT JOIN `+constants.DB_TABLE_USER+` cu ON cu.id = i.completed_by
+		ORDER BY i.id`,
+		func(rows *sql.Rows) error {
+			var i backup.Item
+			err := rows.Scan(&i.Id, &i.ListId, &i.Name, &i.URL, &i.Notes, &i.Priority, &i.Position, &i.SectionId, &i.CompletedAt, &i.CompletedBy, &i.Quantity, &i.Fulfilled, &i.Price, &i.Currency, pq.Array(&i.Tags), &i.DeletedAt)
+			archive.Items = append(archive.Items, i)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading items: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT c.itemid, c.claimer_name, c.claimer_email, c.claim_token, c.claimed_at
+		FROM `+constants.DB_TABLE_ITEM_CLAIM+` c
+		JOIN `+constants.DB_TABLE_ITEM+` i ON i.id = c.itemid
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = i.listid
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = l.userid
+		ORDER BY c.itemid`,
+		func(rows *sql.Rows) error {
+			var c backup.ItemClaim
+			err := rows.Scan(&c.ItemId, &c.ClaimerName, &c.ClaimerEmail, &c.ClaimToken, &c.ClaimedAt)
+			archive.ItemClaims = append(archive.ItemClaims, c)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading item claims: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT c.itemid, c.contributor_name, c.quantity, c.contribution_token, c.contributed_at
+		FROM `+constants.DB_TABLE_CONTRIBUTION+` c
+		JOIN `+constants.DB_TABLE_ITEM+` i ON i.id = c.itemid
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = i.listid
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = l.userid
+		ORDER BY c.id`,
+		func(rows *sql.Rows) error {
+			var c backup.ItemContribution
+			err := rows.Scan(&c.ItemId, &c.ContributorName, &c.Quantity, &c.ContributionToken, &c.ContributedAt)
+			archive.ItemContributions = append(archive.ItemContributions, c)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading item contributions: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT a.itemid, a.storage_key, a.thumbnail_key, a.content_type, a.filename, a.size_bytes, a.width, a.height, a.uploaded_at
+		FROM `+constants.DB_TABLE_ATTACHMENT+` a
+		JOIN `+constants.DB_TABLE_ITEM+` i ON i.id = a.itemid
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = i.listid
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = l.userid
+		ORDER BY a.id`,
+		func(rows *sql.Rows) error {
+			var at backup.ItemAttachment
+			err := rows.Scan(&at.ItemId, &at.StorageKey, &at.ThumbnailKey, &at.ContentType, &at.Filename, &at.SizeBytes, &at.Width, &at.Height, &at.UploadedAt)
+			archive.ItemAttachments = append(archive.ItemAttachments, at)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading item attachments: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT c.id, c.userid, c.name, c.description, c.sharecode, c.deleted_at
+		FROM `+constants.DB_TABLE_COLLECTION+` c
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = c.userid
+		ORDER BY c.id`,
+		func(rows *sql.Rows) error {
+			var c backup.Collection
+			err := rows.Scan(&c.Id, &c.UserId, &c.Name, &c.Description, &c.ShareCode, &c.DeletedAt)
+			archive.Collections = append(archive.Collections, c)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading collections: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT cl.collectionid, cl.listid
+		FROM `+constants.DB_TABLE_COLLECTION_LIST+` cl
+		JOIN `+constants.DB_TABLE_COLLECTION+` c ON c.id = cl.collectionid
+		JOIN `+constants.DB_TABLE_USER+` cu ON cu.id = c.userid
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = cl.listid
+		JOIN `+constants.DB_TABLE_USER+` lu ON lu.id = l.userid
+		ORDER BY cl.collectionid, cl.listid`,
+		func(rows *sql.Rows) error {
+			var cl backup.CollectionList
+			err := rows.Scan(&cl.CollectionId, &cl.ListId)
+			archive.CollectionLists = append(archive.CollectionLists, cl)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading collection lists: %v", err)
+	}
+
+	// Templates of a group that no longer has any users are left behind, like the group itself
+	err = queryEach(ctx, tx, `
+		SELECT t.id, t.groupid, t.name, t.description, t.created_at
+		FROM `+constants.DB_TABLE_TEMPLATE+` t
+		WHERE t.groupid IN (SELECT groupid FROM `+constants.DB_TABLE_USER+`)
+		ORDER BY t.id`,
+		func(rows *sql.Rows) error {
+			var t backup.Template
+			err := rows.Scan(&t.Id, &t.GroupId, &t.Name, &t.Description, &t.CreatedAt)
+			archive.Templates = append(archive.Templates, t)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading templates: %v", err)
+	}
+
+	err = queryEach(ctx, tx, `
+		SELECT ti.templateid, ti.name, ti.url, ti.notes, ti.priority, ti.quantity, ti.price, ti.currency, ti.position
+		FROM `+constants.DB_TABLE_TEMPLATE_ITEM+` ti
+		JOIN `+constants.DB_TABLE_TEMPLATE+` t ON t.id = ti.templateid
+		WHERE t.groupid IN (SELECT groupid FROM `+constants.DB_TABLE_USER+`)
+		ORDER BY ti.templateid, ti.position, ti.id`,
+		func(rows *sql.Rows) error {
+			var ti backup.TemplateItem
+			err := rows.Scan(&ti.TemplateId, &ti.Name, &ti.URL, &ti.Notes, &ti.Priority, &ti.Quantity, &ti.Price, &ti.Currency, &ti.Position)
+			archive.TemplateItems = append(archive.TemplateItems, ti)
+			return err
+		})
+	if err != nil {
+		return backup.Archive{}, fmt.Errorf("error reading template items: %v", err)
+	}
+
+	return archive, archive.Validate()
+}
+
+// Restore loads an archive into an empty database. Every row gets a new id, and references
+// between rows are rewritten to match; groups are renumbered from 1. Share codes, claim and contribution tokens,
+// password hashes and OIDC links are kept, so share links and logins keep working.
+// Nothing is written unless the whole archive restores cleanly.
+func (repo *Repository) Restore(ctx context.Context, archive backup.Archive) error {
+	if err := archive.Validate(); err != nil {
+		return err
+	}
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		return err
+	}
+	if archive.SchemaVersion > latest {
+		return fmt.Errorf("backup was taken from a newer version of Listaway (schema version %d, this build has %d); upgrade before restoring", archive.SchemaVersion, latest)
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range backupTables {
+		if _, err := tx.ExecContext(ctx, "LOCK TABLE "+table+" IN EXCLUSIVE MODE"); err != nil {
+			return err
+		}
+		var hasRows bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+")").Scan(&hasRows); err != nil {
+			return err
+		}
+		if hasRows {
+			return fmt.Errorf("%s already has data; backups can only be restored into an empty database", table)
+		}
+	}
+
+	groupIds := make(map[int]int, len(archive.Groups))
+	for i, g := range archive.Groups {
+		groupIds[g.Id] = i + 1
+		_, err := tx.ExecContext(ctx, "INSERT INTO "+constants.DB_TABLE_GROUP_SETTINGS+" (groupid, group_sharing_enabled, two_factor_required) VALUES ($1, $2, $3)", i+1, g.SharingEnabled, g.TwoFactorRequired)
+		if err != nil {
+			return fmt.Errorf("error restoring group %d: %v", g.Id, err)
+		}
+	}
+
+	userIds := make(map[int64]int64, len(archive.Users))
+	for _, u := range archive.Users {
+		var id int64
+		err := tx.QueryRowContext(ctx, `INSERT INTO `+constants.DB_TABLE_USER+` (groupid, email, name, passwordhash, admin, instanceadmin, oidc_provider, oidc_subject, oidc_email)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+			groupIds[u.GroupId], u.Email, u.Name, u.PasswordHash, u.Admin, u.InstanceAdmin, u.OIDCProvider, u.OIDCSubject, u.OIDCEmail).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error restoring user %d: %v", u.Id, err)
+		}
+		userIds[u.Id] = id
+	}
+
+	listIds := make(map[int64]int64, len(archive.Lists))
+	for _, l := range archive.Lists {
+		completedDisplay := l.CompletedDisplay
+		if completedDisplay == "" {
+			completedDisplay = constants.COMPLETED_DISPLAY_STRIKE
+		} else if !slices.Contains(constants.COMPLETED_DISPLAYS, completedDisplay) {
+			return fmt.Errorf("list %d has unknown completed display %q", l.Id, completedDisplay)
+		}
+		budgetCurrency := l.BudgetCurrency
+		if l.Budget == nil {
+			budgetCurrency = nil
+		}
+		tags, err := constants.NormalizeTags(l.Tags)
+		if err != nil {
+			return fmt.Errorf("list %d has invalid tags: %v", l.Id, err)
+		}
+		var id int64
+		err = tx.QueryRowContext(ctx, `INSERT INTO `+constants.DB_TABLE_LIST+` (userid, name, description, sharecode, share_with_group, group_can_edit, claimable, completed_display, budget, budget_currency, tags, deleted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+			userIds[l.UserId], l.Name, l.Description, l.ShareCode, l.ShareWithGroup, l.GroupCanEdit, l.Claimable, completedDisplay, l.Budget, budgetCurrency, pq.Array(tags), l.DeletedAt).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error restoring list %d: %v", l.Id, err)
+		}
+		listIds[l.Id] = id
+	}
+
+	sectionIds := make(map[int64]int64, len(archive.ItemSections))
+	for _, s := range archive.ItemSections {
+		var id int64
+		err := tx.QueryRowContext(ctx, `INSERT INTO `+constants.DB_TABLE_ITEM_SECTION+` (listid, name, position) VALUES ($1, $2, $3) RETURNING id`,
+			listIds[s.ListId], s.Name, s.Position).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error restoring section %d: %v", s.Id, err)
+		}
+		sectionIds[s.Id] = id
+	}
+
+	itemIds := make(map[int64]int64, len(archive.Items))
+	for _, i := range archive.Items {
+		var sectionId, completedBy *int64
+		if i.SectionId != nil {
+			id := sectionIds[*i.SectionId]
+			sectionId = &id
+		}
+		if i.CompletedBy != nil {
+			id := userIds[*i.CompletedBy]
+			completedBy = &id
+		}
+		quantity, fulfilled := itemQuantities(constants.ItemInsert{Quantity: i.Quantity, Fulfilled: i.Fulfilled})
+		currency := i.Currency
+		if i.Price == nil {
+			currency = nil
+		}
+		tags, err := constants.NormalizeTags(i.Tags)
+		if err != nil {
+			return fmt.Errorf("item %d has invalid tags: %v", i.Id, err)
+		}
+		var id int64
+		err = tx.QueryRowContext(ctx, `INSERT INTO `+constants.DB_TABLE_ITEM+` (listid, name, url, notes, priority, position, sectionid, completed_at, completed_by, quantity, fulfilled, price, currency, tags, deleted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id`,
+			listIds[i.ListId], i.Name, i.URL, i.Notes, i.Priority, i.Position, sectionId, i.CompletedAt, completedBy, quantity, fulfilled, i.Price, currency, pq.Array(tags), i.DeletedAt).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error restoring item %d: %v", i.Id, err)
+		}
+		itemIds[i.Id] = id
+	}
+	// Items were inserted in id order, so this keeps the backed-up order and fills in positions
+	// missing from older archives
+	_, err = tx.ExecContext(ctx, `
+		UPDATE `+constants.DB_TABLE_ITEM+` i SET position = numbered.position
+		FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY listid ORDER BY position, id) AS position FROM `+constants.DB_TABLE_ITEM+`) numbered
+		WHERE i.id = numbered.id`)
+	if err != nil {
+		return fmt.Errorf("error numbering restored items: %v", err)
+	}
+
+	for _, c := range archive.ItemClaims {
+		_, err := tx.ExecContext(ctx, `INSERT INTO `+constants.DB_TABLE_ITEM_CLAIM+` (itemid, claimer_name, claimer_email, claim_token, claimed_at) VALUES ($1, $2, $3, $4, $5)`,
+			itemIds[c.ItemId], c.ClaimerName, c.ClaimerEmail, c.ClaimToken, c.ClaimedAt)
+		if err != nil {
+			return fmt.Errorf("error restoring claim on item %d: %v", c.ItemId, err)
+		}
+	}
+
+	for _, c := range archive.ItemContributions {
+		_, err := tx.ExecContext(ctx, `INSERT INTO `+constants.DB_TABLE_CONTRIBUTION+` (itemid, contributor_name, quantity, contribution_token, contributed_at) VALUES ($1, $2, $3, $4, $5)`,
+			itemIds[c.ItemId], c.ContributorName, c.Quantity, c.ContributionToken, c.ContributedAt)
+		if err != nil {
+			return fmt.Errorf("error restoring contribution to item %d: %v", c.ItemId, err)
+		}
+	}
+
+	for _, at := range archive.ItemAttachments {
+		_, err := tx.ExecContext(ctx, `INSERT INTO `+constants.DB_TABLE_ATTACHMENT+` (itemid, storage_key, thumbnail_key, content_type, filename, size_bytes, width, height, uploaded_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			itemIds[at.ItemId], at.StorageKey, at.ThumbnailKey, at.ContentType, at.Filename, at.SizeBytes, at.Width, at.Height, at.UploadedAt)
+		if err != nil {
+			return fmt.Errorf("error restoring attachment %s on item %d: %v", at.StorageKey, at.ItemId, err)
+		}
+	}
+
+	collectionIds := make(map[int64]int64, len(archive.Collections))
+	for _, c := range archive.Collections {
+		var id int64
+		err := tx.QueryRowContext(ctx, `INSERT INTO `+constants.DB_TABLE_COLLECTION+` (userid, name, description, sharecode, deleted_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			userIds[c.UserId], c.Name, c.Description, c.ShareCode, c.DeletedAt).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error restoring collection %d: %v", c.Id, err)
+		}
+		collectionIds[c.Id] = id
+	}
+
+	for _, cl := range archive.CollectionLists {
+		_, err := tx.ExecContext(ctx, `INSERT INTO `+constants.DB_TABLE_COLLECTION_LIST+` (collectionid, listid) VALUES ($1, $2)`,
+			collectionIds[cl.CollectionId], listIds[cl.ListId])
+		if err != nil {
+			return fmt.Errorf("error restoring list %d in collection %d: %v", cl.ListId, cl.CollectionId, err)
+		}
+	}
+
+	templateIds := make(map[int64]int64, len(archive.Templates))
+	for _, t := range archive.Templates {
+		var id int64
+		err := tx.QueryRowContext(ctx, `INSERT INTO `+constants.DB_TABLE_TEMPLATE+` (groupid, name, description, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+			groupIds[t.GroupId], t.Name, t.Description, t.CreatedAt).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error restoring template %d: %v", t.Id, err)
+		}
+		templateIds[t.Id] = id
+	}
+
+	for _, ti := range archive.TemplateItems {
+		quantity, _ := itemQuantities(constants.ItemInsert{Quantity: ti.Quantity})
+		currency := ti.Currency
+		if ti.Price == nil {
+			currency = nil
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO `+constants.DB_TABLE_TEMPLATE_ITEM+` (templateid, name, url, notes, priority, quantity, price, currency, position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			templateIds[ti.TemplateId], ti.Name, ti.URL, ti.Notes, ti.Priority, quantity, ti.Price, currency, ti.Position)
+		if err != nil {
+			return fmt.Errorf("error restoring item %q of template %d: %v", ti.Name, ti.TemplateId, err)
+		}
+	}
+
+	expected := map[string]int{
+		constants.DB_TABLE_GROUP_SETTINGS:  len(archive.Groups),
+		constants.DB_TABLE_USER:            len(archive.Users),
+		constants.DB_TABLE_LIST:            len(archive.Lists),
+		constants.DB_TABLE_ITEM_SECTION:    len(archive.ItemSections),
+		constants.DB_TABLE_ITEM:            len(archive.Items),
+		constants.DB_TABLE_ITEM_CLAIM:      len(archive.ItemClaims),
+		constants.DB_TABLE_CONTRIBUTION:    len(archive.ItemContributions),
+		constants.DB_TABLE_ATTACHMENT:      len(archive.ItemAttachments),
+		constants.DB_TABLE_COLLECTION:      len(archive.Collections),
+		constants.DB_TABLE_COLLECTION_LIST: len(archive.CollectionLists),
+		constants.DB_TABLE_TEMPLATE:        len(archive.Templates),
+		constants.DB_TABLE_TEMPLATE_ITEM:   len(archive.TemplateItems),
+	}
+	for _, table := range backupTables {
+		var count int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			return err
+		}
+		if count != expected[table] {
+			return fmt.Errorf("integrity check failed: %s has %d rows after restoring, expected %d", table, count, expected[table])
+		}
+	}
+	return tx.Commit()
+}
+
+// queryEach runs a query inside tx and calls scan once per row
+func queryEach(ctx context.Context, tx *sql.Tx, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
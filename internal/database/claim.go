@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	_ "github.com/lib/pq"
+)
+
+// ClaimItem reserves an item for a share link visitor.
+// Returns the token needed to release the claim later, or an empty string if the item was already claimed.
+func (repo *Repository) ClaimItem(ctx context.Context, itemId int, claimerName string, claimerEmail string) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	result, err := repo.db.ExecContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_ITEM_CLAIM+` (itemid, claimer_name, claimer_email, claim_token, claimed_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (itemid) DO NOTHING
+	`, itemId, sql.NullString{String: claimerName, Valid: claimerName != ""}, sql.NullString{String: claimerEmail, Valid: claimerEmail != ""}, token, time.Now())
+	if err != nil {
+		return "", err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if rowsAffected == 0 {
+		return "", nil
+	}
+	return token, nil
+}
+
+// UnclaimItem releases a claim, provided the token matches the one handed out by ClaimItem
+func (repo *Repository) UnclaimItem(ctx context.Context, itemId int, token string) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_ITEM_CLAIM+" WHERE itemid = $1 AND claim_token = $2", itemId, token)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected != 0, nil
+}
+
+// DeleteItemClaim removes any claim on an item regardless of who made it
+func (repo *Repository) DeleteItemClaim(ctx context.Context, itemId int) error {
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_ITEM_CLAIM+" WHERE itemid = $1", itemId)
+	return err
+}
+
+// GetClaimedItemIds returns the ids of all claimed items in a list, without revealing who claimed them
+func (repo *Repository) GetClaimedItemIds(ctx context.Context, listId int) ([]uint64, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT c.itemid
+		FROM `+constants.DB_TABLE_ITEM_CLAIM+` c
+		JOIN `+constants.DB_TABLE_ITEM+` i ON c.itemid = i.id
+		WHERE i.listid = $1
+	`, listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var itemIds []uint64
+	for rows.Next() {
+		var id uint64
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		itemIds = append(itemIds, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return itemIds, nil
+}
+
+// GetListItemClaims returns the full claim details for a list, keyed by item id
+func (repo *Repository) GetListItemClaims(ctx context.Context, listId int) (map[uint64]constants.ItemClaim, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT c.itemid, c.claimer_name, c.claimer_email, c.claimed_at
+		FROM `+constants.DB_TABLE_ITEM_CLAIM+` c
+		JOIN `+constants.DB_TABLE_ITEM+` i ON c.itemid = i.id
+		WHERE i.listid = $1
+	`, listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	claims := make(map[uint64]constants.ItemClaim)
+	for rows.Next() {
+		var c constants.ItemClaim
+		err := rows.Scan(&c.ItemId, &c.ClaimerName, &c.ClaimerEmail, &c.ClaimedAt)
+		if err != nil {
+			return nil, err
+		}
+		claims[c.ItemId] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
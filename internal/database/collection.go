@@ -1,18 +1,18 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
 	"github.com/jeffrpowell/listaway/internal/constants/random"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // GetCollections retrieves all collections owned by a specific user
-func GetCollections(userId int) ([]constants.Collection, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	rows, err := db.Query("SELECT id, name, description, sharecode FROM listaway.collection WHERE userid = $1", userId)
+func (repo *Repository) GetCollections(ctx context.Context, userId int) ([]constants.Collection, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, name, description, sharecode FROM listaway.collection WHERE userid = $1 AND deleted_at IS NULL", userId)
 	if err != nil {
 		return nil, err
 	}
@@ -35,10 +35,8 @@ func GetCollections(userId int) ([]constants.Collection, error) {
 }
 
 // CollectionNameTaken checks if a collection name already exists for a user
-func CollectionNameTaken(userId int, name string) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT COUNT(1) FROM listaway.collection WHERE userid = $1 AND name = $2", userId, name)
+func (repo *Repository) CollectionNameTaken(ctx context.Context, userId int, name string) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM listaway.collection WHERE userid = $1 AND name = $2 AND deleted_at IS NULL", userId, name)
 	var matches int
 	err := row.Scan(&matches)
 	if err != nil {
@@ -48,11 +46,9 @@ func CollectionNameTaken(userId int, name string) (bool, error) {
 }
 
 // CreateCollection creates a new collection for a user
-func CreateCollection(userId int, name string, description string) (int, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) CreateCollection(ctx context.Context, userId int, name string, description string) (int, error) {
 	var newId int
-	err := db.QueryRow(`INSERT INTO listaway.collection (userid, name, description) VALUES($1, $2, $3) RETURNING id`, userId, name, description).Scan(&newId)
+	err := repo.db.QueryRowContext(ctx, `INSERT INTO listaway.collection (userid, name, description) VALUES($1, $2, $3) RETURNING id`, userId, name, description).Scan(&newId)
 	if err != nil {
 		return 0, err
 	}
@@ -60,10 +56,8 @@ func CreateCollection(userId int, name string, description string) (int, error)
 }
 
 // UserOwnsCollection checks if a user owns a specific collection
-func UserOwnsCollection(userId int, collectionId int) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT COUNT(1) FROM listaway.collection WHERE userid = $1 AND id = $2", userId, collectionId)
+func (repo *Repository) UserOwnsCollection(ctx context.Context, userId int, collectionId int) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM listaway.collection WHERE userid = $1 AND id = $2 AND deleted_at IS NULL", userId, collectionId)
 	var matches int
 	err := row.Scan(&matches)
 	if err != nil {
@@ -73,10 +67,8 @@ func UserOwnsCollection(userId int, collectionId int) (bool, error) {
 }
 
 // GetCollection retrieves a collection by its ID
-func GetCollection(collectionId int) (constants.Collection, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT id, name, description, sharecode FROM listaway.collection WHERE id = $1", collectionId)
+func (repo *Repository) GetCollection(ctx context.Context, collectionId int) (constants.Collection, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT id, name, description, sharecode FROM listaway.collection WHERE id = $1", collectionId)
 	var collection constants.Collection
 	err := row.Scan(&collection.Id, &collection.Name, &collection.Description, &collection.ShareCode)
 	if err != nil {
@@ -86,31 +78,27 @@ func GetCollection(collectionId int) (constants.Collection, error) {
 }
 
 // UpdateCollection updates a collection's details
-func UpdateCollection(collectionId int, params constants.CollectionPostParams) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`UPDATE listaway.collection SET name = $1, description = $2 WHERE id = $3`, params.Name, params.Description, collectionId)
+func (repo *Repository) UpdateCollection(ctx context.Context, collectionId int, params constants.CollectionPostParams) error {
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.collection SET name = $1, description = $2 WHERE id = $3`, params.Name, params.Description, collectionId)
 	return err
 }
 
-// DeleteCollection deletes a collection after confirming the name matches
-func DeleteCollection(collectionId int, confirmationName string) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	matches, err := confirmNameMatchesCollectionName(db, collectionId, confirmationName)
+// DeleteCollection moves a collection to its owner's trash after confirming the name matches
+func (repo *Repository) DeleteCollection(ctx context.Context, collectionId int, confirmationName string) (bool, error) {
+	matches, err := confirmNameMatchesCollectionName(ctx, repo.db, collectionId, confirmationName)
 	if err != nil {
 		return false, err
 	}
 	if !matches {
 		return false, nil
 	}
-	_, err = db.Exec(`DELETE FROM listaway.collection WHERE id = $1 AND name = $2`, collectionId, confirmationName)
+	_, err = repo.db.ExecContext(ctx, `UPDATE listaway.collection SET deleted_at = $1 WHERE id = $2 AND name = $3`, time.Now(), collectionId, confirmationName)
 	return true, err
 }
 
 // confirmNameMatchesCollectionName verifies the provided name matches the collection name
-func confirmNameMatchesCollectionName(db *sql.DB, collectionId int, confirmationName string) (bool, error) {
-	row := db.QueryRow("SELECT COUNT(1) FROM listaway.collection WHERE id = $1 AND name = $2", collectionId, confirmationName)
+func confirmNameMatchesCollectionName(ctx context.Context, db *sql.DB, collectionId int, confirmationName string) (bool, error) {
+	row := db.QueryRowContext(ctx, "SELECT COUNT(1) FROM listaway.collection WHERE id = $1 AND name = $2", collectionId, confirmationName)
 	var matches int
 	err := row.Scan(&matches)
 	if err != nil {
@@ -121,16 +109,13 @@ func confirmNameMatchesCollectionName(db *sql.DB, collectionId int, confirmation
 
 // GenerateCollectionShareCode generates a unique share code for a collection
 // and ensures all lists within the collection have share codes as well
-func GenerateCollectionShareCode(collectionId int) (string, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-
+func (repo *Repository) GenerateCollectionShareCode(ctx context.Context, collectionId int) (string, error) {
 	// First, get all lists in this collection that don't have share codes yet
-	rows, err := db.Query(`
+	rows, err := repo.db.QueryContext(ctx, `
 		SELECT l.id 
 		FROM listaway.list l
 		JOIN listaway.collection_list cl ON l.id = cl.listid
-		WHERE cl.collectionid = $1 AND l.sharecode IS NULL
+		WHERE cl.collectionid = $1 AND l.sharecode IS NULL AND l.deleted_at IS NULL
 	`, collectionId)
 
 	if err != nil {
@@ -147,7 +132,7 @@ func GenerateCollectionShareCode(collectionId int) (string, error) {
 		}
 		
 		// Generate a share code for this list
-		_, err = GenerateShareCode(listId)
+		_, err = repo.GenerateShareCode(ctx, listId)
 		if err != nil {
 			return "", err
 		}
@@ -158,16 +143,16 @@ func GenerateCollectionShareCode(collectionId int) (string, error) {
 	}
 
 	// Now generate the collection share code
-	code, err := createUniqueCollectionShareCode(db)
+	code, err := createUniqueCollectionShareCode(ctx, repo.db)
 	if err != nil {
 		return "", err
 	}
-	_, err = db.Exec(`UPDATE listaway.collection SET sharecode = $1 WHERE id = $2`, code, collectionId)
+	_, err = repo.db.ExecContext(ctx, `UPDATE listaway.collection SET sharecode = $1 WHERE id = $2`, code, collectionId)
 	return code, err
 }
 
 // createUniqueCollectionShareCode creates a unique share code for collections
-func createUniqueCollectionShareCode(db *sql.DB) (string, error) {
+func createUniqueCollectionShareCode(ctx context.Context, db *sql.DB) (string, error) {
 	var code string
 	var err error
 	var count int
@@ -180,14 +165,14 @@ func createUniqueCollectionShareCode(db *sql.DB) (string, error) {
 		}
 
 		// Check if the generated code already exists in the database for collections
-		row := db.QueryRow(`SELECT COUNT(1) FROM listaway.collection WHERE sharecode = $1`, code)
+		row := db.QueryRowContext(ctx, `SELECT COUNT(1) FROM listaway.collection WHERE sharecode = $1`, code)
 		err = row.Scan(&count)
 		if err != nil {
 			return "", err
 		}
 
 		// Also check if the code exists for lists to avoid collisions
-		row = db.QueryRow(`SELECT COUNT(1) FROM listaway.list WHERE sharecode = $1`, code)
+		row = db.QueryRowContext(ctx, `SELECT COUNT(1) FROM listaway.list WHERE sharecode = $1`, code)
 		var listCount int
 		err = row.Scan(&listCount)
 		if err != nil {
@@ -204,10 +189,8 @@ func createUniqueCollectionShareCode(db *sql.DB) (string, error) {
 }
 
 // GetCollectionFromShareCode retrieves a collection by its share code
-func GetCollectionFromShareCode(shareCode string) (constants.Collection, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT id, name, description, sharecode FROM listaway.collection WHERE sharecode = $1", shareCode)
+func (repo *Repository) GetCollectionFromShareCode(ctx context.Context, shareCode string) (constants.Collection, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT id, name, description, sharecode FROM listaway.collection WHERE sharecode = $1 AND deleted_at IS NULL", shareCode)
 	var collection constants.Collection
 	err := row.Scan(&collection.Id, &collection.Name, &collection.Description, &collection.ShareCode)
 	if err != nil {
@@ -217,43 +200,34 @@ func GetCollectionFromShareCode(shareCode string) (constants.Collection, error)
 }
 
 // UnpublishCollectionShareCode removes the share code from a collection
-func UnpublishCollectionShareCode(collectionId int) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`UPDATE listaway.collection SET sharecode = NULL WHERE id = $1`, collectionId)
+func (repo *Repository) UnpublishCollectionShareCode(ctx context.Context, collectionId int) error {
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.collection SET sharecode = NULL WHERE id = $1`, collectionId)
 	return err
 }
 
 // AddListToCollection adds a list to a collection
-func AddListToCollection(collectionId int, listId int) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`INSERT INTO listaway.collection_list (collectionid, listid) VALUES($1, $2) 
+func (repo *Repository) AddListToCollection(ctx context.Context, collectionId int, listId int) error {
+	_, err := repo.db.ExecContext(ctx, `INSERT INTO listaway.collection_list (collectionid, listid) VALUES($1, $2) 
 	ON CONFLICT (collectionid, listid) DO NOTHING`, collectionId, listId)
 	return err
 }
 
 // RemoveListFromCollection removes a list from a collection
-func RemoveListFromCollection(collectionId int, listId int) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`DELETE FROM listaway.collection_list WHERE collectionid = $1 AND listid = $2`, collectionId, listId)
+func (repo *Repository) RemoveListFromCollection(ctx context.Context, collectionId int, listId int) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM listaway.collection_list WHERE collectionid = $1 AND listid = $2`, collectionId, listId)
 	return err
 }
 
 // GetCollectionLists retrieves all lists in a collection with item counts and author information
-func GetCollectionLists(collectionId int) ([]constants.ListWithAuthor, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-
-	rows, err := db.Query(`
+func (repo *Repository) GetCollectionLists(ctx context.Context, collectionId int) ([]constants.ListWithAuthor, error) {
+	rows, err := repo.db.QueryContext(ctx, `
 		SELECT l.id, l.name, l.description, l.sharecode, 
-		       (SELECT COUNT(i.id) FROM listaway.item i WHERE i.listid = l.id) as item_count,
-		       l.userid, u.name as author_name
+		       `+listItemCount+` as item_count, `+listOutstandingCount+` as outstanding_count,
+		       l.userid, u.name as author_name, l.completed_display, l.tags
 		FROM listaway.list l
 		JOIN listaway.collection_list cl ON l.id = cl.listid
 		JOIN listaway.user u ON l.userid = u.id
-		WHERE cl.collectionid = $1
+		WHERE cl.collectionid = $1 AND l.deleted_at IS NULL
 		ORDER BY l.name ASC
 	`, collectionId)
 
@@ -266,7 +240,7 @@ func GetCollectionLists(collectionId int) ([]constants.ListWithAuthor, error) {
 	for rows.Next() {
 		var cl constants.ListWithAuthor
 
-		err := rows.Scan(&cl.Id, &cl.Name, &cl.Description, &cl.ShareCode, &cl.ItemCount, &cl.AuthorId, &cl.AuthorName)
+		err := rows.Scan(&cl.Id, &cl.Name, &cl.Description, &cl.ShareCode, &cl.ItemCount, &cl.OutstandingCount, &cl.AuthorId, &cl.AuthorName, &cl.CompletedDisplay, pq.Array(&cl.Tags))
 		if err != nil {
 			return nil, err
 		}
@@ -279,15 +253,12 @@ func GetCollectionLists(collectionId int) ([]constants.ListWithAuthor, error) {
 }
 
 // GetCollectionListIds retrieves all list ids in a collection
-func GetCollectionListIds(collectionId int) ([]uint64, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-
-	rows, err := db.Query(`
+func (repo *Repository) GetCollectionListIds(ctx context.Context, collectionId int) ([]uint64, error) {
+	rows, err := repo.db.QueryContext(ctx, `
 		SELECT l.id 
 		FROM listaway.list l
 		JOIN listaway.collection_list cl ON l.id = cl.listid
-		WHERE cl.collectionid = $1
+		WHERE cl.collectionid = $1 AND l.deleted_at IS NULL
 		ORDER BY l.name ASC
 	`, collectionId)
 
@@ -313,10 +284,8 @@ func GetCollectionListIds(collectionId int) ([]uint64, error) {
 }
 
 // ListInCollection checks if a list is already in a collection
-func ListInCollection(collectionId int, listId int) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT COUNT(1) FROM listaway.collection_list WHERE collectionid = $1 AND listid = $2", collectionId, listId)
+func (repo *Repository) ListInCollection(ctx context.Context, collectionId int, listId int) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM listaway.collection_list WHERE collectionid = $1 AND listid = $2", collectionId, listId)
 	var matches int
 	err := row.Scan(&matches)
 	if err != nil {
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	_ "github.com/lib/pq"
+)
+
+// Contribute pledges quantity of an item on behalf of a share link visitor.
+// Returns the token needed to withdraw the contribution later, or an empty string if fewer than quantity are still needed.
+func (repo *Repository) Contribute(ctx context.Context, itemId int, contributorName string, quantity int) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// Lock the item so two visitors can't both take the last few needed
+	var item constants.Item
+	err = tx.QueryRowContext(ctx, "SELECT i.quantity, i.fulfilled, "+itemContributed+" FROM "+constants.DB_TABLE_ITEM+" i WHERE i.id = $1 FOR UPDATE", itemId).Scan(&item.Quantity, &item.Fulfilled, &item.Contributed)
+	if err != nil {
+		return "", err
+	}
+	if quantity > item.StillNeeded() {
+		return "", nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_CONTRIBUTION+` (itemid, contributor_name, quantity, contribution_token, contributed_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, itemId, sql.NullString{String: contributorName, Valid: contributorName != ""}, quantity, token, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// WithdrawContribution removes a contribution, provided the token matches the one handed out by Contribute
+func (repo *Repository) WithdrawContribution(ctx context.Context, itemId int, token string) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_CONTRIBUTION+" WHERE itemid = $1 AND contribution_token = $2", itemId, token)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected != 0, nil
+}
+
+// DeleteContribution removes one contribution to an item regardless of who made it.
+// Returns false if the contribution doesn't belong to the item.
+func (repo *Repository) DeleteContribution(ctx context.Context, itemId int, contributionId int) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_CONTRIBUTION+" WHERE id = $1 AND itemid = $2", contributionId, itemId)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected != 0, nil
+}
+
+// GetListItemContributions returns every contribution in a list, oldest first, keyed by item id
+func (repo *Repository) GetListItemContributions(ctx context.Context, listId int) (map[uint64][]constants.ItemContribution, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT c.id, c.itemid, c.contributor_name, c.quantity, c.contributed_at
+		FROM `+constants.DB_TABLE_CONTRIBUTION+` c
+		JOIN `+constants.DB_TABLE_ITEM+` i ON c.itemid = i.id
+		WHERE i.listid = $1
+		ORDER BY c.contributed_at, c.id
+	`, listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contributions := make(map[uint64][]constants.ItemContribution)
+	for rows.Next() {
+		var c constants.ItemContribution
+		err := rows.Scan(&c.Id, &c.ItemId, &c.ContributorName, &c.Quantity, &c.ContributedAt)
+		if err != nil {
+			return nil, err
+		}
+		contributions[c.ItemId] = append(contributions[c.ItemId], c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return contributions, nil
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// CopyList creates a list for userId with the description, settings, sections and live items of
+// listId, and returns its id. The copy starts afresh: items are unchecked with nothing fulfilled,
+// and it has no share link. Claims, contributions, images and history stay with the original.
+func (repo *Repository) CopyList(ctx context.Context, userId int, listId int, params constants.ListCopy) (int, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var newListId int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_LIST+` (userid, name, description, completed_display, budget, budget_currency, tags, share_with_group, group_can_edit, claimable)
+		SELECT $1, $2, description, completed_display, budget, budget_currency, tags, $3 AND share_with_group, $3 AND group_can_edit, $3 AND claimable
+		FROM `+constants.DB_TABLE_LIST+`
+		WHERE id = $4
+		RETURNING id`, userId, params.Name, params.CopySharing, listId).Scan(&newListId)
+	if err != nil {
+		return 0, err
+	}
+
+	sectionIds, err := copySections(ctx, tx, listId, newListId)
+	if err != nil {
+		return 0, err
+	}
+
+	itemSections, err := copyableItems(ctx, tx, listId, params.SkipCompleted)
+	if err != nil {
+		return 0, err
+	}
+	for _, is := range itemSections {
+		var sectionId sql.NullInt64
+		if newId, ok := sectionIds[int(is.sectionId.Int64)]; ok && is.sectionId.Valid {
+			sectionId = sql.NullInt64{Int64: int64(newId), Valid: true}
+		}
+		var newItemId int
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO `+constants.DB_TABLE_ITEM+` (listid, name, url, notes, priority, position, sectionid, quantity, price, currency, tags)
+			SELECT $1, name, url, notes, priority, position, $2, quantity, price, currency, tags
+			FROM `+constants.DB_TABLE_ITEM+`
+			WHERE id = $3
+			RETURNING id`, newListId, sectionId, is.itemId).Scan(&newItemId)
+		if err != nil {
+			return 0, err
+		}
+		if err := recordItemCreated(ctx, tx, userId, newItemId); err != nil {
+			return 0, err
+		}
+	}
+	return newListId, tx.Commit()
+}
+
+// copySections copies the sections of one list into another, returning the new id of each old one
+func copySections(ctx context.Context, tx *sql.Tx, fromListId int, toListId int) (map[int]int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM "+constants.DB_TABLE_ITEM_SECTION+" WHERE listid = $1 ORDER BY position, id", fromListId)
+	if err != nil {
+		return nil, err
+	}
+	var oldIds []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		oldIds = append(oldIds, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	newIds := make(map[int]int, len(oldIds))
+	for _, oldId := range oldIds {
+		var newId int
+		err := tx.QueryRowContext(ctx, "INSERT INTO "+constants.DB_TABLE_ITEM_SECTION+" (listid, name, position) SELECT $1, name, position FROM "+constants.DB_TABLE_ITEM_SECTION+" WHERE id = $2 RETURNING id", toListId, oldId).
+			Scan(&newId)
+		if err != nil {
+			return nil, err
+		}
+		newIds[oldId] = newId
+	}
+	return newIds, nil
+}
+
+type itemSection struct {
+	itemId    int
+	sectionId sql.NullInt64
+}
+
+// copyableItems lists the live items of a list in order, with their sections
+func copyableItems(ctx context.Context, tx *sql.Tx, listId int, skipCompleted bool) ([]itemSection, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, sectionid
+		FROM `+constants.DB_TABLE_ITEM+`
+		WHERE listid = $1 AND deleted_at IS NULL AND NOT ($2 AND completed_at IS NOT NULL)
+		ORDER BY position, id`, listId, skipCompleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []itemSection
+	for rows.Next() {
+		var is itemSection
+		if err := rows.Scan(&is.itemId, &is.sectionId); err != nil {
+			return nil, err
+		}
+		items = append(items, is)
+	}
+	return items, rows.Err()
+}
@@ -1,19 +1,18 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // GetGroupSharingEnabled returns whether group sharing is enabled for a given group
-func GetGroupSharingEnabled(groupId int) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) GetGroupSharingEnabled(ctx context.Context, groupId int) (bool, error) {
 	
 	var enabled bool
-	err := db.QueryRow("SELECT group_sharing_enabled FROM "+constants.DB_TABLE_GROUP_SETTINGS+" WHERE groupid = $1", groupId).Scan(&enabled)
+	err := repo.db.QueryRowContext(ctx, "SELECT group_sharing_enabled FROM "+constants.DB_TABLE_GROUP_SETTINGS+" WHERE groupid = $1", groupId).Scan(&enabled)
 	
 	if err == sql.ErrNoRows {
 		// Group settings don't exist yet, default is false
@@ -27,12 +26,10 @@ func GetGroupSharingEnabled(groupId int) (bool, error) {
 }
 
 // SetGroupSharingEnabled sets whether group sharing is enabled for a given group
-func SetGroupSharingEnabled(groupId int, enabled bool) error {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) SetGroupSharingEnabled(ctx context.Context, groupId int, enabled bool) error {
 	
 	// Use UPSERT to handle cases where the group settings don't exist yet
-	_, err := db.Exec(`
+	_, err := repo.db.ExecContext(ctx, `
 		INSERT INTO `+constants.DB_TABLE_GROUP_SETTINGS+` (groupid, group_sharing_enabled) 
 		VALUES ($1, $2)
 		ON CONFLICT (groupid) 
@@ -42,17 +39,40 @@ func SetGroupSharingEnabled(groupId int, enabled bool) error {
 	return err
 }
 
+// GetGroupTwoFactorRequired returns whether everyone in a group must use two-factor authentication
+func (repo *Repository) GetGroupTwoFactorRequired(ctx context.Context, groupId int) (bool, error) {
+	var required bool
+	err := repo.db.QueryRowContext(ctx, "SELECT two_factor_required FROM "+constants.DB_TABLE_GROUP_SETTINGS+" WHERE groupid = $1", groupId).Scan(&required)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return required, nil
+}
+
+// SetGroupTwoFactorRequired sets whether everyone in a group must use two-factor authentication
+func (repo *Repository) SetGroupTwoFactorRequired(ctx context.Context, groupId int, required bool) error {
+	_, err := repo.db.ExecContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_GROUP_SETTINGS+` (groupid, two_factor_required)
+		VALUES ($1, $2)
+		ON CONFLICT (groupid)
+		DO UPDATE SET two_factor_required = $2
+	`, groupId, required)
+	return err
+}
+
 // GetListsSharedWithGroup returns all lists shared with a user's group
-// Returns list ID, list name, sharecode, owner user ID, owner name, and whether the group can edit
-func GetListsSharedWithGroup(userId int) ([]constants.ListSharedWithGroup, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
+// Returns list ID, list name, sharecode, owner user ID, owner name, whether the group can edit, and the list's tags
+func (repo *Repository) GetListsSharedWithGroup(ctx context.Context, userId int) ([]constants.ListSharedWithGroup, error) {
 	
-	rows, err := db.Query(`
-		SELECT l.id, l.name, l.description, l.sharecode, l.userid, u.name, l.group_can_edit
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT l.id, l.name, l.description, l.sharecode, l.userid, u.name, l.group_can_edit, l.tags
 		FROM `+constants.DB_TABLE_LIST+` l
 		JOIN `+constants.DB_TABLE_USER+` u ON l.userid = u.id
 		WHERE l.share_with_group = true
+		AND l.deleted_at IS NULL
 		AND u.groupid = (SELECT groupid FROM `+constants.DB_TABLE_USER+` WHERE id = $1)
 		AND l.userid != $1
 		ORDER BY u.name, l.name
@@ -66,7 +86,7 @@ func GetListsSharedWithGroup(userId int) ([]constants.ListSharedWithGroup, error
 	var lists []constants.ListSharedWithGroup
 	for rows.Next() {
 		var list constants.ListSharedWithGroup
-		err := rows.Scan(&list.Id, &list.Name, &list.Description, &list.ShareCode, &list.OwnerId, &list.OwnerName, &list.GroupCanEdit)
+		err := rows.Scan(&list.Id, &list.Name, &list.Description, &list.ShareCode, &list.OwnerId, &list.OwnerName, &list.GroupCanEdit, pq.Array(&list.Tags))
 		if err != nil {
 			return nil, err
 		}
@@ -81,11 +101,9 @@ func GetListsSharedWithGroup(userId int) ([]constants.ListSharedWithGroup, error
 }
 
 // SetListGroupSharing sets the group sharing settings for a list
-func SetListGroupSharing(listId int, shareWithGroup bool, groupCanEdit bool) error {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) SetListGroupSharing(ctx context.Context, listId int, shareWithGroup bool, groupCanEdit bool) error {
 	
-	_, err := db.Exec(`
+	_, err := repo.db.ExecContext(ctx, `
 		UPDATE `+constants.DB_TABLE_LIST+` 
 		SET share_with_group = $1, group_can_edit = $2 
 		WHERE id = $3
@@ -95,11 +113,9 @@ func SetListGroupSharing(listId int, shareWithGroup bool, groupCanEdit bool) err
 }
 
 // GetListGroupSharing returns the group sharing settings for a list
-func GetListGroupSharing(listId int) (shareWithGroup bool, groupCanEdit bool, err error) {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) GetListGroupSharing(ctx context.Context, listId int) (shareWithGroup bool, groupCanEdit bool, err error) {
 	
-	err = db.QueryRow(`
+	err = repo.db.QueryRowContext(ctx, `
 		SELECT share_with_group, group_can_edit 
 		FROM `+constants.DB_TABLE_LIST+` 
 		WHERE id = $1
@@ -114,12 +130,10 @@ func GetListGroupSharing(listId int) (shareWithGroup bool, groupCanEdit bool, er
 
 // UserCanEditList returns true if the user can edit the list
 // This is true if the user owns the list OR if the list is shared with their group with edit permissions
-func UserCanEditList(userId int, listId int) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) UserCanEditList(ctx context.Context, userId int, listId int) (bool, error) {
 	
 	// Check if user owns the list
-	owns, err := UserOwnsList(userId, listId)
+	owns, err := repo.UserOwnsList(ctx, userId, listId)
 	if err != nil {
 		return false, err
 	}
@@ -129,12 +143,13 @@ func UserCanEditList(userId int, listId int) (bool, error) {
 	
 	// Check if the list is shared with the user's group with edit permissions
 	var canEdit bool
-	err = db.QueryRow(`
+	err = repo.db.QueryRowContext(ctx, `
 		SELECT l.group_can_edit
 		FROM `+constants.DB_TABLE_LIST+` l
 		JOIN `+constants.DB_TABLE_USER+` u ON l.userid = u.id
 		WHERE l.id = $1
 		AND l.share_with_group = true
+		AND l.deleted_at IS NULL
 		AND u.groupid = (SELECT groupid FROM `+constants.DB_TABLE_USER+` WHERE id = $2)
 	`, listId, userId).Scan(&canEdit)
 	
@@ -150,12 +165,10 @@ func UserCanEditList(userId int, listId int) (bool, error) {
 
 // UserCanViewList returns true if the user can view the list
 // This is true if the user owns the list OR if the list is shared with their group
-func UserCanViewList(userId int, listId int) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) UserCanViewList(ctx context.Context, userId int, listId int) (bool, error) {
 	
 	// Check if user owns the list
-	owns, err := UserOwnsList(userId, listId)
+	owns, err := repo.UserOwnsList(ctx, userId, listId)
 	if err != nil {
 		return false, err
 	}
@@ -165,12 +178,13 @@ func UserCanViewList(userId int, listId int) (bool, error) {
 	
 	// Check if the list is shared with the user's group
 	var count int
-	err = db.QueryRow(`
+	err = repo.db.QueryRowContext(ctx, `
 		SELECT COUNT(1)
 		FROM `+constants.DB_TABLE_LIST+` l
 		JOIN `+constants.DB_TABLE_USER+` u ON l.userid = u.id
 		WHERE l.id = $1
 		AND l.share_with_group = true
+		AND l.deleted_at IS NULL
 		AND u.groupid = (SELECT groupid FROM `+constants.DB_TABLE_USER+` WHERE id = $2)
 	`, listId, userId).Scan(&count)
 	
@@ -1,36 +1,66 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
 	"fmt"
-	"log"
+	"strconv"
+	"time"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/storage"
 	_ "github.com/lib/pq"
 )
 
-//go:embed init.sql
-var initSQL string
+// Repository provides all database access on top of a single shared connection pool.
+// Every query takes a context so that a cancelled request also cancels its query.
+type Repository struct {
+	db           *sql.DB
+	itemURLSaved func(url string) // see OnItemURLSaved
+	attachments  storage.Store    // see SetAttachmentStore
+}
 
-func init() {
-	fmt.Printf("Attempting database connection: %s\n", constants.DB_CONNECTION_STRING)
-	db := getDatabaseConnection()
-	defer db.Close()
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
 
-	fmt.Println("Running database initialization queries")
-	_, err := db.Exec(initSQL)
+// OpenPool opens the application's connection pool, sized according to the POSTGRES_* pool env vars,
+// and verifies that the database is reachable
+func OpenPool(ctx context.Context) (*sql.DB, error) {
+	maxOpen, err := strconv.Atoi(constants.DB_MAX_OPEN_CONNS)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("invalid %s: %v", constants.ENV_POSTGRES_MAX_OPEN_CONNS, err)
+	}
+	maxIdle, err := strconv.Atoi(constants.DB_MAX_IDLE_CONNS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", constants.ENV_POSTGRES_MAX_IDLE_CONNS, err)
+	}
+	maxLifetime, err := time.ParseDuration(constants.DB_CONN_MAX_LIFETIME)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", constants.ENV_POSTGRES_CONN_MAX_LIFETIME, err)
+	}
+	maxIdleTime, err := time.ParseDuration(constants.DB_CONN_MAX_IDLE_TIME)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", constants.ENV_POSTGRES_CONN_MAX_IDLE_TIME, err)
 	}
 
-	fmt.Println("Database initialized successfully")
-}
-
-func getDatabaseConnection() *sql.DB {
 	db, err := sql.Open("postgres", constants.DB_CONNECTION_STRING)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	return db
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(maxLifetime)
+	db.SetConnMaxIdleTime(maxIdleTime)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close releases every connection in the pool
+func (repo *Repository) Close() error {
+	return repo.db.Close()
 }
@@ -1,14 +1,49 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/jeffrpowell/listaway/internal/constants"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-func GetListItems(listId int) ([]constants.Item, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	rows, err := db.Query("SELECT id, name, url, priority, notes FROM "+constants.DB_TABLE_ITEM+" WHERE listid = $1", listId)
+func (repo *Repository) GetListItems(ctx context.Context, listId int) ([]constants.Item, error) {
+	return repo.GetListItemsSorted(ctx, listId, constants.ITEM_SORT_POSITION, false, "")
+}
+
+// itemOrders are the ORDER BY clauses for each ITEM_SORT const; %[1]s is ASC or DESC.
+// Ties, and items without a priority or price, fall back to the list's own order.
+var itemOrders = map[string]string{
+	constants.ITEM_SORT_POSITION: "i.position %[1]s, i.id %[1]s",
+	constants.ITEM_SORT_NAME:     "LOWER(i.name) %[1]s, i.position, i.id",
+	constants.ITEM_SORT_PRIORITY: "i.priority %[1]s NULLS LAST, i.position, i.id",
+	constants.ITEM_SORT_PRICE:    "i.currency NULLS LAST, i.price %[1]s NULLS LAST, i.position, i.id",
+}
+
+// GetListItemsSorted returns a list's items in one of the ITEM_SORT orders, falling back to the list's own order for unknown sorts.
+// When tag isn't empty only the items carrying it are returned.
+func (repo *Repository) GetListItemsSorted(ctx context.Context, listId int, sortBy string, descending bool, tag string) ([]constants.Item, error) {
+	order, ok := itemOrders[sortBy]
+	if !ok {
+		order = itemOrders[constants.ITEM_SORT_POSITION]
+	}
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT i.id, i.name, i.url, i.priority, i.notes, i.position, i.sectionid, i.completed_at, i.completed_by, u.name, i.quantity, i.fulfilled, `+itemContributed+`, i.price, i.currency, i.tags,
+		       p.url, p.title, p.image_url, p.price, p.currency, p.availability, p.fetched_at
+		FROM `+constants.DB_TABLE_ITEM+` i
+		LEFT JOIN `+constants.DB_TABLE_USER+` u ON u.id = i.completed_by
+		LEFT JOIN `+constants.DB_TABLE_LINK_PREVIEW+` p ON p.url = i.url
+		WHERE i.listid = $1 AND i.deleted_at IS NULL AND ($2::TEXT = '' OR i.tags @> ARRAY[$2::TEXT])
+		ORDER BY `+fmt.Sprintf(order, direction), listId, tag)
 	if err != nil {
 		return nil, err
 	}
@@ -17,48 +52,344 @@ func GetListItems(listId int) ([]constants.Item, error) {
 	var items []constants.Item
 	for rows.Next() {
 		var i constants.Item
+		var p constants.LinkPreview
+		var previewURL sql.NullString
+		var fetchedAt sql.NullTime
 
-		err := rows.Scan(&i.Id, &i.Name, &i.URL, &i.Priority, &i.Notes)
+		err := rows.Scan(&i.Id, &i.Name, &i.URL, &i.Priority, &i.Notes, &i.Position, &i.SectionId, &i.CompletedAt, &i.CompletedBy, &i.CompletedByName, &i.Quantity, &i.Fulfilled, &i.Contributed, &i.Price, &i.Currency, pq.Array(&i.Tags),
+			&previewURL, &p.Title, &p.ImageURL, &p.Price, &p.Currency, &p.Availability, &fetchedAt)
 		if err != nil {
 			return nil, err
 		}
+		if previewURL.Valid && !p.Empty() {
+			p.URL, p.FetchedAt = previewURL.String, fetchedAt.Time
+			i.Preview = &p
+		}
 		items = append(items, i)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+
+	attachments, err := repo.getListAttachments(ctx, listId)
+	if err != nil {
+		return nil, err
+	}
+	for n := range items {
+		items[n].Attachments = attachments[items[n].Id]
+	}
 	return items, nil
 }
 
-func CreateItem(item constants.ItemInsert) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`INSERT INTO listaway.item (name, listid, url, notes, priority) VALUES($1, $2, $3, $4, $5)`, item.Name, item.ListId, item.URL, item.Notes, item.Priority)
-	return err
+// itemContributed totals the contributions towards item i
+const itemContributed = "(SELECT COALESCE(SUM(c.quantity), 0) FROM " + constants.DB_TABLE_CONTRIBUTION + " c WHERE c.itemid = i.id)"
+
+// itemQuantities clamps an item's quantity to at least 1 and its fulfilled count to 0..quantity
+func itemQuantities(item constants.ItemInsert) (int, int) {
+	quantity := max(item.Quantity, 1)
+	return quantity, min(max(item.Fulfilled, 0), quantity)
 }
 
-func DeleteItem(itemId int) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`DELETE FROM listaway.item WHERE id = $1`, itemId)
+// itemTags is the item's tags ready to store; no tags is an empty array rather than NULL
+func itemTags(item constants.ItemInsert) any {
+	if item.Tags == nil {
+		return pq.Array([]string{})
+	}
+	return pq.Array(item.Tags)
+}
+
+// nextItemPosition places a new item at the end of list $2
+const nextItemPosition = "(SELECT COALESCE(MAX(position), 0) + 1 FROM " + constants.DB_TABLE_ITEM + " WHERE listid = $2)"
+
+// CreateItem adds an item to the end of its list, recording userId as the one who added it
+func (repo *Repository) CreateItem(ctx context.Context, userId int, item constants.ItemInsert) (int, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var newId int
+	quantity, fulfilled := itemQuantities(item)
+	err = tx.QueryRowContext(ctx, `INSERT INTO listaway.item (name, listid, url, notes, priority, sectionid, quantity, fulfilled, price, currency, tags, position) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, `+nextItemPosition+`) RETURNING id`, item.Name, item.ListId, item.URL, item.Notes, item.Priority, item.SectionId, quantity, fulfilled, item.Price, item.Currency, itemTags(item)).Scan(&newId)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordItemCreated(ctx, tx, userId, newId); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	repo.notifyItemURL(item.URL)
+	return newId, nil
+}
+
+// recordItemCreated adds a just-inserted item to its list's history
+func recordItemCreated(ctx context.Context, tx *sql.Tx, userId int, itemId int) error {
+	listId, after, err := itemSnapshot(ctx, tx, itemId)
+	if err != nil {
+		return err
+	}
+	return recordRevision(ctx, tx, listId, uint64(itemId), userId, constants.REVISION_CREATE, nil, &after)
+}
+
+// DeleteItem moves an item to the trash of its list's owner, recording userId as the one who deleted it
+func (repo *Repository) DeleteItem(ctx context.Context, userId int, itemId int) error {
+	_, err := repo.trashItem(ctx, userId, itemId, 0)
 	return err
 }
 
-func GetItem(itemId int) (constants.Item, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT id, name, url, notes, priority FROM "+constants.DB_TABLE_ITEM+" WHERE id = $1", itemId)
+// trashItem moves an item to the trash and records it, reporting whether the item was there to move.
+// A non-zero listId requires the item to be in that list.
+func (repo *Repository) trashItem(ctx context.Context, userId int, itemId int, listId int) (bool, error) {
+	return repo.setItemDeleted(ctx, userId, itemId, listId, true)
+}
+
+// untrashItem brings an item back from the trash and records it, reporting whether it was in the trash.
+// A non-zero listId requires the item to be in that list.
+func (repo *Repository) untrashItem(ctx context.Context, userId int, itemId int, listId int) (bool, error) {
+	return repo.setItemDeleted(ctx, userId, itemId, listId, false)
+}
+
+func (repo *Repository) setItemDeleted(ctx context.Context, userId int, itemId int, listId int, deleted bool) (bool, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	itemListId, snapshot, err := itemSnapshot(ctx, tx, itemId)
+	if err == sql.ErrNoRows || (err == nil && listId != 0 && itemListId != uint64(listId)) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var result sql.Result
+	var before, after *constants.ItemSnapshot
+	action := constants.REVISION_DELETE
+	if deleted {
+		result, err = tx.ExecContext(ctx, `UPDATE listaway.item SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`, time.Now(), itemId)
+		before = &snapshot
+	} else {
+		result, err = tx.ExecContext(ctx, `UPDATE listaway.item SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, itemId)
+		after = &snapshot
+		action = constants.REVISION_RESTORE
+	}
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return false, err
+	}
+	if err := recordRevision(ctx, tx, itemListId, uint64(itemId), userId, action, before, after); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ItemInList checks whether an item belongs to the given list
+func (repo *Repository) ItemInList(ctx context.Context, itemId int, listId int) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM "+constants.DB_TABLE_ITEM+" WHERE id = $1 AND listid = $2 AND deleted_at IS NULL", itemId, listId)
+	var matches int
+	err := row.Scan(&matches)
+	if err != nil {
+		return false, err
+	}
+	return matches != 0, nil
+}
+
+func (repo *Repository) GetItem(ctx context.Context, itemId int) (constants.Item, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT i.id, i.name, i.url, i.notes, i.priority, i.position, i.sectionid, i.completed_at, i.completed_by, i.quantity, i.fulfilled, "+itemContributed+", i.price, i.currency, i.tags FROM "+constants.DB_TABLE_ITEM+" i WHERE i.id = $1", itemId)
 	var item constants.Item
-	err := row.Scan(&item.Id, &item.Name, &item.URL, &item.Notes, &item.Priority)
+	err := row.Scan(&item.Id, &item.Name, &item.URL, &item.Notes, &item.Priority, &item.Position, &item.SectionId, &item.CompletedAt, &item.CompletedBy, &item.Quantity, &item.Fulfilled, &item.Contributed, &item.Price, &item.Currency, pq.Array(&item.Tags))
+	if err != nil {
+		return constants.Item{}, err
+	}
+	item.Attachments, err = repo.GetItemAttachments(ctx, itemId)
 	if err != nil {
 		return constants.Item{}, err
 	}
 	return item, nil
 }
 
-func UpdateItem(itemId int, item constants.ItemInsert) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`UPDATE listaway.item SET name = $1, url = $2, priority = $3, notes = $4 WHERE id = $5`, item.Name, item.URL, item.Priority, item.Notes, itemId)
+// UpdateItem saves an editor's changes to an item, recording what userId changed in its list's history
+func (repo *Repository) UpdateItem(ctx context.Context, userId int, itemId int, item constants.ItemInsert) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	listId, before, err := itemSnapshot(ctx, tx, itemId)
+	if err != nil {
+		return err
+	}
+	quantity, fulfilled := itemQuantities(item)
+	_, err = tx.ExecContext(ctx, `UPDATE listaway.item SET name = $1, url = $2, priority = $3, notes = $4, sectionid = $5, quantity = $6, fulfilled = $7, price = $8, currency = $9, tags = $10 WHERE id = $11`, item.Name, item.URL, item.Priority, item.Notes, item.SectionId, quantity, fulfilled, item.Price, item.Currency, itemTags(item), itemId)
+	if err != nil {
+		return err
+	}
+	_, after, err := itemSnapshot(ctx, tx, itemId)
+	if err != nil {
+		return err
+	}
+	// Saving an item without changing anything leaves no trace
+	if len(constants.DiffItems(&before, &after)) != 0 {
+		if err := recordRevision(ctx, tx, listId, uint64(itemId), userId, constants.REVISION_UPDATE, &before, &after); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	repo.notifyItemURL(item.URL)
+	return nil
+}
+
+// SetItemCompleted checks an item off as completed by userId, or clears it when completed is false.
+// Checking off an already completed item keeps who completed it first and when.
+func (repo *Repository) SetItemCompleted(ctx context.Context, itemId int, userId int, completed bool) error {
+	if !completed {
+		_, err := repo.db.ExecContext(ctx, `UPDATE listaway.item SET completed_at = NULL, completed_by = NULL WHERE id = $1`, itemId)
+		return err
+	}
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.item SET completed_at = $1, completed_by = $2 WHERE id = $3 AND completed_at IS NULL`, time.Now(), userId, itemId)
 	return err
 }
+
+// ItemDedupeKey identifies items that are the same for import purposes: items with a URL match on
+// the URL, ignoring case, scheme and a trailing slash; items without one match on name, ignoring case
+func ItemDedupeKey(name string, url sql.NullString) string {
+	if url.Valid && strings.TrimSpace(url.String) != "" {
+		u := strings.ToLower(strings.TrimSpace(url.String))
+		u = strings.TrimPrefix(strings.TrimPrefix(u, "https://"), "http://")
+		return "url:" + strings.TrimSuffix(strings.TrimPrefix(u, "www."), "/")
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(name))
+}
+
+// ImportItems inserts items into a list in one transaction, skipping any that duplicate an existing
+// item or an earlier one in the same import. It returns how many were inserted, each recorded in
+// the list's history as added by userId.
+func (repo *Repository) ImportItems(ctx context.Context, userId int, listId int, items []constants.ItemInsert) (int, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the list so two imports can't both decide the same item is new
+	_, err = tx.ExecContext(ctx, "SELECT id FROM "+constants.DB_TABLE_LIST+" WHERE id = $1 FOR UPDATE", listId)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT name, url FROM "+constants.DB_TABLE_ITEM+" WHERE listid = $1 AND deleted_at IS NULL", listId)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var url sql.NullString
+		if err := rows.Scan(&name, &url); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		seen[ItemDedupeKey(name, url)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	inserted := 0
+	var urls []sql.NullString
+	for _, item := range items {
+		key := ItemDedupeKey(item.Name, item.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		quantity, fulfilled := itemQuantities(item)
+		var itemId int
+		err = tx.QueryRowContext(ctx, `INSERT INTO listaway.item (name, listid, url, notes, priority, quantity, fulfilled, tags, position) VALUES($1, $2, $3, $4, $5, $6, $7, $8, `+nextItemPosition+`) RETURNING id`, item.Name, listId, item.URL, item.Notes, item.Priority, quantity, fulfilled, itemTags(item)).Scan(&itemId)
+		if err != nil {
+			return 0, err
+		}
+		if err := recordItemCreated(ctx, tx, userId, itemId); err != nil {
+			return 0, err
+		}
+		inserted++
+		urls = append(urls, item.URL)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	for _, url := range urls {
+		repo.notifyItemURL(url)
+	}
+	return inserted, nil
+}
+
+// ReorderItems renumbers a list's items 1..n in one transaction and returns the resulting order.
+// itemIds may name every item in the list or only some of them. Named items are placed in the
+// given order within the positions they already occupy, and every other item stays where it is.
+// It returns false, changing nothing, if itemIds repeats an item or names one outside the list.
+func (repo *Repository) ReorderItems(ctx context.Context, listId int, itemIds []uint64) ([]uint64, bool, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM "+constants.DB_TABLE_ITEM+" WHERE listid = $1 AND deleted_at IS NULL ORDER BY position, id FOR UPDATE", listId)
+	if err != nil {
+		return nil, false, err
+	}
+	var current []uint64
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, false, err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	requested := make(map[uint64]bool, len(itemIds))
+	for _, id := range itemIds {
+		if requested[id] || !slices.Contains(current, id) {
+			return nil, false, nil
+		}
+		requested[id] = true
+	}
+
+	order := slices.Clone(current)
+	next := 0
+	for i, id := range current {
+		if requested[id] {
+			order[i] = itemIds[next]
+			next++
+		}
+	}
+
+	for i, id := range order {
+		_, err := tx.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_ITEM+" SET position = $1 WHERE id = $2 AND position <> $1", i+1, id)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return order, true, nil
+}
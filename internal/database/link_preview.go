@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	_ "github.com/lib/pq"
+)
+
+// How long a link preview is kept before the next save of an item with that URL fetches it again
+const (
+	linkPreviewMaxAge     = "7 days"
+	linkPreviewRetryAfter = "1 hour" // after a failed fetch
+)
+
+// OnItemURLSaved registers f to be called with the URL of every item created, updated or imported
+// with one. It is meant for the link preview worker and must be set before the server starts.
+func (repo *Repository) OnItemURLSaved(f func(url string)) {
+	repo.itemURLSaved = f
+}
+
+func (repo *Repository) notifyItemURL(url sql.NullString) {
+	if repo.itemURLSaved != nil && url.Valid && url.String != "" {
+		repo.itemURLSaved(url.String)
+	}
+}
+
+// LinkPreviewDue reports whether url has no preview yet, or one old enough to fetch again
+func (repo *Repository) LinkPreviewDue(ctx context.Context, url string) (bool, error) {
+	var due bool
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT fetched_at < NOW() - CASE WHEN fetch_error IS NULL THEN $2::INTERVAL ELSE $3::INTERVAL END
+		FROM `+constants.DB_TABLE_LINK_PREVIEW+` WHERE url = $1`, url, linkPreviewMaxAge, linkPreviewRetryAfter).Scan(&due)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return due, err
+}
+
+// SaveLinkPreview stores what was found at a URL, replacing any earlier preview of it
+func (repo *Repository) SaveLinkPreview(ctx context.Context, preview constants.LinkPreview) error {
+	_, err := repo.db.ExecContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_LINK_PREVIEW+` (url, title, image_url, price, currency, availability, fetched_at, fetch_error)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NULL)
+		ON CONFLICT (url) DO UPDATE SET title = $2, image_url = $3, price = $4, currency = $5, availability = $6, fetched_at = NOW(), fetch_error = NULL`,
+		preview.URL, preview.Title, preview.ImageURL, preview.Price, preview.Currency, preview.Availability)
+	return err
+}
+
+// SaveLinkPreviewError records a failed fetch of a URL, keeping whatever an earlier fetch found
+func (repo *Repository) SaveLinkPreviewError(ctx context.Context, url string, message string) error {
+	_, err := repo.db.ExecContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_LINK_PREVIEW+` (url, fetched_at, fetch_error) VALUES ($1, NOW(), $2)
+		ON CONFLICT (url) DO UPDATE SET fetched_at = NOW(), fetch_error = $2`, url, message)
+	return err
+}
@@ -1,17 +1,27 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
 	"github.com/jeffrpowell/listaway/internal/constants/random"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-func GetLists(userId int) ([]constants.List, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	rows, err := db.Query("SELECT id, name, description, shareCode, share_with_group, group_can_edit FROM "+constants.DB_TABLE_LIST+" WHERE userId = $1", userId)
+// listItemCount and listOutstandingCount count the items of list l; an item is outstanding
+// until it is checked off or enough of it has been fulfilled or contributed
+const listItemCount = "(SELECT COUNT(i.id) FROM " + constants.DB_TABLE_ITEM + " i WHERE i.listid = l.id AND i.deleted_at IS NULL)"
+const listOutstandingCount = "(SELECT COUNT(i.id) FROM " + constants.DB_TABLE_ITEM + " i WHERE i.listid = l.id AND i.deleted_at IS NULL AND i.completed_at IS NULL AND i.fulfilled + " + itemContributed + " < i.quantity)"
+
+func (repo *Repository) GetLists(ctx context.Context, userId int) ([]constants.List, error) {
+	return repo.GetListsTagged(ctx, userId, "")
+}
+
+// GetListsTagged returns the user's lists carrying tag, or all of them when tag is empty
+func (repo *Repository) GetListsTagged(ctx context.Context, userId int, tag string) ([]constants.List, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT l.id, l.name, l.description, l.shareCode, l.share_with_group, l.group_can_edit, l.claimable, l.completed_display, l.budget, l.budget_currency, l.tags, "+listItemCount+", "+listOutstandingCount+" FROM "+constants.DB_TABLE_LIST+" l WHERE l.userId = $1 AND l.deleted_at IS NULL AND ($2::TEXT = '' OR l.tags @> ARRAY[$2::TEXT])", userId, tag)
 	if err != nil {
 		return nil, err
 	}
@@ -21,7 +31,7 @@ func GetLists(userId int) ([]constants.List, error) {
 	for rows.Next() {
 		var l constants.List
 
-		err := rows.Scan(&l.Id, &l.Name, &l.Description, &l.ShareCode, &l.ShareWithGroup, &l.GroupCanEdit)
+		err := rows.Scan(&l.Id, &l.Name, &l.Description, &l.ShareCode, &l.ShareWithGroup, &l.GroupCanEdit, &l.Claimable, &l.CompletedDisplay, &l.Budget, &l.BudgetCurrency, pq.Array(&l.Tags), &l.ItemCount, &l.OutstandingCount)
 		if err != nil {
 			return nil, err
 		}
@@ -33,10 +43,8 @@ func GetLists(userId int) ([]constants.List, error) {
 	return lists, nil
 }
 
-func ListNameTaken(userId int, name string) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT COUNT(1) FROM "+constants.DB_TABLE_LIST+" WHERE userId = $1 AND name = $2", userId, name)
+func (repo *Repository) ListNameTaken(ctx context.Context, userId int, name string) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM "+constants.DB_TABLE_LIST+" WHERE userId = $1 AND name = $2 AND deleted_at IS NULL", userId, name)
 	var matches int
 	err := row.Scan(&matches)
 	if err != nil {
@@ -45,21 +53,17 @@ func ListNameTaken(userId int, name string) (bool, error) {
 	return matches != 0, nil
 }
 
-func CreateList(userId int, name string, description string) (int, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) CreateList(ctx context.Context, userId int, name string, description string) (int, error) {
 	var newId int
-	err := db.QueryRow(`INSERT INTO listaway.list (userId, name, description) VALUES($1, $2, $3) RETURNING id`, userId, name, description).Scan(&newId)
+	err := repo.db.QueryRowContext(ctx, `INSERT INTO listaway.list (userId, name, description) VALUES($1, $2, $3) RETURNING id`, userId, name, description).Scan(&newId)
 	if err != nil {
 		return 0, err
 	}
 	return newId, nil
 }
 
-func UserOwnsList(userId int, listId int) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT COUNT(1) FROM "+constants.DB_TABLE_LIST+" WHERE userId = $1 AND id = $2", userId, listId)
+func (repo *Repository) UserOwnsList(ctx context.Context, userId int, listId int) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM "+constants.DB_TABLE_LIST+" WHERE userId = $1 AND id = $2 AND deleted_at IS NULL", userId, listId)
 	var matches int
 	err := row.Scan(&matches)
 	if err != nil {
@@ -68,92 +72,68 @@ func UserOwnsList(userId int, listId int) (bool, error) {
 	return matches != 0, nil
 }
 
-func GetList(listId int) (constants.List, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT id, name, description, sharecode, share_with_group, group_can_edit FROM "+constants.DB_TABLE_LIST+" WHERE id = $1", listId)
+func (repo *Repository) GetList(ctx context.Context, listId int) (constants.List, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT id, name, description, sharecode, share_with_group, group_can_edit, claimable, completed_display, budget, budget_currency, tags FROM "+constants.DB_TABLE_LIST+" WHERE id = $1", listId)
 	var list constants.List
-	err := row.Scan(&list.Id, &list.Name, &list.Description, &list.ShareCode, &list.ShareWithGroup, &list.GroupCanEdit)
+	err := row.Scan(&list.Id, &list.Name, &list.Description, &list.ShareCode, &list.ShareWithGroup, &list.GroupCanEdit, &list.Claimable, &list.CompletedDisplay, &list.Budget, &list.BudgetCurrency, pq.Array(&list.Tags))
 	if err != nil {
 		return constants.List{}, err
 	}
 	return list, nil
 }
 
-func UpdateList(listId int, params constants.ListPostParams) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`UPDATE listaway.list SET name = $1, description = $2, share_with_group = $3, group_can_edit = $4 WHERE id = $5`, 
-		params.Name, params.Description, params.ShareWithGroup, params.GroupCanEdit, listId)
-	return err
-}
-
-func DeleteList(listId int, confirmationName string) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	
-	// Start a transaction to ensure atomicity
-	tx, err := db.Begin()
+// UpdateList saves a list's details, recording what userId changed in its history
+func (repo *Repository) UpdateList(ctx context.Context, userId int, listId int, params constants.ListPostParams) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
 	if err != nil {
-		return false, err
+		return err
 	}
-	
-	// Verify the list exists and the name matches
-	matches, err := confirmNameMatchesListName(db, listId, confirmationName)
+	defer tx.Rollback()
+
+	before, err := listSnapshot(ctx, tx, listId)
 	if err != nil {
-		tx.Rollback()
-		return false, err
-	}
-	if !matches {
-		tx.Rollback()
-		return false, nil
+		return err
 	}
-	
-	// Delete all items associated with this list first
-	_, err = tx.Exec(`DELETE FROM listaway.item WHERE listid = $1`, listId)
+	_, err = tx.ExecContext(ctx, `UPDATE listaway.list SET name = $1, description = $2, share_with_group = $3, group_can_edit = $4 WHERE id = $5`, 
+		params.Name, params.Description, params.ShareWithGroup, params.GroupCanEdit, listId)
 	if err != nil {
-		tx.Rollback()
-		return false, err
+		return err
 	}
-	
-	// Then delete the list itself
-	_, err = tx.Exec(`DELETE FROM listaway.list WHERE id = $1 AND name = $2`, listId, confirmationName)
+	after, err := listSnapshot(ctx, tx, listId)
 	if err != nil {
-		tx.Rollback()
-		return false, err
+		return err
 	}
-	
-	// Commit the transaction
-	err = tx.Commit()
-	if err != nil {
-		return false, err
+	if len(constants.DiffLists(&before, &after)) != 0 {
+		if err := recordRevision(ctx, tx, uint64(listId), 0, userId, constants.REVISION_UPDATE, &before, &after); err != nil {
+			return err
+		}
 	}
-	
-	return true, nil
+	return tx.Commit()
 }
 
-func confirmNameMatchesListName(db *sql.DB, listId int, confirmationName string) (bool, error) {
-	row := db.QueryRow("SELECT COUNT(1) FROM "+constants.DB_TABLE_LIST+" WHERE id = $1 AND name = $2", listId, confirmationName)
-	var matches int
-	err := row.Scan(&matches)
+// DeleteList moves a list, with its items, to its owner's trash after confirming the name matches
+func (repo *Repository) DeleteList(ctx context.Context, listId int, confirmationName string) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, `UPDATE listaway.list SET deleted_at = $1 WHERE id = $2 AND name = $3 AND deleted_at IS NULL`, time.Now(), listId, confirmationName)
 	if err != nil {
 		return false, err
 	}
-	return matches != 0, nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected != 0, nil
 }
 
-func GenerateShareCode(listId int) (string, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	code, err := createUniqueShareCode(db)
+func (repo *Repository) GenerateShareCode(ctx context.Context, listId int) (string, error) {
+	code, err := createUniqueShareCode(ctx, repo.db)
 	if err != nil {
 		return "", err
 	}
-	_, err = db.Exec(`UPDATE listaway.list SET sharecode = $1 WHERE id = $2`, code, listId)
+	_, err = repo.db.ExecContext(ctx, `UPDATE listaway.list SET sharecode = $1 WHERE id = $2`, code, listId)
 	return code, err
 }
 
-func createUniqueShareCode(db *sql.DB) (string, error) {
+func createUniqueShareCode(ctx context.Context, db *sql.DB) (string, error) {
 	var code string
 	var err error
 	var count int
@@ -166,7 +146,7 @@ func createUniqueShareCode(db *sql.DB) (string, error) {
 		}
 
 		// Check if the generated code already exists in the database
-		row := db.QueryRow(`SELECT COUNT(1) FROM listaway.list WHERE sharecode = $1`, code)
+		row := db.QueryRowContext(ctx, `SELECT COUNT(1) FROM listaway.list WHERE sharecode = $1`, code)
 		err = row.Scan(&count)
 		if err != nil {
 			return "", err
@@ -181,30 +161,51 @@ func createUniqueShareCode(db *sql.DB) (string, error) {
 	return code, nil
 }
 
-func GetListFromShareCode(shareCode string) (constants.List, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT id, name, description, sharecode, share_with_group, group_can_edit FROM "+constants.DB_TABLE_LIST+" WHERE sharecode = $1", shareCode)
+func (repo *Repository) GetListFromShareCode(ctx context.Context, shareCode string) (constants.List, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT id, name, description, sharecode, share_with_group, group_can_edit, claimable, completed_display, budget, budget_currency, tags FROM "+constants.DB_TABLE_LIST+" WHERE sharecode = $1 AND deleted_at IS NULL", shareCode)
 	var list constants.List
-	err := row.Scan(&list.Id, &list.Name, &list.Description, &list.ShareCode, &list.ShareWithGroup, &list.GroupCanEdit)
+	err := row.Scan(&list.Id, &list.Name, &list.Description, &list.ShareCode, &list.ShareWithGroup, &list.GroupCanEdit, &list.Claimable, &list.CompletedDisplay, &list.Budget, &list.BudgetCurrency, pq.Array(&list.Tags))
 	if err != nil {
 		return constants.List{}, err
 	}
 	return list, nil
 }
 
-func UnpublishShareCode(listId int) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`UPDATE listaway.list SET sharecode = NULL WHERE id = $1`, listId)
+// SetListClaimable toggles whether share link visitors can claim items on the list
+func (repo *Repository) SetListClaimable(ctx context.Context, listId int, claimable bool) error {
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.list SET claimable = $1 WHERE id = $2`, claimable, listId)
+	return err
+}
+
+// SetListCompletedDisplay sets how the list shows completed items, one of the COMPLETED_DISPLAY consts
+func (repo *Repository) SetListCompletedDisplay(ctx context.Context, listId int, display string) error {
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.list SET completed_display = $1 WHERE id = $2`, display, listId)
+	return err
+}
+
+// SetListBudget sets the list's budget in minor units of currency, or clears it when budget is null
+func (repo *Repository) SetListBudget(ctx context.Context, listId int, budget sql.NullInt64, currency sql.NullString) error {
+	if !budget.Valid {
+		currency = sql.NullString{}
+	}
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.list SET budget = $1, budget_currency = $2 WHERE id = $3`, budget, currency, listId)
+	return err
+}
+
+// SetListTags replaces the list's tags; they should already be normalized
+func (repo *Repository) SetListTags(ctx context.Context, listId int, tags []string) error {
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.list SET tags = $1 WHERE id = $2`, pq.Array(tags), listId)
+	return err
+}
+
+func (repo *Repository) UnpublishShareCode(ctx context.Context, listId int) error {
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.list SET sharecode = NULL WHERE id = $1`, listId)
 	return err
 }
 
 // GetListIdsWithShareCode retrieves all list IDs for a user that have share codes
-func GetListIdsWithShareCode(userId int) ([]uint64, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	rows, err := db.Query("SELECT id FROM "+constants.DB_TABLE_LIST+" WHERE userId = $1 AND sharecode IS NOT NULL", userId)
+func (repo *Repository) GetListIdsWithShareCode(ctx context.Context, userId int) ([]uint64, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id FROM "+constants.DB_TABLE_LIST+" WHERE userId = $1 AND sharecode IS NOT NULL AND deleted_at IS NULL", userId)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration files are named <version>_<name>.<up|down>.sql, e.g. 0002_item_claims.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// MigrateUp applies every pending migration in version order
+func (repo *Repository) MigrateUp(ctx context.Context) error {
+	return repo.withMigrationLock(ctx, func(ctx context.Context, conn *sql.Conn, migrations []migration, applied map[int]sql.NullTime) error {
+		for _, m := range migrations {
+			if _, ok := applied[m.version]; ok {
+				continue
+			}
+			log.Printf("Applying migration %04d_%s", m.version, m.name)
+			err := runMigration(ctx, conn, m.up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "INSERT INTO "+constants.DB_TABLE_MIGRATIONS+" (version, name, applied_at) VALUES ($1, $2, $3)", m.version, m.name, time.Now())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %v", m.version, m.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateDown reverts the most recently applied migrations, newest first
+func (repo *Repository) MigrateDown(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("number of migrations to revert must be at least 1")
+	}
+	return repo.withMigrationLock(ctx, func(ctx context.Context, conn *sql.Conn, migrations []migration, applied map[int]sql.NullTime) error {
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.version]; !ok {
+				continue
+			}
+			log.Printf("Reverting migration %04d_%s", m.version, m.name)
+			err := runMigration(ctx, conn, m.down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_MIGRATIONS+" WHERE version = $1", m.version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %04d_%s failed: %v", m.version, m.name, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// GetMigrationStatus lists every embedded migration alongside when (if ever) it was applied
+func (repo *Repository) GetMigrationStatus(ctx context.Context) ([]constants.MigrationStatus, error) {
+	var statuses []constants.MigrationStatus
+	err := repo.withMigrationLock(ctx, func(ctx context.Context, conn *sql.Conn, migrations []migration, applied map[int]sql.NullTime) error {
+		for _, m := range migrations {
+			appliedAt, ok := applied[m.version]
+			statuses = append(statuses, constants.MigrationStatus{
+				Version:   m.version,
+				Name:      m.name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// LatestMigrationVersion is the newest migration embedded in this build
+func LatestMigrationVersion() (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+// withMigrationLock holds a Postgres advisory lock for the duration of fn so that
+// two replicas booting at the same time can't run migrations concurrently.
+// Advisory locks belong to a session, so everything runs on one dedicated connection.
+func (repo *Repository) withMigrationLock(ctx context.Context, fn func(ctx context.Context, conn *sql.Conn, migrations []migration, applied map[int]sql.NullTime) error) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := repo.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", constants.DB_MIGRATION_LOCK_ID); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %v", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", constants.DB_MIGRATION_LOCK_ID); err != nil {
+			log.Printf("Error releasing migration lock: %v", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+constants.DB_TABLE_MIGRATIONS+` (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating migrations table: %v", err)
+	}
+
+	applied, err := getAppliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, conn, migrations, applied)
+}
+
+// runMigration executes a migration script and its bookkeeping change in a single transaction
+func runMigration(ctx context.Context, conn *sql.Conn, script string, bookkeeping func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := bookkeeping(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func getAppliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]sql.NullTime, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM "+constants.DB_TABLE_MIGRATIONS)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]sql.NullTime)
+	for rows.Next() {
+		var version int
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// loadMigrations reads the embedded migration files, sorted by version.
+// Every version must have both an up and a down script.
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unrecognized migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		contents, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: match[2]}
+			byVersion[version] = m
+		}
+		if m.name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, m.name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(contents)
+		} else {
+			m.down = string(contents)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both an up and a down script", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
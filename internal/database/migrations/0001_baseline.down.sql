@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS listaway.group_settings;
+DROP TABLE IF EXISTS listaway.collection_list;
+DROP TABLE IF EXISTS listaway.collection;
+DROP TABLE IF EXISTS listaway.reset_tokens;
+DROP TABLE IF EXISTS listaway.user;
+DROP TABLE IF EXISTS listaway.item;
+DROP TABLE IF EXISTS listaway.list;
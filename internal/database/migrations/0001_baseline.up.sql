@@ -1,3 +1,11 @@
+----------------------------------------------------
+-- Baseline schema as of 1.18.x
+--
+-- Every statement is idempotent so that installations which
+-- predate versioned migrations (and already have these tables
+-- from the old init.sql) can adopt this migration as-is.
+----------------------------------------------------
+
 ----------------------------------------------------
 --          listaway.list table
 ----------------------------------------------------
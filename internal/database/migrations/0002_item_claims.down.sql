@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS listaway.item_claim;
+
+ALTER TABLE listaway.list DROP COLUMN IF EXISTS claimable;
@@ -0,0 +1,12 @@
+----------------------------------------------------
+--          gift-claiming mode
+----------------------------------------------------
+ALTER TABLE listaway.list ADD COLUMN IF NOT EXISTS claimable BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS listaway.item_claim (
+    itemid BIGINT PRIMARY KEY,
+    claimer_name VARCHAR NULL,
+    claimer_email VARCHAR NULL,
+    claim_token VARCHAR NOT NULL,
+    claimed_at TIMESTAMP NOT NULL
+);
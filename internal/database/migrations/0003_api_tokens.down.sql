@@ -0,0 +1 @@
+DROP TABLE IF EXISTS listaway.api_token;
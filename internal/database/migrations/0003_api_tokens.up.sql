@@ -0,0 +1,15 @@
+----------------------------------------------------
+--          personal access tokens for /api/v1
+----------------------------------------------------
+CREATE TABLE IF NOT EXISTS listaway.api_token (
+    id SERIAL PRIMARY KEY,
+    userid BIGINT NOT NULL,
+    name VARCHAR NOT NULL,
+    token_hash VARCHAR NOT NULL,
+    scope VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_token_token_hash_idx ON listaway.api_token (token_hash);
+CREATE INDEX IF NOT EXISTS api_token_userid_idx ON listaway.api_token (userid);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS listaway.item_listid_position_idx;
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS position;
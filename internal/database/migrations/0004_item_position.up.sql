@@ -0,0 +1,18 @@
+----------------------------------------------------
+--          explicit item ordering
+----------------------------------------------------
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS position INT;
+
+-- Existing items keep the order they were added in
+UPDATE listaway.item i
+SET position = numbered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY listid ORDER BY id) AS position
+    FROM listaway.item
+) numbered
+WHERE i.id = numbered.id AND i.position IS NULL;
+
+ALTER TABLE listaway.item ALTER COLUMN position SET NOT NULL;
+ALTER TABLE listaway.item ALTER COLUMN position SET DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS item_listid_position_idx ON listaway.item (listid, position);
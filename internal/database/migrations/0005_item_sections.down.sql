@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS listaway.item_sectionid_idx;
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS sectionid;
+DROP TABLE IF EXISTS listaway.item_section;
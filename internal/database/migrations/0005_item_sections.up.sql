@@ -0,0 +1,16 @@
+----------------------------------------------------
+--          item sections (headings within a list)
+----------------------------------------------------
+CREATE TABLE IF NOT EXISTS listaway.item_section (
+    id SERIAL PRIMARY KEY,
+    listid BIGINT NOT NULL,
+    name VARCHAR NOT NULL,
+    position INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS item_section_listid_position_idx ON listaway.item_section (listid, position);
+
+-- Items outside any section keep a NULL sectionid
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS sectionid BIGINT NULL;
+
+CREATE INDEX IF NOT EXISTS item_sectionid_idx ON listaway.item (sectionid);
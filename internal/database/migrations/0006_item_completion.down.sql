@@ -0,0 +1,4 @@
+ALTER TABLE listaway.list DROP COLUMN IF EXISTS completed_display;
+
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS completed_by;
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS completed_at;
@@ -0,0 +1,8 @@
+----------------------------------------------------
+--          item completion (checklist mode)
+----------------------------------------------------
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP NULL;
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS completed_by BIGINT NULL;
+
+-- How completed items are shown: 'strike' (in place, struck through), 'hide' or 'archive' (moved under a Completed heading)
+ALTER TABLE listaway.list ADD COLUMN IF NOT EXISTS completed_display VARCHAR NOT NULL DEFAULT 'strike';
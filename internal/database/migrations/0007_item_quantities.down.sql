@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS listaway.item_contribution;
+
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS fulfilled;
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS quantity;
@@ -0,0 +1,18 @@
+----------------------------------------------------
+--          item quantities and contributions
+----------------------------------------------------
+-- quantity is how many are wanted; fulfilled is how many editors have marked as already covered
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS quantity INT NOT NULL DEFAULT 1;
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS fulfilled INT NOT NULL DEFAULT 0;
+
+-- Share link visitors pledging part of an item's quantity, on lists in gift-claiming mode
+CREATE TABLE IF NOT EXISTS listaway.item_contribution (
+    id SERIAL PRIMARY KEY,
+    itemid BIGINT NOT NULL,
+    contributor_name VARCHAR NULL,
+    quantity INT NOT NULL,
+    contribution_token VARCHAR NOT NULL,
+    contributed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS item_contribution_itemid_idx ON listaway.item_contribution (itemid);
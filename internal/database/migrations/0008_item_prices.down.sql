@@ -0,0 +1,5 @@
+ALTER TABLE listaway.list DROP COLUMN IF EXISTS budget_currency;
+ALTER TABLE listaway.list DROP COLUMN IF EXISTS budget;
+
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS currency;
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS price;
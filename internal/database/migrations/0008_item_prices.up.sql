@@ -0,0 +1,10 @@
+----------------------------------------------------
+--          item prices and list budgets
+----------------------------------------------------
+-- Amounts are whole numbers of the currency's minor unit (cents for USD) so totals are exact;
+-- currencies are ISO 4217 codes
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS price BIGINT NULL;
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NULL;
+
+ALTER TABLE listaway.list ADD COLUMN IF NOT EXISTS budget BIGINT NULL;
+ALTER TABLE listaway.list ADD COLUMN IF NOT EXISTS budget_currency VARCHAR(3) NULL;
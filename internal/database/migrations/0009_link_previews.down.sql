@@ -0,0 +1 @@
+DROP TABLE IF EXISTS listaway.link_preview;
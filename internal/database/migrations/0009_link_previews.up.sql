@@ -0,0 +1,15 @@
+----------------------------------------------------
+--          link previews
+----------------------------------------------------
+-- What an item's URL says about itself (OpenGraph and JSON-LD product data), fetched in the background
+-- and shared by every item with the same URL. A failed fetch keeps whatever an earlier one found.
+CREATE TABLE IF NOT EXISTS listaway.link_preview (
+    url TEXT PRIMARY KEY,
+    title VARCHAR NULL,
+    image_url TEXT NULL,
+    price BIGINT NULL,
+    currency VARCHAR(3) NULL,
+    availability VARCHAR NULL,
+    fetched_at TIMESTAMP NOT NULL,
+    fetch_error VARCHAR NULL
+);
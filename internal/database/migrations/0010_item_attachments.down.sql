@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS listaway.item_attachment_itemid_idx;
+DROP TABLE IF EXISTS listaway.item_attachment;
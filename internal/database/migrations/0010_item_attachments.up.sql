@@ -0,0 +1,19 @@
+----------------------------------------------------
+--          item attachments
+----------------------------------------------------
+-- Images uploaded to an item. The files themselves live in the attachment store (local disk or an
+-- S3 bucket) under storage_key, with a smaller JPEG copy under thumbnail_key.
+CREATE TABLE IF NOT EXISTS listaway.item_attachment (
+    id SERIAL PRIMARY KEY,
+    itemid BIGINT NOT NULL,
+    storage_key VARCHAR NOT NULL UNIQUE,
+    thumbnail_key VARCHAR NOT NULL UNIQUE,
+    content_type VARCHAR NOT NULL,
+    filename VARCHAR NULL,
+    size_bytes BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    uploaded_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS item_attachment_itemid_idx ON listaway.item_attachment (itemid);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS listaway.item_tags_idx;
+DROP INDEX IF EXISTS listaway.list_tags_idx;
+
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS tags;
+ALTER TABLE listaway.list DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,9 @@
+----------------------------------------------------
+--          tags on lists and items
+----------------------------------------------------
+-- Tags are free-form labels, stored lowercased and trimmed; the GIN indexes serve tag filters (tags @> ARRAY[...])
+ALTER TABLE listaway.list ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS list_tags_idx ON listaway.list USING GIN (tags);
+CREATE INDEX IF NOT EXISTS item_tags_idx ON listaway.item USING GIN (tags);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS listaway.collection_search_idx;
+DROP INDEX IF EXISTS listaway.item_search_idx;
+DROP INDEX IF EXISTS listaway.list_search_idx;
+
+ALTER TABLE listaway.collection DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE listaway.list DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,22 @@
+----------------------------------------------------
+--          full-text search
+----------------------------------------------------
+-- Generated tsvectors over the text a user would search for, names weighted above descriptions,
+-- notes and URLs; the GIN indexes serve search_vector @@ websearch_to_tsquery(...)
+ALTER TABLE listaway.list ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+) STORED;
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(notes, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(url, '')), 'C')
+) STORED;
+ALTER TABLE listaway.collection ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS list_search_idx ON listaway.list USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS item_search_idx ON listaway.item USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS collection_search_idx ON listaway.collection USING GIN (search_vector);
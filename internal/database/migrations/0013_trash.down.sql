@@ -0,0 +1,18 @@
+-- Whatever is still in the trash was deleted, so it goes for good
+DELETE FROM listaway.item_claim WHERE itemid IN (SELECT id FROM listaway.item WHERE deleted_at IS NOT NULL OR listid IN (SELECT id FROM listaway.list WHERE deleted_at IS NOT NULL));
+DELETE FROM listaway.item_contribution WHERE itemid IN (SELECT id FROM listaway.item WHERE deleted_at IS NOT NULL OR listid IN (SELECT id FROM listaway.list WHERE deleted_at IS NOT NULL));
+DELETE FROM listaway.item_attachment WHERE itemid IN (SELECT id FROM listaway.item WHERE deleted_at IS NOT NULL OR listid IN (SELECT id FROM listaway.list WHERE deleted_at IS NOT NULL));
+DELETE FROM listaway.item WHERE deleted_at IS NOT NULL OR listid IN (SELECT id FROM listaway.list WHERE deleted_at IS NOT NULL);
+DELETE FROM listaway.item_section WHERE listid IN (SELECT id FROM listaway.list WHERE deleted_at IS NOT NULL);
+DELETE FROM listaway.collection_list WHERE listid IN (SELECT id FROM listaway.list WHERE deleted_at IS NOT NULL)
+    OR collectionid IN (SELECT id FROM listaway.collection WHERE deleted_at IS NOT NULL);
+DELETE FROM listaway.list WHERE deleted_at IS NOT NULL;
+DELETE FROM listaway.collection WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS listaway.collection_deleted_at_idx;
+DROP INDEX IF EXISTS listaway.item_deleted_at_idx;
+DROP INDEX IF EXISTS listaway.list_deleted_at_idx;
+
+ALTER TABLE listaway.collection DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE listaway.item DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE listaway.list DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,13 @@
+----------------------------------------------------
+--          trash
+----------------------------------------------------
+-- Deleting a list, item or collection only stamps deleted_at; it stays in its owner's trash until
+-- restored, deleted for good, or purged once the retention window has passed. Items of a deleted
+-- list keep their own deleted_at, so they come back with the list.
+ALTER TABLE listaway.list ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+ALTER TABLE listaway.item ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+ALTER TABLE listaway.collection ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS list_deleted_at_idx ON listaway.list (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS item_deleted_at_idx ON listaway.item (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS collection_deleted_at_idx ON listaway.collection (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS listaway.revision_itemid_idx;
+DROP INDEX IF EXISTS listaway.revision_listid_idx;
+DROP TABLE IF EXISTS listaway.revision;
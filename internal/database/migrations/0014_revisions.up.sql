@@ -0,0 +1,19 @@
+----------------------------------------------------
+--          revision history
+----------------------------------------------------
+-- An append-only record of every change to an item or to a list's own details: who made it, when,
+-- and JSON snapshots of the fields before and after. before is null for a created item and after
+-- for a deleted one. Rows are only removed when their list or item is deleted for good.
+CREATE TABLE IF NOT EXISTS listaway.revision (
+    id SERIAL PRIMARY KEY,
+    listid BIGINT NOT NULL,
+    itemid BIGINT NULL,
+    userid BIGINT NULL,
+    action VARCHAR NOT NULL,
+    before JSONB NULL,
+    after JSONB NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS revision_listid_idx ON listaway.revision (listid, id);
+CREATE INDEX IF NOT EXISTS revision_itemid_idx ON listaway.revision (itemid) WHERE itemid IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS listaway.audit_log_groupid_idx;
+DROP INDEX IF EXISTS listaway.audit_log_created_at_idx;
+DROP TABLE IF EXISTS listaway.audit_log;
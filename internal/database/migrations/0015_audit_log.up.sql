@@ -0,0 +1,22 @@
+----------------------------------------------------
+--          audit log
+----------------------------------------------------
+-- An append-only record of sign-ins and administrative actions. The actor's and target's names are
+-- copied in at the time so the trail still reads once either user is deleted; groupid is the group
+-- the event concerns, which is what a group admin gets to see.
+CREATE TABLE IF NOT EXISTS listaway.audit_log (
+    id SERIAL PRIMARY KEY,
+    actorid BIGINT NULL,
+    actor VARCHAR NULL,
+    targetid BIGINT NULL,
+    target VARCHAR NULL,
+    groupid BIGINT NULL,
+    action VARCHAR NOT NULL,
+    detail VARCHAR NULL,
+    ip VARCHAR NULL,
+    user_agent VARCHAR NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON listaway.audit_log (created_at);
+CREATE INDEX IF NOT EXISTS audit_log_groupid_idx ON listaway.audit_log (groupid, created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS listaway.list_template_item_templateid_idx;
+DROP TABLE IF EXISTS listaway.list_template_item;
+DROP INDEX IF EXISTS listaway.list_template_groupid_name_idx;
+DROP TABLE IF EXISTS listaway.list_template;
@@ -0,0 +1,30 @@
+----------------------------------------------------
+--          list templates
+----------------------------------------------------
+-- Starting points for new lists, curated by a group's admins and offered to everyone in the
+-- group. A template holds its own copy of the items, so it is unaffected by later changes to
+-- the list it was made from.
+CREATE TABLE IF NOT EXISTS listaway.list_template (
+    id SERIAL PRIMARY KEY,
+    groupid BIGINT NOT NULL,
+    name VARCHAR NOT NULL,
+    description VARCHAR NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS list_template_groupid_name_idx ON listaway.list_template (groupid, name);
+
+CREATE TABLE IF NOT EXISTS listaway.list_template_item (
+    id SERIAL PRIMARY KEY,
+    templateid BIGINT NOT NULL,
+    name VARCHAR NOT NULL,
+    url VARCHAR NULL,
+    notes VARCHAR NULL,
+    priority INT NULL,
+    quantity INT NOT NULL DEFAULT 1,
+    price BIGINT NULL,
+    currency VARCHAR(3) NULL,
+    position INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS list_template_item_templateid_idx ON listaway.list_template_item (templateid, position);
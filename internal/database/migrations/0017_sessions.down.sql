@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS listaway.session_expires_at_idx;
+DROP INDEX IF EXISTS listaway.session_userid_idx;
+DROP INDEX IF EXISTS listaway.session_token_hash_idx;
+DROP TABLE IF EXISTS listaway.session;
@@ -0,0 +1,18 @@
+----------------------------------------------------
+--        browser sessions, one per sign-in
+----------------------------------------------------
+CREATE TABLE IF NOT EXISTS listaway.session (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR NOT NULL,
+    userid BIGINT NULL,
+    data BYTEA NOT NULL,
+    user_agent VARCHAR NOT NULL,
+    ip VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS session_token_hash_idx ON listaway.session (token_hash);
+CREATE INDEX IF NOT EXISTS session_userid_idx ON listaway.session (userid);
+CREATE INDEX IF NOT EXISTS session_expires_at_idx ON listaway.session (expires_at);
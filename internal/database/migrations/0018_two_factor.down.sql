@@ -0,0 +1,4 @@
+ALTER TABLE listaway.group_settings DROP COLUMN IF EXISTS two_factor_required;
+DROP INDEX IF EXISTS listaway.two_factor_recovery_code_userid_idx;
+DROP TABLE IF EXISTS listaway.two_factor_recovery_code;
+DROP TABLE IF EXISTS listaway.user_two_factor;
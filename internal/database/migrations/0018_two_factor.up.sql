@@ -0,0 +1,23 @@
+----------------------------------------------------
+--        TOTP two-factor authentication
+----------------------------------------------------
+-- The secret is encrypted with a key derived from LISTAWAY_AUTH_KEY. enabled_at stays NULL until the
+-- user confirms a code from their authenticator app, and last_step is the newest time step accepted
+-- so a code can't be used twice. Recovery codes are stored hashed and spent once.
+CREATE TABLE IF NOT EXISTS listaway.user_two_factor (
+    userid BIGINT PRIMARY KEY,
+    secret BYTEA NOT NULL,
+    enabled_at TIMESTAMP NULL,
+    last_step BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS listaway.two_factor_recovery_code (
+    id SERIAL PRIMARY KEY,
+    userid BIGINT NOT NULL,
+    code_hash VARCHAR NOT NULL,
+    used_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS two_factor_recovery_code_userid_idx ON listaway.two_factor_recovery_code (userid);
+
+ALTER TABLE listaway.group_settings ADD COLUMN IF NOT EXISTS two_factor_required BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS listaway.passkey_user_handle_idx;
+DROP INDEX IF EXISTS listaway.passkey_userid_idx;
+DROP INDEX IF EXISTS listaway.passkey_credential_id_idx;
+DROP TABLE IF EXISTS listaway.passkey;
@@ -0,0 +1,21 @@
+----------------------------------------------------
+--        WebAuthn passkeys
+----------------------------------------------------
+-- Each row is one passkey a user registered. user_handle is the random id the authenticator stores
+-- with a passkey and hands back at sign-in, so it is the same on every row of a user. credential
+-- holds the WebAuthn credential record as JSON: the public key, and the signature counter and flags
+-- as of the last sign-in.
+CREATE TABLE IF NOT EXISTS listaway.passkey (
+    id SERIAL PRIMARY KEY,
+    userid BIGINT NOT NULL,
+    user_handle BYTEA NOT NULL,
+    credential_id BYTEA NOT NULL,
+    credential BYTEA NOT NULL,
+    name VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS passkey_credential_id_idx ON listaway.passkey (credential_id);
+CREATE INDEX IF NOT EXISTS passkey_userid_idx ON listaway.passkey (userid);
+CREATE INDEX IF NOT EXISTS passkey_user_handle_idx ON listaway.passkey (user_handle);
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -9,12 +10,10 @@ import (
 )
 
 // GetUserByOIDC retrieves a user by OIDC provider and subject
-func GetUserByOIDC(provider, subject string) (int, error) {
+func (repo *Repository) GetUserByOIDC(ctx context.Context, provider, subject string) (int, error) {
 	var userId int
 	query := fmt.Sprintf("SELECT id FROM %s WHERE oidc_provider = $1 AND oidc_subject = $2", constants.DB_TABLE_USER)
-	db := getDatabaseConnection()
-	defer db.Close()
-	err := db.QueryRow(query, provider, subject).Scan(&userId)
+	err := repo.db.QueryRowContext(ctx, query, provider, subject).Scan(&userId)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return -1, nil // User not found
@@ -26,9 +25,9 @@ func GetUserByOIDC(provider, subject string) (int, error) {
 }
 
 // CreateOIDCUser creates a new user with OIDC authentication
-func CreateOIDCUser(email, name, provider, subject, oidcEmail string) (int, error) {
+func (repo *Repository) CreateOIDCUser(ctx context.Context, email, name, provider, subject, oidcEmail string) (int, error) {
 	// Get next available group ID for new OIDC user
-	groupId, err := GetNextAvailableGroupId()
+	groupId, err := repo.GetNextAvailableGroupId(ctx)
 	if err != nil {
 		return -1, fmt.Errorf("error getting next group ID: %v", err)
 	}
@@ -38,9 +37,7 @@ func CreateOIDCUser(email, name, provider, subject, oidcEmail string) (int, erro
 		INSERT INTO %s (groupid, email, name, passwordhash, admin, instanceadmin, oidc_provider, oidc_subject, oidc_email) 
 		VALUES ($1, $2, $3, '', true, false, $4, $5, $6) 
 		RETURNING id`, constants.DB_TABLE_USER)
-	db := getDatabaseConnection()
-	defer db.Close()
-	err = db.QueryRow(query, groupId, email, name, provider, subject, oidcEmail).Scan(&userId)
+	err = repo.db.QueryRowContext(ctx, query, groupId, email, name, provider, subject, oidcEmail).Scan(&userId)
 	if err != nil {
 		return -1, fmt.Errorf("error creating OIDC user: %v", err)
 	}
@@ -50,14 +47,12 @@ func CreateOIDCUser(email, name, provider, subject, oidcEmail string) (int, erro
 }
 
 // LinkOIDCToExistingUser links OIDC authentication to an existing user account
-func LinkOIDCToExistingUser(userID int, provider, subject, oidcEmail string) error {
+func (repo *Repository) LinkOIDCToExistingUser(ctx context.Context, userID int, provider, subject, oidcEmail string) error {
 	query := fmt.Sprintf(`
 		UPDATE %s 
 		SET oidc_provider = $1, oidc_subject = $2, oidc_email = $3 
 		WHERE id = $4`, constants.DB_TABLE_USER)
-	db := getDatabaseConnection()
-	defer db.Close()
-	result, err := db.Exec(query, provider, subject, oidcEmail, userID)
+	result, err := repo.db.ExecContext(ctx, query, provider, subject, oidcEmail, userID)
 	if err != nil {
 		return fmt.Errorf("error linking OIDC to user: %v", err)
 	}
@@ -76,7 +71,7 @@ func LinkOIDCToExistingUser(userID int, provider, subject, oidcEmail string) err
 }
 
 // GetUserByEmailForOIDCLinking retrieves a user by email for OIDC account linking
-func GetUserByEmailForOIDCLinking(email string) (int, bool, error) {
+func (repo *Repository) GetUserByEmailForOIDCLinking(ctx context.Context, email string) (int, bool, error) {
 	var userId int
 	var hasOIDC bool
 	query := fmt.Sprintf(`
@@ -84,9 +79,7 @@ func GetUserByEmailForOIDCLinking(email string) (int, bool, error) {
 		       CASE WHEN oidc_provider IS NOT NULL AND oidc_subject IS NOT NULL THEN true ELSE false END as has_oidc
 		FROM %s 
 		WHERE email = $1`, constants.DB_TABLE_USER)
-	db := getDatabaseConnection()
-	defer db.Close()
-	err := db.QueryRow(query, email).Scan(&userId, &hasOIDC)
+	err := repo.db.QueryRowContext(ctx, query, email).Scan(&userId, &hasOIDC)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return -1, false, nil // User not found
@@ -97,15 +90,14 @@ func GetUserByEmailForOIDCLinking(email string) (int, bool, error) {
 	return userId, hasOIDC, nil
 }
 
-// CreateOrUpdateOIDCUser creates a new OIDC user or updates existing one
-func CreateOrUpdateOIDCUser(email, name, provider, subject, oidcEmail string) (int, error) {
+// CreateOrUpdateOIDCUser creates a new OIDC user or updates existing one.
+// linked reports whether the identity was just linked to an existing account with the same email.
+func (repo *Repository) CreateOrUpdateOIDCUser(ctx context.Context, email, name, provider, subject, oidcEmail string) (userID int, linked bool, err error) {
 	// First, check if user exists with this OIDC provider/subject
-	existingUserID, err := GetUserByOIDC(provider, subject)
+	existingUserID, err := repo.GetUserByOIDC(ctx, provider, subject)
 	if err != nil {
-		return -1, fmt.Errorf("error checking existing OIDC user: %v", err)
+		return -1, false, fmt.Errorf("error checking existing OIDC user: %v", err)
 	}
-	db := getDatabaseConnection()
-	defer db.Close()
 	if existingUserID != -1 {
 		// User exists with this OIDC identity, update their info
 		query := fmt.Sprintf(`
@@ -113,43 +105,42 @@ func CreateOrUpdateOIDCUser(email, name, provider, subject, oidcEmail string) (i
 			SET name = $1, oidc_email = $2 
 			WHERE id = $3`, constants.DB_TABLE_USER)
 
-		_, err := db.Exec(query, name, oidcEmail, existingUserID)
+		_, err := repo.db.ExecContext(ctx, query, name, oidcEmail, existingUserID)
 		if err != nil {
-			return -1, fmt.Errorf("error updating existing OIDC user: %v", err)
+			return -1, false, fmt.Errorf("error updating existing OIDC user: %v", err)
 		}
 
 		log.Printf("Updated existing OIDC user with ID %d", existingUserID)
-		return existingUserID, nil
+		return existingUserID, false, nil
 	}
 
 	// Check if user exists with this email but no OIDC
-	emailUserID, hasOIDC, err := GetUserByEmailForOIDCLinking(email)
+	emailUserID, hasOIDC, err := repo.GetUserByEmailForOIDCLinking(ctx, email)
 	if err != nil {
-		return -1, fmt.Errorf("error checking user by email: %v", err)
+		return -1, false, fmt.Errorf("error checking user by email: %v", err)
 	}
 
 	if emailUserID != -1 && !hasOIDC {
 		// User exists with email but no OIDC, link the accounts
-		err := LinkOIDCToExistingUser(emailUserID, provider, subject, oidcEmail)
+		err := repo.LinkOIDCToExistingUser(ctx, emailUserID, provider, subject, oidcEmail)
 		if err != nil {
-			return -1, fmt.Errorf("error linking OIDC to existing user: %v", err)
+			return -1, false, fmt.Errorf("error linking OIDC to existing user: %v", err)
 		}
-		return emailUserID, nil
+		return emailUserID, true, nil
 	}
 
 	// Create new user
-	return CreateOIDCUser(email, name, provider, subject, oidcEmail)
+	userID, err = repo.CreateOIDCUser(ctx, email, name, provider, subject, oidcEmail)
+	return userID, false, err
 }
 
 // UnlinkOIDCFromUser removes OIDC authentication from a user account
-func UnlinkOIDCFromUser(userID int) error {
+func (repo *Repository) UnlinkOIDCFromUser(ctx context.Context, userID int) error {
 	query := fmt.Sprintf(`
 		UPDATE %s 
 		SET oidc_provider = NULL, oidc_subject = NULL, oidc_email = NULL 
 		WHERE id = $1`, constants.DB_TABLE_USER)
-	db := getDatabaseConnection()
-	defer db.Close()
-	result, err := db.Exec(query, userID)
+	result, err := repo.db.ExecContext(ctx, query, userID)
 	if err != nil {
 		return fmt.Errorf("error unlinking OIDC from user: %v", err)
 	}
@@ -168,15 +159,13 @@ func UnlinkOIDCFromUser(userID int) error {
 }
 
 // GetUserOIDCInfo retrieves OIDC information for a user
-func GetUserOIDCInfo(userID int) (string, string, string, error) {
+func (repo *Repository) GetUserOIDCInfo(ctx context.Context, userID int) (string, string, string, error) {
 	var provider, subject, oidcEmail sql.NullString
 	query := fmt.Sprintf(`
 		SELECT oidc_provider, oidc_subject, oidc_email 
 		FROM %s 
 		WHERE id = $1`, constants.DB_TABLE_USER)
-	db := getDatabaseConnection()
-	defer db.Close()
-	err := db.QueryRow(query, userID).Scan(&provider, &subject, &oidcEmail)
+	err := repo.db.QueryRowContext(ctx, query, userID).Scan(&provider, &subject, &oidcEmail)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", "", "", fmt.Errorf("user not found")
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// GetPasskeyCredentials returns the user handle a user's passkeys share and their WebAuthn credentials.
+// The handle is nil if they have no passkeys.
+func (repo *Repository) GetPasskeyCredentials(ctx context.Context, userId int) ([]byte, []webauthn.Credential, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT user_handle, credential FROM "+constants.DB_TABLE_PASSKEY+" WHERE userid = $1 ORDER BY id", userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var handle []byte
+	credentials := make([]webauthn.Credential, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&handle, &data); err != nil {
+			return nil, nil, err
+		}
+		var credential webauthn.Credential
+		if err := json.Unmarshal(data, &credential); err != nil {
+			return nil, nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return handle, credentials, nil
+}
+
+// GetPasskeyUserByHandle returns the user whose passkeys carry userHandle, along with their WebAuthn
+// credentials. Returns -1 if no passkey has that handle.
+func (repo *Repository) GetPasskeyUserByHandle(ctx context.Context, userHandle []byte) (int, []webauthn.Credential, error) {
+	var userId int
+	err := repo.db.QueryRowContext(ctx, "SELECT userid FROM "+constants.DB_TABLE_PASSKEY+" WHERE user_handle = $1 LIMIT 1", userHandle).Scan(&userId)
+	if err == sql.ErrNoRows {
+		return -1, nil, nil
+	}
+	if err != nil {
+		return -1, nil, err
+	}
+	_, credentials, err := repo.GetPasskeyCredentials(ctx, userId)
+	if err != nil {
+		return -1, nil, err
+	}
+	return userId, credentials, nil
+}
+
+// AddPasskey stores a passkey a user just registered
+func (repo *Repository) AddPasskey(ctx context.Context, userId int, userHandle []byte, name string, credential webauthn.Credential) (int, error) {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = repo.db.QueryRowContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_PASSKEY+` (userid, user_handle, credential_id, credential, name, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, userId, userHandle, credential.ID, data, name, time.Now()).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpdatePasskeyCredential records a sign-in with a passkey, keeping the signature counter and flags
+// the authenticator just reported
+func (repo *Repository) UpdatePasskeyCredential(ctx context.Context, credential webauthn.Credential) error {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_PASSKEY+" SET credential = $1, last_used_at = $2 WHERE credential_id = $3",
+		data, time.Now(), credential.ID)
+	return err
+}
+
+// GetUserPasskeys lists a user's passkeys, oldest first
+func (repo *Repository) GetUserPasskeys(ctx context.Context, userId int) ([]constants.Passkey, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, name, created_at, last_used_at FROM "+constants.DB_TABLE_PASSKEY+" WHERE userid = $1 ORDER BY created_at, id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]constants.Passkey, 0)
+	for rows.Next() {
+		var passkey constants.Passkey
+		if err := rows.Scan(&passkey.Id, &passkey.Name, &passkey.CreatedAt, &passkey.LastUsedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, passkey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RenameUserPasskey renames one of a user's passkeys, reporting false if they have no such passkey
+func (repo *Repository) RenameUserPasskey(ctx context.Context, userId int, passkeyId int, name string) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_PASSKEY+" SET name = $1 WHERE id = $2 AND userid = $3", name, passkeyId, userId)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteUserPasskey removes one of a user's passkeys, returning the name it had.
+// Reports false if the user has no such passkey.
+func (repo *Repository) DeleteUserPasskey(ctx context.Context, userId int, passkeyId int) (string, bool, error) {
+	var name string
+	err := repo.db.QueryRowContext(ctx, "DELETE FROM "+constants.DB_TABLE_PASSKEY+" WHERE id = $1 AND userid = $2 RETURNING name", passkeyId, userId).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return name, true, nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	_ "github.com/lib/pq"
+)
+
+// GetListTotals sums the priced items in a list, one total per currency
+func (repo *Repository) GetListTotals(ctx context.Context, listId int) ([]constants.PriceTotal, error) {
+	return repo.queryPriceTotals(ctx, "i.listid = $1", listId)
+}
+
+// GetCollectionTotals sums the priced items across every list in a collection, one total per currency
+func (repo *Repository) GetCollectionTotals(ctx context.Context, collectionId int) ([]constants.PriceTotal, error) {
+	return repo.queryPriceTotals(ctx, "i.listid IN (SELECT cl.listid FROM "+constants.DB_TABLE_COLLECTION_LIST+" cl JOIN "+constants.DB_TABLE_LIST+" l ON l.id = cl.listid WHERE cl.collectionid = $1 AND l.deleted_at IS NULL)", collectionId)
+}
+
+// queryPriceTotals groups the priced items matching where into one PriceTotal per currency.
+// What is still needed leaves out completed items and whatever has been fulfilled or contributed.
+func (repo *Repository) queryPriceTotals(ctx context.Context, where string, arg any) ([]constants.PriceTotal, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT i.currency, COUNT(i.id),
+		       SUM(i.price * i.quantity)::BIGINT,
+		       SUM(CASE WHEN i.completed_at IS NULL THEN i.price * GREATEST(i.quantity - i.fulfilled - `+itemContributed+`, 0) ELSE 0 END)::BIGINT
+		FROM `+constants.DB_TABLE_ITEM+` i
+		WHERE i.price IS NOT NULL AND i.deleted_at IS NULL AND `+where+`
+		GROUP BY i.currency
+		ORDER BY i.currency`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []constants.PriceTotal
+	for rows.Next() {
+		var t constants.PriceTotal
+		err := rows.Scan(&t.Currency, &t.Items, &t.Total, &t.StillNeeded)
+		if err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"time"
@@ -9,7 +10,7 @@ import (
 )
 
 // CreatePasswordResetToken generates a new reset token for the given email
-func CreatePasswordResetToken(email string) (string, error) {
+func (repo *Repository) CreatePasswordResetToken(ctx context.Context, email string) (string, error) {
 	// Generate a secure random token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -22,17 +23,15 @@ func CreatePasswordResetToken(email string) (string, error) {
 	expiresAt := createdAt.Add(1 * time.Hour)
 
 	// Store in database
-	db := getDatabaseConnection()
-	defer db.Close()
 
 	// Delete any existing tokens for this email
-	_, err := db.Exec("DELETE FROM "+constants.DB_TABLE_RESET+" WHERE email = $1", email)
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_RESET+" WHERE email = $1", email)
 	if err != nil {
 		return "", err
 	}
 
 	// Insert new token
-	_, err = db.Exec(
+	_, err = repo.db.ExecContext(ctx, 
 		"INSERT INTO "+constants.DB_TABLE_RESET+" (token, email, created_at, expires_at) VALUES ($1, $2, $3, $4)",
 		token, email, createdAt, expiresAt,
 	)
@@ -44,12 +43,9 @@ func CreatePasswordResetToken(email string) (string, error) {
 }
 
 // ValidatePasswordResetToken checks if a token is valid and returns the associated email
-func ValidatePasswordResetToken(token string) (string, bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-
+func (repo *Repository) ValidatePasswordResetToken(ctx context.Context, token string) (string, bool, error) {
 	// Query for token
-	row := db.QueryRow(
+	row := repo.db.QueryRowContext(ctx, 
 		"SELECT email, expires_at FROM "+constants.DB_TABLE_RESET+" WHERE token = $1",
 		token,
 	)
@@ -64,7 +60,7 @@ func ValidatePasswordResetToken(token string) (string, bool, error) {
 	// Check if token has expired
 	if time.Now().After(expiresAt) {
 		// Token expired, delete it
-		_, _ = db.Exec("DELETE FROM "+constants.DB_TABLE_RESET+" WHERE token = $1", token)
+		_, _ = repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_RESET+" WHERE token = $1", token)
 		return "", false, nil
 	}
 
@@ -72,10 +68,7 @@ func ValidatePasswordResetToken(token string) (string, bool, error) {
 }
 
 // InvalidatePasswordResetToken removes a token from the database
-func InvalidatePasswordResetToken(token string) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-
-	_, err := db.Exec("DELETE FROM "+constants.DB_TABLE_RESET+" WHERE token = $1", token)
+func (repo *Repository) InvalidatePasswordResetToken(ctx context.Context, token string) error {
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_RESET+" WHERE token = $1", token)
 	return err
 }
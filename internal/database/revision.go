@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/lib/pq"
+)
+
+// ErrNotRevertible is returned when a revision can no longer be undone, such as an item deleted
+// since it was made, or one brought back from the trash already
+var ErrNotRevertible = errors.New("revision can no longer be reverted")
+
+// GetRevisions returns the most recent changes to a list and its items, newest first
+func (repo *Repository) GetRevisions(ctx context.Context, listId int) ([]constants.Revision, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT r.id, r.listid, r.itemid, u.name, r.action, r.before, r.after, r.created_at,
+		       i.id IS NOT NULL, i.deleted_at IS NOT NULL
+		FROM `+constants.DB_TABLE_REVISION+` r
+		LEFT JOIN `+constants.DB_TABLE_USER+` u ON u.id = r.userid
+		LEFT JOIN `+constants.DB_TABLE_ITEM+` i ON i.id = r.itemid
+		WHERE r.listid = $1
+		ORDER BY r.id DESC
+		LIMIT $2`, listId, constants.MaxRevisions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []constants.Revision
+	for rows.Next() {
+		var rev constants.Revision
+		var before, after []byte
+		var itemExists, itemDeleted bool
+		err := rows.Scan(&rev.Id, &rev.ListId, &rev.ItemId, &rev.ActorName, &rev.Action, &before, &after, &rev.CreatedAt, &itemExists, &itemDeleted)
+		if err != nil {
+			return nil, err
+		}
+		if err := describeRevision(&rev, before, after); err != nil {
+			return nil, err
+		}
+		switch {
+		case !rev.ItemId.Valid:
+			rev.Revertible = rev.Action == constants.REVISION_UPDATE
+		case rev.Action == constants.REVISION_DELETE:
+			rev.Revertible = itemExists && itemDeleted
+		default:
+			rev.Revertible = itemExists && !itemDeleted
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// describeRevision fills in the subject and field changes of rev from its snapshots
+func describeRevision(rev *constants.Revision, before []byte, after []byte) error {
+	if !rev.ItemId.Valid {
+		b, a, err := unmarshalSnapshots[constants.ListSnapshot](before, after)
+		if err != nil {
+			return err
+		}
+		rev.Subject = snapshotName(b, a, func(s *constants.ListSnapshot) string { return s.Name })
+		rev.Changes = constants.DiffLists(b, a)
+		return nil
+	}
+	b, a, err := unmarshalSnapshots[constants.ItemSnapshot](before, after)
+	if err != nil {
+		return err
+	}
+	rev.Subject = snapshotName(b, a, func(s *constants.ItemSnapshot) string { return s.Name })
+	rev.Changes = constants.DiffItems(b, a)
+	return nil
+}
+
+func unmarshalSnapshots[T any](before []byte, after []byte) (*T, *T, error) {
+	var b, a *T
+	if before != nil {
+		b = new(T)
+		if err := json.Unmarshal(before, b); err != nil {
+			return nil, nil, err
+		}
+	}
+	if after != nil {
+		a = new(T)
+		if err := json.Unmarshal(after, a); err != nil {
+			return nil, nil, err
+		}
+	}
+	return b, a, nil
+}
+
+// snapshotName is the name after the change, or before it for a deletion
+func snapshotName[T any](before *T, after *T, name func(*T) string) string {
+	if after != nil {
+		return name(after)
+	}
+	if before != nil {
+		return name(before)
+	}
+	return ""
+}
+
+// RevertRevision undoes a revision of listId on behalf of userId, recording the undo as a revision of
+// its own. An update puts back the fields as they were before it; a creation or restore moves the
+// item to the trash; a deletion brings the item back. It returns false if the list has no such
+// revision, and ErrNotRevertible if the item has changed state since in a way that rules it out.
+func (repo *Repository) RevertRevision(ctx context.Context, userId int, listId int, revisionId int) (bool, error) {
+	var itemId sql.NullInt64
+	var action string
+	var before []byte
+	err := repo.db.QueryRowContext(ctx, "SELECT itemid, action, before FROM "+constants.DB_TABLE_REVISION+" WHERE id = $1 AND listid = $2", revisionId, listId).
+		Scan(&itemId, &action, &before)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !itemId.Valid {
+		if action != constants.REVISION_UPDATE {
+			return false, ErrNotRevertible
+		}
+		var snapshot constants.ListSnapshot
+		if err := json.Unmarshal(before, &snapshot); err != nil {
+			return false, err
+		}
+		return true, repo.UpdateList(ctx, userId, listId, snapshot.Params())
+	}
+
+	id := int(itemId.Int64)
+	var done bool
+	switch action {
+	case constants.REVISION_UPDATE:
+		var snapshot constants.ItemSnapshot
+		if err := json.Unmarshal(before, &snapshot); err != nil {
+			return false, err
+		}
+		done, err = repo.revertItemUpdate(ctx, userId, listId, id, snapshot)
+	case constants.REVISION_CREATE, constants.REVISION_RESTORE:
+		done, err = repo.trashItem(ctx, userId, id, listId)
+	case constants.REVISION_DELETE:
+		done, err = repo.untrashItem(ctx, userId, id, listId)
+	}
+	if err != nil {
+		return false, err
+	}
+	if !done {
+		return false, ErrNotRevertible
+	}
+	return true, nil
+}
+
+// revertItemUpdate puts an item back the way snapshot has it, as long as it is still in the list.
+// A section deleted since is left off.
+func (repo *Repository) revertItemUpdate(ctx context.Context, userId int, listId int, itemId int, snapshot constants.ItemSnapshot) (bool, error) {
+	inList, err := repo.ItemInList(ctx, itemId, listId)
+	if err != nil || !inList {
+		return false, err
+	}
+	item := snapshot.Insert(uint64(listId))
+	if item.SectionId.Valid {
+		var exists bool
+		err := repo.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+constants.DB_TABLE_ITEM_SECTION+" WHERE id = $1 AND listid = $2)", item.SectionId.Int64, listId).Scan(&exists)
+		if err != nil {
+			return false, err
+		}
+		item.SectionId.Valid = exists
+	}
+	return true, repo.UpdateItem(ctx, userId, itemId, item)
+}
+
+// execer is what recordRevision needs from either the pool or a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// recordRevision appends a change made by userId to listId's history. before and after are
+// snapshots, nil where the item didn't exist; itemId is zero for changes to the list itself.
+func recordRevision[T any](ctx context.Context, q execer, listId uint64, itemId uint64, userId int, action string, before *T, after *T) error {
+	beforeJSON, err := snapshotJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := snapshotJSON(after)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, "INSERT INTO "+constants.DB_TABLE_REVISION+" (listid, itemid, userid, action, before, after, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		listId, sql.NullInt64{Int64: int64(itemId), Valid: itemId != 0}, userId, action, beforeJSON, afterJSON, time.Now())
+	return err
+}
+
+// snapshotJSON encodes a snapshot for a JSONB column, or SQL NULL for none
+func snapshotJSON[T any](snapshot *T) (sql.NullString, error) {
+	if snapshot == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(snapshot)
+	return sql.NullString{String: string(b), Valid: true}, err
+}
+
+// itemSnapshot reads what a revision records of an item, along with its list, locking the row
+// until tx ends. It returns sql.ErrNoRows if the item doesn't exist.
+func itemSnapshot(ctx context.Context, tx *sql.Tx, itemId int) (uint64, constants.ItemSnapshot, error) {
+	var listId uint64
+	var s constants.ItemSnapshot
+	var url, notes, section, currency sql.NullString
+	var priority, sectionId, price sql.NullInt64
+	err := tx.QueryRowContext(ctx, `
+		SELECT i.listid, i.name, i.url, i.notes, i.priority, i.sectionid, s.name, i.quantity, i.fulfilled, i.price, i.currency, i.tags
+		FROM `+constants.DB_TABLE_ITEM+` i
+		LEFT JOIN `+constants.DB_TABLE_ITEM_SECTION+` s ON s.id = i.sectionid
+		WHERE i.id = $1
+		FOR UPDATE OF i`, itemId).
+		Scan(&listId, &s.Name, &url, &notes, &priority, &sectionId, &section, &s.Quantity, &s.Fulfilled, &price, &currency, pq.Array(&s.Tags))
+	if err != nil {
+		return 0, s, err
+	}
+	s.URL, s.Notes, s.Section, s.Currency = url.String, notes.String, section.String, currency.String
+	s.Priority = optionalInt(priority)
+	s.SectionId = optionalInt(sectionId)
+	s.Price = optionalInt(price)
+	return listId, s, nil
+}
+
+// listSnapshot reads what a revision records of a list's details, locking the row until tx ends
+func listSnapshot(ctx context.Context, tx *sql.Tx, listId int) (constants.ListSnapshot, error) {
+	var s constants.ListSnapshot
+	var description sql.NullString
+	err := tx.QueryRowContext(ctx, "SELECT name, description, share_with_group, group_can_edit FROM "+constants.DB_TABLE_LIST+" WHERE id = $1 FOR UPDATE", listId).
+		Scan(&s.Name, &description, &s.ShareWithGroup, &s.GroupCanEdit)
+	s.Description = description.String
+	return s, err
+}
+
+func optionalInt(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
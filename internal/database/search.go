@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/lib/pq"
+)
+
+// searchableList matches the lists l, owned by u, that user $2 can view: the same rule as UserCanViewList
+const searchableList = `(l.userid = $2 OR (l.share_with_group = true AND u.groupid = (SELECT groupid FROM ` + constants.DB_TABLE_USER + ` WHERE id = $2)))`
+
+// Search runs a full-text search over the lists and items the user can view and the collections they own.
+// The query uses web search syntax: quoted phrases, "or", and - to exclude a word.
+func (repo *Repository) Search(ctx context.Context, userId int, query string) (constants.SearchResults, error) {
+	var results constants.SearchResults
+
+	err := querySearch(ctx, repo.db, `
+		SELECT l.id, l.name, l.description, l.tags, l.userid, u.name, l.userid = $2
+		FROM `+constants.DB_TABLE_LIST+` l
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = l.userid,
+		websearch_to_tsquery('english', $1) q
+		WHERE l.search_vector @@ q AND l.deleted_at IS NULL AND `+searchableList+`
+		ORDER BY ts_rank(l.search_vector, q) DESC, LOWER(l.name), l.id
+		LIMIT $3`, query, userId,
+		func(rows *sql.Rows) error {
+			var l constants.SearchList
+			err := rows.Scan(&l.Id, &l.Name, &l.Description, pq.Array(&l.Tags), &l.OwnerId, &l.OwnerName, &l.Owned)
+			results.Lists = append(results.Lists, l)
+			return err
+		})
+	if err != nil {
+		return results, err
+	}
+
+	err = querySearch(ctx, repo.db, `
+		SELECT i.id, i.name, i.url, i.notes, i.tags, l.id, l.name, l.userid = $2
+		FROM `+constants.DB_TABLE_ITEM+` i
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = i.listid
+		JOIN `+constants.DB_TABLE_USER+` u ON u.id = l.userid,
+		websearch_to_tsquery('english', $1) q
+		WHERE i.search_vector @@ q AND i.deleted_at IS NULL AND l.deleted_at IS NULL AND `+searchableList+`
+		ORDER BY ts_rank(i.search_vector, q) DESC, LOWER(l.name), i.position, i.id
+		LIMIT $3`, query, userId,
+		func(rows *sql.Rows) error {
+			var i constants.SearchItem
+			err := rows.Scan(&i.Id, &i.Name, &i.URL, &i.Notes, pq.Array(&i.Tags), &i.ListId, &i.ListName, &i.Owned)
+			results.Items = append(results.Items, i)
+			return err
+		})
+	if err != nil {
+		return results, err
+	}
+
+	err = querySearch(ctx, repo.db, `
+		SELECT c.id, c.name, c.description, c.sharecode
+		FROM `+constants.DB_TABLE_COLLECTION+` c,
+		websearch_to_tsquery('english', $1) q
+		WHERE c.search_vector @@ q AND c.deleted_at IS NULL AND c.userid = $2
+		ORDER BY ts_rank(c.search_vector, q) DESC, LOWER(c.name), c.id
+		LIMIT $3`, query, userId,
+		func(rows *sql.Rows) error {
+			var c constants.Collection
+			err := rows.Scan(&c.Id, &c.Name, &c.Description, &c.ShareCode)
+			results.Collections = append(results.Collections, c)
+			return err
+		})
+	return results, err
+}
+
+// querySearch runs one of Search's queries, which all take the query text, the user and the result cap
+func querySearch(ctx context.Context, db *sql.DB, sqlQuery string, query string, userId int, scan func(*sql.Rows) error) error {
+	rows, err := db.QueryContext(ctx, sqlQuery, query, userId, constants.MaxSearchResults)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	_ "github.com/lib/pq"
+)
+
+// GetListSections returns a list's sections in display order
+func (repo *Repository) GetListSections(ctx context.Context, listId int) ([]constants.ItemSection, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, listid, name, position FROM "+constants.DB_TABLE_ITEM_SECTION+" WHERE listid = $1 ORDER BY position, id", listId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sections []constants.ItemSection
+	for rows.Next() {
+		var s constants.ItemSection
+		if err := rows.Scan(&s.Id, &s.ListId, &s.Name, &s.Position); err != nil {
+			return nil, err
+		}
+		sections = append(sections, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+func (repo *Repository) GetSection(ctx context.Context, sectionId int) (constants.ItemSection, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT id, listid, name, position FROM "+constants.DB_TABLE_ITEM_SECTION+" WHERE id = $1", sectionId)
+	var s constants.ItemSection
+	if err := row.Scan(&s.Id, &s.ListId, &s.Name, &s.Position); err != nil {
+		return constants.ItemSection{}, err
+	}
+	return s, nil
+}
+
+// SectionInList checks whether a section belongs to the given list
+func (repo *Repository) SectionInList(ctx context.Context, sectionId int, listId int) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM "+constants.DB_TABLE_ITEM_SECTION+" WHERE id = $1 AND listid = $2", sectionId, listId)
+	var matches int
+	if err := row.Scan(&matches); err != nil {
+		return false, err
+	}
+	return matches != 0, nil
+}
+
+// CreateSection adds a section after the list's existing ones
+func (repo *Repository) CreateSection(ctx context.Context, listId int, name string) (int, error) {
+	var newId int
+	err := repo.db.QueryRowContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_ITEM_SECTION+` (listid, name, position)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(position), 0) + 1 FROM `+constants.DB_TABLE_ITEM_SECTION+` WHERE listid = $1))
+		RETURNING id
+	`, listId, name).Scan(&newId)
+	if err != nil {
+		return 0, err
+	}
+	return newId, nil
+}
+
+func (repo *Repository) RenameSection(ctx context.Context, sectionId int, name string) error {
+	_, err := repo.db.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_ITEM_SECTION+" SET name = $1 WHERE id = $2", name, sectionId)
+	return err
+}
+
+// DeleteSection removes a section. Its items stay in the list, outside any section.
+func (repo *Repository) DeleteSection(ctx context.Context, sectionId int) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_ITEM+" SET sectionid = NULL WHERE sectionid = $1", sectionId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_ITEM_SECTION+" WHERE id = $1", sectionId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MoveItemToSection puts an item under a section, or outside every section when sectionId is not valid
+func (repo *Repository) MoveItemToSection(ctx context.Context, itemId int, sectionId sql.NullInt64) error {
+	_, err := repo.db.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_ITEM+" SET sectionid = $1 WHERE id = $2", sectionId, itemId)
+	return err
+}
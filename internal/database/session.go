@@ -0,0 +1,248 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/gob"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// sessionTouchInterval is how stale last_seen_at may get before a request refreshes it,
+// so browsing doesn't write to the database on every page load
+const sessionTouchInterval = 5 * time.Minute
+
+// maxUserAgentLength caps the user agent kept with a session; the header is whatever the client sends
+const maxUserAgentLength = 512
+
+// SessionStore keeps browser sessions in Postgres so they can be listed and revoked.
+// The cookie only carries a signed random token; the session values live in the row its hash points to.
+type SessionStore struct {
+	repo     *Repository
+	codecs   []securecookie.Codec
+	options  sessions.Options
+	clientIP func(*http.Request) string
+}
+
+// NewSessionStore returns a session store backed by repo. keyPairs sign the cookie the same way
+// sessions.NewCookieStore does, and clientIP names the address each session was last seen from.
+func NewSessionStore(repo *Repository, clientIP func(*http.Request) string, keyPairs ...[]byte) *SessionStore {
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range codecs {
+		if c, ok := codec.(*securecookie.SecureCookie); ok {
+			c.MaxAge(constants.SESSION_MAX_AGE)
+		}
+	}
+	return &SessionStore{
+		repo:   repo,
+		codecs: codecs,
+		options: sessions.Options{
+			Path:     "/",
+			MaxAge:   constants.SESSION_MAX_AGE,
+			HttpOnly: true,
+			Secure:   false,
+			SameSite: http.SameSiteLaxMode,
+		},
+		clientIP: clientIP,
+	}
+}
+
+// Get returns the named session, cached for the rest of the request
+func (s *SessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session the request's cookie points to. A missing, forged, expired or revoked
+// cookie gets a new empty session rather than an error, as does a cookie left from before sessions
+// were kept in the database.
+func (s *SessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.codecs...); err != nil {
+		return session, nil
+	}
+
+	var id uint64
+	var data []byte
+	var lastSeenAt time.Time
+	err = s.repo.db.QueryRowContext(r.Context(), "SELECT id, data, last_seen_at FROM "+constants.DB_TABLE_SESSION+" WHERE token_hash = $1 AND expires_at > $2",
+		hashSessionToken(token), time.Now()).Scan(&id, &data, &lastSeenAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return session, nil
+		}
+		return session, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.ID = token
+	session.IsNew = false
+
+	if time.Since(lastSeenAt) > sessionTouchInterval {
+		_, err = s.repo.db.ExecContext(r.Context(), "UPDATE "+constants.DB_TABLE_SESSION+" SET last_seen_at = $1, ip = $2 WHERE id = $3", time.Now(), s.clientIP(r), id)
+		if err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
+
+// Save writes the session's values to its row, creating the row and the cookie for a new session.
+// A negative MaxAge deletes the row and clears the cookie. Clearing session.ID before saving starts a
+// fresh session under a new token.
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.repo.DeleteSession(r.Context(), session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(session.Values); err != nil {
+		return err
+	}
+	var userId sql.NullInt64
+	if id, ok := session.Values["userId"].(int); ok {
+		userId = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(session.Options.MaxAge) * time.Second)
+
+	if session.ID == "" {
+		token, err := newSessionToken()
+		if err != nil {
+			return err
+		}
+		userAgent := r.UserAgent()
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = userAgent[:maxUserAgentLength]
+		}
+		tx, err := s.repo.db.BeginTx(r.Context(), nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		// Expired sessions are never read again, so clear them out as new ones arrive
+		if _, err := tx.ExecContext(r.Context(), "DELETE FROM "+constants.DB_TABLE_SESSION+" WHERE expires_at <= $1", now); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(r.Context(), `
+			INSERT INTO `+constants.DB_TABLE_SESSION+` (token_hash, userid, data, user_agent, ip, created_at, last_seen_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $6, $7)
+		`, hashSessionToken(token), userId, data.Bytes(), userAgent, s.clientIP(r), now, expiresAt)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		session.ID = token
+	} else {
+		result, err := s.repo.db.ExecContext(r.Context(), "UPDATE "+constants.DB_TABLE_SESSION+" SET data = $1, userid = $2, expires_at = $3 WHERE token_hash = $4",
+			data.Bytes(), userId, expiresAt, hashSessionToken(session.ID))
+		if err != nil {
+			return err
+		}
+		// The session was revoked while this request was running; leave it revoked
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// GetUserSessions lists a user's unexpired sessions, most recently seen first.
+// currentToken marks which of them made the request.
+func (repo *Repository) GetUserSessions(ctx context.Context, userId int, currentToken string) ([]constants.Session, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, token_hash, user_agent, ip, created_at, last_seen_at FROM "+constants.DB_TABLE_SESSION+" WHERE userid = $1 AND expires_at > $2 ORDER BY last_seen_at DESC",
+		userId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	currentHash := hashSessionToken(currentToken)
+	result := make([]constants.Session, 0)
+	for rows.Next() {
+		var session constants.Session
+		var tokenHash string
+		if err := rows.Scan(&session.Id, &tokenHash, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastSeenAt); err != nil {
+			return nil, err
+		}
+		session.Current = currentToken != "" && tokenHash == currentHash
+		result = append(result, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteUserSession signs out one of a user's sessions, returning the user agent it was signed in with.
+// Reports false if the user has no such session.
+func (repo *Repository) DeleteUserSession(ctx context.Context, userId int, sessionId int) (string, bool, error) {
+	var userAgent string
+	err := repo.db.QueryRowContext(ctx, "DELETE FROM "+constants.DB_TABLE_SESSION+" WHERE id = $1 AND userid = $2 RETURNING user_agent", sessionId, userId).Scan(&userAgent)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return userAgent, true, nil
+}
+
+// DeleteOtherUserSessions signs out every session of a user except the one holding keepToken,
+// returning how many were signed out
+func (repo *Repository) DeleteOtherUserSessions(ctx context.Context, userId int, keepToken string) (int64, error) {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_SESSION+" WHERE userid = $1 AND token_hash <> $2", userId, hashSessionToken(keepToken))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteSession removes the session holding token, whoever it belongs to
+func (repo *Repository) DeleteSession(ctx context.Context, token string) error {
+	_, err := repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_SESSION+" WHERE token_hash = $1", hashSessionToken(token))
+	return err
+}
+
+func newSessionToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tokenBytes), nil
+}
+
+// Like API tokens, session tokens are 256 random bits, so they are stored as a plain hash to look up by
+func hashSessionToken(token string) string {
+	return hashApiToken(token)
+}
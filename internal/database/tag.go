@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/lib/pq"
+)
+
+// GetUserTags returns the tag index of a user: every tag on their lists or on items in their lists, alphabetically
+func (repo *Repository) GetUserTags(ctx context.Context, userId int) ([]constants.Tag, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT t.tag, SUM(t.lists)::INT, SUM(t.items)::INT
+		FROM (
+			SELECT UNNEST(l.tags) AS tag, 1 AS lists, 0 AS items
+			FROM `+constants.DB_TABLE_LIST+` l
+			WHERE l.userid = $1 AND l.deleted_at IS NULL
+			UNION ALL
+			SELECT UNNEST(i.tags), 0, 1
+			FROM `+constants.DB_TABLE_ITEM+` i
+			JOIN `+constants.DB_TABLE_LIST+` l ON l.id = i.listid
+			WHERE l.userid = $1 AND l.deleted_at IS NULL AND i.deleted_at IS NULL
+		) t
+		GROUP BY t.tag
+		ORDER BY t.tag`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []constants.Tag
+	for rows.Next() {
+		var t constants.Tag
+		if err := rows.Scan(&t.Name, &t.ListCount, &t.ItemCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// GetUserTaggedItems returns the items carrying tag across all of a user's lists
+func (repo *Repository) GetUserTaggedItems(ctx context.Context, userId int, tag string) ([]constants.TaggedItem, error) {
+	return repo.queryTaggedItems(ctx, "l.userid = $2", tag, userId)
+}
+
+// GetCollectionTaggedItems returns the items carrying tag across every list in a collection
+func (repo *Repository) GetCollectionTaggedItems(ctx context.Context, collectionId int, tag string) ([]constants.TaggedItem, error) {
+	return repo.queryTaggedItems(ctx, "l.id IN (SELECT listid FROM "+constants.DB_TABLE_COLLECTION_LIST+" WHERE collectionid = $2)", tag, collectionId)
+}
+
+// queryTaggedItems returns the items carrying tag on the lists l matching where, grouped by list
+// and in each list's own order. Link previews and attachments aren't filled in.
+func (repo *Repository) queryTaggedItems(ctx context.Context, where string, tag string, arg any) ([]constants.TaggedItem, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT i.id, i.name, i.url, i.priority, i.notes, i.position, i.sectionid, i.completed_at, i.completed_by, i.quantity, i.fulfilled, `+itemContributed+`, i.price, i.currency, i.tags,
+		       l.id, l.name
+		FROM `+constants.DB_TABLE_ITEM+` i
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = i.listid
+		WHERE i.tags @> ARRAY[$1::TEXT] AND i.deleted_at IS NULL AND l.deleted_at IS NULL AND `+where+`
+		ORDER BY LOWER(l.name), l.id, i.position, i.id`, tag, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []constants.TaggedItem
+	for rows.Next() {
+		var i constants.TaggedItem
+		err := rows.Scan(&i.Id, &i.Name, &i.URL, &i.Priority, &i.Notes, &i.Position, &i.SectionId, &i.CompletedAt, &i.CompletedBy, &i.Quantity, &i.Fulfilled, &i.Contributed, &i.Price, &i.Currency, pq.Array(&i.Tags),
+			&i.ListId, &i.ListName)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// GetGroupTemplates returns the list templates of a group, alphabetically
+func (repo *Repository) GetGroupTemplates(ctx context.Context, groupId int) ([]constants.ListTemplate, error) {
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT t.id, t.groupid, t.name, t.description, (SELECT COUNT(ti.id) FROM `+constants.DB_TABLE_TEMPLATE_ITEM+` ti WHERE ti.templateid = t.id), t.created_at
+		FROM `+constants.DB_TABLE_TEMPLATE+` t
+		WHERE t.groupid = $1
+		ORDER BY LOWER(t.name), t.id`, groupId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]constants.ListTemplate, 0)
+	for rows.Next() {
+		var t constants.ListTemplate
+		if err := rows.Scan(&t.Id, &t.GroupId, &t.Name, &t.Description, &t.ItemCount, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// GetGroupTemplate returns a template of the group, or sql.ErrNoRows if the group has no such template
+func (repo *Repository) GetGroupTemplate(ctx context.Context, groupId int, templateId int) (constants.ListTemplate, error) {
+	var t constants.ListTemplate
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT t.id, t.groupid, t.name, t.description, (SELECT COUNT(ti.id) FROM `+constants.DB_TABLE_TEMPLATE_ITEM+` ti WHERE ti.templateid = t.id), t.created_at
+		FROM `+constants.DB_TABLE_TEMPLATE+` t
+		WHERE t.groupid = $1 AND t.id = $2`, groupId, templateId).
+		Scan(&t.Id, &t.GroupId, &t.Name, &t.Description, &t.ItemCount, &t.CreatedAt)
+	return t, err
+}
+
+func (repo *Repository) TemplateNameTaken(ctx context.Context, groupId int, name string) (bool, error) {
+	var taken bool
+	err := repo.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+constants.DB_TABLE_TEMPLATE+" WHERE groupid = $1 AND name = $2)", groupId, name).Scan(&taken)
+	return taken, err
+}
+
+// CreateTemplateFromList makes a template for the group out of the description and live items of a
+// list, and returns its id. Only what describes each item is kept; completion, claims and the like are not.
+func (repo *Repository) CreateTemplateFromList(ctx context.Context, groupId int, listId int, name string) (int, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var templateId int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_TEMPLATE+` (groupid, name, description, created_at)
+		SELECT $1, $2, NULLIF(description, ''), $3
+		FROM `+constants.DB_TABLE_LIST+`
+		WHERE id = $4
+		RETURNING id`, groupId, name, time.Now(), listId).Scan(&templateId)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_TEMPLATE_ITEM+` (templateid, name, url, notes, priority, quantity, price, currency, position)
+		SELECT $1, name, url, notes, priority, quantity, price, currency, ROW_NUMBER() OVER (ORDER BY position, id)
+		FROM `+constants.DB_TABLE_ITEM+`
+		WHERE listid = $2 AND deleted_at IS NULL`, templateId, listId)
+	if err != nil {
+		return 0, err
+	}
+	return templateId, tx.Commit()
+}
+
+// DeleteGroupTemplate removes a template of the group along with its items. It returns the template's
+// name, and false if the group has no such template. Lists made from it are unaffected.
+func (repo *Repository) DeleteGroupTemplate(ctx context.Context, groupId int, templateId int) (string, bool, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+
+	var name string
+	err = tx.QueryRowContext(ctx, "DELETE FROM "+constants.DB_TABLE_TEMPLATE+" WHERE groupid = $1 AND id = $2 RETURNING name", groupId, templateId).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_TEMPLATE_ITEM+" WHERE templateid = $1", templateId); err != nil {
+		return "", false, err
+	}
+	return name, true, tx.Commit()
+}
+
+// CreateListFromTemplate creates a list for userId holding the template's items, and returns its id
+func (repo *Repository) CreateListFromTemplate(ctx context.Context, userId int, templateId int, name string, description string) (int, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var listId int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_LIST+` (userid, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id`, userId, name, description).Scan(&listId)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_ITEM+` (listid, name, url, notes, priority, quantity, price, currency, position)
+		SELECT $1, name, url, notes, priority, quantity, price, currency, position
+		FROM `+constants.DB_TABLE_TEMPLATE_ITEM+`
+		WHERE templateid = $2
+		ORDER BY position, id
+		RETURNING id`, listId, templateId)
+	if err != nil {
+		return 0, err
+	}
+	var itemIds []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		itemIds = append(itemIds, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, itemId := range itemIds {
+		if err := recordItemCreated(ctx, tx, userId, itemId); err != nil {
+			return 0, err
+		}
+	}
+	return listId, tx.Commit()
+}
@@ -0,0 +1,299 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// ErrNameTaken is returned when restoring a list or collection whose name has since been given to another one
+var ErrNameTaken = errors.New("name already taken")
+
+// GetTrash returns what a user has deleted and can still restore
+func (repo *Repository) GetTrash(ctx context.Context, userId int) (constants.Trash, error) {
+	var trash constants.Trash
+
+	rows, err := repo.db.QueryContext(ctx, `
+		SELECT l.id, l.name, (SELECT COUNT(i.id) FROM `+constants.DB_TABLE_ITEM+` i WHERE i.listid = l.id AND i.deleted_at IS NULL), l.deleted_at
+		FROM `+constants.DB_TABLE_LIST+` l
+		WHERE l.userid = $1 AND l.deleted_at IS NOT NULL
+		ORDER BY l.deleted_at DESC, l.id`, userId)
+	if err != nil {
+		return trash, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l constants.TrashedList
+		if err := rows.Scan(&l.Id, &l.Name, &l.ItemCount, &l.DeletedAt); err != nil {
+			return trash, err
+		}
+		trash.Lists = append(trash.Lists, l)
+	}
+	if err := rows.Err(); err != nil {
+		return trash, err
+	}
+
+	rows, err = repo.db.QueryContext(ctx, `
+		SELECT i.id, i.name, l.id, l.name, i.deleted_at
+		FROM `+constants.DB_TABLE_ITEM+` i
+		JOIN `+constants.DB_TABLE_LIST+` l ON l.id = i.listid
+		WHERE l.userid = $1 AND l.deleted_at IS NULL AND i.deleted_at IS NOT NULL
+		ORDER BY i.deleted_at DESC, i.id`, userId)
+	if err != nil {
+		return trash, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i constants.TrashedItem
+		if err := rows.Scan(&i.Id, &i.Name, &i.ListId, &i.ListName, &i.DeletedAt); err != nil {
+			return trash, err
+		}
+		trash.Items = append(trash.Items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return trash, err
+	}
+
+	rows, err = repo.db.QueryContext(ctx, `
+		SELECT c.id, c.name, c.deleted_at
+		FROM `+constants.DB_TABLE_COLLECTION+` c
+		WHERE c.userid = $1 AND c.deleted_at IS NOT NULL
+		ORDER BY c.deleted_at DESC, c.id`, userId)
+	if err != nil {
+		return trash, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c constants.TrashedCollection
+		if err := rows.Scan(&c.Id, &c.Name, &c.DeletedAt); err != nil {
+			return trash, err
+		}
+		trash.Collections = append(trash.Collections, c)
+	}
+	return trash, rows.Err()
+}
+
+// Each of these selects the ids of one user's ($1) trashed rows
+const (
+	trashedLists       = "SELECT id FROM " + constants.DB_TABLE_LIST + " WHERE userid = $1 AND deleted_at IS NOT NULL"
+	trashedItems       = "SELECT i.id FROM " + constants.DB_TABLE_ITEM + " i JOIN " + constants.DB_TABLE_LIST + " l ON l.id = i.listid WHERE l.userid = $1 AND l.deleted_at IS NULL AND i.deleted_at IS NOT NULL"
+	trashedCollections = "SELECT id FROM " + constants.DB_TABLE_COLLECTION + " WHERE userid = $1 AND deleted_at IS NOT NULL"
+)
+
+// RestoreList takes a list out of the user's trash, reporting whether it was there.
+// It returns ErrNameTaken if the user has since made another list with the same name.
+func (repo *Repository) RestoreList(ctx context.Context, userId int, listId int) (bool, error) {
+	var name string
+	err := repo.db.QueryRowContext(ctx, "SELECT name FROM "+constants.DB_TABLE_LIST+" WHERE id = $2 AND id IN ("+trashedLists+")", userId, listId).Scan(&name)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	taken, err := repo.ListNameTaken(ctx, userId, name)
+	if err != nil {
+		return false, err
+	}
+	if taken {
+		return false, ErrNameTaken
+	}
+	return repo.execRestore(ctx, "UPDATE "+constants.DB_TABLE_LIST+" SET deleted_at = NULL WHERE id = $2 AND id IN ("+trashedLists+")", userId, listId)
+}
+
+// RestoreItem puts an item back in its list, in its old place, reporting whether it was in the user's trash
+func (repo *Repository) RestoreItem(ctx context.Context, userId int, itemId int) (bool, error) {
+	var trashed bool
+	err := repo.db.QueryRowContext(ctx, "SELECT EXISTS ("+trashedItems+" AND i.id = $2)", userId, itemId).Scan(&trashed)
+	if err != nil || !trashed {
+		return false, err
+	}
+	return repo.untrashItem(ctx, userId, itemId, 0)
+}
+
+// RestoreCollection takes a collection out of the user's trash, reporting whether it was there.
+// It returns ErrNameTaken if the user has since made another collection with the same name.
+func (repo *Repository) RestoreCollection(ctx context.Context, userId int, collectionId int) (bool, error) {
+	var name string
+	err := repo.db.QueryRowContext(ctx, "SELECT name FROM "+constants.DB_TABLE_COLLECTION+" WHERE id = $2 AND id IN ("+trashedCollections+")", userId, collectionId).Scan(&name)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	taken, err := repo.CollectionNameTaken(ctx, userId, name)
+	if err != nil {
+		return false, err
+	}
+	if taken {
+		return false, ErrNameTaken
+	}
+	return repo.execRestore(ctx, "UPDATE "+constants.DB_TABLE_COLLECTION+" SET deleted_at = NULL WHERE id = $2 AND id IN ("+trashedCollections+")", userId, collectionId)
+}
+
+func (repo *Repository) execRestore(ctx context.Context, query string, userId int, id int) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, query, userId, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected != 0, nil
+}
+
+// PurgeTrashedList deletes a list in the user's trash for good, with everything in it, reporting whether it was there
+func (repo *Repository) PurgeTrashedList(ctx context.Context, userId int, listId int) (bool, error) {
+	return repo.purge(ctx, func(tx *sql.Tx) (int64, []string, error) {
+		return purgeLists(ctx, tx, "SELECT id FROM ("+trashedLists+") t WHERE id = $2", userId, listId)
+	})
+}
+
+// PurgeTrashedItem deletes an item in the user's trash for good, reporting whether it was there
+func (repo *Repository) PurgeTrashedItem(ctx context.Context, userId int, itemId int) (bool, error) {
+	return repo.purge(ctx, func(tx *sql.Tx) (int64, []string, error) {
+		return purgeItems(ctx, tx, "SELECT id FROM ("+trashedItems+") t WHERE id = $2", userId, itemId)
+	})
+}
+
+// PurgeTrashedCollection deletes a collection in the user's trash for good, reporting whether it was there.
+// Its lists are left alone.
+func (repo *Repository) PurgeTrashedCollection(ctx context.Context, userId int, collectionId int) (bool, error) {
+	return repo.purge(ctx, func(tx *sql.Tx) (int64, []string, error) {
+		purged, err := purgeCollections(ctx, tx, "SELECT id FROM ("+trashedCollections+") t WHERE id = $2", userId, collectionId)
+		return purged, nil, err
+	})
+}
+
+// EmptyTrash deletes everything in the user's trash for good
+func (repo *Repository) EmptyTrash(ctx context.Context, userId int) error {
+	_, err := repo.purge(ctx, func(tx *sql.Tx) (int64, []string, error) {
+		return purgeTrash(ctx, tx, trashedLists, trashedItems, trashedCollections, userId)
+	})
+	return err
+}
+
+// PurgeTrash deletes for good every list, item and collection that was deleted before cutoff, in anyone's trash
+func (repo *Repository) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	_, err := repo.purge(ctx, func(tx *sql.Tx) (int64, []string, error) {
+		var keys []string
+		var err error
+		purged, keys, err = purgeTrash(ctx, tx,
+			"SELECT id FROM "+constants.DB_TABLE_LIST+" WHERE deleted_at < $1",
+			"SELECT id FROM "+constants.DB_TABLE_ITEM+" WHERE deleted_at < $1",
+			"SELECT id FROM "+constants.DB_TABLE_COLLECTION+" WHERE deleted_at < $1",
+			cutoff)
+		return purged, keys, err
+	})
+	return purged, err
+}
+
+// purge runs f in a transaction, then removes the attachment files of whatever it deleted,
+// reporting whether it deleted anything
+func (repo *Repository) purge(ctx context.Context, f func(tx *sql.Tx) (int64, []string, error)) (bool, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	purged, attachmentKeys, err := f(tx)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	repo.removeAttachmentFiles(attachmentKeys...)
+	return purged != 0, nil
+}
+
+// purgeTrash deletes the lists, items and collections the three id queries select, all taking the same args,
+// and returns how many of them there were along with the keys of their attachment files
+func purgeTrash(ctx context.Context, tx *sql.Tx, listIds string, itemIds string, collectionIds string, args ...any) (int64, []string, error) {
+	lists, keys, err := purgeLists(ctx, tx, listIds, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	items, itemKeys, err := purgeItems(ctx, tx, itemIds, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	collections, err := purgeCollections(ctx, tx, collectionIds, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return lists + items + collections, append(keys, itemKeys...), nil
+}
+
+// purgeLists deletes the lists listIds selects, with their items, sections, history and places in collections,
+// and returns how many lists there were along with the keys of their attachment files
+func purgeLists(ctx context.Context, tx *sql.Tx, listIds string, args ...any) (int64, []string, error) {
+	_, keys, err := purgeItems(ctx, tx, "SELECT id FROM "+constants.DB_TABLE_ITEM+" WHERE listid IN ("+listIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_ITEM_SECTION+" WHERE listid IN ("+listIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_COLLECTION_LIST+" WHERE listid IN ("+listIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_REVISION+" WHERE listid IN ("+listIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_LIST+" WHERE id IN ("+listIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	purged, err := result.RowsAffected()
+	return purged, keys, err
+}
+
+// purgeItems deletes the items itemIds selects, with their claims, contributions, attachments and history,
+// and returns how many items there were along with the keys of their attachment files
+func purgeItems(ctx context.Context, tx *sql.Tx, itemIds string, args ...any) (int64, []string, error) {
+	_, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_ITEM_CLAIM+" WHERE itemid IN ("+itemIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_CONTRIBUTION+" WHERE itemid IN ("+itemIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_REVISION+" WHERE itemid IN ("+itemIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	keys, err := deleteAttachmentRows(ctx, tx, "itemid IN ("+itemIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_ITEM+" WHERE id IN ("+itemIds+")", args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	purged, err := result.RowsAffected()
+	return purged, keys, err
+}
+
+// purgeCollections deletes the collections collectionIds selects and returns how many there were
+func purgeCollections(ctx context.Context, tx *sql.Tx, collectionIds string, args ...any) (int64, error) {
+	_, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_COLLECTION_LIST+" WHERE collectionid IN ("+collectionIds+")", args...)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_COLLECTION+" WHERE id IN ("+collectionIds+")", args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
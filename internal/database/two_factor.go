@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/constants/random"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// totpOpts are the RFC 6238 defaults every authenticator app understands. One period of skew
+// either side allows for clock drift and a code typed just as it rolled over.
+var totpOpts = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// BeginTwoFactorSetup generates a new TOTP secret for a user who hasn't turned two-factor
+// authentication on yet, replacing any setup they started before. It only takes effect once
+// ConfirmTwoFactorSetup accepts a code made from it.
+func (repo *Repository) BeginTwoFactorSetup(ctx context.Context, userId int, accountName string) (*otp.Key, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      constants.TWO_FACTOR_ISSUER,
+		AccountName: accountName,
+		Period:      totpOpts.Period,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+	secret, err := encryptTwoFactorSecret(key.Secret())
+	if err != nil {
+		return nil, err
+	}
+	result, err := repo.db.ExecContext(ctx, `
+		INSERT INTO `+constants.DB_TABLE_TWO_FACTOR+` AS tf (userid, secret, enabled_at, last_step)
+		VALUES ($1, $2, NULL, 0)
+		ON CONFLICT (userid)
+		DO UPDATE SET secret = $2, last_step = 0 WHERE tf.enabled_at IS NULL
+	`, userId, secret)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, errors.New("two-factor authentication is already on")
+	}
+	return key, nil
+}
+
+// ConfirmTwoFactorSetup turns two-factor authentication on if code was made from the secret handed
+// out by BeginTwoFactorSetup, returning a fresh set of recovery codes. Reports false if the code
+// doesn't match or no setup was started.
+func (repo *Repository) ConfirmTwoFactorSetup(ctx context.Context, userId int, code string) ([]string, bool, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	var encrypted []byte
+	err = tx.QueryRowContext(ctx, "SELECT secret FROM "+constants.DB_TABLE_TWO_FACTOR+" WHERE userid = $1 AND enabled_at IS NULL FOR UPDATE", userId).Scan(&encrypted)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	secret, err := decryptTwoFactorSecret(encrypted)
+	if err != nil {
+		return nil, false, err
+	}
+	step, ok := matchTotp(secret, code, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_TWO_FACTOR+" SET enabled_at = $1, last_step = $2 WHERE userid = $3", time.Now(), step, userId)
+	if err != nil {
+		return nil, false, err
+	}
+	codes, err := replaceRecoveryCodes(ctx, tx, userId)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
+
+// GetTwoFactorStatus reports whether a user has two-factor authentication on, how many recovery
+// codes they have left, and whether their group requires it
+func (repo *Repository) GetTwoFactorStatus(ctx context.Context, userId int) (constants.TwoFactorStatus, error) {
+	var status constants.TwoFactorStatus
+	err := repo.db.QueryRowContext(ctx, `
+		SELECT tf.enabled_at,
+			(SELECT COUNT(1) FROM `+constants.DB_TABLE_RECOVERY_CODE+` rc WHERE rc.userid = u.id AND rc.used_at IS NULL),
+			COALESCE(gs.two_factor_required, false)
+		FROM `+constants.DB_TABLE_USER+` u
+		LEFT JOIN `+constants.DB_TABLE_TWO_FACTOR+` tf ON tf.userid = u.id
+		LEFT JOIN `+constants.DB_TABLE_GROUP_SETTINGS+` gs ON gs.groupid = u.groupid
+		WHERE u.id = $1
+	`, userId).Scan(&status.EnabledAt, &status.RecoveryCodesLeft, &status.Required)
+	if err != nil {
+		return constants.TwoFactorStatus{}, err
+	}
+	status.Enabled = status.EnabledAt.Valid
+	return status, nil
+}
+
+// VerifyTwoFactorCode checks a code from the user's authenticator app. A code is only accepted
+// once, and never one older than the last code accepted.
+func (repo *Repository) VerifyTwoFactorCode(ctx context.Context, userId int, code string) (bool, error) {
+	var encrypted []byte
+	var lastStep int64
+	err := repo.db.QueryRowContext(ctx, "SELECT secret, last_step FROM "+constants.DB_TABLE_TWO_FACTOR+" WHERE userid = $1 AND enabled_at IS NOT NULL", userId).Scan(&encrypted, &lastStep)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	secret, err := decryptTwoFactorSecret(encrypted)
+	if err != nil {
+		return false, err
+	}
+	step, ok := matchTotp(secret, code, time.Now())
+	if !ok || step <= lastStep {
+		return false, nil
+	}
+	// Another request may have spent the same code in the meantime
+	result, err := repo.db.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_TWO_FACTOR+" SET last_step = $1 WHERE userid = $2 AND last_step < $1", step, userId)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// UseRecoveryCode spends one of the user's recovery codes, reporting false if it isn't one of
+// theirs or was already used
+func (repo *Repository) UseRecoveryCode(ctx context.Context, userId int, code string) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_RECOVERY_CODE+" SET used_at = $1 WHERE userid = $2 AND code_hash = $3 AND used_at IS NULL",
+		time.Now(), userId, hashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ResetRecoveryCodes replaces a user's recovery codes with a fresh set
+func (repo *Repository) ResetRecoveryCodes(ctx context.Context, userId int) ([]string, error) {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	codes, err := replaceRecoveryCodes(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off for a user and drops their recovery codes
+func (repo *Repository) DisableTwoFactor(ctx context.Context, userId int) error {
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_RECOVERY_CODE+" WHERE userid = $1", userId); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_TWO_FACTOR+" WHERE userid = $1", userId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes swaps the user's recovery codes for new ones, returning them formatted
+// for display. Only their hashes are stored.
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userId int) ([]string, error) {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_RECOVERY_CODE+" WHERE userid = $1", userId); err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, constants.TWO_FACTOR_RECOVERY_CODES)
+	for i := 0; i < constants.TWO_FACTOR_RECOVERY_CODES; i++ {
+		code, err := random.String(10, constants.CHARSET_RECOVERY_CODE)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO "+constants.DB_TABLE_RECOVERY_CODE+" (userid, code_hash) VALUES ($1, $2)", userId, hashRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code as typed, ignoring case, spaces and the dash it is shown with
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashApiToken(code)
+}
+
+// matchTotp finds the time step within the allowed skew that code was made for
+func matchTotp(secret string, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpOpts.Digits.Length() {
+		return 0, false
+	}
+	period := int64(totpOpts.Period)
+	current := now.Unix() / period
+	for step := current - int64(totpOpts.Skew); step <= current+int64(totpOpts.Skew); step++ {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*period, 0), totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// twoFactorCipher encrypts TOTP secrets at rest. Its key is derived from the session key, so
+// changing LISTAWAY_AUTH_KEY leaves existing secrets unreadable and users must set up again.
+func twoFactorCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256(append([]byte("listaway two-factor secret:"), constants.SESSION_KEY...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptTwoFactorSecret(secret string) ([]byte, error) {
+	aead, err := twoFactorCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, []byte(secret), nil), nil
+}
+
+func decryptTwoFactorSecret(data []byte) (string, error) {
+	aead, err := twoFactorCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("two-factor secret is too short")
+	}
+	secret, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
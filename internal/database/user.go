@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
@@ -8,10 +9,8 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func AdminUserExists() bool {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT COUNT(1) FROM " + constants.DB_TABLE_USER + " WHERE instanceadmin = true")
+func (repo *Repository) AdminUserExists(ctx context.Context) bool {
+	row := repo.db.QueryRowContext(ctx, "SELECT COUNT(1) FROM " + constants.DB_TABLE_USER + " WHERE instanceadmin = true")
 
 	var numAdmins int
 	err := row.Scan(&numAdmins)
@@ -21,10 +20,11 @@ func AdminUserExists() bool {
 	return numAdmins > 0
 }
 
-func RegisterUser(user constants.UserRegister) error {
+// RegisterUser creates a user and returns its id
+func (repo *Repository) RegisterUser(ctx context.Context, user constants.UserRegister) (int, error) {
 	hash, err := hashPassword(user.Password)
 	if err != nil {
-		return err
+		return -1, err
 	}
 	hashedUser := constants.UserRegister{
 		GroupId:       user.GroupId,
@@ -34,20 +34,17 @@ func RegisterUser(user constants.UserRegister) error {
 		Admin:         user.Admin,
 		InstanceAdmin: user.InstanceAdmin,
 	}
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err = db.Exec("INSERT INTO "+constants.DB_TABLE_USER+" (groupid, email, name, passwordhash, admin, instanceadmin) VALUES ($1, $2, $3, $4, $5, $6)",
-		hashedUser.GroupId, hashedUser.Email, hashedUser.Name, hashedUser.Password, hashedUser.Admin, hashedUser.InstanceAdmin)
+	var userId int
+	err = repo.db.QueryRowContext(ctx, "INSERT INTO "+constants.DB_TABLE_USER+" (groupid, email, name, passwordhash, admin, instanceadmin) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		hashedUser.GroupId, hashedUser.Email, hashedUser.Name, hashedUser.Password, hashedUser.Admin, hashedUser.InstanceAdmin).Scan(&userId)
 	if err != nil {
-		return err
+		return -1, err
 	}
-	return nil
+	return userId, nil
 }
 
-func LoginUser(email, password string) (int, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT id, passwordhash FROM "+constants.DB_TABLE_USER+" WHERE email = $1", email)
+func (repo *Repository) LoginUser(ctx context.Context, email, password string) (int, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT id, passwordhash FROM "+constants.DB_TABLE_USER+" WHERE email = $1", email)
 	var userId int
 	var passwordHash string
 	err := row.Scan(&userId, &passwordHash)
@@ -75,10 +72,8 @@ func checkPasswordHash(password, hash string) bool {
 }
 
 // GetUserByEmail returns the user ID if the email exists, -1 if not found
-func GetUserByEmail(email string) (int, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT id FROM "+constants.DB_TABLE_USER+" WHERE email = $1", email)
+func (repo *Repository) GetUserByEmail(ctx context.Context, email string) (int, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT id FROM "+constants.DB_TABLE_USER+" WHERE email = $1", email)
 	var userId int
 	err := row.Scan(&userId)
 	if err != nil {
@@ -90,24 +85,23 @@ func GetUserByEmail(email string) (int, error) {
 	return userId, nil
 }
 
-// UpdateUserPassword updates a user's password given their email
-func UpdateUserPassword(email, newPassword string) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-
+// UpdateUserPassword updates a user's password given their email and signs out all of their sessions
+func (repo *Repository) UpdateUserPassword(ctx context.Context, email, newPassword string) error {
 	hash, err := hashPassword(newPassword)
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec("UPDATE "+constants.DB_TABLE_USER+" SET passwordhash = $1 WHERE email = $2", hash, email)
+	_, err = repo.db.ExecContext(ctx, "UPDATE "+constants.DB_TABLE_USER+" SET passwordhash = $1 WHERE email = $2", hash, email)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.ExecContext(ctx, "DELETE FROM "+constants.DB_TABLE_SESSION+" WHERE userid = (SELECT id FROM "+constants.DB_TABLE_USER+" WHERE email = $1)", email)
 	return err
 }
 
-func UserIsAdmin(userId int) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT admin FROM "+constants.DB_TABLE_USER+" WHERE id = $1", userId)
+func (repo *Repository) UserIsAdmin(ctx context.Context, userId int) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT admin FROM "+constants.DB_TABLE_USER+" WHERE id = $1", userId)
 	var admin bool
 	err := row.Scan(&admin)
 	if err != nil {
@@ -116,10 +110,8 @@ func UserIsAdmin(userId int) (bool, error) {
 	return admin, nil
 }
 
-func UserIsInstanceAdmin(userId int) (bool, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT instanceadmin FROM "+constants.DB_TABLE_USER+" WHERE id = $1", userId)
+func (repo *Repository) UserIsInstanceAdmin(ctx context.Context, userId int) (bool, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT instanceadmin FROM "+constants.DB_TABLE_USER+" WHERE id = $1", userId)
 	var instanceAdmin bool
 	err := row.Scan(&instanceAdmin)
 	if err != nil {
@@ -128,10 +120,8 @@ func UserIsInstanceAdmin(userId int) (bool, error) {
 	return instanceAdmin, nil
 }
 
-func GetAllUsers() ([]constants.UserRead, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	rows, err := db.Query("SELECT id, groupid, email, name, admin, instanceadmin FROM " + constants.DB_TABLE_USER)
+func (repo *Repository) GetAllUsers(ctx context.Context) ([]constants.UserRead, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, groupid, email, name, admin, instanceadmin FROM " + constants.DB_TABLE_USER)
 	if err != nil {
 		return nil, err
 	}
@@ -153,10 +143,8 @@ func GetAllUsers() ([]constants.UserRead, error) {
 	return users, nil
 }
 
-func GetUsersInSameGroupAsUser(userId int) ([]constants.UserRead, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	rows, err := db.Query("SELECT id, groupid, email, name, admin, instanceadmin FROM "+constants.DB_TABLE_USER+" WHERE groupid = (SELECT groupid FROM listaway.user WHERE id = $1)", userId)
+func (repo *Repository) GetUsersInSameGroupAsUser(ctx context.Context, userId int) ([]constants.UserRead, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, groupid, email, name, admin, instanceadmin FROM "+constants.DB_TABLE_USER+" WHERE groupid = (SELECT groupid FROM listaway.user WHERE id = $1)", userId)
 	if err != nil {
 		return nil, err
 	}
@@ -178,10 +166,8 @@ func GetUsersInSameGroupAsUser(userId int) ([]constants.UserRead, error) {
 	return users, nil
 }
 
-func GetUserGroupId(userId int) (int, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT groupid FROM "+constants.DB_TABLE_USER+" WHERE id = $1", userId)
+func (repo *Repository) GetUserGroupId(ctx context.Context, userId int) (int, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT groupid FROM "+constants.DB_TABLE_USER+" WHERE id = $1", userId)
 	var groupId int
 	err := row.Scan(&groupId)
 	if err != nil {
@@ -191,10 +177,8 @@ func GetUserGroupId(userId int) (int, error) {
 }
 
 // GetUser returns user information by user ID
-func GetUser(userId int) (constants.UserRead, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-	row := db.QueryRow("SELECT id, groupid, email, name, admin, instanceadmin FROM "+constants.DB_TABLE_USER+" WHERE id = $1", userId)
+func (repo *Repository) GetUser(ctx context.Context, userId int) (constants.UserRead, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT id, groupid, email, name, admin, instanceadmin FROM "+constants.DB_TABLE_USER+" WHERE id = $1", userId)
 	var user constants.UserRead
 	err := row.Scan(&user.Id, &user.GroupId, &user.Email, &user.Name, &user.Admin, &user.InstanceAdmin)
 	if err != nil {
@@ -203,32 +187,40 @@ func GetUser(userId int) (constants.UserRead, error) {
 	return user, nil
 }
 
-func DeleteUser(userId int) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`DELETE FROM listaway.user WHERE id = $1`, userId)
+func (repo *Repository) DeleteUser(ctx context.Context, userId int) error {
+	_, err := repo.db.ExecContext(ctx, `DELETE FROM listaway.api_token WHERE userid = $1`, userId)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.ExecContext(ctx, `DELETE FROM listaway.session WHERE userid = $1`, userId)
+	if err != nil {
+		return err
+	}
+	err = repo.DisableTwoFactor(ctx, userId)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.ExecContext(ctx, `DELETE FROM listaway.passkey WHERE userid = $1`, userId)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.ExecContext(ctx, `DELETE FROM listaway.user WHERE id = $1`, userId)
 	return err
 }
 
-func SetUserAdmin(userId int, admin bool) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`UPDATE listaway.user SET admin = $1 WHERE id = $2`, admin, userId)
+func (repo *Repository) SetUserAdmin(ctx context.Context, userId int, admin bool) error {
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.user SET admin = $1 WHERE id = $2`, admin, userId)
 	return err
 }
 
-func SetUserInstanceAdmin(userId int, instanceAdmin bool) error {
-	db := getDatabaseConnection()
-	defer db.Close()
-	_, err := db.Exec(`UPDATE listaway.user SET instanceadmin = $1 WHERE id = $2`, instanceAdmin, userId)
+func (repo *Repository) SetUserInstanceAdmin(ctx context.Context, userId int, instanceAdmin bool) error {
+	_, err := repo.db.ExecContext(ctx, `UPDATE listaway.user SET instanceadmin = $1 WHERE id = $2`, instanceAdmin, userId)
 	return err
 }
 
-func GetAllGroupAdmins() ([]constants.UserRead, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
+func (repo *Repository) GetAllGroupAdmins(ctx context.Context) ([]constants.UserRead, error) {
 	// Get all admin users who are not instance admins (group admins)
-	rows, err := db.Query("SELECT id, groupid, email, name, admin, instanceadmin FROM " + constants.DB_TABLE_USER + " WHERE admin = true")
+	rows, err := repo.db.QueryContext(ctx, "SELECT id, groupid, email, name, admin, instanceadmin FROM " + constants.DB_TABLE_USER + " WHERE admin = true")
 	if err != nil {
 		return nil, err
 	}
@@ -251,11 +243,8 @@ func GetAllGroupAdmins() ([]constants.UserRead, error) {
 }
 
 // GetNextAvailableGroupId returns the next available group ID (max + 1)
-func GetNextAvailableGroupId() (int, error) {
-	db := getDatabaseConnection()
-	defer db.Close()
-
-	row := db.QueryRow("SELECT COALESCE(MAX(groupid), 0) + 1 FROM " + constants.DB_TABLE_USER)
+func (repo *Repository) GetNextAvailableGroupId(ctx context.Context) (int, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(groupid), 0) + 1 FROM " + constants.DB_TABLE_USER)
 
 	var nextGroupId int
 	if err := row.Scan(&nextGroupId); err != nil {
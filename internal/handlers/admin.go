@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
 	"github.com/jeffrpowell/listaway/web"
@@ -49,13 +48,13 @@ func createUserHandler(w http.ResponseWriter, r *http.Request) {
 
 /* Register admin page */
 func registerAdminGET(w http.ResponseWriter, r *http.Request) {
-	params := web.RegisterAdminParams(database.AdminUserExists())
+	params := web.RegisterAdminParams(repo.AdminUserExists(r.Context()))
 	web.RegisterAdmin(w, params)
 }
 
 /* Submit new admin user */
 func registerAdminPUT(w http.ResponseWriter, r *http.Request) {
-	if database.AdminUserExists() {
+	if repo.AdminUserExists(r.Context()) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -71,16 +70,18 @@ func registerAdminPUT(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, reason, http.StatusBadRequest)
 		return
 	}
-	err := database.RegisterUser(newUser)
+	newUserId, err := repo.RegisterUser(r.Context(), newUser)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 	} else {
 		constants.ADMIN_EXISTS = true
+		auditAs(r, newUserId, constants.AUDIT_USER_CREATE, auditUser(r, newUserId), "First admin, registered at setup")
 		// Set user as authenticated
-		session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
-		session.Values["authenticated"] = true
-		session.Save(r, w)
+		session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+		if err := signIn(w, r, session, newUserId); err != nil {
+			log.Print(err)
+		}
 		w.Header().Add("Location", "/auth")
 		w.WriteHeader(http.StatusOK)
 	}
@@ -107,28 +108,28 @@ func userAdminGET(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	users, err := database.GetUsersInSameGroupAsUser(selfId)
+	users, err := repo.GetUsersInSameGroupAsUser(r.Context(), selfId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 	params := web.UserAdminPageParams(r, users, selfId, admin, instanceAdmin)
 	web.UserAdminPage(w, params)
 }
 
 /* Create user page */
 func createUserGET(w http.ResponseWriter, r *http.Request) {
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 
 	var groupAdmins []constants.UserRead
 	if instanceAdmin {
 		// Fetch all group admins for instance admin to select from
 		var err error
-		groupAdmins, err = database.GetAllGroupAdmins()
+		groupAdmins, err = repo.GetAllGroupAdmins(r.Context())
 		if err != nil {
 			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 			log.Print(err)
@@ -151,7 +152,7 @@ func createUserPUT(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if the current user is an instance admin
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 
 	var groupId int
 	var admin = r.FormValue("admin") == "on"
@@ -166,7 +167,7 @@ func createUserPUT(w http.ResponseWriter, r *http.Request) {
 			// Create a new group with this user as the group admin
 			// Get the next available group ID from the database layer
 			var err error
-			groupId, err = database.GetNextAvailableGroupId()
+			groupId, err = repo.GetNextAvailableGroupId(r.Context())
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
@@ -192,7 +193,7 @@ func createUserPUT(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Get the group ID of the selected admin
-			groupId, err = database.GetUserGroupId(existingGroupAdminId)
+			groupId, err = repo.GetUserGroupId(r.Context(), existingGroupAdminId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
@@ -205,7 +206,7 @@ func createUserPUT(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		// Regular admin - use their group ID
-		groupId, err = database.GetUserGroupId(selfId)
+		groupId, err = repo.GetUserGroupId(r.Context(), selfId)
 		if err != nil {
 			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 			log.Print(err)
@@ -228,11 +229,16 @@ func createUserPUT(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = database.RegisterUser(newUser)
+	newUserId, err := repo.RegisterUser(r.Context(), newUser)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 	} else {
+		detail := "Member"
+		if newUser.Admin {
+			detail = "Group admin"
+		}
+		audit(r, constants.AUDIT_USER_CREATE, auditUser(r, newUserId), detail)
 		// Return to appropriate page - all users for instance admin, users in group for group admin
 		if instanceAdmin {
 			w.Header().Add("Location", "/admin/allusers")
@@ -250,7 +256,7 @@ func userListCountGET(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	lists, err := database.GetLists(targetUserId)
+	lists, err := repo.GetLists(r.Context(), targetUserId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -270,12 +276,14 @@ func deleteUser(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	err = database.DeleteUser(userId)
+	target := auditUser(r, userId) // read before the user is gone
+	err = repo.DeleteUser(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
+	audit(r, constants.AUDIT_USER_DELETE, target, "")
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -297,18 +305,23 @@ func toggleUserAdmin(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Cannot change your own admin status", http.StatusForbidden)
 		return
 	}
-	admin, err := database.UserIsAdmin(userId)
+	admin, err := repo.UserIsAdmin(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	err = database.SetUserAdmin(userId, !admin)
+	err = repo.SetUserAdmin(r.Context(), userId, !admin)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
+	if admin {
+		audit(r, constants.AUDIT_ADMIN_REVOKE, auditUser(r, userId), "")
+	} else {
+		audit(r, constants.AUDIT_ADMIN_GRANT, auditUser(r, userId), "")
+	}
 	if admin {
 		w.Write([]byte("false"))
 	} else {
@@ -324,14 +337,14 @@ func getGroupSharing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	groupId, err := database.GetUserGroupId(selfId)
+	groupId, err := repo.GetUserGroupId(r.Context(), selfId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 	
-	enabled, err := database.GetGroupSharingEnabled(groupId)
+	enabled, err := repo.GetGroupSharingEnabled(r.Context(), groupId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -353,27 +366,33 @@ func toggleGroupSharing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	groupId, err := database.GetUserGroupId(selfId)
+	groupId, err := repo.GetUserGroupId(r.Context(), selfId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 	
-	enabled, err := database.GetGroupSharingEnabled(groupId)
+	enabled, err := repo.GetGroupSharingEnabled(r.Context(), groupId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 	
-	err = database.SetGroupSharingEnabled(groupId, !enabled)
+	err = repo.SetGroupSharingEnabled(r.Context(), groupId, !enabled)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 	
+	if enabled {
+		audit(r, constants.AUDIT_GROUP_SHARING_DISABLE, nil, "")
+	} else {
+		audit(r, constants.AUDIT_GROUP_SHARING_ENABLE, nil, "")
+	}
+	
 	if enabled {
 		w.Write([]byte("false"))
 	} else {
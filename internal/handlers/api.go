@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+// The /api/v1 surface is authenticated with personal access tokens rather than the session cookie.
+// Handlers live in apiLists.go and apiCollections.go; the types below are the JSON contract
+// and are deliberately decoupled from the database types so the schema can stay stable.
+
+func init() {
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists", middleware.DefaultApiMiddlewareChain(apiListsHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/groupshared", middleware.DefaultApiMiddlewareChain(apiGroupSharedListsGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}", apiListChain(apiListHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/items", apiListChain(apiListItemsHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/items/order", apiListChain(itemOrderPUT)).Methods("PUT")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/items/{itemId:[0-9]+}", apiListChain(apiItemHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/items/{itemId:[0-9]+}/complete", apiListChain(apiItemCompleteHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/items/{itemId:[0-9]+}/attachments", apiListChain(apiAttachmentsHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/items/{itemId:[0-9]+}/attachments/{attachmentId:[0-9]+}", apiListChain(attachmentDELETE)).Methods("DELETE")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/completed-display", apiListChain(listCompletedDisplayPUT)).Methods("PUT")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/budget", apiListChain(listBudgetHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/tags", apiListChain(listTagsPUT)).Methods("PUT")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/totals", apiListChain(apiListTotalsGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/history", apiListChain(apiListHistoryGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/history/{revisionId:[0-9]+}/revert", apiListChain(revisionRevertPOST)).Methods("POST")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/copy", apiListChain(listCopyPOST)).Methods("POST")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/sections", apiListChain(apiSectionsHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/sections/{sectionId:[0-9]+}", apiListChain(apiSectionHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/lists/{listId:[0-9]+}/share", apiListChain(apiListShareHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/templates", middleware.DefaultApiMiddlewareChain(apiTemplatesGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/tags", middleware.DefaultApiMiddlewareChain(tagsGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/items", middleware.DefaultApiMiddlewareChain(apiTaggedItemsGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/search", middleware.DefaultApiMiddlewareChain(apiSearchGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/trash", middleware.DefaultApiMiddlewareChain(apiTrashHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/trash/{kind:(?:lists|items|collections)}/{id:[0-9]+}", middleware.DefaultApiMiddlewareChain(trashEntryDELETE)).Methods("DELETE")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/trash/{kind:(?:lists|items|collections)}/{id:[0-9]+}/restore", middleware.DefaultApiMiddlewareChain(trashEntryRestorePOST)).Methods("POST")
+
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/collections", middleware.DefaultApiMiddlewareChain(apiCollectionsHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/collections/{collectionId:[0-9]+}", apiCollectionChain(apiCollectionHandler))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/collections/{collectionId:[0-9]+}/lists", apiCollectionChain(apiCollectionListsGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/collections/{collectionId:[0-9]+}/totals", apiCollectionChain(apiCollectionTotalsGET)).Methods("GET")
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/collections/{collectionId:[0-9]+}/items", apiCollectionChain(apiCollectionTaggedItemsGET)).Methods("GET")
+	// Same rules as the web UI: lists the user can view may be added to collections the user owns
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/collections/{collectionId:[0-9]+}/lists/{listId:[0-9]+}", middleware.Chain(collectionListHandler, append([]middleware.Middleware{middleware.ListIdViewer("listId"), middleware.CollectionIdOwner("collectionId")}, middleware.DefaultApiMiddlewareSlice...)...))
+	constants.ROUTER.HandleFunc(constants.API_V1_PATH+"/collections/{collectionId:[0-9]+}/share", apiCollectionChain(apiCollectionShareHandler))
+}
+
+func apiListChain(f http.HandlerFunc) http.HandlerFunc {
+	return middleware.Chain(f, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultApiMiddlewareSlice...)...)
+}
+
+func apiCollectionChain(f http.HandlerFunc) http.HandlerFunc {
+	return middleware.Chain(f, append([]middleware.Middleware{middleware.CollectionIdOwner("collectionId")}, middleware.DefaultApiMiddlewareSlice...)...)
+}
+
+type apiList struct {
+	Id             uint64 `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	ShareCode      string `json:"shareCode,omitempty"`
+	ShareWithGroup bool   `json:"shareWithGroup"`
+	GroupCanEdit   bool   `json:"groupCanEdit"`
+	Claimable      bool   `json:"claimable"`
+	// How completed items are shown: strike, hide or archive
+	CompletedDisplay string      `json:"completedDisplay"`
+	Budget           *listBudget `json:"budget"`
+	Tags             []string    `json:"tags"`
+}
+
+// apiListCreate is the body of a new list, which can start out with the items of one of the group's templates
+type apiListCreate struct {
+	constants.ListPostParams
+	TemplateId int `json:"templateId,omitempty"` // a blank description then takes the template's
+}
+
+type apiGroupSharedList struct {
+	Id           uint64   `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	ShareCode    string   `json:"shareCode,omitempty"`
+	OwnerId      uint64   `json:"ownerId"`
+	OwnerName    string   `json:"ownerName"`
+	GroupCanEdit bool     `json:"groupCanEdit"`
+	Tags         []string `json:"tags"`
+}
+
+type apiItem struct {
+	Id        uint64  `json:"id"`
+	ListId    uint64  `json:"listId"`
+	Name      string  `json:"name"`
+	URL       string  `json:"url,omitempty"`
+	Notes     string  `json:"notes,omitempty"`
+	Priority  *int64  `json:"priority,omitempty"`
+	Position  int     `json:"position"`
+	SectionId *uint64 `json:"sectionId"`
+	// Set while the item is checked off
+	CompletedAt *time.Time `json:"completedAt"`
+	CompletedBy *uint64    `json:"completedBy"`
+	// How many are wanted, how many editors have covered and how many share link visitors have pledged
+	Quantity    int `json:"quantity"`
+	Fulfilled   int `json:"fulfilled"`
+	Contributed int `json:"contributed"`
+	StillNeeded int `json:"stillNeeded"`
+	// Unit price as a decimal, e.g. "12.50", with its ISO 4217 currency
+	Price    string `json:"price,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// What the item URL's page says about itself, once it has been fetched; only returned by the items listing
+	Preview *apiLinkPreview `json:"preview,omitempty"`
+	// Uploaded images, oldest first
+	Attachments []apiAttachment `json:"attachments"`
+	Tags        []string        `json:"tags"`
+}
+
+type apiLinkPreview struct {
+	Title        string    `json:"title,omitempty"`
+	ImageURL     string    `json:"imageUrl,omitempty"`
+	Price        string    `json:"price,omitempty"` // decimal, as on apiItem
+	Currency     string    `json:"currency,omitempty"`
+	Availability string    `json:"availability,omitempty"` // a schema.org ItemAvailability term, e.g. InStock
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+type apiItemInput struct {
+	Name      string  `json:"name" openapi:"required,nonempty"`
+	URL       string  `json:"url"`
+	Notes     string  `json:"notes"`
+	Priority  *int64  `json:"priority"`
+	SectionId *uint64 `json:"sectionId"`
+	// Defaults to 1 wanted and 0 fulfilled when omitted
+	Quantity  *int `json:"quantity"`
+	Fulfilled *int `json:"fulfilled"`
+	// Unit price as a decimal, e.g. "12.50"; currency is required with it
+	Price    string `json:"price"`
+	Currency string `json:"currency"`
+	// Replaces the item's tags; they are lowercased and repeats dropped
+	Tags []string `json:"tags"`
+}
+
+type apiCollection struct {
+	Id          uint64 `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ShareCode   string `json:"shareCode,omitempty"`
+}
+
+type apiCollectionList struct {
+	Id          uint64 `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ShareCode   string `json:"shareCode,omitempty"`
+	ItemCount   int    `json:"itemCount"`
+	// Items not yet checked off and short of their quantity
+	OutstandingCount int      `json:"outstandingCount"`
+	AuthorId         uint64   `json:"authorId"`
+	AuthorName       string   `json:"authorName"`
+	Tags             []string `json:"tags"`
+}
+
+type apiShare struct {
+	ShareCode string `json:"shareCode"`
+	URL       string `json:"url"`
+}
+
+func toApiList(l constants.List) apiList {
+	list := apiList{
+		Id:               l.Id,
+		Name:             l.Name,
+		Description:      l.Description.String,
+		ShareCode:        l.ShareCode.String,
+		ShareWithGroup:   l.ShareWithGroup,
+		GroupCanEdit:     l.GroupCanEdit,
+		Claimable:        l.Claimable,
+		CompletedDisplay: l.CompletedDisplay,
+		Tags:             append([]string{}, l.Tags...),
+	}
+	if l.Budget.Valid {
+		list.Budget = &listBudget{Amount: constants.FormatAmount(l.Budget.Int64, l.BudgetCurrency.String), Currency: l.BudgetCurrency.String}
+	}
+	return list
+}
+
+func toApiItem(listId uint64, i constants.Item) apiItem {
+	item := apiItem{
+		Id:          i.Id,
+		ListId:      listId,
+		Name:        i.Name,
+		URL:         i.URL.String,
+		Notes:       i.Notes.String,
+		Position:    i.Position,
+		Quantity:    i.Quantity,
+		Fulfilled:   i.Fulfilled,
+		Contributed: i.Contributed,
+		StillNeeded: i.StillNeeded(),
+		Attachments: toApiAttachments(i.Attachments),
+		Tags:        append([]string{}, i.Tags...),
+	}
+	if i.Priority.Valid {
+		priority := i.Priority.Int64
+		item.Priority = &priority
+	}
+	if i.SectionId.Valid {
+		sectionId := uint64(i.SectionId.Int64)
+		item.SectionId = &sectionId
+	}
+	if i.CompletedAt.Valid {
+		completedAt := i.CompletedAt.Time
+		item.CompletedAt = &completedAt
+	}
+	if i.CompletedBy.Valid {
+		completedBy := uint64(i.CompletedBy.Int64)
+		item.CompletedBy = &completedBy
+	}
+	if i.Price.Valid {
+		item.Price = constants.FormatAmount(i.Price.Int64, i.Currency.String)
+		item.Currency = i.Currency.String
+	}
+	if p := i.Preview; p != nil {
+		item.Preview = &apiLinkPreview{Title: p.Title.String, ImageURL: p.ImageURL.String, Availability: p.Availability.String, FetchedAt: p.FetchedAt}
+		if p.Price.Valid {
+			item.Preview.Price = constants.FormatAmount(p.Price.Int64, p.Currency.String)
+			item.Preview.Currency = p.Currency.String
+		}
+	}
+	return item
+}
+
+// toItemInsert returns an error, worded for the caller, if the price, its currency or the tags can't be read
+func (in apiItemInput) toItemInsert(listId uint64) (constants.ItemInsert, error) {
+	item := constants.ItemInsert{
+		Name:   in.Name,
+		ListId: listId,
+		URL:    sql.NullString{String: in.URL, Valid: in.URL != ""},
+		Notes:  sql.NullString{String: in.Notes, Valid: in.Notes != ""},
+	}
+	if in.Priority != nil {
+		item.Priority = sql.NullInt64{Int64: *in.Priority, Valid: true}
+	}
+	if in.SectionId != nil {
+		item.SectionId = sql.NullInt64{Int64: int64(*in.SectionId), Valid: true}
+	}
+	if in.Quantity != nil {
+		item.Quantity = *in.Quantity
+	}
+	if in.Fulfilled != nil {
+		item.Fulfilled = *in.Fulfilled
+	}
+	var err error
+	item.Price, item.Currency, err = parsePrice(in.Price, in.Currency)
+	if err != nil {
+		return item, errors.New("Invalid price: " + err.Error())
+	}
+	item.Tags, err = constants.NormalizeTags(in.Tags)
+	if err != nil {
+		return item, errors.New("Invalid tags: " + err.Error())
+	}
+	return item, nil
+}
+
+func toApiCollection(c constants.Collection) apiCollection {
+	return apiCollection{
+		Id:          c.Id,
+		Name:        c.Name,
+		Description: c.Description.String,
+		ShareCode:   c.ShareCode.String,
+	}
+}
+
+// writeJSON sends v as the response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print(err)
+	}
+}
+
+// decodeJSON reads the request body into v, writing a 400 and returning false if it isn't valid JSON
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
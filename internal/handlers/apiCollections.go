@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+)
+
+func apiCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiCollectionsGET(w, r)
+	case "POST":
+		apiCollectionsPOST(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiCollectionGET(w, r)
+	case "PUT":
+		apiCollectionPUT(w, r)
+	case "DELETE":
+		apiCollectionDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiCollectionShareHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		apiCollectionSharePUT(w, r)
+	case "DELETE":
+		collectionShareDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Get all collections owned by the token's user */
+func apiCollectionsGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	collections, err := repo.GetCollections(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	response := make([]apiCollection, 0, len(collections))
+	for _, c := range collections {
+		response = append(response, toApiCollection(c))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/* Create collection */
+func apiCollectionsPOST(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	var params constants.CollectionPostParams
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	taken, err := repo.CollectionNameTaken(r.Context(), userId, params.Name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if taken {
+		http.Error(w, "You already have a collection with that name", http.StatusConflict)
+		return
+	}
+
+	collectionId, err := repo.CreateCollection(r.Context(), userId, params.Name, params.Description)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	collection, err := repo.GetCollection(r.Context(), collectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Add("Location", fmt.Sprintf("%s/collections/%d", constants.API_V1_PATH, collectionId))
+	writeJSON(w, http.StatusCreated, toApiCollection(collection))
+}
+
+/* Get collection */
+func apiCollectionGET(w http.ResponseWriter, r *http.Request) {
+	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
+	collection, err := repo.GetCollection(r.Context(), collectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiCollection(collection))
+}
+
+/* Update collection */
+func apiCollectionPUT(w http.ResponseWriter, r *http.Request) {
+	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	var params constants.CollectionPostParams
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	// Check if name is already taken by another collection (excluding current collection)
+	collections, err := repo.GetCollections(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	for _, c := range collections {
+		if c.Name == params.Name && int(c.Id) != collectionId {
+			http.Error(w, "You already have a collection with that name", http.StatusConflict)
+			return
+		}
+	}
+
+	err = repo.UpdateCollection(r.Context(), collectionId, params)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	collection, err := repo.GetCollection(r.Context(), collectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiCollection(collection))
+}
+
+/* Delete collection */
+func apiCollectionDELETE(w http.ResponseWriter, r *http.Request) {
+	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
+	collection, err := repo.GetCollection(r.Context(), collectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	_, err = repo.DeleteCollection(r.Context(), collectionId, collection.Name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Get the lists in a collection */
+func apiCollectionListsGET(w http.ResponseWriter, r *http.Request) {
+	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
+	lists, err := repo.GetCollectionLists(r.Context(), collectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	tag := tagParam(r)
+	response := make([]apiCollectionList, 0, len(lists))
+	for _, l := range lists {
+		if !hasTag(l.Tags, tag) {
+			continue
+		}
+		response = append(response, apiCollectionList{
+			Id:               l.Id,
+			Name:             l.Name,
+			Description:      l.Description.String,
+			ShareCode:        l.ShareCode.String,
+			ItemCount:        l.ItemCount,
+			OutstandingCount: l.OutstandingCount,
+			AuthorId:         l.AuthorId,
+			AuthorName:       l.AuthorName,
+			Tags:             append([]string{}, l.Tags...),
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/* Create share link, which also publishes every list in the collection */
+func apiCollectionSharePUT(w http.ResponseWriter, r *http.Request) {
+	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
+	code, err := repo.GenerateCollectionShareCode(r.Context(), collectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiShare{
+		ShareCode: code,
+		URL:       constants.APP_URL + "/" + constants.SHARED_COLLECTION_PATH + "/" + code,
+	})
+}
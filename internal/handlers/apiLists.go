@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+)
+
+func apiListsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiListsGET(w, r)
+	case "POST":
+		apiListsPOST(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiListGET(w, r)
+	case "PUT":
+		apiListPUT(w, r)
+	case "DELETE":
+		apiListDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiListItemsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiListItemsGET(w, r)
+	case "POST":
+		apiListItemsPOST(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiItemHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiItemGET(w, r)
+	case "PUT":
+		apiItemPUT(w, r)
+	case "DELETE":
+		apiItemDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiItemCompleteHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		apiItemCompleteToggle(w, r, true)
+	case "DELETE":
+		apiItemCompleteToggle(w, r, false)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiSectionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		sectionsGET(w, r)
+	case "POST":
+		apiSectionsPOST(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+// The web app's section handlers already speak JSON, so only create and get need API-specific versions
+func apiSectionHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiSectionGET(w, r)
+	case "PUT":
+		sectionPOST(w, r)
+	case "DELETE":
+		sectionDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiListShareHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		apiListSharePUT(w, r)
+	case "DELETE":
+		apiListShareDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Get all lists owned by the token's user */
+func apiListsGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	lists, err := repo.GetListsTagged(r.Context(), userId, tagParam(r))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	response := make([]apiList, 0, len(lists))
+	for _, l := range lists {
+		response = append(response, toApiList(l))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/* Get lists other group members have shared with the token's user */
+func apiGroupSharedListsGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	lists, err := repo.GetListsSharedWithGroup(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	response := make([]apiGroupSharedList, 0, len(lists))
+	for _, l := range lists {
+		response = append(response, apiGroupSharedList{
+			Id:           l.Id,
+			Name:         l.Name,
+			Description:  l.Description.String,
+			ShareCode:    l.ShareCode.String,
+			OwnerId:      l.OwnerId,
+			OwnerName:    l.OwnerName,
+			GroupCanEdit: l.GroupCanEdit,
+			Tags:         append([]string{}, l.Tags...),
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/* Create list */
+func apiListsPOST(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	var params apiListCreate
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	taken, err := repo.ListNameTaken(r.Context(), userId, params.Name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if taken {
+		http.Error(w, "List name already taken", http.StatusConflict)
+		return
+	}
+
+	var listId int
+	if params.TemplateId == 0 {
+		listId, err = repo.CreateList(r.Context(), userId, params.Name, params.Description)
+	} else {
+		template, ok := groupTemplate(w, r, userId, params.TemplateId)
+		if !ok {
+			return
+		}
+		if params.Description == "" {
+			params.Description = template.Description.String
+		}
+		listId, err = repo.CreateListFromTemplate(r.Context(), userId, params.TemplateId, params.Name, params.Description)
+	}
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if params.ShareWithGroup || params.GroupCanEdit {
+		err = repo.UpdateList(r.Context(), userId, listId, params.ListPostParams)
+		if err != nil {
+			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+			log.Print(err)
+			return
+		}
+	}
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Add("Location", fmt.Sprintf("%s/lists/%d", constants.API_V1_PATH, listId))
+	writeJSON(w, http.StatusCreated, toApiList(list))
+}
+
+/* Get list */
+func apiListGET(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiList(list))
+}
+
+/* Update list */
+func apiListPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	var params constants.ListPostParams
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	userId, _ := helper.GetUserId(r) // err already tripped in requireListEdit
+	err := repo.UpdateList(r.Context(), userId, listId, params)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiList(list))
+}
+
+/* Delete list */
+func apiListDELETE(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+
+	// Only the owner can delete a list, even if it's shared with group edit permissions
+	owns, err := repo.UserOwnsList(r.Context(), userId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !owns {
+		http.Error(w, "Forbidden - only the list owner can delete it", http.StatusForbidden)
+		return
+	}
+
+	// The token holder has already proven intent, so confirm with the list's own name
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	_, err = repo.DeleteList(r.Context(), listId, list.Name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Get list items */
+func apiListItemsGET(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	sortBy, descending, ok := itemSortParams(w, r)
+	if !ok {
+		return
+	}
+	items, err := repo.GetListItemsSorted(r.Context(), listId, sortBy, descending, tagParam(r))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	response := make([]apiItem, 0, len(items))
+	for _, i := range items {
+		response = append(response, toApiItem(uint64(listId), i))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/* Create item */
+func apiListItemsPOST(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	var input apiItemInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	item, err := input.toItemInsert(uint64(listId))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !requireItemSection(w, r, listId, item.SectionId) {
+		return
+	}
+	userId, _ := helper.GetUserId(r) // err already tripped in requireListEdit
+	itemId, err := repo.CreateItem(r.Context(), userId, item)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	created, err := repo.GetItem(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Add("Location", fmt.Sprintf("%s/lists/%d/items/%d", constants.API_V1_PATH, listId, itemId))
+	writeJSON(w, http.StatusCreated, toApiItem(uint64(listId), created))
+}
+
+/* Get item */
+func apiItemGET(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	item, err := repo.GetItem(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiItem(uint64(listId), item))
+}
+
+/* Update item */
+func apiItemPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	var input apiItemInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	update, err := input.toItemInsert(uint64(listId))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !requireItemSection(w, r, listId, update.SectionId) {
+		return
+	}
+	userId, _ := helper.GetUserId(r) // err already tripped in requireListEdit
+	err = repo.UpdateItem(r.Context(), userId, itemId, update)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	item, err := repo.GetItem(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiItem(uint64(listId), item))
+}
+
+/* Delete item */
+func apiItemDELETE(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	userId, _ := helper.GetUserId(r) // err already tripped in requireListEdit
+	err := repo.DeleteItem(r.Context(), userId, itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Check an item off, or clear it, and return the item */
+func apiItemCompleteToggle(w http.ResponseWriter, r *http.Request, completed bool) {
+	listId, itemId, ok := setItemCompleted(w, r, completed)
+	if !ok {
+		return
+	}
+	item, err := repo.GetItem(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiItem(uint64(listId), item))
+}
+
+/* Create section */
+func apiSectionsPOST(w http.ResponseWriter, r *http.Request) {
+	section, ok := createSection(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Add("Location", fmt.Sprintf("%s/lists/%d/sections/%d", constants.API_V1_PATH, section.ListId, section.Id))
+	writeJSON(w, http.StatusCreated, section)
+}
+
+/* Get section */
+func apiSectionGET(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	sectionId, ok := requireSectionInList(w, r, listId)
+	if !ok {
+		return
+	}
+	section, err := repo.GetSection(r.Context(), sectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, section)
+}
+
+/* Create share link */
+func apiListSharePUT(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	code, err := repo.GenerateShareCode(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiShare{
+		ShareCode: code,
+		URL:       constants.APP_URL + "/" + constants.SHARED_LIST_PATH + "/" + code,
+	})
+}
+
+/* Unpublish share link */
+func apiListShareDELETE(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	err := repo.UnpublishShareCode(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireApiItemInList writes a 404 and returns false unless the item in the path belongs to the list
+func requireApiItemInList(w http.ResponseWriter, r *http.Request, listId int) (int, bool) {
+	itemId, err := helper.GetPathVarInt(r, "itemId")
+	if err != nil {
+		http.Error(w, "Invalid itemId supplied in path", http.StatusBadRequest)
+		return 0, false
+	}
+	inList, err := repo.ItemInList(r.Context(), itemId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return 0, false
+	}
+	if !inList {
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return 0, false
+	}
+	return itemId, true
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// Personal access tokens are managed from the browser session only, so a leaked token can't mint more tokens
+func init() {
+	constants.ROUTER.HandleFunc("/tokens", middleware.DefaultMiddlewareChain(apiTokensHandler))
+	constants.ROUTER.HandleFunc("/tokens/{tokenId:[0-9]+}", middleware.DefaultMiddlewareChain(apiTokenDELETE)).Methods("DELETE")
+}
+
+func apiTokensHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiTokensGET(w, r)
+	case "PUT":
+		apiTokenPUT(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Personal access tokens page */
+func apiTokensGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	tokens, err := repo.GetApiTokens(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.ApiTokensPage(w, web.ApiTokensPageParams(r, tokens, admin, instanceAdmin))
+}
+
+/* Create token; the plaintext token is returned once and never stored */
+func apiTokenPUT(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	scope := r.FormValue("scope")
+	if scope != constants.API_SCOPE_READ && scope != constants.API_SCOPE_WRITE {
+		http.Error(w, "Scope must be read or write", http.StatusBadRequest)
+		return
+	}
+	token, err := repo.CreateApiToken(r.Context(), userId, name, scope)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	audit(r, constants.AUDIT_API_TOKEN_CREATE, nil, name+" ("+scope+")")
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(token))
+}
+
+/* Revoke token */
+func apiTokenDELETE(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	tokenId, err := helper.GetPathVarInt(r, "tokenId")
+	if err != nil {
+		http.Error(w, "Invalid tokenId supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	name, deleted, err := repo.DeleteApiToken(r.Context(), userId, tokenId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+	audit(r, constants.AUDIT_API_TOKEN_REVOKE, nil, name)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/database"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/internal/imaging"
+)
+
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/{itemId:[0-9]+}/attachment", middleware.Chain(attachmentsPUT, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("PUT")
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/{itemId:[0-9]+}/attachment/{attachmentId:[0-9]+}", middleware.Chain(attachmentDELETE, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("DELETE")
+}
+
+const (
+	// attachmentField is the multipart field images are uploaded in; it may repeat
+	attachmentField = "images"
+	// maxAttachmentBytes caps the size of each uploaded image
+	maxAttachmentBytes = 10 << 20
+	// maxAttachmentUploadBytes caps a whole upload: a full item's worth of images plus room for the multipart framing
+	maxAttachmentUploadBytes = maxAttachmentBytes*database.MaxAttachmentsPerItem + 1<<20
+)
+
+// apiAttachment is an uploaded image with the URLs it is served from
+type apiAttachment struct {
+	Id           uint64    `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	ContentType  string    `json:"contentType"`
+	Filename     string    `json:"filename,omitempty"`
+	SizeBytes    int64     `json:"sizeBytes"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	UploadedAt   time.Time `json:"uploadedAt"`
+}
+
+func toApiAttachment(a constants.ItemAttachment) apiAttachment {
+	return apiAttachment{
+		Id:           a.Id,
+		URL:          a.URL(),
+		ThumbnailURL: a.ThumbnailURL(),
+		ContentType:  a.ContentType,
+		Filename:     a.Filename.String,
+		SizeBytes:    a.SizeBytes,
+		Width:        a.Width,
+		Height:       a.Height,
+		UploadedAt:   a.UploadedAt,
+	}
+}
+
+func toApiAttachments(attachments []constants.ItemAttachment) []apiAttachment {
+	result := make([]apiAttachment, 0, len(attachments))
+	for _, a := range attachments {
+		result = append(result, toApiAttachment(a))
+	}
+	return result
+}
+
+func apiAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiAttachmentsGET(w, r)
+	case "POST":
+		attachmentsPUT(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Images attached to an item JSON */
+func apiAttachmentsGET(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	attachments, err := repo.GetItemAttachments(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiAttachments(attachments))
+}
+
+/* Upload one or more images to an item. Every file is checked before any is stored, so a bad file saves nothing. */
+func attachmentsPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	if r.MultipartForm == nil {
+		http.Error(w, "Request body must be multipart/form-data", http.StatusUnsupportedMediaType)
+		return
+	}
+	files := r.MultipartForm.File[attachmentField]
+	if len(files) == 0 {
+		http.Error(w, attachmentField+" is required", http.StatusBadRequest)
+		return
+	}
+	count, err := repo.CountItemAttachments(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if count+len(files) > database.MaxAttachmentsPerItem {
+		http.Error(w, fmt.Sprintf("An item can have at most %d images", database.MaxAttachmentsPerItem), http.StatusBadRequest)
+		return
+	}
+
+	uploads := make([]constants.ItemAttachmentInsert, 0, len(files))
+	for _, file := range files {
+		upload, status, err := readAttachment(file)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		uploads = append(uploads, upload)
+	}
+
+	created := make([]constants.ItemAttachment, 0, len(uploads))
+	for _, upload := range uploads {
+		attachment, err := repo.AddItemAttachment(r.Context(), itemId, upload)
+		if errors.Is(err, database.ErrTooManyAttachments) {
+			http.Error(w, fmt.Sprintf("An item can have at most %d images", database.MaxAttachmentsPerItem), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+			log.Print(err)
+			return
+		}
+		created = append(created, attachment)
+	}
+	writeJSON(w, http.StatusCreated, toApiAttachments(created))
+}
+
+// readAttachment reads and checks one uploaded image and makes its thumbnail. On failure it returns
+// a message fit to show the uploader and the status to send it with.
+func readAttachment(file *multipart.FileHeader) (constants.ItemAttachmentInsert, int, error) {
+	name := filepath.Base(file.Filename)
+	if file.Size > maxAttachmentBytes {
+		return constants.ItemAttachmentInsert{}, http.StatusRequestEntityTooLarge, fmt.Errorf("%s is larger than %d MB", name, maxAttachmentBytes>>20)
+	}
+	f, err := file.Open()
+	if err != nil {
+		log.Print(err)
+		return constants.ItemAttachmentInsert{}, http.StatusInternalServerError, errors.New("Unexpected error occurred")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxAttachmentBytes+1))
+	if err != nil {
+		log.Print(err)
+		return constants.ItemAttachmentInsert{}, http.StatusInternalServerError, errors.New("Unexpected error occurred")
+	}
+	if len(data) > maxAttachmentBytes {
+		return constants.ItemAttachmentInsert{}, http.StatusRequestEntityTooLarge, fmt.Errorf("%s is larger than %d MB", name, maxAttachmentBytes>>20)
+	}
+
+	info, err := imaging.Inspect(data)
+	if err != nil {
+		return constants.ItemAttachmentInsert{}, http.StatusUnsupportedMediaType, fmt.Errorf("%s: %v", name, err)
+	}
+	thumbnail, err := imaging.Thumbnail(data)
+	if err != nil {
+		return constants.ItemAttachmentInsert{}, http.StatusUnsupportedMediaType, fmt.Errorf("%s: %v", name, err)
+	}
+	upload := constants.ItemAttachmentInsert{
+		ContentType: info.ContentType,
+		Extension:   info.Extension,
+		Width:       info.Width,
+		Height:      info.Height,
+		Data:        data,
+		Thumbnail:   thumbnail,
+	}
+	if name != "" && name != "." && name != "/" {
+		upload.Filename = sql.NullString{String: name, Valid: true}
+	}
+	return upload, 0, nil
+}
+
+/* Remove an image from an item */
+func attachmentDELETE(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	attachmentId, err := helper.GetPathVarInt(r, "attachmentId")
+	if err != nil {
+		http.Error(w, "Invalid attachmentId supplied in path", http.StatusBadRequest)
+		return
+	}
+	deleted, err := repo.DeleteItemAttachment(r.Context(), itemId, attachmentId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// Instance admins see the whole audit log; group admins only the events concerning their group
+func init() {
+	constants.ROUTER.HandleFunc("/admin/audit", middleware.Chain(auditLogGET, append([]middleware.Middleware{middleware.RequireAnyAdmin()}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
+	constants.ROUTER.HandleFunc("/admin/audit/export", middleware.Chain(auditLogExportGET, append([]middleware.Middleware{middleware.RequireAnyAdmin()}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
+}
+
+const auditDateLayout = "2006-01-02"
+
+/* Audit log page */
+func auditLogGET(w http.ResponseWriter, r *http.Request) {
+	filter, instanceAdmin, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.Limit = constants.MaxAuditEvents
+	events, err := repo.GetAuditEvents(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	web.AuditLogPage(w, web.AuditLogPageParams(r, events, r.URL.Query(), admin, instanceAdmin))
+}
+
+/* Audit log as CSV, with the same filters as the page and no limit */
+func auditLogExportGET(w http.ResponseWriter, r *http.Request) {
+	filter, _, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+	events, err := repo.GetAuditEvents(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.csv"`)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Time", "Actor", "Target", "Group", "Action", "Detail", "IP", "User agent"})
+	for _, e := range events {
+		group := ""
+		if e.GroupId.Valid {
+			group = strconv.FormatInt(e.GroupId.Int64, 10)
+		}
+		record := []string{e.CreatedAt.UTC().Format(time.RFC3339), e.Actor.String, e.Target.String, group, e.Action, e.Detail, e.IP, e.UserAgent}
+		// Names, details and user agents can come from anyone, even someone failing to sign in
+		for i := range record {
+			record[i] = helper.CSVSafe(record[i])
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Print(err)
+	}
+}
+
+// auditFilter reads the audit log filters from the query string, holding group admins to their own
+// group, and reports whether the user is an instance admin. It writes the error response and returns
+// false if a filter is malformed.
+func auditFilter(w http.ResponseWriter, r *http.Request) (constants.AuditFilter, bool, bool) {
+	var filter constants.AuditFilter
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return filter, false, false
+	}
+	user, err := repo.GetUser(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return filter, false, false
+	}
+
+	query := r.URL.Query()
+	filter.Action = query.Get("action")
+	if filter.Action != "" && !slices.ContainsFunc(constants.AuditActions, func(a constants.AuditAction) bool { return a.Action == filter.Action }) {
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return filter, false, false
+	}
+	filter.User = strings.TrimSpace(query.Get("user"))
+	if from := query.Get("from"); from != "" {
+		filter.From, err = time.ParseInLocation(auditDateLayout, from, time.Local)
+		if err != nil {
+			http.Error(w, "From must be a date like 2006-01-02", http.StatusBadRequest)
+			return filter, false, false
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		filter.Until, err = time.ParseInLocation(auditDateLayout, until, time.Local)
+		if err != nil {
+			http.Error(w, "Until must be a date like 2006-01-02", http.StatusBadRequest)
+			return filter, false, false
+		}
+		filter.Until = filter.Until.AddDate(0, 0, 1) // the whole of the last day
+	}
+	if !user.InstanceAdmin {
+		filter.GroupId = sql.NullInt64{Int64: int64(user.GroupId), Valid: true}
+	} else if group := query.Get("group"); group != "" {
+		groupId, err := strconv.ParseInt(group, 10, 64)
+		if err != nil {
+			http.Error(w, "Group must be a number", http.StatusBadRequest)
+			return filter, false, false
+		}
+		filter.GroupId = sql.NullInt64{Int64: groupId, Valid: true}
+	}
+	return filter, user.InstanceAdmin, true
+}
+
+// audit records action in the audit log as taken by the signed-in user, if any, against target,
+// which is nil when the action has none
+func audit(r *http.Request, action string, target *constants.UserRead, detail string) {
+	actorId, err := helper.GetUserId(r)
+	if err != nil {
+		actorId = -1
+	}
+	auditAs(r, actorId, action, target, detail)
+}
+
+// auditAs is audit for an actor the session doesn't name yet, such as a user who has only just
+// signed in; actorId is -1 for none. The event belongs to the target's group, or else the actor's.
+// Failing to record is only logged, since the action itself has already happened.
+func auditAs(r *http.Request, actorId int, action string, target *constants.UserRead, detail string) {
+	event := constants.AuditEvent{
+		Action:    action,
+		Detail:    detail,
+		IP:        helper.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+	if actor := auditUser(r, actorId); actor != nil {
+		event.ActorId = sql.NullInt64{Int64: int64(actor.Id), Valid: true}
+		event.Actor = sql.NullString{String: auditUserLabel(*actor), Valid: true}
+		event.GroupId = sql.NullInt64{Int64: int64(actor.GroupId), Valid: true}
+	}
+	if target != nil {
+		event.TargetId = sql.NullInt64{Int64: int64(target.Id), Valid: true}
+		event.Target = sql.NullString{String: auditUserLabel(*target), Valid: true}
+		event.GroupId = sql.NullInt64{Int64: int64(target.GroupId), Valid: true}
+	}
+	if err := repo.RecordAudit(r.Context(), event); err != nil {
+		log.Print(err)
+	}
+}
+
+// auditUser looks up a user to name in the audit log, or nil if there's no such user
+func auditUser(r *http.Request, userId int) *constants.UserRead {
+	if userId == -1 {
+		return nil
+	}
+	user, err := repo.GetUser(r.Context(), userId)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Print(err)
+		}
+		return nil
+	}
+	return &user
+}
+
+func auditUserLabel(user constants.UserRead) string {
+	return user.Name + " <" + user.Email + ">"
+}
@@ -7,8 +7,8 @@ import (
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
 	"github.com/jeffrpowell/listaway/internal/constants"
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
 	"github.com/jeffrpowell/listaway/web"
@@ -19,7 +19,6 @@ func init() {
 	constants.ROUTER.HandleFunc("/auth", middleware.DefaultPublicMiddlewareChain(authHandler))
 	constants.ROUTER.HandleFunc("/reset", middleware.DefaultPublicMiddlewareChain(resetHandler)).Methods("POST")
 	constants.ROUTER.HandleFunc("/reset/{token}", middleware.DefaultPublicMiddlewareChain(resetTokenHandler))
-	constants.ADMIN_EXISTS = database.AdminUserExists()
 }
 
 func rootHandler(w http.ResponseWriter, r *http.Request) {
@@ -48,13 +47,15 @@ func resetHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user exists with this email
-	userID, err := database.GetUserByEmail(email)
+	userID, err := repo.GetUserByEmail(r.Context(), email)
 	if err != nil {
 		log.Printf("Error checking user email: %v", err)
 		http.Error(w, "An unexpected error occurred", http.StatusInternalServerError)
 		return
 	}
 
+	auditAs(r, -1, constants.AUDIT_PASSWORD_RESET_REQUEST, auditUser(r, userID), email)
+
 	// Always return success even if email not found (security best practice)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("If your email is registered, a reset link has been sent."))
@@ -65,7 +66,7 @@ func resetHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate token and store in database
-	token, err := database.CreatePasswordResetToken(email)
+	token, err := repo.CreatePasswordResetToken(r.Context(), email)
 	if err != nil {
 		log.Printf("Error creating reset token: %v", err)
 		return
@@ -113,7 +114,7 @@ func resetTokenHandler(w http.ResponseWriter, r *http.Request) {
 	token = strings.TrimSpace(token)
 
 	// Validate token from database
-	email, valid, err := database.ValidatePasswordResetToken(token)
+	email, valid, err := repo.ValidatePasswordResetToken(r.Context(), token)
 	if err != nil {
 		log.Printf("Error validating reset token: %v", err)
 		web.ResetFormPage(w, false)
@@ -130,14 +131,20 @@ func resetTokenHandler(w http.ResponseWriter, r *http.Request) {
 		web.ResetFormPage(w, true)
 	case "POST":
 		password := r.FormValue("password")
-		err := database.UpdateUserPassword(email, password)
+		err := repo.UpdateUserPassword(r.Context(), email, password)
 		if err != nil {
 			log.Printf("Error updating user password: %v", err)
 			http.Error(w, "An unexpected error occurred", http.StatusInternalServerError)
 			return
 		}
 
-		_ = database.InvalidatePasswordResetToken(token)
+		_ = repo.InvalidatePasswordResetToken(r.Context(), token)
+
+		userId, err := repo.GetUserByEmail(r.Context(), email)
+		if err != nil {
+			log.Print(err)
+		}
+		auditAs(r, -1, constants.AUDIT_PASSWORD_RESET, auditUser(r, userId), "Through an emailed reset link")
 
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Password updated. You may now log in."))
@@ -153,30 +160,78 @@ func authGET(w http.ResponseWriter, r *http.Request) {
 
 /* Login */
 func authPOST(w http.ResponseWriter, r *http.Request) {
-	session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
 
-	userId, err := database.LoginUser(r.FormValue("email"), r.FormValue("password"))
+	email := r.FormValue("email")
+	userId, err := repo.LoginUser(r.Context(), email, r.FormValue("password"))
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 	if userId == -1 {
+		// Name the account if there is one, so its group admin sees attempts against it
+		targetId, err := repo.GetUserByEmail(r.Context(), email)
+		if err != nil {
+			log.Print(err)
+		}
+		auditAs(r, -1, constants.AUDIT_LOGIN_FAILED, auditUser(r, targetId), email)
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	twoFactor, err := repo.GetTwoFactorStatus(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if twoFactor.Enabled {
+		// Not signed in yet: the password only earns a go at the second step
+		if err := startTwoFactorLogin(w, r, session, userId, "Password"); err != nil {
+			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+			log.Print(err)
+			return
+		}
+		w.Header().Add("Location", "/auth/2fa")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	auditAs(r, userId, constants.AUDIT_LOGIN, nil, "Password")
 
-	// Set user as authenticated
+	location := "/list"
+	if twoFactor.Required {
+		session.Values["twoFactorSetupRequired"] = true
+		location = "/account/2fa"
+	}
+	if err := signIn(w, r, session, userId); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Add("Location", location)
+	w.WriteHeader(http.StatusOK)
+}
+
+// signIn marks session as authenticated for userId and saves it under a new token, dropping the
+// one it had before so a session id planted ahead of sign-in is worthless afterwards
+func signIn(w http.ResponseWriter, r *http.Request, session *sessions.Session, userId int) error {
+	if session.ID != "" {
+		if err := repo.DeleteSession(r.Context(), session.ID); err != nil {
+			return err
+		}
+		session.ID = ""
+	}
 	session.Values["authenticated"] = true
 	session.Values["userId"] = userId
-	session.Save(r, w)
-	w.Header().Add("Location", "/list")
-	w.WriteHeader(http.StatusOK)
+	return session.Save(r, w)
 }
 
 /* Logout */
 func authDELETE(w http.ResponseWriter, r *http.Request) {
-	session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	if _, ok := session.Values["userId"].(int); ok {
+		audit(r, constants.AUDIT_LOGOUT, nil, "")
+	}
 
 	// Revoke users authentication
 	session.Values["authenticated"] = false
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/claimable", middleware.Chain(listClaimableHandler, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...))
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/{itemId:[0-9]+}/claim", middleware.Chain(itemClaimDELETE, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("DELETE")
+	constants.ROUTER.HandleFunc("/"+constants.SHARED_LIST_PATH+"/{shareCode}/item/{itemId:[0-9]+}/claim", middleware.DefaultPublicMiddlewareChain(sharedItemClaimHandler))
+}
+
+func listClaimableHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		listClaimableToggle(w, r, true)
+	case "DELETE":
+		listClaimableToggle(w, r, false)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func sharedItemClaimHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		sharedItemClaimPUT(w, r)
+	case "DELETE":
+		sharedItemClaimDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Enable or disable claiming mode on a list */
+func listClaimableToggle(w http.ResponseWriter, r *http.Request, claimable bool) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+
+	// Only the owner decides whether visitors may claim items
+	owns, err := repo.UserOwnsList(r.Context(), userId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !owns {
+		http.Error(w, "Forbidden - only the list owner can change claiming mode", http.StatusForbidden)
+		return
+	}
+
+	err = repo.SetListClaimable(r.Context(), listId, claimable)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Owner releases a claim on one of their items */
+func itemClaimDELETE(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+
+	canEdit, err := repo.UserCanEditList(r.Context(), userId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !canEdit {
+		http.Error(w, "Forbidden - you don't have permission to edit this list", http.StatusForbidden)
+		return
+	}
+
+	itemId, err := helper.GetPathVarInt(r, "itemId")
+	if err != nil {
+		http.Error(w, "Invalid itemId supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	inList, err := repo.ItemInList(r.Context(), itemId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !inList {
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return
+	}
+
+	err = repo.DeleteItemClaim(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Share link visitor claims an item */
+func sharedItemClaimPUT(w http.ResponseWriter, r *http.Request) {
+	itemId, ok := getClaimableSharedItem(w, r)
+	if !ok {
+		return
+	}
+	item, err := repo.GetItem(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if item.Quantity > 1 {
+		http.Error(w, "Several of this item are wanted; contribute towards it instead", http.StatusBadRequest)
+		return
+	}
+
+	token, err := repo.ClaimItem(r.Context(), itemId, strings.TrimSpace(r.FormValue("name")), strings.TrimSpace(r.FormValue("email")))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if token == "" {
+		http.Error(w, "Item has already been claimed", http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(token))
+}
+
+/* Share link visitor releases their own claim */
+func sharedItemClaimDELETE(w http.ResponseWriter, r *http.Request) {
+	itemId, ok := getClaimableSharedItem(w, r)
+	if !ok {
+		return
+	}
+
+	token := r.FormValue("token")
+	if token == "" {
+		http.Error(w, "Claim token is required", http.StatusBadRequest)
+		return
+	}
+
+	released, err := repo.UnclaimItem(r.Context(), itemId, token)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !released {
+		http.Error(w, "Claim token did not match", http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getClaimableSharedItem resolves the item in a shared list claim request, writing an error response and returning false
+// if the share code is unknown, the list is not in claiming mode, or the item belongs to a different list
+func getClaimableSharedItem(w http.ResponseWriter, r *http.Request) (int, bool) {
+	shareCode := mux.Vars(r)["shareCode"]
+	list, err := repo.GetListFromShareCode(r.Context(), shareCode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "List not found", http.StatusNotFound)
+			return 0, false
+		}
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return 0, false
+	}
+	if !list.Claimable {
+		http.Error(w, "This list does not allow claiming items", http.StatusForbidden)
+		return 0, false
+	}
+
+	itemId, err := helper.GetPathVarInt(r, "itemId")
+	if err != nil {
+		http.Error(w, "Invalid itemId supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return 0, false
+	}
+	inList, err := repo.ItemInList(r.Context(), itemId, int(list.Id))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return 0, false
+	}
+	if !inList {
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return 0, false
+	}
+	return itemId, true
+}
@@ -9,7 +9,6 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/jeffrpowell/listaway/internal/constants"
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
 	"github.com/jeffrpowell/listaway/web"
@@ -42,7 +41,7 @@ func collectionsPOST(w http.ResponseWriter, r *http.Request) {
 	var collectionName string = r.FormValue("name")
 	//Don't trust client input
 
-	taken, err := database.CollectionNameTaken(userId, collectionName)
+	taken, err := repo.CollectionNameTaken(r.Context(), userId, collectionName)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -54,7 +53,7 @@ func collectionsPOST(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var description string = r.FormValue("description")
-	newId, err := database.CreateCollection(userId, collectionName, description)
+	newId, err := repo.CreateCollection(r.Context(), userId, collectionName, description)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -93,7 +92,7 @@ func collectionGET(w http.ResponseWriter, r *http.Request) {
 
 	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
 
-	collection, err := database.GetCollection(collectionId)
+	collection, err := repo.GetCollection(r.Context(), collectionId)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			http.Error(w, "Collection not found", http.StatusNotFound)
@@ -104,15 +103,16 @@ func collectionGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	listIdsInCollection, err := database.GetCollectionListIds(collectionId)
+	listIdsInCollection, err := repo.GetCollectionListIds(r.Context(), collectionId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 
-	// Get lists owned by the user
-	ownedLists, err := database.GetLists(userId)
+	// Get lists owned by the user, only those carrying the tag when filtering by one
+	tag := tagParam(r)
+	ownedLists, err := repo.GetListsTagged(r.Context(), userId, tag)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -120,7 +120,7 @@ func collectionGET(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Get lists shared with the user's group
-	sharedLists, err := database.GetListsSharedWithGroup(userId)
+	sharedLists, err := repo.GetListsSharedWithGroup(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -131,7 +131,7 @@ func collectionGET(w http.ResponseWriter, r *http.Request) {
 	allLists := make([]constants.ListWithAuthor, 0, len(ownedLists)+len(sharedLists))
 	
 	// Add owned lists with current user as author
-	user, err := database.GetUser(userId)
+	user, err := repo.GetUser(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -144,14 +144,19 @@ func collectionGET(w http.ResponseWriter, r *http.Request) {
 			Description: list.Description,
 			ShareCode:   list.ShareCode,
 			ItemCount:   list.ItemCount,
+			OutstandingCount: list.OutstandingCount,
 			AuthorId:    uint64(userId),
 			AuthorName:  user.Name,
 			CanEdit:     true, // User owns this list
+			Tags:        list.Tags,
 		})
 	}
 	
 	// Add shared lists with their original authors
 	for _, list := range sharedLists {
+		if !hasTag(list.Tags, tag) {
+			continue
+		}
 		allLists = append(allLists, constants.ListWithAuthor{
 			Id:          list.Id,
 			Name:        list.Name,
@@ -161,6 +166,7 @@ func collectionGET(w http.ResponseWriter, r *http.Request) {
 			AuthorId:    list.OwnerId,
 			AuthorName:  list.OwnerName,
 			CanEdit:     list.GroupCanEdit, // User can edit if group sharing allows it
+			Tags:        list.Tags,
 		})
 	}
 
@@ -184,15 +190,32 @@ func collectionGET(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		// Get list IDs that have share codes for this user
-		listIdsWithShareCode, err := database.GetListIdsWithShareCode(userId)
+		listIdsWithShareCode, err := repo.GetListIdsWithShareCode(r.Context(), userId)
+		if err != nil {
+			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+			log.Print(err)
+			return
+		}
+
+		totals, err := repo.GetCollectionTotals(r.Context(), collectionId)
 		if err != nil {
 			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 			log.Print(err)
 			return
 		}
 
-		admin := helper.IsUserAdmin(r)
-		instanceAdmin := helper.IsUserInstanceAdmin(r)
+		var taggedItems []constants.TaggedItem
+		if tag != "" {
+			taggedItems, err = repo.GetCollectionTaggedItems(r.Context(), collectionId, tag)
+			if err != nil {
+				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+				log.Print(err)
+				return
+			}
+		}
+
+		admin := helper.IsUserAdmin(r, repo)
+		instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 
 		// Render the collection detail page
 		collectionDetailPage := web.CollectionDetailPageParams(
@@ -201,6 +224,9 @@ func collectionGET(w http.ResponseWriter, r *http.Request) {
 			listIdsInCollection,
 			listIdsWithShareCode,
 			allLists,
+			totals,
+			tag,
+			taggedItems,
 			admin,
 			instanceAdmin,
 		)
@@ -231,7 +257,7 @@ func collectionPUT(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if name is already taken by another collection (excluding current collection)
-	rows, err := database.GetCollections(userId)
+	rows, err := repo.GetCollections(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -245,7 +271,7 @@ func collectionPUT(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = database.UpdateCollection(collectionId, params)
+	err = repo.UpdateCollection(r.Context(), collectionId, params)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -265,7 +291,7 @@ func collectionDELETE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	success, err := database.DeleteCollection(collectionId, confirmationName)
+	success, err := repo.DeleteCollection(r.Context(), collectionId, confirmationName)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -298,7 +324,7 @@ func collectionListPUT(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId")             // Error already checked in middleware
 
 	// Check if list is already in collection
-	inCollection, err := database.ListInCollection(collectionId, listId)
+	inCollection, err := repo.ListInCollection(r.Context(), collectionId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -308,7 +334,7 @@ func collectionListPUT(w http.ResponseWriter, r *http.Request) {
 	// If not in collection, add it
 	if !inCollection {
 		// First check if the collection has a share code
-		collection, err := database.GetCollection(collectionId)
+		collection, err := repo.GetCollection(r.Context(), collectionId)
 		if err != nil {
 			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 			log.Print(err)
@@ -317,7 +343,7 @@ func collectionListPUT(w http.ResponseWriter, r *http.Request) {
 
 		// If collection has a share code, check if list already has a share code
 		if collection.ShareCode.Valid && len(collection.ShareCode.String) > 0 {
-			list, err := database.GetList(listId)
+			list, err := repo.GetList(r.Context(), listId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
@@ -326,7 +352,7 @@ func collectionListPUT(w http.ResponseWriter, r *http.Request) {
 
 			// If list doesn't have a share code, generate one
 			if !list.ShareCode.Valid || len(list.ShareCode.String) == 0 {
-				_, err = database.GenerateShareCode(listId)
+				_, err = repo.GenerateShareCode(r.Context(), listId)
 				if err != nil {
 					http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 					log.Print(err)
@@ -336,7 +362,7 @@ func collectionListPUT(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Now add the list to the collection
-		err = database.AddListToCollection(collectionId, listId)
+		err = repo.AddListToCollection(r.Context(), collectionId, listId)
 		if err != nil {
 			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 			log.Print(err)
@@ -352,7 +378,7 @@ func collectionListDELETE(w http.ResponseWriter, r *http.Request) {
 	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
 	listId, _ := helper.GetPathVarInt(r, "listId")             // Error already checked in middleware
 
-	err := database.RemoveListFromCollection(collectionId, listId)
+	err := repo.RemoveListFromCollection(r.Context(), collectionId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -364,8 +390,8 @@ func collectionListDELETE(w http.ResponseWriter, r *http.Request) {
 
 // createCollectionGET handles GET requests for /collections/create
 func createCollectionGET(w http.ResponseWriter, r *http.Request) {
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 
 	// Render the collection creation page
 	params := web.CreateCollectionParams(r, admin, instanceAdmin)
@@ -376,7 +402,7 @@ func createCollectionGET(w http.ResponseWriter, r *http.Request) {
 func editCollectionGET(w http.ResponseWriter, r *http.Request) {
 	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
 
-	collection, err := database.GetCollection(collectionId)
+	collection, err := repo.GetCollection(r.Context(), collectionId)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			http.Error(w, "Collection not found", http.StatusNotFound)
@@ -387,8 +413,8 @@ func editCollectionGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 
 	// Render the collection edit page
 	editParams := web.EditCollectionParams(r, collection, admin, instanceAdmin)
@@ -412,7 +438,7 @@ func collectionShareHandler(w http.ResponseWriter, r *http.Request) {
 func collectionSharePUT(w http.ResponseWriter, r *http.Request) {
 	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
 
-	code, err := database.GenerateCollectionShareCode(collectionId)
+	code, err := repo.GenerateCollectionShareCode(r.Context(), collectionId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -427,7 +453,7 @@ func collectionSharePUT(w http.ResponseWriter, r *http.Request) {
 func collectionShareDELETE(w http.ResponseWriter, r *http.Request) {
 	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
 
-	err := database.UnpublishCollectionShareCode(collectionId)
+	err := repo.UnpublishCollectionShareCode(r.Context(), collectionId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -442,7 +468,7 @@ func collectionShareDELETE(w http.ResponseWriter, r *http.Request) {
 func sharedCollectionGET(w http.ResponseWriter, r *http.Request) {
 	shareCode := mux.Vars(r)["shareCode"]
 
-	collection, err := database.GetCollectionFromShareCode(shareCode)
+	collection, err := repo.GetCollectionFromShareCode(r.Context(), shareCode)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			sharedCollection404Page := web.SharedCollection404PageParams(shareCode)
@@ -454,15 +480,15 @@ func sharedCollectionGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lists, err := database.GetCollectionLists(int(collection.Id))
+	lists, err := repo.GetCollectionLists(r.Context(), int(collection.Id))
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 
 	// Call the web package function to render the shared collection page
 	sharedCollectionPage := web.SharedCollectionPageParams(r, shareCode, collection, lists, admin, instanceAdmin)
@@ -485,7 +511,7 @@ func collectionNameCheckGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	taken, err := database.CollectionNameTaken(userId, name)
+	taken, err := repo.CollectionNameTaken(r.Context(), userId, name)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
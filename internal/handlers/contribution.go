@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/{itemId:[0-9]+}/contribution/{contributionId:[0-9]+}", middleware.Chain(itemContributionDELETE, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("DELETE")
+	constants.ROUTER.HandleFunc("/"+constants.SHARED_LIST_PATH+"/{shareCode}/item/{itemId:[0-9]+}/contribution", middleware.DefaultPublicMiddlewareChain(sharedItemContributionHandler))
+}
+
+func sharedItemContributionHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		sharedItemContributionPUT(w, r)
+	case "DELETE":
+		sharedItemContributionDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Owner removes a contribution towards one of their items */
+func itemContributionDELETE(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	contributionId, err := helper.GetPathVarInt(r, "contributionId")
+	if err != nil {
+		http.Error(w, "Invalid contributionId supplied in path", http.StatusBadRequest)
+		return
+	}
+	deleted, err := repo.DeleteContribution(r.Context(), itemId, contributionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Contribution not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Share link visitor contributes towards an item's quantity */
+func sharedItemContributionPUT(w http.ResponseWriter, r *http.Request) {
+	itemId, ok := getClaimableSharedItem(w, r)
+	if !ok {
+		return
+	}
+
+	quantity, err := strconv.Atoi(r.FormValue("quantity"))
+	if err != nil || quantity < 1 {
+		http.Error(w, "Quantity must be a whole number of at least 1", http.StatusBadRequest)
+		return
+	}
+	item, err := repo.GetItem(r.Context(), itemId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if item.Quantity <= 1 {
+		http.Error(w, "Only one of this item is wanted; claim it instead", http.StatusBadRequest)
+		return
+	}
+
+	token, err := repo.Contribute(r.Context(), itemId, strings.TrimSpace(r.FormValue("name")), quantity)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if token == "" {
+		http.Error(w, "Fewer than that are still needed", http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(token))
+}
+
+/* Share link visitor withdraws their own contribution */
+func sharedItemContributionDELETE(w http.ResponseWriter, r *http.Request) {
+	itemId, ok := getClaimableSharedItem(w, r)
+	if !ok {
+		return
+	}
+
+	token := r.FormValue("token")
+	if token == "" {
+		http.Error(w, "Contribution token is required", http.StatusBadRequest)
+		return
+	}
+
+	withdrawn, err := repo.WithdrawContribution(r.Context(), itemId, token)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !withdrawn {
+		http.Error(w, "Contribution token did not match", http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/copy", middleware.Chain(listCopyPOST, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("POST")
+	// Copying from a share link needs an account to copy into, but no access to the list beyond the link
+	constants.ROUTER.HandleFunc("/"+constants.SHARED_LIST_PATH+"/{shareCode}/copy", middleware.DefaultMiddlewareChain(sharedListCopyPOST)).Methods("POST")
+}
+
+type listCopyParams struct {
+	Name        string `json:"name"`
+	CopySharing bool   `json:"copySharing"`
+}
+
+type sharedListCopyParams struct {
+	Name string `json:"name"`
+}
+
+/* Duplicate an owned list */
+func listCopyPOST(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	owns, err := repo.UserOwnsList(r.Context(), userId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !owns {
+		http.Error(w, "Forbidden - only the list owner can copy it", http.StatusForbidden)
+		return
+	}
+	var params listCopyParams
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	copyList(w, r, userId, list, constants.ListCopy{
+		Name:        params.Name,
+		CopySharing: params.CopySharing,
+	})
+}
+
+/* Save a copy of a shared list to the signed-in user's account */
+func sharedListCopyPOST(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	list, err := repo.GetListFromShareCode(r.Context(), mux.Vars(r)["shareCode"])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Shared list not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	var params sharedListCopyParams
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	// Only copy what the link shows; the sharing settings belong to the original owner's group
+	copyList(w, r, userId, list, constants.ListCopy{
+		Name:          params.Name,
+		SkipCompleted: list.CompletedDisplay == constants.COMPLETED_DISPLAY_HIDE,
+	})
+}
+
+// copyList copies list for userId and answers with the new list, naming it after the original when no name is given
+func copyList(w http.ResponseWriter, r *http.Request, userId int, list constants.List, params constants.ListCopy) {
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		params.Name = list.Name + " (copy)"
+	}
+	taken, err := repo.ListNameTaken(r.Context(), userId, params.Name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if taken {
+		http.Error(w, "List name already taken", http.StatusConflict)
+		return
+	}
+	newListId, err := repo.CopyList(r.Context(), userId, int(list.Id), params)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	newList, err := repo.GetList(r.Context(), newListId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, constants.API_V1_PATH) {
+		w.Header().Add("Location", fmt.Sprintf("%s/lists/%d", constants.API_V1_PATH, newListId))
+	} else {
+		w.Header().Add("Location", fmt.Sprintf("/list/%d", newListId))
+	}
+	writeJSON(w, http.StatusCreated, toApiList(newList))
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/exporter"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/export", middleware.Chain(listExportGET, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
+	constants.ROUTER.HandleFunc("/collections/{collectionId:[0-9]+}/export", middleware.Chain(collectionExportGET, append([]middleware.Middleware{middleware.CollectionIdOwner("collectionId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
+	constants.ROUTER.HandleFunc("/"+constants.SHARED_LIST_PATH+"/{shareCode}/export", middleware.DefaultPublicMiddlewareChain(sharedListExportGET)).Methods("GET")
+	constants.ROUTER.HandleFunc("/"+constants.SHARED_COLLECTION_PATH+"/{shareCode}/export", middleware.DefaultPublicMiddlewareChain(sharedCollectionExportGET)).Methods("GET")
+}
+
+/* Export a list */
+func listExportGET(w http.ResponseWriter, r *http.Request) {
+	if !exportFormatOk(w, r) {
+		return
+	}
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	items, err := repo.GetListItems(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeExport(w, r, exporter.ForList(list, items))
+}
+
+/* Export a shared list */
+func sharedListExportGET(w http.ResponseWriter, r *http.Request) {
+	if !exportFormatOk(w, r) {
+		return
+	}
+	list, err := repo.GetListFromShareCode(r.Context(), mux.Vars(r)["shareCode"])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "List not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	items, err := repo.GetListItems(r.Context(), int(list.Id))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeExport(w, r, exporter.ForList(list, sharedItems(list.CompletedDisplay, items)))
+}
+
+/* Export every list in a collection that the owner can still view */
+func collectionExportGET(w http.ResponseWriter, r *http.Request) {
+	if !exportFormatOk(w, r) {
+		return
+	}
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
+	collection, err := repo.GetCollection(r.Context(), collectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	lists, err := collectionExportLists(r.Context(), collection, false, func(list constants.ListWithAuthor) (bool, error) {
+		return repo.UserCanViewList(r.Context(), userId, int(list.Id))
+	})
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeExport(w, r, exporter.ForCollection(collection, lists))
+}
+
+/* Export a shared collection; as on the shared collection page, only lists with their own share link are included */
+func sharedCollectionExportGET(w http.ResponseWriter, r *http.Request) {
+	if !exportFormatOk(w, r) {
+		return
+	}
+	collection, err := repo.GetCollectionFromShareCode(r.Context(), mux.Vars(r)["shareCode"])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Collection not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	lists, err := collectionExportLists(r.Context(), collection, true, func(list constants.ListWithAuthor) (bool, error) {
+		return list.ShareCode.Valid, nil
+	})
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeExport(w, r, exporter.ForCollection(collection, lists))
+}
+
+// exportFormatOk writes a 400 and returns false unless the format query parameter names a known export format
+func exportFormatOk(w http.ResponseWriter, r *http.Request) bool {
+	if !slices.Contains(exporter.Formats, r.URL.Query().Get("format")) {
+		http.Error(w, "Unknown export format", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// collectionExportLists loads the items of each list in the collection that include accepts.
+// Shared exports leave out completed items that a list hides from share links.
+func collectionExportLists(ctx context.Context, collection constants.Collection, shared bool, include func(constants.ListWithAuthor) (bool, error)) ([]exporter.List, error) {
+	collectionLists, err := repo.GetCollectionLists(ctx, int(collection.Id))
+	if err != nil {
+		return nil, err
+	}
+	lists := make([]exporter.List, 0, len(collectionLists))
+	for _, list := range collectionLists {
+		ok, err := include(list)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		items, err := repo.GetListItems(ctx, int(list.Id))
+		if err != nil {
+			return nil, err
+		}
+		if shared {
+			items = sharedItems(list.CompletedDisplay, items)
+		}
+		lists = append(lists, exporter.List{Name: list.Name, Description: list.Description.String, Author: list.AuthorName, Items: items})
+	}
+	return lists, nil
+}
+
+// sharedItems leaves out completed items when the list hides them from share links
+func sharedItems(completedDisplay string, items []constants.Item) []constants.Item {
+	if completedDisplay != constants.COMPLETED_DISPLAY_HIDE {
+		return items
+	}
+	return slices.DeleteFunc(slices.Clone(items), constants.Item.Completed)
+}
+
+func writeExport(w http.ResponseWriter, r *http.Request, export exporter.Export) {
+	format := r.URL.Query().Get("format")
+	w.Header().Set("Content-Type", exporter.ContentType(format))
+	w.Header().Set("Content-Disposition", export.ContentDisposition(format))
+	if err := exporter.Write(w, format, export); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,261 @@
+package exporter
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+)
+
+// Supported export formats
+const (
+	FormatCSV      string = "csv"
+	FormatJSON     string = "json"
+	FormatMarkdown string = "md"
+	FormatHTML     string = "html" // printable page, served inline rather than as a download
+)
+
+var Formats = []string{FormatCSV, FormatJSON, FormatMarkdown, FormatHTML}
+
+// List is one list's worth of exported items
+type List struct {
+	Name        string
+	Description string
+	Author      string // only set for lists exported as part of a collection
+	Items       []constants.Item
+}
+
+// Export is a single list, or a collection of lists, ready to be written in any format
+type Export struct {
+	Name        string
+	Description string
+	Collection  bool
+	Lists       []List
+}
+
+// ForList exports one list
+func ForList(list constants.List, items []constants.Item) Export {
+	return Export{
+		Name:        list.Name,
+		Description: list.Description.String,
+		Lists:       []List{{Name: list.Name, Description: list.Description.String, Items: items}},
+	}
+}
+
+// ForCollection exports a collection; lists holds the lists the reader may see, in display order
+func ForCollection(collection constants.Collection, lists []List) Export {
+	return Export{
+		Name:        collection.Name,
+		Description: collection.Description.String,
+		Collection:  true,
+		Lists:       lists,
+	}
+}
+
+// ContentType is the media type to serve a format with
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "text/html; charset=utf-8"
+	}
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^\pL\pN]+`)
+
+// ContentDisposition names the download after the export. HTML is shown inline so it can be printed.
+func (e Export) ContentDisposition(format string) string {
+	name := strings.Trim(unsafeFilenameChars.ReplaceAllString(strings.ToLower(e.Name), "-"), "-")
+	if name == "" {
+		name = "list"
+	}
+	disposition := "attachment"
+	if format == FormatHTML {
+		disposition = "inline"
+	}
+	return mime.FormatMediaType(disposition, map[string]string{"filename": name + "." + format})
+}
+
+// Write writes the export in the given format. Items keep the order they are given in, which is the list's own order.
+func Write(w io.Writer, format string, e Export) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, e)
+	case FormatJSON:
+		return writeJSON(w, e)
+	case FormatMarkdown:
+		return writeMarkdown(w, e)
+	case FormatHTML:
+		return printTemplate.Execute(w, e)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func priority(item constants.Item) string {
+	if !item.Priority.Valid {
+		return ""
+	}
+	return strconv.FormatInt(item.Priority.Int64, 10)
+}
+
+// writeCSV uses the same header names the importer recognizes, so an exported list can be imported again.
+// Collections get a leading List column.
+func writeCSV(w io.Writer, e Export) error {
+	writer := csv.NewWriter(w)
+	header := []string{"Name", "URL", "Priority", "Notes"}
+	if e.Collection {
+		header = append([]string{"List"}, header...)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, list := range e.Lists {
+		for _, item := range list.Items {
+			record := []string{helper.CSVSafe(item.Name), item.URL.String, priority(item), helper.CSVSafe(item.Notes.String)}
+			if e.Collection {
+				record = append([]string{helper.CSVSafe(list.Name)}, record...)
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type jsonItem struct {
+	Name      string `json:"name"`
+	URL       string `json:"url,omitempty"`
+	Priority  *int64 `json:"priority,omitempty"`
+	Notes     string `json:"notes,omitempty"`
+	Completed bool   `json:"completed,omitempty"`
+	Quantity  int    `json:"quantity,omitempty"` // left out when only one is wanted
+	Price     string `json:"price,omitempty"`    // decimal amount per item, e.g. "12.50"
+	Currency  string `json:"currency,omitempty"`
+}
+
+type jsonList struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonCollection struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Lists       []jsonList `json:"lists"`
+}
+
+func toJSONList(list List) jsonList {
+	out := jsonList{Name: list.Name, Description: list.Description, Author: list.Author, Items: make([]jsonItem, 0, len(list.Items))}
+	for _, item := range list.Items {
+		exported := jsonItem{Name: item.Name, URL: item.URL.String, Notes: item.Notes.String, Completed: item.Completed()}
+		if item.Priority.Valid {
+			exported.Priority = &item.Priority.Int64
+		}
+		if item.Quantity > 1 {
+			exported.Quantity = item.Quantity
+		}
+		if item.Price.Valid {
+			exported.Price = constants.FormatAmount(item.Price.Int64, item.Currency.String)
+			exported.Currency = item.Currency.String
+		}
+		out.Items = append(out.Items, exported)
+	}
+	return out
+}
+
+// writeJSON writes a list as {"name", "description", "items"}, which the importer accepts as-is,
+// and a collection as {"name", "description", "lists"}
+func writeJSON(w io.Writer, e Export) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if !e.Collection {
+		return encoder.Encode(toJSONList(e.Lists[0]))
+	}
+	out := jsonCollection{Name: e.Name, Description: e.Description, Lists: make([]jsonList, 0, len(e.Lists))}
+	for _, list := range e.Lists {
+		out.Lists = append(out.Lists, toJSONList(list))
+	}
+	return encoder.Encode(out)
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`)
+
+// markdownURL keeps a link target from ending the link early
+var markdownURL = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29")
+
+func writeMarkdown(w io.Writer, e Export) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscaper.Replace(e.Name))
+	if e.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", markdownEscaper.Replace(e.Description))
+	}
+	for _, list := range e.Lists {
+		if e.Collection {
+			fmt.Fprintf(&b, "## %s\n\n", markdownEscaper.Replace(list.Name))
+			if list.Author != "" {
+				fmt.Fprintf(&b, "By %s\n\n", markdownEscaper.Replace(list.Author))
+			}
+			if list.Description != "" {
+				fmt.Fprintf(&b, "%s\n\n", markdownEscaper.Replace(list.Description))
+			}
+		}
+		if len(list.Items) == 0 {
+			b.WriteString("_No items_\n\n")
+			continue
+		}
+		for _, item := range list.Items {
+			name := markdownEscaper.Replace(item.Name)
+			if item.URL.Valid && item.URL.String != "" {
+				name = fmt.Sprintf("[%s](%s)", name, markdownURL.Replace(item.URL.String))
+			}
+			box := " "
+			if item.Completed() {
+				box = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s", box, name)
+			if item.Quantity > 1 {
+				fmt.Fprintf(&b, " ×%d", item.Quantity)
+			}
+			if item.Price.Valid {
+				fmt.Fprintf(&b, " — %s", item.PriceText())
+			}
+			if item.Priority.Valid {
+				fmt.Fprintf(&b, " (priority %d)", item.Priority.Int64)
+			}
+			b.WriteString("\n")
+			if item.Notes.Valid && item.Notes.String != "" {
+				for _, line := range strings.Split(strings.TrimSpace(item.Notes.String), "\n") {
+					fmt.Fprintf(&b, "  %s\n", markdownEscaper.Replace(strings.TrimRight(line, "\r")))
+				}
+			}
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+//go:embed print.html
+var printHTML string
+
+// The printable page stands alone, without the app's layout or scripts, so it prints cleanly
+var printTemplate = template.Must(template.New("print").Parse(printHTML))
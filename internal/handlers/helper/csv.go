@@ -0,0 +1,11 @@
+package helper
+
+import "strings"
+
+// CSVSafe stops spreadsheets from treating a value as a formula
+func CSVSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -1,8 +1,11 @@
 package helper
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"errors"
 
@@ -11,8 +14,22 @@ import (
 	"github.com/jeffrpowell/listaway/internal/database"
 )
 
+type contextKey string
+
+const apiUserIdKey contextKey = "apiUserId"
+
+// WithApiUserId marks a request as authenticated by a personal access token belonging to userId
+func WithApiUserId(r *http.Request, userId int) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), apiUserIdKey, userId))
+}
+
 func GetUserId(r *http.Request) (int, error) {
-	session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	// Requests to /api/v1 carry the token owner's id instead of a session
+	if userId, ok := r.Context().Value(apiUserIdKey).(int); ok {
+		return userId, nil
+	}
+
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
 
 	// Retrieve our struct and type-assert it
 	val := session.Values["userId"]
@@ -22,28 +39,44 @@ func GetUserId(r *http.Request) (int, error) {
 	return 0, errors.New("bad userid")
 }
 
+// ClientIP is the address the request came from: the first X-Forwarded-For hop when TRUST_PROXY is
+// set, otherwise the connection's own address
+func ClientIP(r *http.Request) string {
+	if constants.TRUST_PROXY {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func GetPathVarInt(r *http.Request, pathNodeName string) (int, error) {
 	return strconv.Atoi(mux.Vars(r)[pathNodeName])
 }
 
-func IsUserAdmin(r *http.Request) bool {
+func IsUserAdmin(r *http.Request, repo *database.Repository) bool {
 	userId, err := GetUserId(r)
 	if err != nil {
 		return false
 	}
-	admin, err := database.UserIsAdmin(userId)
+	admin, err := repo.UserIsAdmin(r.Context(), userId)
 	if err != nil {
 		return false
 	}
 	return admin
 }
 
-func IsUserInstanceAdmin(r *http.Request) bool {
+func IsUserInstanceAdmin(r *http.Request, repo *database.Repository) bool {
 	userId, err := GetUserId(r)
 	if err != nil {
 		return false
 	}
-	instanceAdmin, err := database.UserIsInstanceAdmin(userId)
+	instanceAdmin, err := repo.UserIsInstanceAdmin(r.Context(), userId)
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/database"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/importer"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// maxImportBytes caps the pasted or uploaded content of a single import
+const maxImportBytes = 1 << 20
+
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/import", middleware.Chain(listImportHandler, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...))
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/import/preview", middleware.Chain(listImportPreviewPOST, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("POST")
+}
+
+func listImportHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		listImportGET(w, r)
+	case "PUT":
+		listImportPUT(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+type importPreviewRow struct {
+	importer.Row
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+type importPreview struct {
+	Headers []string           `json:"headers,omitempty"`
+	Columns *importer.Columns  `json:"columns,omitempty"`
+	Rows    []importPreviewRow `json:"rows"`
+	New     int                `json:"new"`
+}
+
+type importSummary struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+/* Import items page */
+func listImportGET(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.ListImportPage(w, web.ListImportPageParams(r, list, importer.MaxRows, admin, instanceAdmin))
+}
+
+/* Parse an import and report what would be added, without changing the list */
+func listImportPreviewPOST(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	result, ok := parseImport(w, r)
+	if !ok {
+		return
+	}
+	existing, err := repo.GetListItems(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, item := range existing {
+		seen[database.ItemDedupeKey(item.Name, item.URL)] = true
+	}
+
+	preview := importPreview{Headers: result.Headers, Columns: result.Columns, Rows: make([]importPreviewRow, 0, len(result.Rows))}
+	for _, row := range result.Rows {
+		previewRow := importPreviewRow{Row: row}
+		if row.Error == "" {
+			item := row.ItemInsert(uint64(listId))
+			key := database.ItemDedupeKey(item.Name, item.URL)
+			previewRow.Duplicate = seen[key]
+			if !previewRow.Duplicate {
+				seen[key] = true
+				preview.New++
+			}
+		}
+		preview.Rows = append(preview.Rows, previewRow)
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+/* Import items; rows with errors and duplicates are skipped, and the rest are added together or not at all */
+func listImportPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	result, ok := parseImport(w, r)
+	if !ok {
+		return
+	}
+	items := make([]constants.ItemInsert, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		if row.Error == "" {
+			items = append(items, row.ItemInsert(uint64(listId)))
+		}
+	}
+	userId, _ := helper.GetUserId(r) // err already tripped in requireListEdit
+	imported, err := repo.ImportItems(r.Context(), userId, listId, items)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Add("Location", fmt.Sprintf("/list/%d", listId))
+	writeJSON(w, http.StatusOK, importSummary{Imported: imported, Skipped: len(result.Rows) - imported})
+}
+
+// parseImport reads the import form fields, writing a 400 and returning false if the content can't be parsed
+func parseImport(w http.ResponseWriter, r *http.Request) (importer.Result, bool) {
+	content := r.FormValue("content")
+	if len(content) > maxImportBytes {
+		http.Error(w, "Import is too large", http.StatusRequestEntityTooLarge)
+		return importer.Result{}, false
+	}
+	result, err := importer.Parse(r.FormValue("format"), content, importer.Columns{
+		Name:     r.FormValue("nameColumn"),
+		URL:      r.FormValue("urlColumn"),
+		Priority: r.FormValue("priorityColumn"),
+		Notes:    r.FormValue("notesColumn"),
+	})
+	if err != nil {
+		http.Error(w, "Could not import: "+err.Error(), http.StatusBadRequest)
+		return importer.Result{}, false
+	}
+	return result, true
+}
@@ -0,0 +1,330 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// Supported import formats
+const (
+	FormatCSV  string = "csv"
+	FormatJSON string = "json"
+	FormatURLs string = "urls" // one URL per line
+)
+
+// MaxRows caps how many items a single import may contain
+const MaxRows = 1000
+
+// Columns maps CSV header names onto item fields; an empty name leaves the field unmapped
+type Columns struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Priority string `json:"priority"`
+	Notes    string `json:"notes"`
+}
+
+// Row is one parsed item. Rows that can't become an item carry an Error and are never inserted.
+type Row struct {
+	Line     int    `json:"line"`
+	Name     string `json:"name"`
+	URL      string `json:"url,omitempty"`
+	Priority *int64 `json:"priority,omitempty"`
+	Notes    string `json:"notes,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Result is everything parsed from an import. Headers and Columns are only set for CSV,
+// so the caller can show which column feeds which field and let the user change it.
+type Result struct {
+	Headers []string `json:"headers,omitempty"`
+	Columns *Columns `json:"columns,omitempty"`
+	Rows    []Row    `json:"rows"`
+}
+
+// ItemInsert converts a valid row into an item for the given list
+func (row Row) ItemInsert(listId uint64) constants.ItemInsert {
+	item := constants.ItemInsert{
+		Name:   row.Name,
+		ListId: listId,
+		URL:    sql.NullString{String: row.URL, Valid: row.URL != ""},
+		Notes:  sql.NullString{String: row.Notes, Valid: row.Notes != ""},
+	}
+	if row.Priority != nil {
+		item.Priority = sql.NullInt64{Int64: *row.Priority, Valid: true}
+	}
+	return item
+}
+
+// Parse reads content in the given format. Problems with individual rows are reported on the
+// rows; an error is only returned when the content as a whole can't be read.
+func Parse(format string, content string, columns Columns) (Result, error) {
+	var result Result
+	var err error
+	switch format {
+	case FormatCSV:
+		result, err = parseCSV(content, columns)
+	case FormatJSON:
+		result, err = parseJSON(content)
+	case FormatURLs:
+		result, err = parseURLs(content)
+	default:
+		return Result{}, fmt.Errorf("unknown import format %q", format)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if len(result.Rows) == 0 {
+		return Result{}, errors.New("no items found")
+	}
+	if len(result.Rows) > MaxRows {
+		return Result{}, fmt.Errorf("imports are limited to %d items", MaxRows)
+	}
+	for i := range result.Rows {
+		result.Rows[i].validate()
+	}
+	return result, nil
+}
+
+func (row *Row) validate() {
+	row.Name = strings.TrimSpace(row.Name)
+	row.URL = strings.TrimSpace(row.URL)
+	row.Notes = strings.TrimSpace(row.Notes)
+	if row.Error != "" {
+		return
+	}
+	if row.Name == "" {
+		row.Error = "Name is missing"
+		return
+	}
+	if row.URL != "" && !isWebURL(row.URL) {
+		row.Error = "URL is not a valid http or https link"
+	}
+}
+
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Header names commonly used by spreadsheets and wishlist exports (Amazon uses Title, URL and Comment)
+var columnAliases = map[string][]string{
+	"name":     {"name", "item", "title", "product", "product name", "item name"},
+	"url":      {"url", "link", "product link", "product url", "item url", "href"},
+	"priority": {"priority", "rank", "rating", "stars"},
+	"notes":    {"notes", "note", "comment", "comments", "description", "details"},
+}
+
+// GuessColumns maps headers onto item fields by their usual names
+func GuessColumns(headers []string) Columns {
+	find := func(field string) string {
+		for _, alias := range columnAliases[field] {
+			for _, header := range headers {
+				if strings.EqualFold(strings.TrimSpace(header), alias) {
+					return header
+				}
+			}
+		}
+		return ""
+	}
+	return Columns{
+		Name:     find("name"),
+		URL:      find("url"),
+		Priority: find("priority"),
+		Notes:    find("notes"),
+	}
+}
+
+func parseCSV(content string, columns Columns) (Result, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\ufeff"))) // Excel prepends a BOM
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return Result{}, errors.New("the CSV is empty")
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("could not read the CSV header: %v", err)
+	}
+	if columns == (Columns{}) {
+		columns = GuessColumns(headers)
+	}
+	index := func(header string) (int, error) {
+		if header == "" {
+			return -1, nil
+		}
+		for i, h := range headers {
+			if h == header {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("the CSV has no %q column", header)
+	}
+	nameCol, err := index(columns.Name)
+	if err != nil {
+		return Result{}, err
+	}
+	if nameCol == -1 {
+		return Result{}, errors.New("choose which column holds the item name")
+	}
+	urlCol, err := index(columns.URL)
+	if err != nil {
+		return Result{}, err
+	}
+	priorityCol, err := index(columns.Priority)
+	if err != nil {
+		return Result{}, err
+	}
+	notesCol, err := index(columns.Notes)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Headers: headers, Columns: &columns}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("could not read the CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}
+		row := Row{Line: line, Name: field(nameCol), URL: field(urlCol), Notes: field(notesCol)}
+		if raw := strings.TrimSpace(field(priorityCol)); raw != "" {
+			priority, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				row.Error = fmt.Sprintf("Priority %q is not a whole number", raw)
+			} else {
+				row.Priority = &priority
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonItem accepts both the shape /list/{listId}/items returns, where nullable fields are
+// {"String": ..., "Valid": ...} objects, and plain values as returned by the /api/v1 routes
+type jsonItem struct {
+	Name     string          `json:"name"`
+	URL      json.RawMessage `json:"url"`
+	Priority json.RawMessage `json:"priority"`
+	Notes    json.RawMessage `json:"notes"`
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func nullableString(raw json.RawMessage) (string, error) {
+	if isNull(raw) {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var wrapped sql.NullString
+	if err := json.Unmarshal(raw, &wrapped); err != nil {
+		return "", errors.New("expected a string")
+	}
+	return wrapped.String, nil
+}
+
+func nullableInt64(raw json.RawMessage) (*int64, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+	var i int64
+	if err := json.Unmarshal(raw, &i); err == nil {
+		return &i, nil
+	}
+	var wrapped sql.NullInt64
+	if err := json.Unmarshal(raw, &wrapped); err != nil {
+		return nil, errors.New("expected a whole number")
+	}
+	if !wrapped.Valid {
+		return nil, nil
+	}
+	return &wrapped.Int64, nil
+}
+
+// parseJSON reads an array of items, or a list export's {"name", "items": [...]} object
+func parseJSON(content string) (Result, error) {
+	var items []jsonItem
+	if strings.HasPrefix(strings.TrimSpace(content), "{") {
+		var exported struct {
+			Items []jsonItem `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(content), &exported); err != nil || exported.Items == nil {
+			return Result{}, errors.New("expected a JSON array of items, or an object with an items array")
+		}
+		items = exported.Items
+	} else if err := json.Unmarshal([]byte(content), &items); err != nil {
+		return Result{}, errors.New("expected a JSON array of items")
+	}
+	var result Result
+	for i, item := range items {
+		row := Row{Line: i + 1, Name: item.Name}
+		var err error
+		if row.URL, err = nullableString(item.URL); err != nil {
+			row.Error = "url: " + err.Error()
+		} else if row.Notes, err = nullableString(item.Notes); err != nil {
+			row.Error = "notes: " + err.Error()
+		} else if row.Priority, err = nullableInt64(item.Priority); err != nil {
+			row.Error = "priority: " + err.Error()
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// parseURLs reads one link per line, skipping blank lines and # comments. Each item is named after its link.
+func parseURLs(content string) (Result, error) {
+	var result Result
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result.Rows = append(result.Rows, Row{Line: i + 1, Name: nameFromURL(line), URL: line})
+	}
+	return result, nil
+}
+
+// nameFromURL gives a link a readable placeholder name: its host and path without the scheme
+func nameFromURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(u.Host, "www.")+u.Path, "/")
+}
@@ -6,7 +6,6 @@ import (
 	"strconv"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
 	"github.com/jeffrpowell/listaway/web"
@@ -27,7 +26,7 @@ func allUsersGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Instance admin can see all users
-	users, err := database.GetAllUsers()
+	users, err := repo.GetAllUsers(r.Context())
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -60,19 +59,24 @@ func toggleUserInstanceAdmin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	instanceAdmin, err := database.UserIsInstanceAdmin(userId)
+	instanceAdmin, err := repo.UserIsInstanceAdmin(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 
-	err = database.SetUserInstanceAdmin(userId, !instanceAdmin)
+	err = repo.SetUserInstanceAdmin(r.Context(), userId, !instanceAdmin)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
+	if instanceAdmin {
+		audit(r, constants.AUDIT_INSTANCE_ADMIN_REVOKE, auditUser(r, userId), "")
+	} else {
+		audit(r, constants.AUDIT_INSTANCE_ADMIN_GRANT, auditUser(r, userId), "")
+	}
 
 	// Return the new status
 	w.Header().Set("Content-Type", "text/plain")
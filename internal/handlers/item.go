@@ -8,7 +8,6 @@ import (
 	"strconv"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
 	"github.com/jeffrpowell/listaway/web"
@@ -18,6 +17,8 @@ func init() {
 	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item", middleware.Chain(itemPUT, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("PUT")
 	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/create", middleware.Chain(createItemGET, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
 	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/{itemId:[0-9]+}", middleware.Chain(itemHandler, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...))
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/{itemId:[0-9]+}/complete", middleware.Chain(itemCompleteHandler, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...))
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/items/order", middleware.Chain(itemOrderPUT, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("PUT")
 	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/{itemId:[0-9]+}/edit", middleware.Chain(itemEditGET, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
 }
 
@@ -35,15 +36,24 @@ func itemHandler(w http.ResponseWriter, r *http.Request) {
 /* Create item page */
 func createItemGET(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
-	list, err := database.GetList(listId)
+	list, err := repo.GetList(r.Context(), listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
-	web.CreateEditItemPage(w, web.CreateItemParams(r, list, admin, instanceAdmin))
+	sections, err := repo.GetListSections(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	// The "add item" link under a section heading starts the item in that section
+	sectionId, err := strconv.ParseInt(r.URL.Query().Get("section"), 10, 64)
+	section := sql.NullInt64{Int64: sectionId, Valid: err == nil}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.CreateEditItemPage(w, web.CreateItemParams(r, list, sections, section, admin, instanceAdmin))
 }
 
 /* Create item */
@@ -58,7 +68,7 @@ func itemPUT(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
 	
 	// Check if user can edit this list
-	canEdit, err := database.UserCanEditList(userId, listId)
+	canEdit, err := repo.UserCanEditList(r.Context(), userId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -69,16 +79,38 @@ func itemPUT(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	sectionId, err := strconv.ParseInt(r.FormValue("section"), 10, 64)
+	section := sql.NullInt64{Int64: sectionId, Valid: err == nil}
+	if !requireItemSection(w, r, listId, section) {
+		return
+	}
+	price, currency, err := parsePrice(r.FormValue("price"), r.FormValue("currency"))
+	if err != nil {
+		http.Error(w, "Invalid price: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := constants.ParseTags(r.FormValue("tags"))
+	if err != nil {
+		http.Error(w, "Invalid tags: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 	var itemName string = r.FormValue("name")
 	var url string = r.FormValue("url")
 	priority, err := strconv.ParseInt(r.FormValue("priority"), 10, 64)
 	var notes string = r.FormValue("notes")
-	err = database.CreateItem(constants.ItemInsert{
-		Name:     itemName,
-		ListId:   uint64(listId),
-		URL:      sql.NullString{String: url, Valid: url != ""},
-		Priority: sql.NullInt64{Int64: priority, Valid: err == nil},
-		Notes:    sql.NullString{String: notes, Valid: notes != ""},
+	quantity, fulfilled := formItemQuantities(r)
+	_, err = repo.CreateItem(r.Context(), userId, constants.ItemInsert{
+		Name:      itemName,
+		ListId:    uint64(listId),
+		URL:       sql.NullString{String: url, Valid: url != ""},
+		Priority:  sql.NullInt64{Int64: priority, Valid: err == nil},
+		Notes:     sql.NullString{String: notes, Valid: notes != ""},
+		SectionId: section,
+		Quantity:  quantity,
+		Fulfilled: fulfilled,
+		Price:     price,
+		Currency:  currency,
+		Tags:      tags,
 	})
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
@@ -92,27 +124,31 @@ func itemPUT(w http.ResponseWriter, r *http.Request) {
 /* Edit item page */
 func itemEditGET(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
-	itemId, err := helper.GetPathVarInt(r, "itemId")
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	list, err := repo.GetList(r.Context(), listId)
 	if err != nil {
-		http.Error(w, "Invalid itemId supplied", http.StatusBadRequest)
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	list, err := database.GetList(listId)
+	item, err := repo.GetItem(r.Context(), itemId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	item, err := database.GetItem(itemId)
+	sections, err := repo.GetListSections(r.Context(), listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
-	web.CreateEditItemPage(w, web.EditItemParams(r, list, item, admin, instanceAdmin))
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.CreateEditItemPage(w, web.EditItemParams(r, list, item, sections, admin, instanceAdmin))
 }
 
 /* Update item */
@@ -127,7 +163,7 @@ func itemPOST(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
 	
 	// Check if user can edit this list
-	canEdit, err := database.UserCanEditList(userId, listId)
+	canEdit, err := repo.UserCanEditList(r.Context(), userId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -138,22 +174,42 @@ func itemPOST(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	itemId, err := helper.GetPathVarInt(r, "itemId")
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	sectionId, err := strconv.ParseInt(r.FormValue("section"), 10, 64)
+	section := sql.NullInt64{Int64: sectionId, Valid: err == nil}
+	if !requireItemSection(w, r, listId, section) {
+		return
+	}
+	price, currency, err := parsePrice(r.FormValue("price"), r.FormValue("currency"))
 	if err != nil {
-		http.Error(w, "Invalid itemId supplied", http.StatusBadRequest)
-		log.Print(err)
+		http.Error(w, "Invalid price: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := constants.ParseTags(r.FormValue("tags"))
+	if err != nil {
+		http.Error(w, "Invalid tags: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 	var itemName string = r.FormValue("name")
 	var url string = r.FormValue("url")
 	priority, err := strconv.ParseInt(r.FormValue("priority"), 10, 64)
 	var notes string = r.FormValue("notes")
-	err = database.UpdateItem(itemId, constants.ItemInsert{
-		Name:     itemName,
-		ListId:   uint64(listId),
-		URL:      sql.NullString{String: url, Valid: url != ""},
-		Priority: sql.NullInt64{Int64: priority, Valid: err == nil},
-		Notes:    sql.NullString{String: notes, Valid: notes != ""},
+	quantity, fulfilled := formItemQuantities(r)
+	err = repo.UpdateItem(r.Context(), userId, itemId, constants.ItemInsert{
+		Name:      itemName,
+		ListId:    uint64(listId),
+		URL:       sql.NullString{String: url, Valid: url != ""},
+		Priority:  sql.NullInt64{Int64: priority, Valid: err == nil},
+		Notes:     sql.NullString{String: notes, Valid: notes != ""},
+		SectionId: section,
+		Quantity:  quantity,
+		Fulfilled: fulfilled,
+		Price:     price,
+		Currency:  currency,
+		Tags:      tags,
 	})
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
@@ -164,6 +220,63 @@ func itemPOST(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// formItemQuantities reads the wanted and already fulfilled counts from an item form; blanks mean 1 wanted, none fulfilled
+func formItemQuantities(r *http.Request) (int, int) {
+	quantity, err := strconv.Atoi(r.FormValue("quantity"))
+	if err != nil {
+		quantity = 1
+	}
+	fulfilled, err := strconv.Atoi(r.FormValue("fulfilled"))
+	if err != nil {
+		fulfilled = 0
+	}
+	return quantity, fulfilled
+}
+
+func itemCompleteHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		itemCompleteToggle(w, r, true)
+	case "DELETE":
+		itemCompleteToggle(w, r, false)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Check an item off, or clear it */
+func itemCompleteToggle(w http.ResponseWriter, r *http.Request, completed bool) {
+	if _, _, ok := setItemCompleted(w, r, completed); !ok {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setItemCompleted checks off or clears the item in the path as the current user, writing any error response itself
+func setItemCompleted(w http.ResponseWriter, r *http.Request, completed bool) (int, int, bool) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return 0, 0, false
+	}
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return 0, 0, false
+	}
+	err = repo.SetItemCompleted(r.Context(), itemId, userId, completed)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return 0, 0, false
+	}
+	return listId, itemId, true
+}
+
 /* Delete item */
 func itemDELETE(w http.ResponseWriter, r *http.Request) {
 	userId, err := helper.GetUserId(r)
@@ -176,7 +289,7 @@ func itemDELETE(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
 	
 	// Check if user can edit this list
-	canEdit, err := database.UserCanEditList(userId, listId)
+	canEdit, err := repo.UserCanEditList(r.Context(), userId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -187,17 +300,43 @@ func itemDELETE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	itemId, err := helper.GetPathVarInt(r, "itemId")
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	err = repo.DeleteItem(r.Context(), userId, itemId)
 	if err != nil {
-		http.Error(w, "Invalid itemId supplied in path", http.StatusBadRequest)
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	err = database.DeleteItem(itemId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// itemOrder is a list's custom item order, first item first
+type itemOrder struct {
+	ItemIds []uint64 `json:"itemIds" openapi:"required"`
+}
+
+/* Reorder items. The body may list every item or only some; listed items swap into each other's places. */
+func itemOrderPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	var order itemOrder
+	if !decodeJSON(w, r, &order) {
+		return
+	}
+	itemIds, ok, err := repo.ReorderItems(r.Context(), listId, order.ItemIds)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+	if !ok {
+		http.Error(w, "Every item must belong to this list and appear only once", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, itemOrder{ItemIds: itemIds})
 }
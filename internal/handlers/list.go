@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
 	"github.com/jeffrpowell/listaway/web"
@@ -19,6 +20,7 @@ func init() {
 	constants.ROUTER.HandleFunc("/list/namecheck", middleware.DefaultMiddlewareChain(nameCheckGET)).Methods("GET")
 	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}", middleware.Chain(listHandler, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...))
 	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/edit", middleware.Chain(editListGET, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/completed-display", middleware.Chain(listCompletedDisplayPUT, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("PUT")
 	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/items", middleware.Chain(listItemsGET, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
 	constants.ROUTER.HandleFunc("/list/groupshared", middleware.DefaultMiddlewareChain(groupSharedListsGET)).Methods("GET")
 }
@@ -56,16 +58,34 @@ func listsGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user's lists
-	lists, err := database.GetLists(userId)
+	// Get user's lists, only those carrying the tag when filtering by one
+	tag := tagParam(r)
+	lists, err := repo.GetListsTagged(r.Context(), userId, tag)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 
+	// The tag index, and what carries the tag across the user's lists when filtering
+	tags, err := repo.GetUserTags(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	var taggedItems []constants.TaggedItem
+	if tag != "" {
+		taggedItems, err = repo.GetUserTaggedItems(r.Context(), userId, tag)
+		if err != nil {
+			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+			log.Print(err)
+			return
+		}
+	}
+
 	// Get user's collections
-	collections, err := database.GetCollections(userId)
+	collections, err := repo.GetCollections(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -73,7 +93,7 @@ func listsGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get group shared lists
-	groupSharedLists, err := database.GetListsSharedWithGroup(userId)
+	groupSharedLists, err := repo.GetListsSharedWithGroup(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -81,30 +101,43 @@ func listsGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if group sharing is enabled for user's group
-	groupId, err := database.GetUserGroupId(userId)
+	groupId, err := repo.GetUserGroupId(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	groupSharingEnabled, err := database.GetGroupSharingEnabled(groupId)
+	groupSharingEnabled, err := repo.GetGroupSharingEnabled(r.Context(), groupId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
-	listsPage := web.ListsPageParams(r, lists, collections, groupSharedLists, groupSharingEnabled, admin, instanceAdmin)
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	groupSharedLists = slices.DeleteFunc(groupSharedLists, func(l constants.ListSharedWithGroup) bool { return !hasTag(l.Tags, tag) })
+	listsPage := web.ListsPageParams(r, lists, collections, groupSharedLists, groupSharingEnabled, tags, tag, taggedItems, admin, instanceAdmin)
 	web.ListsPage(w, listsPage)
 }
 
 /* Create list page */
 func createListGET(w http.ResponseWriter, r *http.Request) {
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
-	params := web.CreateListParams(r, admin, instanceAdmin)
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	templates, err := userTemplates(r, userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	params := web.CreateListParams(r, templates, admin, instanceAdmin)
 	web.CreateListPage(w, params)
 }
 
@@ -116,7 +149,7 @@ func nameCheckGET(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	taken, err := database.ListNameTaken(userId, r.URL.Query().Get("name"))
+	taken, err := repo.ListNameTaken(r.Context(), userId, r.URL.Query().Get("name"))
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -139,7 +172,7 @@ func listPUT(w http.ResponseWriter, r *http.Request) {
 	}
 	var listName string = r.FormValue("name")
 	//Don't trust client input
-	taken, err := database.ListNameTaken(userId, listName)
+	taken, err := repo.ListNameTaken(r.Context(), userId, listName)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -149,7 +182,24 @@ func listPUT(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "List name already taken", http.StatusBadRequest)
 	} else {
 		var description string = r.FormValue("description")
-		id, err := database.CreateList(userId, listName, description)
+		var id int
+		if r.FormValue("templateId") == "" {
+			id, err = repo.CreateList(r.Context(), userId, listName, description)
+		} else {
+			templateId, convErr := strconv.Atoi(r.FormValue("templateId"))
+			if convErr != nil {
+				http.Error(w, "Invalid templateId supplied", http.StatusBadRequest)
+				return
+			}
+			template, ok := groupTemplate(w, r, userId, templateId)
+			if !ok {
+				return
+			}
+			if description == "" {
+				description = template.Description.String
+			}
+			id, err = repo.CreateListFromTemplate(r.Context(), userId, templateId, listName, description)
+		}
 		if err != nil {
 			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 			log.Print(err)
@@ -170,7 +220,7 @@ func editListGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
-	list, err := database.GetList(listId)
+	list, err := repo.GetList(r.Context(), listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -178,7 +228,7 @@ func editListGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user owns this list
-	isOwner, err := database.UserOwnsList(userId, listId)
+	isOwner, err := repo.UserOwnsList(r.Context(), userId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -186,21 +236,21 @@ func editListGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if group sharing is enabled for user's group
-	groupId, err := database.GetUserGroupId(userId)
+	groupId, err := repo.GetUserGroupId(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	groupSharingEnabled, err := database.GetGroupSharingEnabled(groupId)
+	groupSharingEnabled, err := repo.GetGroupSharingEnabled(r.Context(), groupId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 	editListPageParams := web.EditListParams(r, list, isOwner, groupSharingEnabled, admin, instanceAdmin)
 	web.EditListPage(w, editListPageParams)
 }
@@ -217,7 +267,7 @@ func listPOST(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
 
 	// Check if user can edit this list
-	canEdit, err := database.UserCanEditList(userId, listId)
+	canEdit, err := repo.UserCanEditList(r.Context(), userId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -236,7 +286,7 @@ func listPOST(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	err = database.UpdateList(listId, listParams)
+	err = repo.UpdateList(r.Context(), userId, listId, listParams)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -262,7 +312,7 @@ func listDELETE(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Only the owner can delete a list, even if it's shared with group edit permissions
-	owns, err := database.UserOwnsList(userId, listId)
+	owns, err := repo.UserOwnsList(r.Context(), userId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -281,7 +331,7 @@ func listDELETE(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	deleted, err := database.DeleteList(listId, confirmationName)
+	deleted, err := repo.DeleteList(r.Context(), listId, confirmationName)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -298,13 +348,20 @@ func listDELETE(w http.ResponseWriter, r *http.Request) {
 /* View list items page */
 func listGET(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
-	list, err := database.GetList(listId)
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	tag := tagParam(r)
+	items, err := repo.GetListItemsSorted(r.Context(), listId, constants.ITEM_SORT_POSITION, false, tag)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	items, err := database.GetListItems(listId)
+	sections, err := repo.GetListSections(r.Context(), listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -318,23 +375,84 @@ func listGET(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	canEdit, err := database.UserCanEditList(userId, listId)
+	canEdit, err := repo.UserCanEditList(r.Context(), userId, listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 	
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
-	listItemsPage := web.ListItemsPageParams(r, list, items, canEdit, admin, instanceAdmin)
+	// Claims stay hidden unless explicitly requested so the surprise isn't spoiled
+	showClaims := list.Claimable && r.URL.Query().Get("showClaims") == "true"
+	var claims map[uint64]constants.ItemClaim
+	var contributions map[uint64][]constants.ItemContribution
+	if showClaims {
+		claims, err = repo.GetListItemClaims(r.Context(), listId)
+		if err != nil {
+			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+			log.Print(err)
+			return
+		}
+		contributions, err = repo.GetListItemContributions(r.Context(), listId)
+		if err != nil {
+			http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+			log.Print(err)
+			return
+		}
+	}
+
+	// Lists that hide completed items still show them to editors on request, so they can be unchecked
+	showCompleted := r.URL.Query().Get("showCompleted") == "true"
+
+	totals, err := repo.GetListTotals(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	listItemsPage := web.ListItemsPageParams(r, list, items, sections, canEdit, showClaims, claims, contributions, showCompleted, totals, tag, admin, instanceAdmin)
 	web.ListItemsPage(w, listItemsPage)
 }
 
+// listCompletedDisplay is how a list shows its completed items
+type listCompletedDisplay struct {
+	CompletedDisplay string `json:"completedDisplay" openapi:"required,enum=strike|hide|archive"`
+}
+
+/* Set how completed items are shown */
+func listCompletedDisplayPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	var params listCompletedDisplay
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	if !slices.Contains(constants.COMPLETED_DISPLAYS, params.CompletedDisplay) {
+		http.Error(w, "completedDisplay must be one of strike, hide or archive", http.StatusBadRequest)
+		return
+	}
+	err := repo.SetListCompletedDisplay(r.Context(), listId, params.CompletedDisplay)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 /* List items JSON */
 func listItemsGET(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
-	items, err := database.GetListItems(listId)
+	sortBy, descending, ok := itemSortParams(w, r)
+	if !ok {
+		return
+	}
+	items, err := repo.GetListItemsSorted(r.Context(), listId, sortBy, descending, tagParam(r))
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -356,7 +474,7 @@ func groupSharedListsGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	groupSharedLists, err := database.GetListsSharedWithGroup(userId)
+	groupSharedLists, err := repo.GetListsSharedWithGroup(r.Context(), userId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -369,3 +487,25 @@ func groupSharedListsGET(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 	}
 }
+
+// requireListEdit writes a 403 and returns false unless the signed-in or token user can edit the list in the path
+func requireListEdit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return 0, false
+	}
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	canEdit, err := repo.UserCanEditList(r.Context(), userId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return 0, false
+	}
+	if !canEdit {
+		http.Error(w, "Forbidden - you don't have permission to edit this list", http.StatusForbidden)
+		return 0, false
+	}
+	return listId, true
+}
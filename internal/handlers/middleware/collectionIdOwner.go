@@ -4,7 +4,6 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 )
 
@@ -28,7 +27,7 @@ func CollectionIdOwner(pathVarName string) Middleware {
 				log.Print(err)
 				return
 			}
-			granted, err := database.UserOwnsCollection(userId, collectionId)
+			granted, err := repo.UserOwnsCollection(r.Context(), userId, collectionId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
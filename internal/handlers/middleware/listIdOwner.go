@@ -4,7 +4,6 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 )
 
@@ -30,7 +29,7 @@ func ListIdOwner(pathVarName string) Middleware {
 			}
 			
 			// Check if user owns the list OR if the list is shared with their group
-			canView, err := database.UserCanViewList(userId, listId)
+			canView, err := repo.UserCanViewList(r.Context(), userId, listId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
@@ -4,7 +4,6 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 )
 
@@ -31,7 +30,7 @@ func ListIdViewer(pathVarName string) Middleware {
 			}
 			
 			// Check if user can view the list (owns it OR it's shared with their group)
-			canView, err := database.UserCanViewList(userId, listId)
+			canView, err := repo.UserCanViewList(r.Context(), userId, listId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
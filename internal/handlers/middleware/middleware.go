@@ -2,12 +2,16 @@ package middleware
 
 import (
 	"net/http"
+
+	"github.com/jeffrpowell/listaway/internal/database"
 )
 
 type Middleware func(http.HandlerFunc) http.HandlerFunc
 
-var DefaultPublicMiddlewareSlice []Middleware = []Middleware{Cors()}
-var DefaultMiddlewareSlice []Middleware = []Middleware{RequireAuth(), Cors()}
+// ValidateRequest comes first so it runs innermost, after the caller is authenticated
+var DefaultPublicMiddlewareSlice []Middleware = []Middleware{ValidateRequest(), Cors()}
+var DefaultMiddlewareSlice []Middleware = []Middleware{ValidateRequest(), RequireAuth(), Cors()}
+var DefaultApiMiddlewareSlice []Middleware = []Middleware{ValidateRequest(), RequireApiToken(), Cors()}
 
 func Chain(f http.HandlerFunc, middlewares ...Middleware) http.HandlerFunc {
 	for _, m := range middlewares {
@@ -23,3 +27,14 @@ func DefaultMiddlewareChain(f http.HandlerFunc) http.HandlerFunc {
 func DefaultPublicMiddlewareChain(f http.HandlerFunc) http.HandlerFunc {
 	return Chain(f, DefaultPublicMiddlewareSlice...)
 }
+
+func DefaultApiMiddlewareChain(f http.HandlerFunc) http.HandlerFunc {
+	return Chain(f, DefaultApiMiddlewareSlice...)
+}
+
+// repo is the database access used by the authorization middleware, injected at startup
+var repo *database.Repository
+
+func SetRepository(r *database.Repository) {
+	repo = r
+}
@@ -4,7 +4,6 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 )
 
@@ -23,7 +22,7 @@ func RequireAdmin() Middleware {
 				log.Print(err)
 				return
 			}
-			admin, err := database.UserIsAdmin(userId)
+			admin, err := repo.UserIsAdmin(r.Context(), userId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
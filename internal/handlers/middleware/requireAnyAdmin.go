@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+)
+
+// RequireAnyAdmin lets through group admins and instance admins alike, for pages that show each
+// what concerns them
+func RequireAnyAdmin() Middleware {
+	// Create a new Middleware
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		// Define the http.HandlerFunc
+		return func(w http.ResponseWriter, r *http.Request) {
+			// Check if user is either kind of admin
+			userId, err := helper.GetUserId(r)
+			if err != nil {
+				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+				log.Print(err)
+				return
+			}
+			user, err := repo.GetUser(r.Context(), userId)
+			if err != nil {
+				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+				log.Print(err)
+				return
+			}
+			if !user.Admin && !user.InstanceAdmin {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			// Call the next middleware/handler in chain
+			f(w, r)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+)
+
+// RequireApiToken authenticates /api/v1 requests with a personal access token sent as
+// "Authorization: Bearer <token>". Read-scoped tokens may only make GET and HEAD requests.
+func RequireApiToken() Middleware {
+
+	// Create a new Middleware
+	return func(f http.HandlerFunc) http.HandlerFunc {
+
+		// Define the http.HandlerFunc
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="listaway"`)
+				http.Error(w, "Missing personal access token", http.StatusUnauthorized)
+				return
+			}
+
+			userId, scope, err := repo.AuthenticateApiToken(r.Context(), strings.TrimSpace(token))
+			if err != nil {
+				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+				log.Print(err)
+				return
+			}
+			if userId == -1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="listaway", error="invalid_token"`)
+				http.Error(w, "Invalid or revoked personal access token", http.StatusUnauthorized)
+				return
+			}
+			if scope != constants.API_SCOPE_WRITE && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				http.Error(w, "Forbidden - this token is read-only", http.StatusForbidden)
+				return
+			}
+
+			// Call the next middleware/handler in chain
+			f(w, helper.WithApiUserId(r, userId))
+		}
+	}
+}
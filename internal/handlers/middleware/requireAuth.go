@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/jeffrpowell/listaway/internal/constants"
 )
@@ -22,7 +23,7 @@ func RequireAuth() Middleware {
 				return
 			}
 			// Check if user has a valid session
-			session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+			session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
 			if auth, ok := session.Values["authenticated"].(bool); !ok || !auth {
                 w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, post-check=0, pre-check=0")
                 w.Header().Set("Pragma", "no-cache")
@@ -30,6 +31,11 @@ func RequireAuth() Middleware {
 				http.Redirect(w, r, "/auth", http.StatusSeeOther)
 				return
 			}
+			// Someone whose group requires two-factor authentication can only set it up until they have
+			if setup, ok := session.Values["twoFactorSetupRequired"].(bool); ok && setup && !strings.HasPrefix(r.URL.Path, "/account/2fa") {
+				http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+				return
+			}
 
 			// Call the next middleware/handler in chain
 			f(w, r)
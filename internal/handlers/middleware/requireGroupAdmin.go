@@ -4,7 +4,6 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 )
 
@@ -21,7 +20,7 @@ func RequireGroupAdmin(userIdPathVarName string) Middleware {
 				log.Print(err)
 				return
 			}
-			admin, err := database.UserIsAdmin(selfUserId)
+			admin, err := repo.UserIsAdmin(r.Context(), selfUserId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
@@ -38,19 +37,19 @@ func RequireGroupAdmin(userIdPathVarName string) Middleware {
 				log.Print(err)
 				return
 			}
-			selfGroupId, err := database.GetUserGroupId(selfUserId)
+			selfGroupId, err := repo.GetUserGroupId(r.Context(), selfUserId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
 				return
 			}
-			targetGroupId, err := database.GetUserGroupId(targetUserId)
+			targetGroupId, err := repo.GetUserGroupId(r.Context(), targetUserId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
 				return
 			}
-			instanceAdmin, err := database.UserIsInstanceAdmin(selfUserId)
+			instanceAdmin, err := repo.UserIsInstanceAdmin(r.Context(), selfUserId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
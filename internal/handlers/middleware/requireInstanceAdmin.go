@@ -4,7 +4,6 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 )
 
@@ -22,7 +21,7 @@ func RequireInstanceAdmin() Middleware {
 			}
 
 			// We need to add a function to check if a user is an instance admin
-			isInstanceAdmin, err := database.UserIsInstanceAdmin(userId)
+			isInstanceAdmin, err := repo.UserIsInstanceAdmin(r.Context(), userId)
 			if err != nil {
 				http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 				log.Print(err)
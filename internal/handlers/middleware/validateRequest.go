@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jeffrpowell/listaway/internal/handlers/openapi"
+)
+
+// ValidateRequest rejects requests whose query parameters or body don't match the operation
+// documented in /api/openapi.json for the route they matched. Routes without a documented
+// operation pass through untouched.
+func ValidateRequest() Middleware {
+
+	// Create a new Middleware
+	return func(f http.HandlerFunc) http.HandlerFunc {
+
+		// Define the http.HandlerFunc
+		return func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					if op, ok := openapi.Lookup(r.Method, template); ok {
+						if reqErr := op.Validate(r); reqErr != nil {
+							http.Error(w, reqErr.Message, reqErr.Status)
+							return
+						}
+					}
+				}
+			}
+
+			// Call the next middleware/handler in chain
+			f(w, r)
+		}
+	}
+}
@@ -6,8 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gorilla/sessions"
 	"github.com/jeffrpowell/listaway/internal/constants"
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
 	"github.com/jeffrpowell/listaway/internal/handlers/oidc"
 )
@@ -44,7 +44,7 @@ func oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Store state in session for verification
-	session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
 	session.Values["oidc_state"] = state
 	session.Values["oidc_timestamp"] = time.Now().Unix()
 	if err := session.Save(r, w); err != nil {
@@ -67,7 +67,7 @@ func oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify state parameter
-	session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
 	expectedState, ok := session.Values["oidc_state"].(string)
 	if !ok {
 		http.Error(w, "Invalid session state", http.StatusBadRequest)
@@ -130,7 +130,7 @@ func oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create or update user
-	userID, err := database.CreateOrUpdateOIDCUser(
+	userID, linked, err := repo.CreateOrUpdateOIDCUser(r.Context(), 
 		claims.Email,
 		claims.Name,
 		client.ProviderName,
@@ -143,17 +143,44 @@ func oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set user as authenticated in session
-	session.Values["authenticated"] = true
-	session.Values["userId"] = userID
-	if err := session.Save(r, w); err != nil {
-		log.Printf("Error saving session: %v", err)
+	log.Printf("OIDC authentication successful for user ID %d", userID)
+	if linked {
+		auditAs(r, userID, constants.AUDIT_OIDC_LINK, nil, client.ProviderName)
+	}
+	location, err := finishOIDCLogin(w, r, session, userID, "Single sign-on with "+client.ProviderName)
+	if err != nil {
+		log.Printf("Error signing in OIDC user: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	http.Redirect(w, r, location, http.StatusTemporaryRedirect)
+}
 
-	log.Printf("OIDC authentication successful for user ID %d", userID)
-	http.Redirect(w, r, "/list", http.StatusTemporaryRedirect)
+// finishOIDCLogin signs userID in after the identity provider vouched for them, unless they have
+// two-factor authentication on, in which case it only starts the second step. It returns where to
+// send them next.
+func finishOIDCLogin(w http.ResponseWriter, r *http.Request, session *sessions.Session, userID int, method string) (string, error) {
+	twoFactor, err := repo.GetTwoFactorStatus(r.Context(), userID)
+	if err != nil {
+		return "", err
+	}
+	if twoFactor.Enabled {
+		if err := startTwoFactorLogin(w, r, session, userID, method); err != nil {
+			return "", err
+		}
+		return "/auth/2fa", nil
+	}
+	auditAs(r, userID, constants.AUDIT_LOGIN, nil, method)
+
+	location := "/list"
+	if twoFactor.Required {
+		session.Values["twoFactorSetupRequired"] = true
+		location = "/account/2fa"
+	}
+	if err := signIn(w, r, session, userID); err != nil {
+		return "", err
+	}
+	return location, nil
 }
 
 // oidcLinkHandler links an OIDC account to the current user's account
@@ -165,7 +192,7 @@ func oidcLinkHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current user from session
-	session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
 	userID, ok := session.Values["userId"].(int)
 	if !ok {
 		http.Error(w, "Not authenticated", http.StatusUnauthorized)
@@ -173,7 +200,7 @@ func oidcLinkHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user already has OIDC linked
-	provider, subject, _, err := database.GetUserOIDCInfo(userID)
+	provider, subject, _, err := repo.GetUserOIDCInfo(r.Context(), userID)
 	if err != nil {
 		log.Printf("Error checking user OIDC info: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -194,7 +221,7 @@ func oidcLinkHandler(w http.ResponseWriter, r *http.Request) {
 // oidcUnlinkHandler removes OIDC authentication from the current user's account
 func oidcUnlinkHandler(w http.ResponseWriter, r *http.Request) {
 	// Get current user from session
-	session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
 	userID, ok := session.Values["userId"].(int)
 	if !ok {
 		http.Error(w, "Not authenticated", http.StatusUnauthorized)
@@ -202,12 +229,13 @@ func oidcUnlinkHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Unlink OIDC from user
-	err := database.UnlinkOIDCFromUser(userID)
+	err := repo.UnlinkOIDCFromUser(r.Context(), userID)
 	if err != nil {
 		log.Printf("Error unlinking OIDC from user: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	audit(r, constants.AUDIT_OIDC_UNLINK, nil, "")
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OIDC account unlinked successfully"))
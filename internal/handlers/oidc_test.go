@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/sessions"
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/database"
+)
+
+// useMockRepo points the handlers at a mock database and a cookie session store
+func useMockRepo(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousRepo, previousStore := repo, constants.SESSION_STORE
+	repo = database.NewRepository(db)
+	constants.SESSION_STORE = sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))
+	t.Cleanup(func() {
+		repo, constants.SESSION_STORE = previousRepo, previousStore
+		db.Close()
+	})
+	return mock
+}
+
+func expectTwoFactorStatus(mock sqlmock.Sqlmock, userId int, enabled bool, required bool) {
+	enabledAt := sql.NullTime{Time: time.Now(), Valid: enabled}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT tf.enabled_at")).WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{"enabled_at", "count", "two_factor_required"}).AddRow(enabledAt, 10, required))
+}
+
+func TestFinishOIDCLogin(t *testing.T) {
+	tests := []struct {
+		name              string
+		enabled, required bool
+		wantLocation      string
+		wantSignedIn      bool
+	}{
+		{"without two-factor", false, false, "/list", true},
+		{"with two-factor on", true, false, "/auth/2fa", false},
+		{"with two-factor on and required", true, true, "/auth/2fa", false},
+		{"required but not set up", false, true, "/account/2fa", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := useMockRepo(t)
+			expectTwoFactorStatus(mock, 7, tt.enabled, tt.required)
+			if tt.wantSignedIn {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT id, groupid, email, name, admin, instanceadmin FROM " + constants.DB_TABLE_USER)).WithArgs(7).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "groupid", "email", "name", "admin", "instanceadmin"}).AddRow(7, 1, "sam@example.com", "Sam", false, false))
+				mock.ExpectExec("INSERT INTO " + regexp.QuoteMeta(constants.DB_TABLE_AUDIT_LOG)).WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+
+			r := httptest.NewRequest("GET", "/auth/oidc/callback", nil)
+			w := httptest.NewRecorder()
+			session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+			// Someone else signed in earlier on this browser
+			session.Values["authenticated"] = true
+			session.Values["userId"] = 3
+
+			location, err := finishOIDCLogin(w, r, session, 7, "Single sign-on with Example")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+			if location != tt.wantLocation {
+				t.Errorf("sent to %s, want %s", location, tt.wantLocation)
+			}
+
+			authenticated, _ := session.Values["authenticated"].(bool)
+			userId, hasUser := session.Values["userId"].(int)
+			if tt.wantSignedIn {
+				if !authenticated || userId != 7 {
+					t.Errorf("not signed in as user 7: %v", session.Values)
+				}
+			} else {
+				if authenticated || hasUser {
+					t.Errorf("signed in before the second step: %v", session.Values)
+				}
+				if pending, ok := pendingTwoFactorLogin(session); !ok || pending != 7 {
+					t.Errorf("no two-factor step pending for user 7: %v", session.Values)
+				}
+			}
+			_, setupRequired := session.Values["twoFactorSetupRequired"]
+			if setupRequired != (tt.required && !tt.enabled) {
+				t.Errorf("twoFactorSetupRequired = %v", setupRequired)
+			}
+		})
+	}
+}
@@ -0,0 +1,591 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/database"
+	"github.com/jeffrpowell/listaway/internal/handlers/exporter"
+	"github.com/jeffrpowell/listaway/internal/handlers/importer"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/internal/handlers/openapi"
+)
+
+// /api/openapi.json is generated by walking constants.ROUTER, so every registered route appears in it.
+// The operations below add summaries and schemas; middleware.ValidateRequest enforces the same schemas,
+// so a route's entry here is its contract. Add an entry alongside any new route.
+
+func init() {
+	constants.ROUTER.HandleFunc("/api/openapi.json", middleware.DefaultPublicMiddlewareChain(openapiGET)).Methods("GET")
+	openapi.Register(webOperations()...)
+	openapi.Register(apiV1Operations()...)
+}
+
+// The router is complete once every package init has run, so the document only needs building once
+var openapiDocument = sync.OnceValues(func() ([]byte, error) {
+	var servers []openapi.Server
+	if constants.APP_URL != "" {
+		servers = append(servers, openapi.Server{URL: constants.APP_URL})
+	}
+	doc, err := openapi.Build(constants.ROUTER, openapi.Info{
+		Title:       "Listaway",
+		Description: "Routes under " + constants.API_V1_PATH + " authenticate with a personal access token; the rest serve the web app and use the browser session.",
+		Version:     "1",
+	}, servers, constants.COOKIE_NAME_SESSION)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+})
+
+/* OpenAPI document describing every route */
+func openapiGET(w http.ResponseWriter, r *http.Request) {
+	doc, err := openapiDocument()
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
+
+var (
+	listFormSchema = openapi.Object(map[string]*openapi.Schema{
+		"name":        openapi.String().NonEmpty(),
+		"description": openapi.String(),
+		"templateId":  openapi.Integer().Describe("One of the group's templates to take the items from, and the description when none is given; leave blank for an empty list"),
+	}).Require("name")
+	itemFormSchema = openapi.Object(map[string]*openapi.Schema{
+		"name":      openapi.String().NonEmpty(),
+		"url":       openapi.String().Describe("Link to where the item can be found"),
+		"priority":  openapi.Integer().Describe("Leave blank for no priority"),
+		"notes":     openapi.String(),
+		"section":   openapi.Integer().Describe("Id of one of the list's sections; leave blank for none"),
+		"quantity":  openapi.Integer().Describe("How many are wanted; leave blank for 1"),
+		"fulfilled": openapi.Integer().Describe("How many of those the list already has; leave blank for none"),
+		"price":     openapi.String().Describe("Decimal price of one, e.g. 12.50; leave blank for no price"),
+		"currency":  openapi.String().Describe("ISO 4217 code of the price, e.g. USD; required with a price"),
+		"tags":      openapi.String().Describe("Comma-separated tags, e.g. kitchen, gifts; leave blank for none"),
+	}).Require("name")
+	importFormSchema = openapi.Object(map[string]*openapi.Schema{
+		"format":         {Type: "string", Enum: []string{importer.FormatCSV, importer.FormatJSON, importer.FormatURLs}},
+		"content":        openapi.String().NonEmpty().Describe("CSV with a header row, a JSON array of items (or a list export), or one URL per line"),
+		"nameColumn":     openapi.String().Describe("CSV header holding the item name; guessed from common header names when no column is given"),
+		"urlColumn":      openapi.String(),
+		"priorityColumn": openapi.String(),
+		"notesColumn":    openapi.String(),
+	}).Require("format", "content")
+	userFormSchema = map[string]*openapi.Schema{
+		"email":    openapi.String().NonEmpty(),
+		"name":     openapi.String().NonEmpty(),
+		"password": openapi.String().NonEmpty(),
+	}
+
+	htmlPage      = map[int]openapi.Response{http.StatusOK: {Description: "HTML page", ContentType: openapi.ContentTypeHTML}}
+	noContent     = map[int]openapi.Response{http.StatusNoContent: {Description: "Done"}}
+	shareCodeText = map[int]openapi.Response{http.StatusOK: {Description: "The share code", ContentType: openapi.ContentTypeText, Schema: openapi.String()}}
+	toggledText   = map[int]openapi.Response{http.StatusOK: {Description: "The new value, true or false", ContentType: openapi.ContentTypeText, Schema: openapi.String().Describe("true or false")}}
+	itemsJSON     = map[int]openapi.Response{http.StatusOK: {Description: "Items in the list", ContentType: openapi.ContentTypeJSON, Schema: openapi.ArrayOf(openapi.SchemaOf(constants.Item{}))}}
+
+	itemOrderBody = openapi.JSONBody(openapi.SchemaOf(itemOrder{}).Describe("Item ids in their new order. Listing only some items moves them among the places they already hold."))
+	itemOrderJSON = jsonResponse(http.StatusOK, "Every item id in the list's new order", itemOrder{})
+
+	sectionBody    = openapi.JSONBody(openapi.SchemaOf(sectionParams{}))
+	sectionJSON    = jsonResponse(http.StatusOK, "Section", constants.ItemSection{})
+	sectionsJSON   = jsonResponse(http.StatusOK, "Sections in display order", []constants.ItemSection{})
+	sectionCreated = jsonResponse(http.StatusCreated, "Created section, placed after the existing ones", constants.ItemSection{})
+	sectionDeleted = map[int]openapi.Response{http.StatusNoContent: {Description: "Deleted; its items stay in the list outside any section"}}
+
+	completedDisplayBody = openapi.JSONBody(openapi.SchemaOf(listCompletedDisplay{}).Describe("strike keeps completed items in place, struck through; " +
+		"archive moves them under a Completed heading at the end; hide leaves them out of share links and shows them to editors on request"))
+
+	exportQuery = []openapi.Parameter{openapi.QueryParam("format", true, &openapi.Schema{Type: "string", Enum: exporter.Formats,
+		Description: "csv, json and md are downloads; html is a printable page"})}
+	exportFile = map[int]openapi.Response{http.StatusOK: {Description: "The export, served with the content type of the chosen format"}}
+
+	auditQuery = []openapi.Parameter{
+		openapi.QueryParam("action", false, &openapi.Schema{Type: "string", Enum: auditActionNames()}),
+		openapi.QueryParam("user", false, openapi.String().Describe("Part of the actor's or target's name or email")),
+		openapi.QueryParam("from", false, openapi.String().Describe("First day to include, as YYYY-MM-DD")),
+		openapi.QueryParam("until", false, openapi.String().Describe("Last day to include, as YYYY-MM-DD")),
+		openapi.QueryParam("group", false, openapi.Integer().Describe("Instance admins only; group admins always see their own group")),
+	}
+	auditCSV = map[int]openapi.Response{http.StatusOK: {Description: "Every matching event as a CSV download", ContentType: "text/csv"},
+		http.StatusBadRequest: {Description: "A filter is malformed"}}
+
+	itemSortQuery = []openapi.Parameter{
+		openapi.QueryParam("sort", false, &openapi.Schema{Type: "string", Enum: constants.ITEM_SORTS,
+			Description: "position is the list's own order; price groups items by currency and puts unpriced items last"}),
+		openapi.QueryParam("order", false, &openapi.Schema{Type: "string", Enum: []string{"asc", "desc"}}),
+	}
+	tagQuery    = openapi.QueryParam("tag", false, openapi.String().Describe("Only include those carrying this tag"))
+	searchQuery = openapi.String().Describe("Web search syntax: quoted phrases, or, and -word to exclude")
+	tagsBody    = openapi.JSONBody(openapi.SchemaOf(listTags{}).Describe(fmt.Sprintf("Every tag the list should have, at most %d of up to %d characters each; they are lowercased and repeats dropped", constants.MaxTags, constants.MaxTagLength)))
+	tagsJSON    = jsonResponse(http.StatusOK, "Every tag on the user's lists and their items, alphabetically, with how many of each carry it", []constants.Tag{})
+
+	budgetBody = openapi.JSONBody(openapi.SchemaOf(listBudget{}).Describe("A decimal amount, e.g. 250.00, and its ISO 4217 currency code"))
+	totalsJSON = jsonResponse(http.StatusOK, "One total per currency; stillNeeded leaves out completed items and what has been fulfilled or contributed", apiTotals{})
+
+	attachmentBody = openapi.MultipartBody(openapi.Object(map[string]*openapi.Schema{
+		attachmentField: openapi.ArrayOf(openapi.Binary()).Describe(fmt.Sprintf("JPEG, PNG or GIF images of up to %d MB each; an item holds at most %d", maxAttachmentBytes>>20, database.MaxAttachmentsPerItem)),
+	}).Require(attachmentField), maxAttachmentUploadBytes)
+	attachmentsCreated = jsonResponse(http.StatusCreated, "The uploaded images", []apiAttachment{})
+	attachmentDeleted  = map[int]openapi.Response{http.StatusNoContent: {Description: "Removed, along with its files"}, http.StatusNotFound: {Description: "Image not found"}}
+
+	trashRestored = map[int]openapi.Response{http.StatusNoContent: {Description: "Restored"}, http.StatusNotFound: {Description: "Not found in trash"},
+		http.StatusConflict: {Description: "A list or collection with the same name exists"}}
+	trashPurged  = map[int]openapi.Response{http.StatusNoContent: {Description: "Deleted for good"}, http.StatusNotFound: {Description: "Not found in trash"}}
+	trashEmptied = map[int]openapi.Response{http.StatusNoContent: {Description: "Everything in the trash deleted for good"}}
+
+	historyJSON      = jsonResponse(http.StatusOK, "Newest first, up to the most recent 200 changes", []apiRevision{})
+	revisionReverted = map[int]openapi.Response{http.StatusNoContent: {Description: "Reverted, recorded as a new change"}, http.StatusNotFound: {Description: "Revision not found"},
+		http.StatusConflict: {Description: "The item has changed since in a way that rules out reverting"}}
+
+	templateForm = openapi.FormBody(openapi.Object(map[string]*openapi.Schema{
+		"listId": openapi.Integer().Describe("One of the admin's own lists, whose description and items the template copies"),
+		"name":   openapi.String().NonEmpty(),
+	}).Require("listId", "name"))
+	templateCreated = map[int]openapi.Response{http.StatusNoContent: {Description: "Created"}, http.StatusForbidden: {Description: "Not one of the admin's lists"},
+		http.StatusConflict: {Description: "The group already has a template with that name"}}
+	templateDeleted = map[int]openapi.Response{http.StatusNoContent: {Description: "Deleted; lists made from it are unaffected"}, http.StatusNotFound: {Description: "Template not found"}}
+	templatesJSON   = jsonResponse(http.StatusOK, "Templates of the user's group, alphabetically", []apiTemplate{})
+
+	listCopyBody = openapi.JSONBody(openapi.SchemaOf(listCopyParams{}).Describe("A blank name gives the original's name followed by (copy); " +
+		"copySharing keeps the group sharing and gift claiming settings"))
+	sharedListCopyBody = openapi.JSONBody(openapi.SchemaOf(sharedListCopyParams{}).Describe("A blank name gives the original's name followed by (copy)"))
+
+	twoFactorCodeForm = openapi.FormBody(openapi.Object(map[string]*openapi.Schema{
+		"code": openapi.String().NonEmpty().Describe("6-digit code from the authenticator app, or a recovery code where the operation accepts one"),
+	}).Require("code"))
+	recoveryCodesText = map[int]openapi.Response{
+		http.StatusOK:         {Description: "The new recovery codes, one per line, shown only this once", ContentType: openapi.ContentTypeText, Schema: openapi.String()},
+		http.StatusBadRequest: {Description: "Code is not recognized"},
+	}
+
+	passkeyCredentialBody = openapi.JSONBody(openapi.Object(map[string]*openapi.Schema{
+		"id":       openapi.String().NonEmpty(),
+		"rawId":    openapi.String().NonEmpty().Describe("The credential id, base64url-encoded"),
+		"type":     {Type: "string", Enum: []string{"public-key"}},
+		"response": openapi.Object(map[string]*openapi.Schema{}).Describe("The authenticator's response, with its binary fields base64url-encoded"),
+	}).Require("id", "rawId", "type", "response").Describe("The PublicKeyCredential the browser returned, as JSON"))
+	passkeyNameForm = openapi.FormBody(openapi.Object(map[string]*openapi.Schema{
+		"name": openapi.String().NonEmpty().Describe(fmt.Sprintf("Up to %d characters", constants.PASSKEY_MAX_NAME_LENGTH)),
+	}).Require("name"))
+)
+
+func auditActionNames() []string {
+	names := make([]string, 0, len(constants.AuditActions))
+	for _, a := range constants.AuditActions {
+		names = append(names, a.Action)
+	}
+	return names
+}
+
+// listCopied is a fresh map for each operation, since withConflict adds to the one it is given
+func listCopied() map[int]openapi.Response {
+	return withConflict(jsonResponse(http.StatusCreated, "The new list; Location points at it", apiList{}))
+}
+
+// passkeyOptions is a fresh map for each operation, since withPasskeyLimit adds to the one it is given
+func passkeyOptions(description string) map[int]openapi.Response {
+	return map[int]openapi.Response{
+		http.StatusOK: {Description: description, ContentType: openapi.ContentTypeJSON,
+			Schema: openapi.Object(map[string]*openapi.Schema{"publicKey": openapi.Object(map[string]*openapi.Schema{})}).Describe("WebAuthn options with binary fields base64url-encoded")},
+		http.StatusNotFound: {Description: "Passkeys are not available: APP_URL doesn't name the site"},
+	}
+}
+
+func jsonResponse(status int, description string, v any) map[int]openapi.Response {
+	return map[int]openapi.Response{status: {Description: description, ContentType: openapi.ContentTypeJSON, Schema: openapi.SchemaOf(v)}}
+}
+
+func withConflict(responses map[int]openapi.Response) map[int]openapi.Response {
+	responses[http.StatusConflict] = openapi.Response{Description: "Name already taken"}
+	return responses
+}
+
+func withPasskeyLimit(responses map[int]openapi.Response) map[int]openapi.Response {
+	responses[http.StatusConflict] = openapi.Response{Description: fmt.Sprintf("The user already has %d passkeys", constants.PASSKEY_MAX_PER_USER)}
+	return responses
+}
+
+// webOperations describes the routes used by the web app, authenticated with the session cookie
+func webOperations() []openapi.Operation {
+	sharedList := "/" + constants.SHARED_LIST_PATH + "/{shareCode}"
+	nestedSharedList := "/" + constants.SHARED_COLLECTION_PATH + "/{collectionShareCode}/" + constants.SHARED_LIST_PATH + "/{listShareCode}"
+
+	return []openapi.Operation{
+		// auth.go
+		{Method: "GET", Path: "/", Summary: "Redirect to the list overview", Tag: "auth",
+			Responses: map[int]openapi.Response{http.StatusPermanentRedirect: {Description: "Redirect to /list"}}},
+		{Method: "GET", Path: "/auth", Summary: "Login page", Tag: "auth", Auth: openapi.AuthPublic, Responses: htmlPage},
+		{Method: "POST", Path: "/auth", Summary: "Log in", Tag: "auth", Auth: openapi.AuthPublic,
+			Body: openapi.FormBody(openapi.Object(map[string]*openapi.Schema{
+				"email":    openapi.String().NonEmpty(),
+				"password": openapi.String().NonEmpty(),
+			}).Require("email", "password")),
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "Password accepted; Location points at the list overview, the two-factor code page, or two-factor setup when the group requires it"}, http.StatusUnauthorized: {Description: "Invalid credentials"}}},
+		{Method: "DELETE", Path: "/auth", Summary: "Log out", Tag: "auth", Auth: openapi.AuthPublic, Responses: noContent},
+		{Method: "POST", Path: "/reset", Summary: "Email a password reset link", Tag: "auth", Auth: openapi.AuthPublic,
+			Body: openapi.FormBody(openapi.Object(map[string]*openapi.Schema{"email": openapi.String().NonEmpty()}).Require("email"))},
+		{Method: "GET", Path: "/reset/{token}", Summary: "Password reset page", Tag: "auth", Auth: openapi.AuthPublic, Responses: htmlPage},
+		{Method: "POST", Path: "/reset/{token}", Summary: "Set a new password", Tag: "auth", Auth: openapi.AuthPublic,
+			Body: openapi.FormBody(openapi.Object(map[string]*openapi.Schema{"password": openapi.String().NonEmpty()}).Require("password"))},
+
+		// oidc.go, only registered when OIDC is enabled
+		{Method: "GET", Path: "/auth/oidc/login", Summary: "Start OIDC login", Tag: "auth", Auth: openapi.AuthPublic,
+			Responses: map[int]openapi.Response{http.StatusTemporaryRedirect: {Description: "Redirect to the identity provider"}}},
+		{Method: "GET", Path: "/auth/oidc/callback", Summary: "OIDC login callback", Tag: "auth", Auth: openapi.AuthPublic,
+			Query: []openapi.Parameter{
+				openapi.QueryParam("state", false, openapi.String()),
+				openapi.QueryParam("code", false, openapi.String()),
+				openapi.QueryParam("error", false, openapi.String()),
+			},
+			Responses: map[int]openapi.Response{http.StatusTemporaryRedirect: {Description: "Redirect to the list overview"}}},
+		{Method: "POST", Path: "/auth/oidc/link", Summary: "Link an OIDC account", Tag: "auth"},
+		{Method: "POST", Path: "/auth/oidc/unlink", Summary: "Unlink the OIDC account", Tag: "auth"},
+		{Method: "GET", Path: "/api/oidc/status", Summary: "Whether OIDC login is enabled", Tag: "auth", Auth: openapi.AuthPublic,
+			Responses: jsonResponse(http.StatusOK, "OIDC status", struct {
+				Enabled bool `json:"enabled"`
+			}{})},
+
+		// admin.go, instanceAdmin.go
+		{Method: "GET", Path: "/admin/register", Summary: "First admin registration page", Tag: "admin", Auth: openapi.AuthPublic, Responses: htmlPage},
+		{Method: "PUT", Path: "/admin/register", Summary: "Register the first admin", Tag: "admin", Auth: openapi.AuthPublic,
+			Body: openapi.FormBody(openapi.Object(userFormSchema).Require("email", "name", "password"))},
+		{Method: "GET", Path: "/admin/users", Summary: "Group users page", Tag: "admin", Responses: htmlPage},
+		{Method: "GET", Path: "/admin/users/create", Summary: "Create user page", Tag: "admin", Responses: htmlPage},
+		{Method: "PUT", Path: "/admin/users/create", Summary: "Create user", Tag: "admin",
+			Body: openapi.FormBody(openapi.Object(map[string]*openapi.Schema{
+				"email":              userFormSchema["email"],
+				"name":               userFormSchema["name"],
+				"password":           userFormSchema["password"],
+				"admin":              {Type: "string", Enum: []string{"on"}, Description: "Checkbox; present to make the user a group admin"},
+				"userCreationType":   {Type: "string", Enum: []string{"", "newGroup", "existingGroup"}, Description: "Instance admins only"},
+				"existingGroupAdmin": openapi.Integer().Describe("Instance admins only: a user in the group to join"),
+			}).Require("email", "name", "password"))},
+		{Method: "GET", Path: "/admin/user/{userId}/listscount", Summary: "Number of lists a user owns", Tag: "admin",
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "The count", ContentType: openapi.ContentTypeText, Schema: openapi.Integer()}}},
+		{Method: "DELETE", Path: "/admin/user/{userId}", Summary: "Delete user", Tag: "admin", Responses: noContent},
+		{Method: "POST", Path: "/admin/user/{userId}/toggleadmin", Summary: "Toggle group admin", Tag: "admin", Responses: toggledText},
+		{Method: "GET", Path: "/admin/groupsharing", Summary: "Whether group sharing is enabled", Tag: "admin", Responses: toggledText},
+		{Method: "POST", Path: "/admin/groupsharing", Summary: "Toggle group sharing", Tag: "admin", Responses: toggledText},
+		{Method: "GET", Path: "/admin/allusers", Summary: "All users page", Tag: "admin", Responses: htmlPage},
+		{Method: "POST", Path: "/admin/user/{userId}/toggleinstanceadmin", Summary: "Toggle instance admin", Tag: "admin", Responses: toggledText},
+
+		// template.go
+		{Method: "GET", Path: "/admin/templates", Summary: "List templates page", Tag: "admin", Responses: htmlPage},
+		{Method: "PUT", Path: "/admin/templates", Summary: "Create a list template from one of the admin's lists", Tag: "admin", Body: templateForm, Responses: templateCreated},
+		{Method: "DELETE", Path: "/admin/templates/{templateId}", Summary: "Delete list template", Tag: "admin", Responses: templateDeleted},
+
+		// audit.go
+		{Method: "GET", Path: "/admin/audit", Summary: "Audit log page", Tag: "admin", Query: auditQuery, Responses: htmlPage},
+		{Method: "GET", Path: "/admin/audit/export", Summary: "Export the audit log", Tag: "admin", Query: auditQuery, Responses: auditCSV},
+
+		// list.go
+		{Method: "GET", Path: "/list", Summary: "List overview page", Tag: "lists", Query: []openapi.Parameter{tagQuery}, Responses: htmlPage},
+		{Method: "PUT", Path: "/list", Summary: "Create list", Tag: "lists", Body: openapi.FormBody(listFormSchema),
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "Created; Location points at the new list"}, http.StatusBadRequest: {Description: "List name already taken, or the template is not one of the group's"}}},
+		{Method: "GET", Path: "/list/create", Summary: "Create list page", Tag: "lists", Responses: htmlPage},
+		{Method: "GET", Path: "/list/namecheck", Summary: "Check whether a list name is free", Tag: "lists",
+			Query:     []openapi.Parameter{openapi.QueryParam("name", false, openapi.String())},
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "Name is free"}, http.StatusBadRequest: {Description: "Name is taken"}}},
+		{Method: "GET", Path: "/list/groupshared", Summary: "Lists shared with the user's group", Tag: "lists",
+			Responses: jsonResponse(http.StatusOK, "Shared lists", []constants.ListSharedWithGroup{})},
+		{Method: "GET", Path: "/list/{listId}", Summary: "List items page", Tag: "lists",
+			Query: []openapi.Parameter{openapi.QueryParam("showCompleted", false, openapi.Boolean().Describe("Show completed items on a list that hides them")), tagQuery}, Responses: htmlPage},
+		{Method: "POST", Path: "/list/{listId}", Summary: "Update list", Tag: "lists", Body: openapi.JSONBody(openapi.SchemaOf(constants.ListPostParams{}))},
+		{Method: "DELETE", Path: "/list/{listId}", Summary: "Move list to the trash", Tag: "lists",
+			Body:      openapi.JSONBody(openapi.String().NonEmpty().Describe("The list's name, as confirmation")),
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "Moved to the trash; Location points at the list overview"}, http.StatusBadRequest: {Description: "Confirmation name did not match"}}},
+		{Method: "GET", Path: "/list/{listId}/edit", Summary: "Edit list page", Tag: "lists", Responses: htmlPage},
+		{Method: "GET", Path: "/list/{listId}/items", Summary: "Items in a list", Tag: "lists", Query: append(itemSortQuery, tagQuery), Responses: itemsJSON},
+		{Method: "PUT", Path: "/list/{listId}/completed-display", Summary: "Set how completed items are shown", Tag: "lists", Body: completedDisplayBody, Responses: noContent},
+
+		// item.go
+		{Method: "PUT", Path: "/list/{listId}/item", Summary: "Create item", Tag: "items", Body: openapi.FormBody(itemFormSchema), Responses: noContent},
+		{Method: "GET", Path: "/list/{listId}/item/create", Summary: "Create item page", Tag: "items",
+			Query: []openapi.Parameter{openapi.QueryParam("section", false, openapi.Integer().Describe("Section to start the item in"))}, Responses: htmlPage},
+		{Method: "POST", Path: "/list/{listId}/item/{itemId}", Summary: "Update item", Tag: "items", Body: openapi.FormBody(itemFormSchema), Responses: noContent},
+		{Method: "DELETE", Path: "/list/{listId}/item/{itemId}", Summary: "Move item to the trash", Tag: "items", Responses: noContent},
+		{Method: "GET", Path: "/list/{listId}/item/{itemId}/edit", Summary: "Edit item page", Tag: "items", Responses: htmlPage},
+		{Method: "PUT", Path: "/list/{listId}/item/{itemId}/complete", Summary: "Check an item off", Tag: "items", Responses: noContent},
+		{Method: "DELETE", Path: "/list/{listId}/item/{itemId}/complete", Summary: "Clear an item's completion", Tag: "items", Responses: noContent},
+		{Method: "PUT", Path: "/list/{listId}/items/order", Summary: "Reorder items", Tag: "items", Body: itemOrderBody, Responses: itemOrderJSON},
+
+		// attachment.go
+		{Method: "PUT", Path: "/list/{listId}/item/{itemId}/attachment", Summary: "Upload images to an item", Tag: "items", Body: attachmentBody, Responses: attachmentsCreated},
+		{Method: "DELETE", Path: "/list/{listId}/item/{itemId}/attachment/{attachmentId}", Summary: "Remove an image from an item", Tag: "items", Responses: attachmentDeleted},
+
+		// section.go
+		{Method: "GET", Path: "/list/{listId}/section", Summary: "Sections in a list", Tag: "items", Responses: sectionsJSON},
+		{Method: "PUT", Path: "/list/{listId}/section", Summary: "Create section", Tag: "items", Body: sectionBody, Responses: sectionCreated},
+		{Method: "POST", Path: "/list/{listId}/section/{sectionId}", Summary: "Rename section", Tag: "items", Body: sectionBody, Responses: sectionJSON},
+		{Method: "DELETE", Path: "/list/{listId}/section/{sectionId}", Summary: "Delete section", Tag: "items", Responses: sectionDeleted},
+		{Method: "PUT", Path: "/list/{listId}/item/{itemId}/section", Summary: "Move an item into or out of a section", Tag: "items",
+			Body: openapi.JSONBody(openapi.SchemaOf(itemSection{})), Responses: noContent},
+
+		// import.go
+		{Method: "GET", Path: "/list/{listId}/import", Summary: "Import items page", Tag: "items", Responses: htmlPage},
+		{Method: "POST", Path: "/list/{listId}/import/preview", Summary: "Preview an import without changing the list", Tag: "items",
+			Body:      openapi.FormBody(importFormSchema),
+			Responses: jsonResponse(http.StatusOK, "Parsed rows, marking errors and items already in the list", importPreview{})},
+		{Method: "PUT", Path: "/list/{listId}/import", Summary: "Import items, skipping errors and duplicates", Tag: "items",
+			Body:      openapi.FormBody(importFormSchema),
+			Responses: jsonResponse(http.StatusOK, "How many items were imported; Location points at the list", importSummary{})},
+
+		// export.go
+		{Method: "GET", Path: "/list/{listId}/export", Summary: "Export a list", Tag: "lists", Query: exportQuery, Responses: exportFile},
+		{Method: "GET", Path: "/collections/{collectionId}/export", Summary: "Export the lists in a collection", Tag: "collections", Query: exportQuery, Responses: exportFile},
+		{Method: "GET", Path: sharedList + "/export", Summary: "Export a shared list", Tag: "sharing", Auth: openapi.AuthPublic, Query: exportQuery, Responses: exportFile},
+		{Method: "GET", Path: "/" + constants.SHARED_COLLECTION_PATH + "/{shareCode}/export", Summary: "Export the shared lists in a shared collection", Tag: "sharing", Auth: openapi.AuthPublic, Query: exportQuery, Responses: exportFile},
+
+		// claim.go
+		{Method: "PUT", Path: "/list/{listId}/claimable", Summary: "Let share link visitors claim items", Tag: "claims", Responses: noContent},
+		{Method: "DELETE", Path: "/list/{listId}/claimable", Summary: "Stop share link visitors claiming items", Tag: "claims", Responses: noContent},
+		{Method: "DELETE", Path: "/list/{listId}/item/{itemId}/claim", Summary: "Clear a claim on an item", Tag: "claims", Responses: noContent},
+		{Method: "PUT", Path: sharedList + "/item/{itemId}/claim", Summary: "Claim an item", Tag: "claims", Auth: openapi.AuthPublic,
+			Body: openapi.FormBody(openapi.Object(map[string]*openapi.Schema{
+				"name":  openapi.String(),
+				"email": openapi.String(),
+			})),
+			Responses: map[int]openapi.Response{
+				http.StatusOK:       {Description: "Claimed; the body is the token needed to release the claim", ContentType: openapi.ContentTypeText, Schema: openapi.String()},
+				http.StatusConflict: {Description: "Item has already been claimed"},
+			}},
+		{Method: "DELETE", Path: sharedList + "/item/{itemId}/claim", Summary: "Release your claim on an item", Tag: "claims", Auth: openapi.AuthPublic,
+			Query:     []openapi.Parameter{openapi.QueryParam("token", true, openapi.String().Describe("Token returned when the item was claimed"))},
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Description: "Released"}, http.StatusForbidden: {Description: "Claim token did not match"}}},
+
+		// contribution.go
+		{Method: "DELETE", Path: "/list/{listId}/item/{itemId}/contribution/{contributionId}", Summary: "Remove a contribution towards an item", Tag: "claims", Responses: noContent},
+		{Method: "PUT", Path: sharedList + "/item/{itemId}/contribution", Summary: "Contribute towards an item wanted more than once", Tag: "claims", Auth: openapi.AuthPublic,
+			Body: openapi.FormBody(openapi.Object(map[string]*openapi.Schema{
+				"name":     openapi.String(),
+				"quantity": openapi.Integer().Describe("How many the visitor will bring; at most the number still needed"),
+			}).Require("quantity")),
+			Responses: map[int]openapi.Response{
+				http.StatusOK:       {Description: "Contributed; the body is the token needed to withdraw the contribution", ContentType: openapi.ContentTypeText, Schema: openapi.String()},
+				http.StatusConflict: {Description: "Fewer than that are still needed"},
+			}},
+		{Method: "DELETE", Path: sharedList + "/item/{itemId}/contribution", Summary: "Withdraw your contribution towards an item", Tag: "claims", Auth: openapi.AuthPublic,
+			Query:     []openapi.Parameter{openapi.QueryParam("token", true, openapi.String().Describe("Token returned when the contribution was made"))},
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Description: "Withdrawn"}, http.StatusForbidden: {Description: "Contribution token did not match"}}},
+
+		// price.go
+		{Method: "PUT", Path: "/list/{listId}/budget", Summary: "Set a list's budget", Tag: "lists", Body: budgetBody, Responses: noContent},
+		{Method: "DELETE", Path: "/list/{listId}/budget", Summary: "Clear a list's budget", Tag: "lists", Responses: noContent},
+
+		// tag.go
+		{Method: "GET", Path: "/tags", Summary: "Tag index of the user's lists and items", Tag: "lists", Responses: tagsJSON},
+		{Method: "PUT", Path: "/list/{listId}/tags", Summary: "Replace a list's tags", Tag: "lists", Body: tagsBody, Responses: noContent},
+
+		// search.go
+		{Method: "GET", Path: "/search", Summary: "Search page", Tag: "lists", Query: []openapi.Parameter{openapi.QueryParam("q", false, searchQuery)}, Responses: htmlPage},
+
+		// trash.go
+		{Method: "GET", Path: "/trash", Summary: "Trash page", Tag: "trash", Responses: htmlPage},
+		{Method: "DELETE", Path: "/trash", Summary: "Empty trash", Tag: "trash", Responses: trashEmptied},
+		{Method: "POST", Path: "/trash/{kind}/{id}/restore", Summary: "Restore a list, item or collection from the trash", Tag: "trash", Responses: trashRestored},
+		{Method: "DELETE", Path: "/trash/{kind}/{id}", Summary: "Delete a list, item or collection in the trash for good", Tag: "trash", Responses: trashPurged},
+
+		// copy.go
+		{Method: "POST", Path: "/list/{listId}/copy", Summary: "Copy a list, owner only", Tag: "lists", Body: listCopyBody, Responses: listCopied()},
+		{Method: "POST", Path: sharedList + "/copy", Summary: "Save a copy of a shared list to the signed-in user's account", Tag: "sharing", Body: sharedListCopyBody, Responses: listCopied()},
+
+		// revision.go
+		{Method: "GET", Path: "/list/{listId}/history", Summary: "List history page", Tag: "lists", Responses: htmlPage},
+		{Method: "POST", Path: "/list/{listId}/history/{revisionId}/revert", Summary: "Revert a change to a list or item", Tag: "lists", Responses: revisionReverted},
+
+		// share.go
+		{Method: "PUT", Path: "/list/{listId}/share", Summary: "Create share link", Tag: "sharing", Responses: shareCodeText},
+		{Method: "DELETE", Path: "/list/{listId}/share", Summary: "Unpublish share link", Tag: "sharing", Responses: noContent},
+		{Method: "GET", Path: sharedList, Summary: "Shared list page", Tag: "sharing", Auth: openapi.AuthPublic, Responses: htmlPage},
+		{Method: "GET", Path: sharedList + "/items", Summary: "Items in a shared list", Tag: "sharing", Auth: openapi.AuthPublic, Responses: itemsJSON},
+		{Method: "GET", Path: nestedSharedList, Summary: "Shared list page within a shared collection", Tag: "sharing", Auth: openapi.AuthPublic, Responses: htmlPage},
+		{Method: "GET", Path: nestedSharedList + "/items", Summary: "Items in a shared list within a shared collection", Tag: "sharing", Auth: openapi.AuthPublic, Responses: itemsJSON},
+
+		// collection.go
+		{Method: "POST", Path: "/collections", Summary: "Create collection", Tag: "collections", Body: openapi.FormBody(listFormSchema),
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "Created; Location points at the new collection"}, http.StatusBadRequest: {Description: "Collection name already taken"}}},
+		{Method: "GET", Path: "/collections/create", Summary: "Create collection page", Tag: "collections", Responses: htmlPage},
+		{Method: "GET", Path: "/collections/namecheck", Summary: "Check whether a collection name is free", Tag: "collections",
+			Query:     []openapi.Parameter{openapi.QueryParam("name", true, openapi.String())},
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "Name is free"}, http.StatusBadRequest: {Description: "Name is taken"}}},
+		{Method: "GET", Path: "/collections/{collectionId}", Summary: "Collection page", Tag: "collections",
+			Query: []openapi.Parameter{openapi.QueryParam("tag", false, openapi.String().Describe("Only show lists carrying this tag, and the items carrying it across the collection's lists"))}, Responses: htmlPage},
+		{Method: "PUT", Path: "/collections/{collectionId}", Summary: "Update collection", Tag: "collections",
+			Body: openapi.JSONBody(openapi.SchemaOf(constants.CollectionPostParams{})), Responses: noContent},
+		{Method: "DELETE", Path: "/collections/{collectionId}", Summary: "Move collection to the trash", Tag: "collections",
+			Query:     []openapi.Parameter{openapi.QueryParam("name", true, openapi.String().Describe("The collection's name, as confirmation"))},
+			Responses: noContent},
+		{Method: "GET", Path: "/collections/{collectionId}/edit", Summary: "Edit collection page", Tag: "collections", Responses: htmlPage},
+		{Method: "PUT", Path: "/collections/{collectionId}/lists/{listId}", Summary: "Add list to collection", Tag: "collections", Responses: noContent},
+		{Method: "DELETE", Path: "/collections/{collectionId}/lists/{listId}", Summary: "Remove list from collection", Tag: "collections", Responses: noContent},
+		{Method: "PUT", Path: "/collections/{collectionId}/share", Summary: "Create collection share link", Tag: "sharing", Responses: shareCodeText},
+		{Method: "POST", Path: "/collections/{collectionId}/share", Summary: "Create collection share link", Tag: "sharing", Responses: shareCodeText},
+		{Method: "DELETE", Path: "/collections/{collectionId}/share", Summary: "Unpublish collection share link", Tag: "sharing", Responses: noContent},
+		{Method: "GET", Path: "/" + constants.SHARED_COLLECTION_PATH + "/{shareCode}", Summary: "Shared collection page", Tag: "sharing", Auth: openapi.AuthPublic, Responses: htmlPage},
+
+		// apiToken.go
+		{Method: "GET", Path: "/tokens", Summary: "Personal access tokens page", Tag: "tokens", Responses: htmlPage},
+		{Method: "PUT", Path: "/tokens", Summary: "Create personal access token", Tag: "tokens",
+			Body: openapi.FormBody(openapi.Object(map[string]*openapi.Schema{
+				"name":  openapi.String().NonEmpty(),
+				"scope": {Type: "string", Enum: []string{constants.API_SCOPE_READ, constants.API_SCOPE_WRITE}},
+			}).Require("name", "scope")),
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "The token, shown only this once", ContentType: openapi.ContentTypeText, Schema: openapi.String()}}},
+		{Method: "DELETE", Path: "/tokens/{tokenId}", Summary: "Revoke personal access token", Tag: "tokens",
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Description: "Revoked"}, http.StatusNotFound: {Description: "Token not found"}}},
+
+		// session.go
+		{Method: "GET", Path: "/sessions", Summary: "Your sessions page", Tag: "auth", Responses: htmlPage},
+		{Method: "DELETE", Path: "/sessions", Summary: "Sign out every session but this one", Tag: "auth",
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Description: "Signed out"}}},
+		{Method: "DELETE", Path: "/sessions/{sessionId}", Summary: "Sign out one session", Tag: "auth",
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Description: "Signed out"}, http.StatusNotFound: {Description: "Session not found"}}},
+
+		// twoFactor.go
+		{Method: "GET", Path: "/auth/2fa", Summary: "Two-factor code page, after the password", Tag: "auth", Auth: openapi.AuthPublic, Responses: htmlPage},
+		{Method: "POST", Path: "/auth/2fa", Summary: "Finish logging in with a two-factor or recovery code", Tag: "auth", Auth: openapi.AuthPublic,
+			Body: twoFactorCodeForm,
+			Responses: map[int]openapi.Response{
+				http.StatusOK:              {Description: "Logged in; Location points at the list overview"},
+				http.StatusBadRequest:      {Description: "Code is not recognized"},
+				http.StatusUnauthorized:    {Description: "No password was entered in the last few minutes"},
+				http.StatusTooManyRequests: {Description: "Too many wrong codes; the password must be entered again"},
+			}},
+		{Method: "GET", Path: "/account/2fa", Summary: "Two-factor authentication settings page", Tag: "auth", Responses: htmlPage},
+		{Method: "POST", Path: "/account/2fa/setup", Summary: "Start setting up an authenticator app", Tag: "auth",
+			Responses: map[int]openapi.Response{
+				http.StatusOK:       {Description: "Secret and QR code for the authenticator app", ContentType: openapi.ContentTypeJSON, Schema: openapi.SchemaOf(constants.TwoFactorSetup{})},
+				http.StatusConflict: {Description: "Two-factor authentication is already on"},
+			}},
+		{Method: "PUT", Path: "/account/2fa", Summary: "Turn on two-factor authentication with the app's first code", Tag: "auth",
+			Body:      twoFactorCodeForm,
+			Responses: recoveryCodesText},
+		{Method: "POST", Path: "/account/2fa/recovery", Summary: "Replace recovery codes", Tag: "auth",
+			Body:      twoFactorCodeForm,
+			Responses: recoveryCodesText},
+		{Method: "POST", Path: "/account/2fa/disable", Summary: "Turn off two-factor authentication", Tag: "auth",
+			Body: twoFactorCodeForm,
+			Responses: map[int]openapi.Response{
+				http.StatusNoContent:  {Description: "Turned off"},
+				http.StatusBadRequest: {Description: "Code is not recognized"},
+				http.StatusForbidden:  {Description: "The user's group requires two-factor authentication"},
+			}},
+		{Method: "GET", Path: "/admin/grouptwofactor", Summary: "Whether the group requires two-factor authentication", Tag: "admin", Responses: toggledText},
+		{Method: "POST", Path: "/admin/grouptwofactor", Summary: "Toggle requiring two-factor authentication for the group", Tag: "admin", Responses: toggledText},
+
+		// passkey.go
+		{Method: "POST", Path: "/auth/passkey/options", Summary: "Challenge for signing in with a passkey", Tag: "auth", Auth: openapi.AuthPublic,
+			Responses: passkeyOptions("Options for navigator.credentials.get(); any of the site's passkeys may answer")},
+		{Method: "POST", Path: "/auth/passkey", Summary: "Log in with a passkey, without a password or two-factor code", Tag: "auth", Auth: openapi.AuthPublic,
+			Body: passkeyCredentialBody,
+			Responses: map[int]openapi.Response{
+				http.StatusOK:           {Description: "Logged in; Location points at the list overview"},
+				http.StatusUnauthorized: {Description: "Passkey is not recognized, or no challenge is waiting"},
+			}},
+		{Method: "GET", Path: "/account/passkeys", Summary: "Passkeys page", Tag: "auth", Responses: htmlPage},
+		{Method: "POST", Path: "/account/passkeys/options", Summary: "Start adding a passkey", Tag: "auth",
+			Responses: withPasskeyLimit(passkeyOptions("Options for navigator.credentials.create()"))},
+		{Method: "POST", Path: "/account/passkeys", Summary: "Add a passkey, named after the browser", Tag: "auth",
+			Body: passkeyCredentialBody,
+			Responses: map[int]openapi.Response{
+				http.StatusNoContent:  {Description: "Added"},
+				http.StatusBadRequest: {Description: "The authenticator's response was not accepted, or no registration was started"},
+			}},
+		{Method: "PUT", Path: "/account/passkeys/{passkeyId}", Summary: "Rename a passkey", Tag: "auth", Body: passkeyNameForm,
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Description: "Renamed"}, http.StatusNotFound: {Description: "Passkey not found"}}},
+		{Method: "DELETE", Path: "/account/passkeys/{passkeyId}", Summary: "Remove a passkey", Tag: "auth",
+			Responses: map[int]openapi.Response{http.StatusNoContent: {Description: "Removed; it no longer signs in"}, http.StatusNotFound: {Description: "Passkey not found"}}},
+
+		// web.go, openapi.go
+		{Method: "GET", Path: "/static/{pathname}", Summary: "Static asset", Tag: "web", Auth: openapi.AuthPublic,
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "File contents"}, http.StatusNotFound: {Description: "File not found"}}},
+		{Method: "GET", Path: "/" + constants.ATTACHMENT_PATH + "/{key}", Summary: "Uploaded image or thumbnail; anyone with the link can view it", Tag: "web", Auth: openapi.AuthPublic,
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "Image contents"}, http.StatusNotFound: {Description: "Image not found"}}},
+		{Method: "GET", Path: "/api/openapi.json", Summary: "This document", Tag: "web", Auth: openapi.AuthPublic,
+			Responses: map[int]openapi.Response{http.StatusOK: {Description: "OpenAPI 3.0 document", ContentType: openapi.ContentTypeJSON}}},
+	}
+}
+
+// apiV1Operations describes the /api/v1 routes in api.go, authenticated with a personal access token
+func apiV1Operations() []openapi.Operation {
+	v1 := constants.API_V1_PATH
+	listBody := openapi.JSONBody(openapi.SchemaOf(constants.ListPostParams{}))
+	listCreateBody := openapi.JSONBody(openapi.SchemaOf(apiListCreate{}))
+	itemBody := openapi.JSONBody(openapi.SchemaOf(apiItemInput{}))
+	collectionBody := openapi.JSONBody(openapi.SchemaOf(constants.CollectionPostParams{}))
+	shareJSON := jsonResponse(http.StatusOK, "Share link", apiShare{})
+
+	ops := []openapi.Operation{
+		{Method: "GET", Path: v1 + "/lists", Summary: "Lists owned by the token's user", Query: []openapi.Parameter{tagQuery}, Responses: jsonResponse(http.StatusOK, "Lists", []apiList{})},
+		{Method: "POST", Path: v1 + "/lists", Summary: "Create list", Body: listCreateBody, Responses: withConflict(jsonResponse(http.StatusCreated, "Created list", apiList{}))},
+		{Method: "GET", Path: v1 + "/lists/groupshared", Summary: "Lists shared with the user's group", Responses: jsonResponse(http.StatusOK, "Shared lists", []apiGroupSharedList{})},
+		{Method: "GET", Path: v1 + "/lists/{listId}", Summary: "Get list", Responses: jsonResponse(http.StatusOK, "List", apiList{})},
+		{Method: "PUT", Path: v1 + "/lists/{listId}", Summary: "Update list", Body: listBody, Responses: jsonResponse(http.StatusOK, "Updated list", apiList{})},
+		{Method: "DELETE", Path: v1 + "/lists/{listId}", Summary: "Move list to the trash", Responses: noContent},
+		{Method: "GET", Path: v1 + "/lists/{listId}/items", Summary: "Items in a list", Query: append(itemSortQuery, tagQuery), Responses: jsonResponse(http.StatusOK, "Items", []apiItem{})},
+		{Method: "POST", Path: v1 + "/lists/{listId}/items", Summary: "Create item", Body: itemBody, Responses: jsonResponse(http.StatusCreated, "Created item", apiItem{})},
+		{Method: "GET", Path: v1 + "/lists/{listId}/items/{itemId}", Summary: "Get item", Responses: jsonResponse(http.StatusOK, "Item", apiItem{})},
+		{Method: "PUT", Path: v1 + "/lists/{listId}/items/{itemId}", Summary: "Update item", Body: itemBody, Responses: jsonResponse(http.StatusOK, "Updated item", apiItem{})},
+		{Method: "DELETE", Path: v1 + "/lists/{listId}/items/{itemId}", Summary: "Move item to the trash", Responses: noContent},
+		{Method: "PUT", Path: v1 + "/lists/{listId}/items/order", Summary: "Reorder items", Body: itemOrderBody, Responses: itemOrderJSON},
+		{Method: "PUT", Path: v1 + "/lists/{listId}/items/{itemId}/complete", Summary: "Check an item off", Responses: jsonResponse(http.StatusOK, "Completed item", apiItem{})},
+		{Method: "DELETE", Path: v1 + "/lists/{listId}/items/{itemId}/complete", Summary: "Clear an item's completion", Responses: jsonResponse(http.StatusOK, "Item", apiItem{})},
+		{Method: "GET", Path: v1 + "/lists/{listId}/items/{itemId}/attachments", Summary: "Images attached to an item", Responses: jsonResponse(http.StatusOK, "Images, oldest first", []apiAttachment{})},
+		{Method: "POST", Path: v1 + "/lists/{listId}/items/{itemId}/attachments", Summary: "Upload images to an item", Body: attachmentBody, Responses: attachmentsCreated},
+		{Method: "DELETE", Path: v1 + "/lists/{listId}/items/{itemId}/attachments/{attachmentId}", Summary: "Remove an image from an item", Responses: attachmentDeleted},
+		{Method: "PUT", Path: v1 + "/lists/{listId}/completed-display", Summary: "Set how completed items are shown", Body: completedDisplayBody, Responses: noContent},
+		{Method: "GET", Path: v1 + "/lists/{listId}/sections", Summary: "Sections in a list", Responses: sectionsJSON},
+		{Method: "POST", Path: v1 + "/lists/{listId}/sections", Summary: "Create section", Body: sectionBody, Responses: sectionCreated},
+		{Method: "GET", Path: v1 + "/lists/{listId}/sections/{sectionId}", Summary: "Get section", Responses: sectionJSON},
+		{Method: "PUT", Path: v1 + "/lists/{listId}/sections/{sectionId}", Summary: "Rename section", Body: sectionBody, Responses: sectionJSON},
+		{Method: "DELETE", Path: v1 + "/lists/{listId}/sections/{sectionId}", Summary: "Delete section", Responses: sectionDeleted},
+		{Method: "PUT", Path: v1 + "/lists/{listId}/budget", Summary: "Set budget", Body: budgetBody, Responses: noContent},
+		{Method: "DELETE", Path: v1 + "/lists/{listId}/budget", Summary: "Clear budget", Responses: noContent},
+		{Method: "PUT", Path: v1 + "/lists/{listId}/tags", Summary: "Replace a list's tags", Body: tagsBody, Responses: noContent},
+		{Method: "GET", Path: v1 + "/lists/{listId}/totals", Summary: "Price totals and budget of a list", Responses: totalsJSON},
+		{Method: "GET", Path: v1 + "/lists/{listId}/history", Summary: "History of a list and its items", Responses: historyJSON},
+		{Method: "POST", Path: v1 + "/lists/{listId}/history/{revisionId}/revert", Summary: "Revert a change to a list or item", Responses: revisionReverted},
+		{Method: "POST", Path: v1 + "/lists/{listId}/copy", Summary: "Copy a list, owner only", Body: listCopyBody, Responses: listCopied()},
+		{Method: "PUT", Path: v1 + "/lists/{listId}/share", Summary: "Create share link", Responses: shareJSON},
+		{Method: "DELETE", Path: v1 + "/lists/{listId}/share", Summary: "Unpublish share link", Responses: noContent},
+
+		{Method: "GET", Path: v1 + "/templates", Summary: "List templates offered to the token user's group", Responses: templatesJSON},
+		{Method: "GET", Path: v1 + "/tags", Summary: "Tag index of the token user's lists and items", Responses: tagsJSON},
+		{Method: "GET", Path: v1 + "/items", Summary: "Items carrying a tag across the token user's lists",
+			Query: []openapi.Parameter{openapi.QueryParam("tag", true, openapi.String())}, Responses: jsonResponse(http.StatusOK, "Items, grouped by list", []apiTaggedItem{})},
+		{Method: "GET", Path: v1 + "/search", Summary: "Search lists the token user can view, their items, and the user's collections",
+			Query: []openapi.Parameter{openapi.QueryParam("q", true, searchQuery)}, Responses: jsonResponse(http.StatusOK, "Best matches first", apiSearchResults{})},
+		{Method: "GET", Path: v1 + "/trash", Summary: "The token user's trash", Responses: jsonResponse(http.StatusOK, "Most recently deleted first", apiTrash{})},
+		{Method: "DELETE", Path: v1 + "/trash", Summary: "Empty trash", Responses: trashEmptied},
+		{Method: "POST", Path: v1 + "/trash/{kind}/{id}/restore", Summary: "Restore a list, item or collection from the trash", Responses: trashRestored},
+		{Method: "DELETE", Path: v1 + "/trash/{kind}/{id}", Summary: "Delete a list, item or collection in the trash for good", Responses: trashPurged},
+
+		{Method: "GET", Path: v1 + "/collections", Summary: "Collections owned by the token's user", Responses: jsonResponse(http.StatusOK, "Collections", []apiCollection{})},
+		{Method: "POST", Path: v1 + "/collections", Summary: "Create collection", Body: collectionBody, Responses: withConflict(jsonResponse(http.StatusCreated, "Created collection", apiCollection{}))},
+		{Method: "GET", Path: v1 + "/collections/{collectionId}", Summary: "Get collection", Responses: jsonResponse(http.StatusOK, "Collection", apiCollection{})},
+		{Method: "PUT", Path: v1 + "/collections/{collectionId}", Summary: "Update collection", Body: collectionBody, Responses: withConflict(jsonResponse(http.StatusOK, "Updated collection", apiCollection{}))},
+		{Method: "DELETE", Path: v1 + "/collections/{collectionId}", Summary: "Move collection to the trash", Responses: noContent},
+		{Method: "GET", Path: v1 + "/collections/{collectionId}/lists", Summary: "Lists in a collection", Query: []openapi.Parameter{tagQuery}, Responses: jsonResponse(http.StatusOK, "Lists", []apiCollectionList{})},
+		{Method: "GET", Path: v1 + "/collections/{collectionId}/totals", Summary: "Price totals across a collection's lists", Responses: totalsJSON},
+		{Method: "GET", Path: v1 + "/collections/{collectionId}/items", Summary: "Items carrying a tag across a collection's lists",
+			Query: []openapi.Parameter{openapi.QueryParam("tag", true, openapi.String())}, Responses: jsonResponse(http.StatusOK, "Items, grouped by list", []apiTaggedItem{})},
+		{Method: "PUT", Path: v1 + "/collections/{collectionId}/lists/{listId}", Summary: "Add list to collection", Responses: noContent},
+		{Method: "DELETE", Path: v1 + "/collections/{collectionId}/lists/{listId}", Summary: "Remove list from collection", Responses: noContent},
+		{Method: "PUT", Path: v1 + "/collections/{collectionId}/share", Summary: "Create collection share link", Responses: shareJSON},
+		{Method: "DELETE", Path: v1 + "/collections/{collectionId}/share", Summary: "Unpublish collection share link", Responses: noContent},
+	}
+	for i := range ops {
+		ops[i].Tag = "api/v1"
+		ops[i].Auth = openapi.AuthToken
+	}
+	return ops
+}
@@ -0,0 +1,185 @@
+package openapi
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object that listaway uses to describe
+// request and response bodies. The same value drives both the published document and
+// request validation, so the two can't drift apart.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+func String() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func Integer() *Schema {
+	return &Schema{Type: "integer", Format: "int64"}
+}
+
+func Boolean() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+// Binary is an uploaded file in a multipart body
+func Binary() *Schema {
+	return &Schema{Type: "string", Format: "binary"}
+}
+
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// Object builds an object schema from name/schema pairs; use Require to mark required properties
+func Object(properties map[string]*Schema) *Schema {
+	return &Schema{Type: "object", Properties: properties}
+}
+
+// Require marks the named properties as required and returns the schema for chaining
+func (s *Schema) Require(names ...string) *Schema {
+	s.Required = append(s.Required, names...)
+	return s
+}
+
+// NonEmpty requires a string to have at least one character
+func (s *Schema) NonEmpty() *Schema {
+	one := 1
+	s.MinLength = &one
+	return s
+}
+
+// Describe sets the description and returns the schema for chaining
+func (s *Schema) Describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	nullStringType = reflect.TypeOf(sql.NullString{})
+	nullInt64Type  = reflect.TypeOf(sql.NullInt64{})
+	nullTimeType   = reflect.TypeOf(sql.NullTime{})
+)
+
+// SchemaOf derives a schema from the JSON encoding of v's type. Struct fields are named by
+// their json tag and may carry an `openapi` tag with comma-separated constraints:
+// required, nonempty, maxLength=N, minimum=N, format=F and enum=a|b.
+func SchemaOf(v any) *Schema {
+	return schemaOfType(reflect.TypeOf(v))
+}
+
+func schemaOfType(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case nullTimeType:
+		// sql.Null* types have no MarshalJSON, so they encode as their struct fields
+		return Object(map[string]*Schema{"Time": {Type: "string", Format: "date-time"}, "Valid": Boolean()})
+	case nullStringType:
+		return Object(map[string]*Schema{"String": String(), "Valid": Boolean()})
+	case nullInt64Type:
+		return Object(map[string]*Schema{"Int64": Integer(), "Valid": Boolean()})
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaOfType(t.Elem())
+		s.Nullable = true
+		return s
+	case reflect.String:
+		return String()
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Integer()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s := Integer()
+		zero := 0.0
+		s.Minimum = &zero
+		return s
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return ArrayOf(schemaOfType(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		s := Object(map[string]*Schema{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+				// Embedded structs without a json name are flattened, as encoding/json does
+				embedded := schemaOfType(field.Type)
+				for name, fieldSchema := range embedded.Properties {
+					s.Properties[name] = fieldSchema
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag, ok := field.Tag.Lookup("json"); ok {
+				tagName, _, _ := strings.Cut(tag, ",")
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+			}
+			fieldSchema := schemaOfType(field.Type)
+			if applyTag(fieldSchema, field.Tag.Get("openapi")) {
+				s.Required = append(s.Required, name)
+			}
+			s.Properties[name] = fieldSchema
+		}
+		return s
+	}
+	return &Schema{}
+}
+
+// applyTag applies the constraints in an `openapi` struct tag, reporting whether the field is required
+func applyTag(s *Schema, tag string) bool {
+	required := false
+	for _, part := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "required":
+			required = true
+		case "nonempty":
+			s.NonEmpty()
+		case "maxLength":
+			if n, err := strconv.Atoi(value); err == nil {
+				s.MaxLength = &n
+			}
+		case "minimum":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				s.Minimum = &n
+			}
+		case "format":
+			s.Format = value
+		case "enum":
+			s.Enum = strings.Split(value, "|")
+		}
+	}
+	return required
+}
@@ -0,0 +1,302 @@
+package openapi
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeForm = "application/x-www-form-urlencoded"
+	ContentTypeHTML = "text/html"
+	ContentTypeText = "text/plain"
+
+	// ContentTypeMultipart is a form with file uploads
+	ContentTypeMultipart = "multipart/form-data"
+)
+
+// Auth says how a caller authenticates to an operation
+type Auth int
+
+const (
+	AuthSession Auth = iota // browser session cookie set by /auth
+	AuthToken               // personal access token in the Authorization header
+	AuthPublic              // no authentication
+)
+
+const (
+	securitySession = "session"
+	securityToken   = "personalAccessToken"
+)
+
+// Operation documents one method on one route. Path uses OpenAPI templating
+// ("/list/{listId}"), not the gorilla/mux patterns the route was registered with.
+type Operation struct {
+	Method    string
+	Path      string
+	Summary   string
+	Tag       string
+	Auth      Auth
+	Query     []Parameter
+	Body      *Body
+	Responses map[int]Response
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Body is a required request body in a single encoding
+type Body struct {
+	ContentType string
+	Schema      *Schema
+	MaxBytes    int64 // for multipart bodies; larger requests are refused before they are parsed
+}
+
+type Response struct {
+	Description string
+	ContentType string
+	Schema      *Schema
+}
+
+func JSONBody(s *Schema) *Body {
+	return &Body{ContentType: ContentTypeJSON, Schema: s}
+}
+
+func FormBody(s *Schema) *Body {
+	return &Body{ContentType: ContentTypeForm, Schema: s}
+}
+
+// MultipartBody accepts a form with file uploads of up to maxBytes in total. File fields are
+// described with Binary and are only checked for presence; handlers read them from r.MultipartForm.
+func MultipartBody(s *Schema, maxBytes int64) *Body {
+	return &Body{ContentType: ContentTypeMultipart, Schema: s, MaxBytes: maxBytes}
+}
+
+// QueryParam documents a query string parameter
+func QueryParam(name string, required bool, s *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Required: required, Schema: s}
+}
+
+var operations = map[string]Operation{}
+
+func operationKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// Register adds operations to the document; registering the same method and path twice replaces the first
+func Register(ops ...Operation) {
+	for _, op := range ops {
+		operations[operationKey(op.Method, op.Path)] = op
+	}
+}
+
+// Lookup finds the operation for a request method and the mux path template of the route it matched
+func Lookup(method, template string) (Operation, bool) {
+	path, _ := PathFromTemplate(template)
+	op, ok := operations[operationKey(method, path)]
+	return op, ok
+}
+
+// PathFromTemplate converts a gorilla/mux path template into an OpenAPI path and its path parameters.
+// Variables constrained to digits are documented as integers.
+func PathFromTemplate(template string) (string, []Parameter) {
+	var path strings.Builder
+	var params []Parameter
+	for i := 0; i < len(template); i++ {
+		if template[i] != '{' {
+			path.WriteByte(template[i])
+			continue
+		}
+		// Patterns may contain their own braces, e.g. {id:[0-9]{4}}
+		depth, end := 1, i+1
+		for ; end < len(template) && depth > 0; end++ {
+			switch template[end] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+		}
+		name, pattern, _ := strings.Cut(template[i+1:end-1], ":")
+		name = strings.TrimSuffix(name, "...")
+		schema := String()
+		if pattern == "[0-9]+" {
+			schema = Integer()
+		} else if pattern != "" {
+			schema.Pattern = "^" + pattern + "$"
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		path.WriteString("{" + name + "}")
+		i = end - 1
+	}
+	return path.String(), params
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Document struct {
+	OpenAPI    string                                `json:"openapi"`
+	Info       Info                                  `json:"info"`
+	Servers    []Server                              `json:"servers,omitempty"`
+	Paths      map[string]map[string]operationObject `json:"paths"`
+	Components components                            `json:"components"`
+}
+
+type operationObject struct {
+	OperationId string                    `json:"operationId"`
+	Summary     string                    `json:"summary,omitempty"`
+	Tags        []string                  `json:"tags,omitempty"`
+	Parameters  []Parameter               `json:"parameters,omitempty"`
+	RequestBody *requestBodyObject        `json:"requestBody,omitempty"`
+	Responses   map[string]responseObject `json:"responses"`
+	Security    []map[string][]string     `json:"security"`
+}
+
+type requestBodyObject struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type responseObject struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type components struct {
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Build walks every route on the router and documents it. Routes registered without a method
+// restriction are documented with the methods registered for their path; a route with nothing
+// registered still appears, with only its path parameters, so the document never omits a route.
+func Build(router *mux.Router, info Info, servers []Server, sessionCookie string) (Document, error) {
+	doc := Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Servers: servers,
+		Paths:   map[string]map[string]operationObject{},
+		Components: components{SecuritySchemes: map[string]securityScheme{
+			securitySession: {Type: "apiKey", In: "cookie", Name: sessionCookie, Description: "Browser session created by signing in at /auth"},
+			securityToken:   {Type: "http", Scheme: "bearer", Description: "Personal access token created at /tokens"},
+		}},
+	}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil // Routes matched only by host, headers, etc. have no path to document
+		}
+		path, params := PathFromTemplate(template)
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = registeredMethods(path)
+		}
+		if len(methods) == 0 {
+			methods = []string{http.MethodGet}
+		}
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]operationObject{}
+		}
+		for _, method := range methods {
+			op, ok := operations[operationKey(method, path)]
+			if !ok {
+				op = Operation{Method: method, Path: path}
+			}
+			doc.Paths[path][strings.ToLower(method)] = op.object(params)
+		}
+		return nil
+	})
+	return doc, err
+}
+
+func registeredMethods(path string) []string {
+	var methods []string
+	for _, op := range operations {
+		if op.Path == path {
+			methods = append(methods, strings.ToUpper(op.Method))
+		}
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+func (op Operation) object(pathParams []Parameter) operationObject {
+	o := operationObject{
+		OperationId: operationId(op.Method, op.Path),
+		Summary:     op.Summary,
+		Parameters:  append(append([]Parameter{}, pathParams...), op.Query...),
+		Responses:   map[string]responseObject{},
+		Security:    []map[string][]string{},
+	}
+	if op.Tag != "" {
+		o.Tags = []string{op.Tag}
+	}
+	switch op.Auth {
+	case AuthSession:
+		o.Security = append(o.Security, map[string][]string{securitySession: {}})
+	case AuthToken:
+		o.Security = append(o.Security, map[string][]string{securityToken: {}})
+		o.Responses["401"] = responseObject{Description: "Missing, invalid or revoked token"}
+	}
+	if op.Body != nil {
+		o.RequestBody = &requestBodyObject{
+			Required: true,
+			Content:  map[string]mediaType{op.Body.ContentType: {Schema: op.Body.Schema}},
+		}
+	}
+	if op.Body != nil || len(op.Query) > 0 {
+		o.Responses["400"] = responseObject{Description: "Request does not match this schema"}
+	}
+	for status, response := range op.Responses {
+		ro := responseObject{Description: response.Description}
+		if response.ContentType != "" {
+			ro.Content = map[string]mediaType{response.ContentType: {Schema: response.Schema}}
+		}
+		o.Responses[strconv.Itoa(status)] = ro
+	}
+	if len(op.Responses) == 0 {
+		o.Responses["200"] = responseObject{Description: "OK"}
+	}
+	return o
+}
+
+// operationId turns "PUT /list/{listId}/item" into "putListListIdItem"
+func operationId(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '.' || r == '-' }) {
+		segment = strings.Trim(segment, "{}")
+		if segment == "" {
+			continue
+		}
+		id += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+	return id
+}
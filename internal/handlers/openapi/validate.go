@@ -0,0 +1,303 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// maxJSONBodyBytes caps how much of a JSON body is buffered for validation
+const maxJSONBodyBytes = 1 << 20
+
+// maxMultipartMemory is how much of a multipart body is held in memory; the rest of its files go to temporary files
+const maxMultipartMemory = 32 << 20
+
+// RequestError explains why a request was rejected and with which status
+type RequestError struct {
+	Status  int
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+func invalid(format string, args ...any) *RequestError {
+	return &RequestError{Status: http.StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validate checks the request's query parameters and body against the operation. A JSON body is
+// buffered and put back on the request; a form body is parsed into r.Form, so handlers can read
+// either as they did before.
+func (op Operation) Validate(r *http.Request) *RequestError {
+	query := r.URL.Query()
+	for _, p := range op.Query {
+		value := query.Get(p.Name)
+		if value == "" {
+			if p.Required {
+				return invalid("%s is required", p.Name)
+			}
+			continue
+		}
+		if err := p.Schema.validateFormValue(value, p.Name); err != nil {
+			return err
+		}
+	}
+	if op.Body == nil {
+		return nil
+	}
+
+	mediaType := ""
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return &RequestError{Status: http.StatusUnsupportedMediaType, Message: "Malformed Content-Type header"}
+		}
+	}
+
+	switch op.Body.ContentType {
+	case ContentTypeJSON:
+		if mediaType != "" && mediaType != ContentTypeJSON {
+			return &RequestError{Status: http.StatusUnsupportedMediaType, Message: "Request body must be " + ContentTypeJSON}
+		}
+		return validateJSONBody(r, op.Body.Schema)
+	case ContentTypeForm:
+		var err error
+		switch mediaType {
+		case "", ContentTypeForm:
+			err = r.ParseForm()
+		case "multipart/form-data":
+			err = r.ParseMultipartForm(maxMultipartMemory)
+		default:
+			return &RequestError{Status: http.StatusUnsupportedMediaType, Message: "Request body must be " + ContentTypeForm}
+		}
+		if err != nil {
+			return invalid("Invalid form body")
+		}
+		return op.Body.Schema.validateForm(r.Form)
+	case ContentTypeMultipart:
+		if mediaType != ContentTypeMultipart {
+			return &RequestError{Status: http.StatusUnsupportedMediaType, Message: "Request body must be " + ContentTypeMultipart}
+		}
+		if op.Body.MaxBytes > 0 {
+			r.Body = http.MaxBytesReader(nil, r.Body, op.Body.MaxBytes)
+		}
+		if err := r.ParseMultipartForm(maxMultipartMemory); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return &RequestError{Status: http.StatusRequestEntityTooLarge, Message: "Request body is too large"}
+			}
+			return invalid("Invalid multipart body")
+		}
+		return op.Body.Schema.validateMultipart(r.MultipartForm)
+	}
+	return nil
+}
+
+func validateJSONBody(r *http.Request, s *Schema) *RequestError {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxJSONBodyBytes+1))
+	if err != nil {
+		return invalid("Could not read request body")
+	}
+	if len(body) > maxJSONBodyBytes {
+		return &RequestError{Status: http.StatusRequestEntityTooLarge, Message: "Request body is too large"}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return invalid("Request body is required")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return invalid("Invalid JSON body")
+	}
+	return s.validate(value, "body")
+}
+
+// validate checks a value decoded from JSON (with UseNumber) against the schema
+func (s *Schema) validate(value any, at string) *RequestError {
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return invalid("%s must not be null", at)
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return invalid("%s must be an object", at)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return invalid("%s.%s is required", at, name)
+			}
+		}
+		for _, name := range s.propertyNames() {
+			if v, ok := obj[name]; ok {
+				if err := s.Properties[name].validate(v, at+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return invalid("%s must be an array", at)
+		}
+		if s.Items != nil {
+			for i, v := range arr {
+				if err := s.Items.validate(v, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return invalid("%s must be a string", at)
+		}
+		return s.validateString(str, at)
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return invalid("%s must be an integer", at)
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return invalid("%s must be an integer", at)
+		}
+		return s.validateMinimum(float64(i), at)
+	case "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return invalid("%s must be a number", at)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return invalid("%s must be a number", at)
+		}
+		return s.validateMinimum(f, at)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid("%s must be true or false", at)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateString(str, at string) *RequestError {
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+		return invalid("%s must be one of %v", at, s.Enum)
+	}
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			return invalid("%s must not be empty", at)
+		}
+		return invalid("%s must be at least %d characters", at, *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return invalid("%s must be at most %d characters", at, *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		if matched, err := regexp.MatchString(s.Pattern, str); err == nil && !matched {
+			return invalid("%s is not in the expected format", at)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateMinimum(n float64, at string) *RequestError {
+	if s.Minimum != nil && n < *s.Minimum {
+		return invalid("%s must be at least %v", at, *s.Minimum)
+	}
+	return nil
+}
+
+// validateForm checks form values against an object schema. Browsers send every field of a form,
+// so a blank value for a non-string field counts as absent, as it does in the handlers.
+func (s *Schema) validateForm(values url.Values) *RequestError {
+	for _, name := range s.propertyNames() {
+		prop := s.Properties[name]
+		vals, ok := values[name]
+		present := ok && len(vals) > 0 && (prop.Type == "string" || vals[0] != "")
+		if !present {
+			if slices.Contains(s.Required, name) {
+				return invalid("%s is required", name)
+			}
+			continue
+		}
+		if err := prop.validateFormValue(vals[0], name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMultipart checks a multipart body's text fields as validateForm does, and that its
+// required file fields have at least one file
+func (s *Schema) validateMultipart(form *multipart.Form) *RequestError {
+	text := &Schema{Type: s.Type, Properties: map[string]*Schema{}}
+	for _, name := range s.propertyNames() {
+		prop := s.Properties[name]
+		if !prop.isBinary() {
+			text.Properties[name] = prop
+			if slices.Contains(s.Required, name) {
+				text.Required = append(text.Required, name)
+			}
+			continue
+		}
+		if slices.Contains(s.Required, name) && len(form.File[name]) == 0 {
+			return invalid("%s is required", name)
+		}
+	}
+	return text.validateForm(form.Value)
+}
+
+// isBinary reports whether the schema is a file, or an array of them
+func (s *Schema) isBinary() bool {
+	if s.Type == "array" && s.Items != nil {
+		return s.Items.isBinary()
+	}
+	return s.Type == "string" && s.Format == "binary"
+}
+
+// validateFormValue checks a single form or query string value, which is always text on the wire
+func (s *Schema) validateFormValue(raw, at string) *RequestError {
+	switch s.Type {
+	case "integer", "number":
+		return s.validate(json.Number(raw), at)
+	case "boolean":
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return invalid("%s must be true or false", at)
+		}
+		return nil
+	default:
+		return s.validate(raw, at)
+	}
+}
+
+// propertyNames returns property names in a stable order so the first reported error is deterministic
+func (s *Schema) propertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/internal/handlers/passkey"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// passkeys runs the WebAuthn ceremonies against the repository; see initPasskeys
+var passkeys *passkey.Passkeys
+
+// Passkeys sign in without a password or a two-factor code: the authenticator holds the key and
+// checks the user's fingerprint, face or PIN before using it. The browser talks to the authenticator;
+// each ceremony is an options request whose challenge waits in the session, then the signed response.
+func init() {
+	constants.ROUTER.HandleFunc("/auth/passkey/options", middleware.DefaultPublicMiddlewareChain(passkeyLoginOptionsPOST)).Methods("POST")
+	constants.ROUTER.HandleFunc("/auth/passkey", middleware.DefaultPublicMiddlewareChain(passkeyLoginPOST)).Methods("POST")
+	constants.ROUTER.HandleFunc("/account/passkeys", middleware.DefaultMiddlewareChain(passkeysHandler))
+	constants.ROUTER.HandleFunc("/account/passkeys/options", middleware.DefaultMiddlewareChain(passkeyRegisterOptionsPOST)).Methods("POST")
+	constants.ROUTER.HandleFunc("/account/passkeys/{passkeyId:[0-9]+}", middleware.DefaultMiddlewareChain(passkeyHandler))
+}
+
+// initPasskeys binds passkeys to the host name in APP_URL, which has to be the address users reach
+// the app at. Passkeys stay unavailable if it can't name one.
+func initPasskeys(store passkey.Store) {
+	var err error
+	passkeys, err = passkey.New(passkey.Config(constants.APP_URL), store)
+	if err != nil {
+		log.Printf("Passkeys are unavailable; check %s: %v", constants.ENV_APP_URL, err)
+	}
+}
+
+func passkeysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		passkeysGET(w, r)
+	case "POST":
+		passkeysPOST(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func passkeyHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		passkeyPUT(w, r)
+	case "DELETE":
+		passkeyDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Challenge for signing in with a passkey */
+func passkeyLoginOptionsPOST(w http.ResponseWriter, r *http.Request) {
+	if passkeys == nil {
+		http.Error(w, "Passkeys are not available", http.StatusNotFound)
+		return
+	}
+	assertion, state, err := passkeys.BeginLogin()
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session.Values["passkeyLogin"] = state
+	if err := session.Save(r, w); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, assertion)
+}
+
+/* Sign in with a passkey */
+func passkeyLoginPOST(w http.ResponseWriter, r *http.Request) {
+	if passkeys == nil {
+		http.Error(w, "Passkeys are not available", http.StatusNotFound)
+		return
+	}
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	state, ok := session.Values["passkeyLogin"].([]byte)
+	if !ok {
+		http.Error(w, "Sign in again", http.StatusUnauthorized)
+		return
+	}
+	// Each challenge is answered once, right or wrong
+	delete(session.Values, "passkeyLogin")
+	userId, err := passkeys.FinishLogin(r.Context(), state, r.Body)
+	if errors.Is(err, passkey.ErrRejected) {
+		log.Print(err)
+		auditAs(r, -1, constants.AUDIT_LOGIN_FAILED, auditUser(r, userId), "Passkey not accepted")
+		if err := session.Save(r, w); err != nil {
+			log.Print(err)
+		}
+		http.Error(w, "Passkey is not recognized", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	// A passkey is something the user has, unlocked by something they are or know, so it stands in for
+	// the password and the two-factor code together, and meets a group's two-factor requirement
+	clearTwoFactorLogin(session)
+	delete(session.Values, "twoFactorSetupRequired")
+	auditAs(r, userId, constants.AUDIT_LOGIN, nil, "Passkey")
+	if err := signIn(w, r, session, userId); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Add("Location", "/list")
+	w.WriteHeader(http.StatusOK)
+}
+
+/* Passkeys page */
+func passkeysGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	result, err := repo.GetUserPasskeys(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.PasskeysPage(w, web.PasskeysPageParams(r, result, passkeys != nil, admin, instanceAdmin))
+}
+
+/* Options for adding a passkey */
+func passkeyRegisterOptionsPOST(w http.ResponseWriter, r *http.Request) {
+	if passkeys == nil {
+		http.Error(w, "Passkeys are not available", http.StatusNotFound)
+		return
+	}
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	user, err := repo.GetUser(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	creation, state, err := passkeys.BeginRegistration(r.Context(), userId, user.Email, user.Name)
+	if errors.Is(err, passkey.ErrTooManyPasskeys) {
+		http.Error(w, fmt.Sprintf("You already have %d passkeys; remove one first", constants.PASSKEY_MAX_PER_USER), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session.Values["passkeyRegistration"] = state
+	if err := session.Save(r, w); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, creation)
+}
+
+/* Add a passkey from the authenticator's response; it is named after the browser until renamed */
+func passkeysPOST(w http.ResponseWriter, r *http.Request) {
+	if passkeys == nil {
+		http.Error(w, "Passkeys are not available", http.StatusNotFound)
+		return
+	}
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	state, ok := session.Values["passkeyRegistration"].([]byte)
+	if !ok {
+		http.Error(w, "Start adding the passkey again", http.StatusBadRequest)
+		return
+	}
+	delete(session.Values, "passkeyRegistration")
+	if err := session.Save(r, w); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	name := constants.Session{UserAgent: r.UserAgent()}.Device()
+	_, err = passkeys.FinishRegistration(r.Context(), userId, state, r.Body, name)
+	if errors.Is(err, passkey.ErrRejected) {
+		log.Print(err)
+		http.Error(w, "The passkey could not be added", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	audit(r, constants.AUDIT_PASSKEY_ADD, nil, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Rename a passkey */
+func passkeyPUT(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	passkeyId, err := helper.GetPathVarInt(r, "passkeyId")
+	if err != nil {
+		http.Error(w, "Invalid passkeyId supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" || len(name) > constants.PASSKEY_MAX_NAME_LENGTH {
+		http.Error(w, fmt.Sprintf("Name must be 1 to %d characters", constants.PASSKEY_MAX_NAME_LENGTH), http.StatusBadRequest)
+		return
+	}
+	renamed, err := repo.RenameUserPasskey(r.Context(), userId, passkeyId, name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !renamed {
+		http.Error(w, "Passkey not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Remove a passkey; the authenticator keeps its copy, but it no longer signs in */
+func passkeyDELETE(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	passkeyId, err := helper.GetPathVarInt(r, "passkeyId")
+	if err != nil {
+		http.Error(w, "Invalid passkeyId supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	name, deleted, err := repo.DeleteUserPasskey(r.Context(), userId, passkeyId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Passkey not found", http.StatusNotFound)
+		return
+	}
+	audit(r, constants.AUDIT_PASSKEY_REMOVE, nil, name)
+	w.WriteHeader(http.StatusNoContent)
+}
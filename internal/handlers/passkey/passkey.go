@@ -0,0 +1,227 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// ceremonyTimeout is how long the browser and the user have to finish registering or signing in with a passkey
+const ceremonyTimeout = 5 * time.Minute
+
+// userHandleLength is the size of the random user handle, the most WebAuthn allows
+const userHandleLength = 64
+
+// ErrRejected wraps every reason a passkey response is turned away: a bad signature, an unknown passkey,
+// an expired or replayed challenge, or a malformed response. Anything else is a server-side failure.
+var ErrRejected = errors.New("passkey response rejected")
+
+// ErrTooManyPasskeys is returned by BeginRegistration when the user already has constants.PASSKEY_MAX_PER_USER passkeys
+var ErrTooManyPasskeys = errors.New("too many passkeys")
+
+// Store is where passkeys are kept. *database.Repository implements it against Postgres; an in-memory
+// Store lets the ceremonies run end to end against a software authenticator.
+type Store interface {
+	// GetPasskeyCredentials returns a user's user handle and passkeys; the handle is nil if they have none
+	GetPasskeyCredentials(ctx context.Context, userId int) ([]byte, []webauthn.Credential, error)
+	// GetPasskeyUserByHandle returns the user a handle belongs to and their passkeys, or -1 if it is unknown
+	GetPasskeyUserByHandle(ctx context.Context, userHandle []byte) (int, []webauthn.Credential, error)
+	// AddPasskey stores a newly registered passkey and returns its id
+	AddPasskey(ctx context.Context, userId int, userHandle []byte, name string, credential webauthn.Credential) (int, error)
+	// UpdatePasskeyCredential saves the sign count and flags of a passkey that was just used
+	UpdatePasskeyCredential(ctx context.Context, credential webauthn.Credential) error
+}
+
+// Passkeys runs the WebAuthn registration and sign-in ceremonies against a Store. The ceremony state
+// handed back by each Begin method must be kept out of the browser's reach (the server-side session
+// does this) and passed to the matching Finish method.
+type Passkeys struct {
+	webAuthn *webauthn.WebAuthn
+	store    Store
+}
+
+// Config is the relying party configuration for the app served at appURL: passkeys are bound to
+// its host name and only accepted from its origin
+func Config(appURL string) *webauthn.Config {
+	config := &webauthn.Config{
+		RPDisplayName: constants.PASSKEY_RP_NAME,
+		// Passkeys are the only thing the user presents, so the authenticator must check it's them
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: ceremonyTimeout, TimeoutUVD: ceremonyTimeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: ceremonyTimeout, TimeoutUVD: ceremonyTimeout},
+		},
+	}
+	if u, err := url.Parse(appURL); err == nil && u.Hostname() != "" {
+		config.RPID = u.Hostname()
+		config.RPOrigins = []string{u.Scheme + "://" + u.Host}
+	}
+	return config
+}
+
+// New checks config and returns Passkeys keeping credentials in store
+func New(config *webauthn.Config, store Store) (*Passkeys, error) {
+	webAuthn, err := webauthn.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Passkeys{webAuthn: webAuthn, store: store}, nil
+}
+
+// BeginRegistration starts adding a passkey for a signed-in user, returning the options for
+// navigator.credentials.create() and the ceremony state to keep for FinishRegistration
+func (p *Passkeys) BeginRegistration(ctx context.Context, userId int, email string, name string) (*protocol.CredentialCreation, []byte, error) {
+	handle, credentials, err := p.store.GetPasskeyCredentials(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(credentials) >= constants.PASSKEY_MAX_PER_USER {
+		return nil, nil, ErrTooManyPasskeys
+	}
+	if handle == nil {
+		handle = make([]byte, userHandleLength)
+		if _, err := rand.Read(handle); err != nil {
+			return nil, nil, err
+		}
+	}
+	u := &user{id: userId, handle: handle, name: email, displayName: name, credentials: credentials}
+	// Excluding the user's passkeys stops an authenticator that already holds one from adding another
+	creation, session, err := p.webAuthn.BeginRegistration(u,
+		webauthn.WithExclusions(webauthn.Credentials(credentials).CredentialDescriptors()))
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := json.Marshal(session)
+	if err != nil {
+		return nil, nil, err
+	}
+	return creation, state, nil
+}
+
+// FinishRegistration checks the authenticator's response to BeginRegistration's options and stores the
+// new passkey under name, returning its id
+func (p *Passkeys) FinishRegistration(ctx context.Context, userId int, state []byte, response io.Reader, name string) (int, error) {
+	var session webauthn.SessionData
+	if err := json.Unmarshal(state, &session); err != nil {
+		return 0, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBody(response)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	handle, credentials, err := p.store.GetPasskeyCredentials(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	// The handle is chosen when registration begins; a passkey the user added meanwhile may have settled it
+	if handle != nil && !bytes.Equal(handle, session.UserID) {
+		return 0, fmt.Errorf("%w: user handle changed during registration", ErrRejected)
+	}
+	u := &user{id: userId, handle: session.UserID, credentials: credentials}
+	credential, err := p.webAuthn.CreateCredential(u, session, parsed)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return p.store.AddPasskey(ctx, userId, session.UserID, name, *credential)
+}
+
+// BeginLogin starts a passwordless sign-in, returning the options for navigator.credentials.get() and
+// the ceremony state to keep for FinishLogin. No account is named up front: the user picks one of the
+// passkeys their authenticator holds for this site.
+func (p *Passkeys) BeginLogin() (*protocol.CredentialAssertion, []byte, error) {
+	assertion, session, err := p.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := json.Marshal(session)
+	if err != nil {
+		return nil, nil, err
+	}
+	return assertion, state, nil
+}
+
+// FinishLogin checks the authenticator's response to BeginLogin's options and returns the user it
+// signs in. A response from an unknown passkey is rejected with user -1; one the user's own passkey
+// signed but that failed a check returns the user alongside the error, so the attempt can be recorded
+// against them.
+func (p *Passkeys) FinishLogin(ctx context.Context, state []byte, response io.Reader) (int, error) {
+	var session webauthn.SessionData
+	if err := json.Unmarshal(state, &session); err != nil {
+		return -1, err
+	}
+	if !session.Expires.IsZero() && session.Expires.Before(time.Now()) {
+		return -1, fmt.Errorf("%w: sign-in took too long", ErrRejected)
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(response)
+	if err != nil {
+		return -1, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+
+	userId := -1
+	var lookupErr error
+	found, credential, err := p.webAuthn.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		id, credentials, err := p.store.GetPasskeyUserByHandle(ctx, userHandle)
+		if err != nil {
+			lookupErr = err
+			return nil, err
+		}
+		if id == -1 {
+			return nil, errors.New("no user has this passkey")
+		}
+		userId = id
+		return &user{id: id, handle: userHandle, credentials: credentials}, nil
+	}, session, parsed)
+	if lookupErr != nil {
+		return -1, lookupErr
+	}
+	if err != nil {
+		return userId, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	// A signature counter that went backwards means the private key has been copied
+	if credential.Authenticator.CloneWarning {
+		return userId, fmt.Errorf("%w: signature counter of passkey %x went backwards", ErrRejected, credential.ID)
+	}
+	if err := p.store.UpdatePasskeyCredential(ctx, *credential); err != nil {
+		return -1, err
+	}
+	return found.(*user).id, nil
+}
+
+// user is a Listaway user as WebAuthn sees them
+type user struct {
+	id          int
+	handle      []byte
+	name        string
+	displayName string
+	credentials []webauthn.Credential
+}
+
+func (u *user) WebAuthnID() []byte {
+	return u.handle
+}
+
+func (u *user) WebAuthnName() string {
+	return u.name
+}
+
+func (u *user) WebAuthnDisplayName() string {
+	return u.displayName
+}
+
+func (u *user) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
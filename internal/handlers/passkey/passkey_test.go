@@ -0,0 +1,504 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+const (
+	testAppURL = "https://lists.example.com"
+	testOrigin = "https://lists.example.com"
+	testRPID   = "lists.example.com"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+var b64 = base64.RawURLEncoding
+
+// memoryStore keeps passkeys the way the database does, credentials round-tripping through JSON
+type memoryStore struct {
+	handles     map[int][]byte
+	credentials map[int][][]byte
+	updates     int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{handles: map[int][]byte{}, credentials: map[int][][]byte{}}
+}
+
+func (m *memoryStore) GetPasskeyCredentials(ctx context.Context, userId int) ([]byte, []webauthn.Credential, error) {
+	credentials := make([]webauthn.Credential, 0)
+	for _, data := range m.credentials[userId] {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(data, &credential); err != nil {
+			return nil, nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return m.handles[userId], credentials, nil
+}
+
+func (m *memoryStore) GetPasskeyUserByHandle(ctx context.Context, userHandle []byte) (int, []webauthn.Credential, error) {
+	for userId, handle := range m.handles {
+		if bytes.Equal(handle, userHandle) {
+			_, credentials, err := m.GetPasskeyCredentials(ctx, userId)
+			return userId, credentials, err
+		}
+	}
+	return -1, nil, nil
+}
+
+func (m *memoryStore) AddPasskey(ctx context.Context, userId int, userHandle []byte, name string, credential webauthn.Credential) (int, error) {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return 0, err
+	}
+	m.handles[userId] = userHandle
+	m.credentials[userId] = append(m.credentials[userId], data)
+	return len(m.credentials[userId]), nil
+}
+
+func (m *memoryStore) UpdatePasskeyCredential(ctx context.Context, credential webauthn.Credential) error {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	for userId, stored := range m.credentials {
+		for i := range stored {
+			var c webauthn.Credential
+			if err := json.Unmarshal(stored[i], &c); err != nil {
+				return err
+			}
+			if bytes.Equal(c.ID, credential.ID) {
+				m.credentials[userId][i] = data
+				m.updates++
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) signCount(t *testing.T, userId int) uint32 {
+	t.Helper()
+	_, credentials, err := m.GetPasskeyCredentials(context.Background(), userId)
+	if err != nil || len(credentials) == 0 {
+		t.Fatalf("no credentials for user %d: %v", userId, err)
+	}
+	return credentials[0].Authenticator.SignCount
+}
+
+// softwareAuthenticator is a passkey held in memory: an ES256 key that answers the ceremonies the way
+// a platform authenticator would, attesting with "none"
+type softwareAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	id        []byte
+	handle    []byte
+	signCount uint32
+	origin    string
+	flags     byte
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &softwareAuthenticator{key: key, id: id, origin: testOrigin, flags: flagUserPresent | flagUserVerified}
+}
+
+func (a *softwareAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	var data bytes.Buffer
+	data.Write(rpIDHash[:])
+	data.WriteByte(flags)
+	binary.Write(&data, binary.BigEndian, a.signCount)
+	data.Write(attested)
+	return data.Bytes()
+}
+
+func (a *softwareAuthenticator) clientData(t *testing.T, ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"type": ceremony, "challenge": challenge.String(), "origin": a.origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// create answers navigator.credentials.create() with a new passkey for the user in options
+func (a *softwareAuthenticator) create(t *testing.T, options *protocol.CredentialCreation) []byte {
+	t.Helper()
+	handle, ok := options.Response.User.ID.(protocol.URLEncodedBase64)
+	if !ok {
+		t.Fatalf("user handle is %T", options.Response.User.ID)
+	}
+	a.handle = handle
+	publicKey, err := webauthncbor.Marshal(map[int]any{
+		1: 2, 3: -7, -1: 1, // EC2 key, ES256, P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attested bytes.Buffer
+	attested.Write(make([]byte, 16)) // AAGUID
+	binary.Write(&attested, binary.BigEndian, uint16(len(a.id)))
+	attested.Write(a.id)
+	attested.Write(publicKey)
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(a.flags|flagAttested, attested.Bytes()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.response(t, map[string]any{
+		"clientDataJSON":    b64.EncodeToString(a.clientData(t, "webauthn.create", options.Response.Challenge)),
+		"attestationObject": b64.EncodeToString(attestation),
+	})
+}
+
+// get answers navigator.credentials.get(), signing the challenge in options
+func (a *softwareAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion) []byte {
+	t.Helper()
+	a.signCount++
+	authenticatorData := a.authenticatorData(a.flags, nil)
+	clientData := a.clientData(t, "webauthn.get", options.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authenticatorData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.response(t, map[string]any{
+		"clientDataJSON":    b64.EncodeToString(clientData),
+		"authenticatorData": b64.EncodeToString(authenticatorData),
+		"signature":         b64.EncodeToString(signature),
+		"userHandle":        b64.EncodeToString(a.handle),
+	})
+}
+
+func (a *softwareAuthenticator) response(t *testing.T, response map[string]any) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{
+		"id":       b64.EncodeToString(a.id),
+		"rawId":    b64.EncodeToString(a.id),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func newPasskeys(t *testing.T, store Store) *Passkeys {
+	t.Helper()
+	p, err := New(Config(testAppURL), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// register adds a passkey held by a new software authenticator for userId
+func register(t *testing.T, p *Passkeys, userId int) *softwareAuthenticator {
+	t.Helper()
+	ctx := context.Background()
+	options, state, err := p.BeginRegistration(ctx, userId, "user@example.com", "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := newSoftwareAuthenticator(t)
+	if _, err := p.FinishRegistration(ctx, userId, state, bytes.NewReader(authenticator.create(t, options)), "Test"); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	return authenticator
+}
+
+// login signs in with authenticator and returns what FinishLogin does
+func login(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) (int, error) {
+	t.Helper()
+	options, state, err := p.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.FinishLogin(context.Background(), state, bytes.NewReader(authenticator.get(t, options)))
+}
+
+// expire moves the deadline in ceremony state into the past
+func expire(t *testing.T, state []byte) []byte {
+	t.Helper()
+	var session webauthn.SessionData
+	if err := json.Unmarshal(state, &session); err != nil {
+		t.Fatal(err)
+	}
+	session.Expires = time.Now().Add(-time.Second)
+	expired, err := json.Marshal(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expired
+}
+
+func TestConfig(t *testing.T) {
+	config := Config("https://lists.example.com:8443/app")
+	if config.RPID != "lists.example.com" {
+		t.Errorf("RPID = %q", config.RPID)
+	}
+	if len(config.RPOrigins) != 1 || config.RPOrigins[0] != "https://lists.example.com:8443" {
+		t.Errorf("RPOrigins = %v", config.RPOrigins)
+	}
+	if _, err := New(Config("not a url"), newMemoryStore()); err == nil {
+		t.Error("New accepted a config without a host name")
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	store := newMemoryStore()
+	p := newPasskeys(t, store)
+	authenticator := register(t, p, 7)
+
+	userId, err := login(t, p, authenticator)
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if userId != 7 {
+		t.Errorf("signed in user %d, want 7", userId)
+	}
+	if store.updates != 1 || store.signCount(t, 7) != 1 {
+		t.Errorf("sign count was not saved: %d updates, count %d", store.updates, store.signCount(t, 7))
+	}
+	if _, err := login(t, p, authenticator); err != nil {
+		t.Errorf("second sign-in: %v", err)
+	}
+}
+
+func TestRegistrationExcludesExistingPasskeys(t *testing.T) {
+	p := newPasskeys(t, newMemoryStore())
+	authenticator := register(t, p, 7)
+
+	options, _, err := p.BeginRegistration(context.Background(), 7, "user@example.com", "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	excluded := options.Response.CredentialExcludeList
+	if len(excluded) != 1 || !bytes.Equal(excluded[0].CredentialID, authenticator.id) {
+		t.Errorf("excluded %v, want the registered passkey", excluded)
+	}
+	if handle := options.Response.User.ID.(protocol.URLEncodedBase64); !bytes.Equal(handle, authenticator.handle) {
+		t.Error("a second passkey got a different user handle")
+	}
+}
+
+func TestRegistrationRejectsChangedUserHandle(t *testing.T) {
+	ctx := context.Background()
+	p := newPasskeys(t, newMemoryStore())
+	// Two registrations start before either finishes, so each picks its own handle
+	firstOptions, firstState, err := p.BeginRegistration(ctx, 7, "user@example.com", "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondOptions, secondState, err := p.BeginRegistration(ctx, 7, "user@example.com", "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := newSoftwareAuthenticator(t)
+	if _, err := p.FinishRegistration(ctx, 7, firstState, bytes.NewReader(first.create(t, firstOptions)), "First"); err != nil {
+		t.Fatal(err)
+	}
+	second := newSoftwareAuthenticator(t)
+	_, err = p.FinishRegistration(ctx, 7, secondState, bytes.NewReader(second.create(t, secondOptions)), "Second")
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("got %v, want ErrRejected", err)
+	}
+}
+
+func TestRegistrationRejectsBadResponses(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		respond func(t *testing.T, options *protocol.CredentialCreation, state []byte) ([]byte, []byte)
+	}{
+		{"wrong origin", func(t *testing.T, options *protocol.CredentialCreation, state []byte) ([]byte, []byte) {
+			authenticator := newSoftwareAuthenticator(t)
+			authenticator.origin = "https://phishing.example.net"
+			return state, authenticator.create(t, options)
+		}},
+		{"expired challenge", func(t *testing.T, options *protocol.CredentialCreation, state []byte) ([]byte, []byte) {
+			return expire(t, state), newSoftwareAuthenticator(t).create(t, options)
+		}},
+		{"no user verification", func(t *testing.T, options *protocol.CredentialCreation, state []byte) ([]byte, []byte) {
+			authenticator := newSoftwareAuthenticator(t)
+			authenticator.flags = flagUserPresent
+			return state, authenticator.create(t, options)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			p := newPasskeys(t, store)
+			options, state, err := p.BeginRegistration(ctx, 7, "user@example.com", "User")
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, body := tt.respond(t, options, state)
+			if _, err := p.FinishRegistration(ctx, 7, state, bytes.NewReader(body), "Test"); !errors.Is(err, ErrRejected) {
+				t.Errorf("got %v, want ErrRejected", err)
+			}
+			if len(store.credentials[7]) != 0 {
+				t.Error("a rejected passkey was stored")
+			}
+		})
+	}
+}
+
+func TestLoginRejectsBadResponses(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// respond signs in with a registered passkey, returning the state and response to finish with
+		respond func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte)
+		// wantUser is who the attempt is recorded against: the passkey's owner once it is found, else -1
+		wantUser int
+	}{
+		{"replayed challenge", func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte) {
+			stale, _, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, state, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return state, authenticator.get(t, stale)
+		}, 7},
+		{"replayed response", func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte) {
+			options, state, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := authenticator.get(t, options)
+			if _, err := p.FinishLogin(ctx, state, bytes.NewReader(body)); err != nil {
+				t.Fatalf("first use: %v", err)
+			}
+			return state, body
+		}, 7},
+		{"expired challenge", func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte) {
+			options, state, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return expire(t, state), authenticator.get(t, options)
+		}, -1},
+		{"wrong origin", func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte) {
+			options, state, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			authenticator.origin = "https://phishing.example.net"
+			return state, authenticator.get(t, options)
+		}, 7},
+		{"no user verification", func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte) {
+			options, state, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			authenticator.flags = flagUserPresent
+			return state, authenticator.get(t, options)
+		}, 7},
+		{"bad signature", func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte) {
+			options, state, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			authenticator.key = other
+			return state, authenticator.get(t, options)
+		}, 7},
+		{"unknown credential", func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte) {
+			options, state, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			stranger := newSoftwareAuthenticator(t)
+			stranger.handle = []byte("nobody")
+			return state, stranger.get(t, options)
+		}, -1},
+		{"cloned passkey", func(t *testing.T, p *Passkeys, authenticator *softwareAuthenticator) ([]byte, []byte) {
+			if _, err := login(t, p, authenticator); err != nil {
+				t.Fatal(err)
+			}
+			// The copy carries on from an older signature count
+			authenticator.signCount = 0
+			options, state, err := p.BeginLogin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return state, authenticator.get(t, options)
+		}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			p := newPasskeys(t, store)
+			authenticator := register(t, p, 7)
+			state, body := tt.respond(t, p, authenticator)
+			updates := store.updates
+
+			userId, err := p.FinishLogin(ctx, state, bytes.NewReader(body))
+			if !errors.Is(err, ErrRejected) {
+				t.Fatalf("got user %d, %v, want ErrRejected", userId, err)
+			}
+			if userId != tt.wantUser {
+				t.Errorf("rejected attempt was recorded against user %d, want %d", userId, tt.wantUser)
+			}
+			if store.updates != updates {
+				t.Error("a rejected sign-in updated the passkey")
+			}
+		})
+	}
+}
+
+func TestTooManyPasskeys(t *testing.T) {
+	ctx := context.Background()
+	p := newPasskeys(t, newMemoryStore())
+	for i := 0; i < constants.PASSKEY_MAX_PER_USER; i++ {
+		register(t, p, 7)
+	}
+	if _, _, err := p.BeginRegistration(ctx, 7, "user@example.com", "User"); !errors.Is(err, ErrTooManyPasskeys) {
+		t.Errorf("got %v, want ErrTooManyPasskeys", err)
+	}
+	// The limit is per user
+	register(t, p, 8)
+}
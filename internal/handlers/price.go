@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/budget", middleware.Chain(listBudgetHandler, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...))
+}
+
+func listBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		listBudgetPUT(w, r)
+	case "DELETE":
+		listBudgetDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+// listBudget is a list's budget as a decimal amount, e.g. "250.00", in an ISO 4217 currency
+type listBudget struct {
+	Amount   string `json:"amount" openapi:"required,nonempty"`
+	Currency string `json:"currency" openapi:"required,nonempty"`
+}
+
+// apiPriceTotal is a constants.PriceTotal with its amounts as decimals
+type apiPriceTotal struct {
+	Currency    string `json:"currency"`
+	Items       int    `json:"items"`
+	Total       string `json:"total"`
+	StillNeeded string `json:"stillNeeded"`
+}
+
+// apiTotals is what a list or collection adds up to; lists also report their budget
+type apiTotals struct {
+	Totals []apiPriceTotal `json:"totals"`
+	Budget *listBudget     `json:"budget,omitempty"`
+}
+
+/* Set a list's budget */
+func listBudgetPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	var params listBudget
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	amount, currency, err := parsePrice(params.Amount, params.Currency)
+	if err != nil {
+		http.Error(w, "Invalid budget: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !amount.Valid {
+		http.Error(w, "Amount is required", http.StatusBadRequest)
+		return
+	}
+	err = repo.SetListBudget(r.Context(), listId, amount, currency)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Clear a list's budget */
+func listBudgetDELETE(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	err := repo.SetListBudget(r.Context(), listId, sql.NullInt64{}, sql.NullString{})
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Price totals and budget of a list JSON */
+func apiListTotalsGET(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	totals, err := repo.GetListTotals(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	response := toApiTotals(totals)
+	if list.Budget.Valid {
+		response.Budget = &listBudget{Amount: constants.FormatAmount(list.Budget.Int64, list.BudgetCurrency.String), Currency: list.BudgetCurrency.String}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/* Price totals across the lists in a collection JSON */
+func apiCollectionTotalsGET(w http.ResponseWriter, r *http.Request) {
+	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
+	totals, err := repo.GetCollectionTotals(r.Context(), collectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiTotals(totals))
+}
+
+func toApiTotals(totals []constants.PriceTotal) apiTotals {
+	response := apiTotals{Totals: make([]apiPriceTotal, 0, len(totals))}
+	for _, t := range totals {
+		response.Totals = append(response.Totals, apiPriceTotal{
+			Currency:    t.Currency,
+			Items:       t.Items,
+			Total:       constants.FormatAmount(t.Total, t.Currency),
+			StillNeeded: constants.FormatAmount(t.StillNeeded, t.Currency),
+		})
+	}
+	return response
+}
+
+// parsePrice reads a decimal amount and its currency code. A blank amount means no price;
+// otherwise the currency must be a three-letter code.
+func parsePrice(amount string, currency string) (sql.NullInt64, sql.NullString, error) {
+	if strings.TrimSpace(amount) == "" {
+		return sql.NullInt64{}, sql.NullString{}, nil
+	}
+	code, ok := constants.NormalizeCurrency(currency)
+	if !ok {
+		return sql.NullInt64{}, sql.NullString{}, fmt.Errorf("currency %q is not a three-letter ISO 4217 code", currency)
+	}
+	minor, err := constants.ParseAmount(amount, code)
+	if err != nil {
+		return sql.NullInt64{}, sql.NullString{}, err
+	}
+	return sql.NullInt64{Int64: minor, Valid: true}, sql.NullString{String: code, Valid: true}, nil
+}
+
+// itemSortParams reads the sort and order query parameters of an items request, writing a 400 and returning false if either is unknown
+func itemSortParams(w http.ResponseWriter, r *http.Request) (string, bool, bool) {
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = constants.ITEM_SORT_POSITION
+	}
+	if !slices.Contains(constants.ITEM_SORTS, sortBy) {
+		http.Error(w, "sort must be one of "+strings.Join(constants.ITEM_SORTS, ", "), http.StatusBadRequest)
+		return "", false, false
+	}
+	order := r.URL.Query().Get("order")
+	if order != "" && order != "asc" && order != "desc" {
+		http.Error(w, "order must be asc or desc", http.StatusBadRequest)
+		return "", false, false
+	}
+	return sortBy, order == "desc", true
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/jeffrpowell/listaway/internal/database"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+// repo is the database access shared by every handler, injected at startup
+var repo *database.Repository
+
+// SetRepository hands the shared repository to the handlers, their middleware and the passkey ceremonies.
+// Must be called before the server starts accepting requests.
+func SetRepository(r *database.Repository) {
+	repo = r
+	middleware.SetRepository(r)
+	initPasskeys(r)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/database"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// Anyone who can view a list can see its history; reverting a change takes edit access
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/history", middleware.Chain(listHistoryGET, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/history/{revisionId:[0-9]+}/revert", middleware.Chain(revisionRevertPOST, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("POST")
+}
+
+type apiRevision struct {
+	Id         uint64           `json:"id"`
+	ItemId     *uint64          `json:"itemId"` // null for changes to the list itself
+	Actor      string           `json:"actor,omitempty"`
+	Action     string           `json:"action" openapi:"enum=create|update|delete|restore"`
+	Subject    string           `json:"subject"` // the item's or list's name
+	Changes    []apiFieldChange `json:"changes"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	Revertible bool             `json:"revertible"`
+}
+
+type apiFieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+/* List history page */
+func listHistoryGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	list, err := repo.GetList(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	revisions, err := repo.GetRevisions(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	canEdit, err := repo.UserCanEditList(r.Context(), userId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.ListHistoryPage(w, web.ListHistoryPageParams(r, list, revisions, canEdit, admin, instanceAdmin))
+}
+
+/* History of a list and its items */
+func apiListHistoryGET(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	revisions, err := repo.GetRevisions(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	result := make([]apiRevision, 0, len(revisions))
+	for _, rev := range revisions {
+		result = append(result, toApiRevision(rev))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+/* Revert a change, itself recorded as a new change */
+func revisionRevertPOST(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	userId, _ := helper.GetUserId(r) // err already tripped in requireListEdit
+	revisionId, err := helper.GetPathVarInt(r, "revisionId")
+	if err != nil {
+		http.Error(w, "Invalid revisionId supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	found, err := repo.RevertRevision(r.Context(), userId, listId, revisionId)
+	if errors.Is(err, database.ErrNotRevertible) {
+		http.Error(w, "This change can no longer be reverted", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !found {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toApiRevision(rev constants.Revision) apiRevision {
+	result := apiRevision{
+		Id:         rev.Id,
+		Actor:      rev.ActorName.String,
+		Action:     rev.Action,
+		Subject:    rev.Subject,
+		Changes:    make([]apiFieldChange, 0, len(rev.Changes)),
+		CreatedAt:  rev.CreatedAt,
+		Revertible: rev.Revertible,
+	}
+	if rev.ItemId.Valid {
+		itemId := uint64(rev.ItemId.Int64)
+		result.ItemId = &itemId
+	}
+	for _, c := range rev.Changes {
+		result.Changes = append(result.Changes, apiFieldChange{Field: c.Field, Before: c.Before, After: c.After})
+	}
+	return result
+}
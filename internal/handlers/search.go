@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// maxSearchQueryLength caps the search text, in characters
+const maxSearchQueryLength = 200
+
+func init() {
+	constants.ROUTER.HandleFunc("/search", middleware.DefaultMiddlewareChain(searchGET)).Methods("GET")
+}
+
+type apiSearchResults struct {
+	Lists       []apiSearchList `json:"lists"`
+	Items       []apiSearchItem `json:"items"`
+	Collections []apiCollection `json:"collections"`
+}
+
+type apiSearchList struct {
+	Id          uint64   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	OwnerId     uint64   `json:"ownerId"`
+	OwnerName   string   `json:"ownerName"`
+	Owned       bool     `json:"owned"` // false for lists shared with the user's group
+}
+
+type apiSearchItem struct {
+	Id       uint64   `json:"id"`
+	ListId   uint64   `json:"listId"`
+	ListName string   `json:"listName"`
+	Name     string   `json:"name"`
+	URL      string   `json:"url,omitempty"`
+	Notes    string   `json:"notes,omitempty"`
+	Tags     []string `json:"tags"`
+	Owned    bool     `json:"owned"` // false for items on lists shared with the user's group
+}
+
+// runSearch reads ?q= and searches what the user can see. An empty query searches nothing and
+// returns no results; a query that is too long writes a 400 and returns ok false.
+func runSearch(w http.ResponseWriter, r *http.Request) (query string, results constants.SearchResults, ok bool) {
+	query = strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		return query, results, true
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		http.Error(w, "Search text is too long", http.StatusBadRequest)
+		return query, results, false
+	}
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return query, results, false
+	}
+	results, err = repo.Search(r.Context(), userId, query)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return query, results, false
+	}
+	return query, results, true
+}
+
+/* Search page */
+func searchGET(w http.ResponseWriter, r *http.Request) {
+	query, results, ok := runSearch(w, r)
+	if !ok {
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.SearchPage(w, web.SearchPageParams(r, query, results, admin, instanceAdmin))
+}
+
+/* Search the token user's lists, group-shared lists, their items and the user's collections */
+func apiSearchGET(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimSpace(r.URL.Query().Get("q")) == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	_, results, ok := runSearch(w, r)
+	if !ok {
+		return
+	}
+	response := apiSearchResults{
+		Lists:       make([]apiSearchList, 0, len(results.Lists)),
+		Items:       make([]apiSearchItem, 0, len(results.Items)),
+		Collections: make([]apiCollection, 0, len(results.Collections)),
+	}
+	for _, l := range results.Lists {
+		response.Lists = append(response.Lists, apiSearchList{
+			Id:          l.Id,
+			Name:        l.Name,
+			Description: l.Description.String,
+			Tags:        append([]string{}, l.Tags...),
+			OwnerId:     l.OwnerId,
+			OwnerName:   l.OwnerName,
+			Owned:       l.Owned,
+		})
+	}
+	for _, i := range results.Items {
+		response.Items = append(response.Items, apiSearchItem{
+			Id:       i.Id,
+			ListId:   i.ListId,
+			ListName: i.ListName,
+			Name:     i.Name,
+			URL:      i.URL.String,
+			Notes:    i.Notes.String,
+			Tags:     append([]string{}, i.Tags...),
+			Owned:    i.Owned,
+		})
+	}
+	for _, c := range results.Collections {
+		response.Collections = append(response.Collections, toApiCollection(c))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+func init() {
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/section", middleware.Chain(sectionsHandler, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...))
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/section/{sectionId:[0-9]+}", middleware.Chain(sectionHandler, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...))
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/item/{itemId:[0-9]+}/section", middleware.Chain(itemSectionPUT, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("PUT")
+}
+
+func sectionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		sectionsGET(w, r)
+	case "PUT":
+		sectionPUT(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func sectionHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		sectionPOST(w, r)
+	case "DELETE":
+		sectionDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+// sectionParams names a section when creating or renaming it
+type sectionParams struct {
+	Name string `json:"name" openapi:"required,nonempty"`
+}
+
+// itemSection moves an item into a section; a null sectionId moves it out of every section
+type itemSection struct {
+	SectionId *uint64 `json:"sectionId"`
+}
+
+/* Sections in a list JSON */
+func sectionsGET(w http.ResponseWriter, r *http.Request) {
+	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
+	sections, err := repo.GetListSections(r.Context(), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if sections == nil {
+		sections = []constants.ItemSection{}
+	}
+	writeJSON(w, http.StatusOK, sections)
+}
+
+/* Create section */
+func sectionPUT(w http.ResponseWriter, r *http.Request) {
+	section, ok := createSection(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusCreated, section)
+}
+
+/* Rename section */
+func sectionPOST(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	sectionId, ok := requireSectionInList(w, r, listId)
+	if !ok {
+		return
+	}
+	var params sectionParams
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	err := repo.RenameSection(r.Context(), sectionId, params.Name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	section, err := repo.GetSection(r.Context(), sectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, section)
+}
+
+/* Delete section; its items stay in the list outside any section */
+func sectionDELETE(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	sectionId, ok := requireSectionInList(w, r, listId)
+	if !ok {
+		return
+	}
+	err := repo.DeleteSection(r.Context(), sectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Move an item into or out of a section */
+func itemSectionPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	itemId, ok := requireApiItemInList(w, r, listId)
+	if !ok {
+		return
+	}
+	var move itemSection
+	if !decodeJSON(w, r, &move) {
+		return
+	}
+	var sectionId sql.NullInt64
+	if move.SectionId != nil {
+		sectionId = sql.NullInt64{Int64: int64(*move.SectionId), Valid: true}
+	}
+	if !requireItemSection(w, r, listId, sectionId) {
+		return
+	}
+	err := repo.MoveItemToSection(r.Context(), itemId, sectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createSection adds the section named in the JSON body to the list in the path, writing any error response itself
+func createSection(w http.ResponseWriter, r *http.Request) (constants.ItemSection, bool) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return constants.ItemSection{}, false
+	}
+	var params sectionParams
+	if !decodeJSON(w, r, &params) {
+		return constants.ItemSection{}, false
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return constants.ItemSection{}, false
+	}
+	sectionId, err := repo.CreateSection(r.Context(), listId, params.Name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return constants.ItemSection{}, false
+	}
+	section, err := repo.GetSection(r.Context(), sectionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return constants.ItemSection{}, false
+	}
+	return section, true
+}
+
+// requireSectionInList writes a 404 and returns false unless the section in the path belongs to the list
+func requireSectionInList(w http.ResponseWriter, r *http.Request, listId int) (int, bool) {
+	sectionId, err := helper.GetPathVarInt(r, "sectionId")
+	if err != nil {
+		http.Error(w, "Invalid sectionId supplied in path", http.StatusBadRequest)
+		return 0, false
+	}
+	inList, err := repo.SectionInList(r.Context(), sectionId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return 0, false
+	}
+	if !inList {
+		http.Error(w, "Section not found", http.StatusNotFound)
+		return 0, false
+	}
+	return sectionId, true
+}
+
+// requireItemSection writes a 400 and returns false if an item is being put in a section of another list
+func requireItemSection(w http.ResponseWriter, r *http.Request, listId int, sectionId sql.NullInt64) bool {
+	if !sectionId.Valid {
+		return true
+	}
+	inList, err := repo.SectionInList(r.Context(), int(sectionId.Int64), listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return false
+	}
+	if !inList {
+		http.Error(w, fmt.Sprintf("Section %d does not belong to this list", sectionId.Int64), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
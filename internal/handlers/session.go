@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// Every browser a user is signed in on has its own session, which they can sign out from here
+func init() {
+	constants.ROUTER.HandleFunc("/sessions", middleware.DefaultMiddlewareChain(sessionsHandler))
+	constants.ROUTER.HandleFunc("/sessions/{sessionId:[0-9]+}", middleware.DefaultMiddlewareChain(sessionDELETE)).Methods("DELETE")
+}
+
+func sessionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		sessionsGET(w, r)
+	case "DELETE":
+		sessionsDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+/* Your sessions page */
+func sessionsGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	sessions, err := repo.GetUserSessions(r.Context(), userId, session.ID)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.SessionsPage(w, web.SessionsPageParams(r, sessions, admin, instanceAdmin))
+}
+
+/* Sign out every other session */
+func sessionsDELETE(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	count, err := repo.DeleteOtherUserSessions(r.Context(), userId, session.ID)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if count > 0 {
+		audit(r, constants.AUDIT_SESSION_REVOKE, nil, fmt.Sprintf("All other sessions (%d)", count))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Sign out one session; signing out the current one works like logging out */
+func sessionDELETE(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	sessionId, err := helper.GetPathVarInt(r, "sessionId")
+	if err != nil {
+		http.Error(w, "Invalid sessionId supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	userAgent, deleted, err := repo.DeleteUserSession(r.Context(), userId, sessionId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	audit(r, constants.AUDIT_SESSION_REVOKE, nil, constants.Session{UserAgent: userAgent}.Device())
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"log"
@@ -8,7 +9,6 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/jeffrpowell/listaway/internal/constants"
-	"github.com/jeffrpowell/listaway/internal/database"
 	"github.com/jeffrpowell/listaway/internal/handlers/helper"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
 	"github.com/jeffrpowell/listaway/web"
@@ -37,7 +37,7 @@ func listShareHandler(w http.ResponseWriter, r *http.Request) {
 /* Create share link */
 func listSharePUT(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
-	code, err := database.GenerateShareCode(listId)
+	code, err := repo.GenerateShareCode(r.Context(), listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -49,7 +49,7 @@ func listSharePUT(w http.ResponseWriter, r *http.Request) {
 /* Unpublish */
 func listShareDELETE(w http.ResponseWriter, r *http.Request) {
 	listId, _ := helper.GetPathVarInt(r, "listId") //err will trip in listIdOwner middleware first
-	err := database.UnpublishShareCode(listId)
+	err := repo.UnpublishShareCode(r.Context(), listId)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -61,7 +61,7 @@ func listShareDELETE(w http.ResponseWriter, r *http.Request) {
 /* View shared list */
 func shareGET(w http.ResponseWriter, r *http.Request) {
 	shareCode := mux.Vars(r)["shareCode"]
-	list, err := database.GetListFromShareCode(shareCode)
+	list, err := repo.GetListFromShareCode(r.Context(), shareCode)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			sharedList404Page := web.SharedList404PageParams(shareCode)
@@ -72,22 +72,34 @@ func shareGET(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	items, err := database.GetListItems(int(list.Id))
+	items, err := repo.GetListItems(r.Context(), int(list.Id))
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
-	sharedListItemsPage := web.SharedListItemsPageParams(r, shareCode, list, items, admin, instanceAdmin)
+	sections, err := repo.GetListSections(r.Context(), int(list.Id))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	claimedItemIds, err := getClaimedItemIds(r.Context(), list)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	sharedListItemsPage := web.SharedListItemsPageParams(r, shareCode, list, items, sections, claimedItemIds, admin, instanceAdmin)
 	web.SharedListItemsPage(w, sharedListItemsPage)
 }
 
 /* Shared list items JSON */
 func sharedItemsGET(w http.ResponseWriter, r *http.Request) {
 	shareCode := mux.Vars(r)["shareCode"]
-	list, err := database.GetListFromShareCode(shareCode)
+	list, err := repo.GetListFromShareCode(r.Context(), shareCode)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			sharedList404Page := web.SharedList404PageParams(shareCode)
@@ -98,7 +110,7 @@ func sharedItemsGET(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 		return
 	}
-	items, err := database.GetListItems(int(list.Id))
+	items, err := repo.GetListItems(r.Context(), int(list.Id))
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -118,7 +130,7 @@ func nestedShareGET(w http.ResponseWriter, r *http.Request) {
 	collectionShareCode := vars["collectionShareCode"]
 
 	// Verify that the collection exists
-	collection, err := database.GetCollectionFromShareCode(collectionShareCode)
+	collection, err := repo.GetCollectionFromShareCode(r.Context(), collectionShareCode)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			sharedCollection404Page := web.SharedCollection404PageParams(collectionShareCode)
@@ -131,7 +143,7 @@ func nestedShareGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the list from the share code
-	list, err := database.GetListFromShareCode(listShareCode)
+	list, err := repo.GetListFromShareCode(r.Context(), listShareCode)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			sharedList404Page := web.SharedList404PageParams(listShareCode)
@@ -144,7 +156,7 @@ func nestedShareGET(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify the list belongs to the collection
-	belongs, err := database.ListInCollection(int(collection.Id), int(list.Id))
+	belongs, err := repo.ListInCollection(r.Context(), int(collection.Id), int(list.Id))
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -158,18 +170,31 @@ func nestedShareGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	items, err := database.GetListItems(int(list.Id))
+	items, err := repo.GetListItems(r.Context(), int(list.Id))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+
+	sections, err := repo.GetListSections(r.Context(), int(list.Id))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	claimedItemIds, err := getClaimedItemIds(r.Context(), list)
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
 		return
 	}
 
-	admin := helper.IsUserAdmin(r)
-	instanceAdmin := helper.IsUserInstanceAdmin(r)
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
 
 	// Render with collection context
-	sharedListItemsPage := web.NestedSharedListItemsPageParams(r, listShareCode, collectionShareCode, list, items, admin, instanceAdmin)
+	sharedListItemsPage := web.NestedSharedListItemsPageParams(r, listShareCode, collectionShareCode, list, items, sections, claimedItemIds, admin, instanceAdmin)
 	web.SharedListItemsPage(w, sharedListItemsPage)
 }
 
@@ -179,7 +204,7 @@ func nestedSharedItemsGET(w http.ResponseWriter, r *http.Request) {
 	listShareCode := vars["listShareCode"]
 
 	// Get the list from the share code
-	list, err := database.GetListFromShareCode(listShareCode)
+	list, err := repo.GetListFromShareCode(r.Context(), listShareCode)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			http.Error(w, "List not found", http.StatusNotFound)
@@ -190,7 +215,7 @@ func nestedSharedItemsGET(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	items, err := database.GetListItems(int(list.Id))
+	items, err := repo.GetListItems(r.Context(), int(list.Id))
 	if err != nil {
 		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
 		log.Print(err)
@@ -203,3 +228,11 @@ func nestedSharedItemsGET(w http.ResponseWriter, r *http.Request) {
 		log.Print(err)
 	}
 }
+
+// getClaimedItemIds returns the claimed item ids for lists in claiming mode, and nothing otherwise
+func getClaimedItemIds(ctx context.Context, list constants.List) ([]uint64, error) {
+	if !list.Claimable {
+		return nil, nil
+	}
+	return repo.GetClaimedItemIds(ctx, int(list.Id))
+}
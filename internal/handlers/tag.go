@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+)
+
+func init() {
+	constants.ROUTER.HandleFunc("/tags", middleware.DefaultMiddlewareChain(tagsGET)).Methods("GET")
+	constants.ROUTER.HandleFunc("/list/{listId:[0-9]+}/tags", middleware.Chain(listTagsPUT, append([]middleware.Middleware{middleware.ListIdOwner("listId")}, middleware.DefaultMiddlewareSlice...)...)).Methods("PUT")
+}
+
+// listTags is a list's full set of tags; sending an empty array clears them
+type listTags struct {
+	Tags []string `json:"tags" openapi:"required"`
+}
+
+// apiTaggedItem is an item found by tag across lists, with the name of the list it is on
+type apiTaggedItem struct {
+	apiItem
+	ListName string `json:"listName"`
+}
+
+// tagParam reads the optional ?tag= filter, normalized the way tags are stored; empty means no filter
+func tagParam(r *http.Request) string {
+	return constants.NormalizeTag(r.URL.Query().Get("tag"))
+}
+
+// hasTag reports whether tags, already normalized, include tag; every list has the empty tag
+func hasTag(tags []string, tag string) bool {
+	return tag == "" || slices.Contains(tags, tag)
+}
+
+/* Tag index of the user's lists and items JSON */
+func tagsGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	tags, err := repo.GetUserTags(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if tags == nil {
+		tags = []constants.Tag{}
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+/* Replace a list's tags */
+func listTagsPUT(w http.ResponseWriter, r *http.Request) {
+	listId, ok := requireListEdit(w, r)
+	if !ok {
+		return
+	}
+	var params listTags
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+	tags, err := constants.NormalizeTags(params.Tags)
+	if err != nil {
+		http.Error(w, "Invalid tags: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = repo.SetListTags(r.Context(), listId, tags)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Items carrying a tag across the token user's lists */
+func apiTaggedItemsGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	tag := tagParam(r)
+	if tag == "" {
+		http.Error(w, "tag is required", http.StatusBadRequest)
+		return
+	}
+	items, err := repo.GetUserTaggedItems(r.Context(), userId, tag)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiTaggedItems(items))
+}
+
+/* Items carrying a tag across the lists in a collection */
+func apiCollectionTaggedItemsGET(w http.ResponseWriter, r *http.Request) {
+	collectionId, _ := helper.GetPathVarInt(r, "collectionId") // Error already checked in middleware
+	tag := tagParam(r)
+	if tag == "" {
+		http.Error(w, "tag is required", http.StatusBadRequest)
+		return
+	}
+	items, err := repo.GetCollectionTaggedItems(r.Context(), collectionId, tag)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toApiTaggedItems(items))
+}
+
+func toApiTaggedItems(items []constants.TaggedItem) []apiTaggedItem {
+	result := make([]apiTaggedItem, 0, len(items))
+	for _, i := range items {
+		result = append(result, apiTaggedItem{apiItem: toApiItem(i.ListId, i.Item), ListName: i.ListName})
+	}
+	return result
+}
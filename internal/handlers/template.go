@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// List templates belong to a group: its admins curate them, and everyone in the group can start a list from one
+func init() {
+	constants.ROUTER.HandleFunc("/admin/templates", middleware.Chain(templatesHandler, append([]middleware.Middleware{middleware.RequireAdmin()}, middleware.DefaultMiddlewareSlice...)...))
+	constants.ROUTER.HandleFunc("/admin/templates/{templateId:[0-9]+}", middleware.Chain(templateDELETE, append([]middleware.Middleware{middleware.RequireAdmin()}, middleware.DefaultMiddlewareSlice...)...)).Methods("DELETE")
+}
+
+func templatesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		templatesGET(w, r)
+	case "PUT":
+		templatePUT(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+type apiTemplate struct {
+	Id          uint64    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ItemCount   int       `json:"itemCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func toApiTemplate(t constants.ListTemplate) apiTemplate {
+	return apiTemplate{
+		Id:          t.Id,
+		Name:        t.Name,
+		Description: t.Description.String,
+		ItemCount:   t.ItemCount,
+		CreatedAt:   t.CreatedAt,
+	}
+}
+
+/* List templates page */
+func templatesGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	groupId, err := repo.GetUserGroupId(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	templates, err := repo.GetGroupTemplates(r.Context(), groupId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	// Templates are made from the admin's own lists
+	lists, err := repo.GetLists(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	params := web.TemplatesPageParams(r, templates, lists, admin, instanceAdmin)
+	web.TemplatesPage(w, params)
+}
+
+/* Create a template from one of the admin's lists */
+func templatePUT(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	listId, err := strconv.Atoi(r.FormValue("listId"))
+	if err != nil {
+		http.Error(w, "Invalid listId supplied", http.StatusBadRequest)
+		return
+	}
+	owns, err := repo.UserOwnsList(r.Context(), userId, listId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !owns {
+		http.Error(w, "Forbidden - templates can only be made from your own lists", http.StatusForbidden)
+		return
+	}
+	groupId, err := repo.GetUserGroupId(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	taken, err := repo.TemplateNameTaken(r.Context(), groupId, name)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if taken {
+		http.Error(w, "Template name already taken", http.StatusConflict)
+		return
+	}
+	if _, err := repo.CreateTemplateFromList(r.Context(), groupId, listId, name); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	audit(r, constants.AUDIT_TEMPLATE_CREATE, nil, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Delete template */
+func templateDELETE(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	templateId, err := helper.GetPathVarInt(r, "templateId")
+	if err != nil {
+		http.Error(w, "Invalid templateId supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	groupId, err := repo.GetUserGroupId(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	name, deleted, err := repo.DeleteGroupTemplate(r.Context(), groupId, templateId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+	audit(r, constants.AUDIT_TEMPLATE_DELETE, nil, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Templates offered to the token user's group */
+func apiTemplatesGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	templates, err := userTemplates(r, userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	result := make([]apiTemplate, 0, len(templates))
+	for _, t := range templates {
+		result = append(result, toApiTemplate(t))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// userTemplates returns the templates offered to the user's group
+func userTemplates(r *http.Request, userId int) ([]constants.ListTemplate, error) {
+	groupId, err := repo.GetUserGroupId(r.Context(), userId)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetGroupTemplates(r.Context(), groupId)
+}
+
+// groupTemplate returns one of the templates of the user's group. If templateId is not one of them
+// it writes a 400 and returns false.
+func groupTemplate(w http.ResponseWriter, r *http.Request, userId int, templateId int) (constants.ListTemplate, bool) {
+	groupId, err := repo.GetUserGroupId(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return constants.ListTemplate{}, false
+	}
+	template, err := repo.GetGroupTemplate(r.Context(), groupId, templateId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Template not found", http.StatusBadRequest)
+			return constants.ListTemplate{}, false
+		}
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return constants.ListTemplate{}, false
+	}
+	return template, true
+}
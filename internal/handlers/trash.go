@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/database"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+// Trash routes only ever touch the signed-in user's own trash, so they need no ownership middleware
+func init() {
+	constants.ROUTER.HandleFunc("/trash", middleware.DefaultMiddlewareChain(trashHandler))
+	constants.ROUTER.HandleFunc("/trash/{kind:(?:lists|items|collections)}/{id:[0-9]+}", middleware.DefaultMiddlewareChain(trashEntryDELETE)).Methods("DELETE")
+	constants.ROUTER.HandleFunc("/trash/{kind:(?:lists|items|collections)}/{id:[0-9]+}/restore", middleware.DefaultMiddlewareChain(trashEntryRestorePOST)).Methods("POST")
+}
+
+func trashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		trashGET(w, r)
+	case "DELETE":
+		trashDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func apiTrashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		apiTrashGET(w, r)
+	case "DELETE":
+		trashDELETE(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+type apiTrash struct {
+	Lists       []apiTrashedList       `json:"lists"`
+	Items       []apiTrashedItem       `json:"items"`
+	Collections []apiTrashedCollection `json:"collections"`
+}
+
+type apiTrashedList struct {
+	Id        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	ItemCount int       `json:"itemCount"` // items that come back with the list
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type apiTrashedItem struct {
+	Id        uint64    `json:"id"`
+	ListId    uint64    `json:"listId"`
+	ListName  string    `json:"listName"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type apiTrashedCollection struct {
+	Id        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+/* Trash page */
+func trashGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	trash, err := repo.GetTrash(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.TrashPage(w, web.TrashPageParams(r, trash, admin, instanceAdmin))
+}
+
+/* The token user's trash */
+func apiTrashGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	trash, err := repo.GetTrash(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	response := apiTrash{
+		Lists:       make([]apiTrashedList, 0, len(trash.Lists)),
+		Items:       make([]apiTrashedItem, 0, len(trash.Items)),
+		Collections: make([]apiTrashedCollection, 0, len(trash.Collections)),
+	}
+	for _, l := range trash.Lists {
+		response.Lists = append(response.Lists, apiTrashedList{Id: l.Id, Name: l.Name, ItemCount: l.ItemCount, DeletedAt: l.DeletedAt})
+	}
+	for _, i := range trash.Items {
+		response.Items = append(response.Items, apiTrashedItem{Id: i.Id, ListId: i.ListId, ListName: i.ListName, Name: i.Name, DeletedAt: i.DeletedAt})
+	}
+	for _, c := range trash.Collections {
+		response.Collections = append(response.Collections, apiTrashedCollection{Id: c.Id, Name: c.Name, DeletedAt: c.DeletedAt})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/* Empty trash; everything in it is deleted for good */
+func trashDELETE(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	err = repo.EmptyTrash(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/* Restore a list, item or collection from the trash */
+func trashEntryRestorePOST(w http.ResponseWriter, r *http.Request) {
+	var restore func(context.Context, int, int) (bool, error)
+	switch mux.Vars(r)["kind"] {
+	case "lists":
+		restore = repo.RestoreList
+	case "items":
+		restore = repo.RestoreItem
+	case "collections":
+		restore = repo.RestoreCollection
+	}
+	trashEntry(w, r, restore)
+}
+
+/* Delete a list, item or collection in the trash for good */
+func trashEntryDELETE(w http.ResponseWriter, r *http.Request) {
+	var purge func(context.Context, int, int) (bool, error)
+	switch mux.Vars(r)["kind"] {
+	case "lists":
+		purge = repo.PurgeTrashedList
+	case "items":
+		purge = repo.PurgeTrashedItem
+	case "collections":
+		purge = repo.PurgeTrashedCollection
+	}
+	trashEntry(w, r, purge)
+}
+
+// trashEntry applies f to the user's trash entry named by the {id} path variable
+func trashEntry(w http.ResponseWriter, r *http.Request, f func(ctx context.Context, userId int, id int) (bool, error)) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	id, err := helper.GetPathVarInt(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid id supplied in path", http.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	found, err := f(r.Context(), userId, id)
+	if errors.Is(err, database.ErrNameTaken) {
+		http.Error(w, "You already have one with that name; rename it first", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !found {
+		http.Error(w, "Not found in trash", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image/png"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/jeffrpowell/listaway/internal/constants"
+	"github.com/jeffrpowell/listaway/internal/handlers/helper"
+	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/web"
+)
+
+const (
+	twoFactorLoginTimeout     = 5 * time.Minute // how long after the password or single sign-on the code may be entered
+	twoFactorLoginMaxAttempts = 5               // wrong codes before signing in has to start over
+	twoFactorQRCodeSize       = 256             // pixels
+)
+
+// Two-factor authentication adds a code from an authenticator app, or a recovery code, to password
+// and single sign-on sign-in. A passkey already counts as both factors.
+func init() {
+	constants.ROUTER.HandleFunc("/auth/2fa", middleware.DefaultPublicMiddlewareChain(twoFactorLoginHandler))
+	constants.ROUTER.HandleFunc("/account/2fa", middleware.DefaultMiddlewareChain(twoFactorHandler))
+	constants.ROUTER.HandleFunc("/account/2fa/setup", middleware.DefaultMiddlewareChain(twoFactorSetupPOST)).Methods("POST")
+	constants.ROUTER.HandleFunc("/account/2fa/recovery", middleware.DefaultMiddlewareChain(recoveryCodesPOST)).Methods("POST")
+	constants.ROUTER.HandleFunc("/account/2fa/disable", middleware.DefaultMiddlewareChain(twoFactorDisablePOST)).Methods("POST")
+	constants.ROUTER.HandleFunc("/admin/grouptwofactor", middleware.Chain(getGroupTwoFactor, append([]middleware.Middleware{middleware.RequireAdmin()}, middleware.DefaultMiddlewareSlice...)...)).Methods("GET")
+	constants.ROUTER.HandleFunc("/admin/grouptwofactor", middleware.Chain(toggleGroupTwoFactor, append([]middleware.Middleware{middleware.RequireAdmin()}, middleware.DefaultMiddlewareSlice...)...)).Methods("POST")
+}
+
+func twoFactorLoginHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		twoFactorLoginGET(w, r)
+	case "POST":
+		twoFactorLoginPOST(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func twoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		twoFactorGET(w, r)
+	case "PUT":
+		twoFactorPUT(w, r)
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+// startTwoFactorLogin records that userId got past firstFactor, such as their password, so the session is only one code away from signing in
+func startTwoFactorLogin(w http.ResponseWriter, r *http.Request, session *sessions.Session, userId int, firstFactor string) error {
+	// Whoever was signed in here before is signed out
+	delete(session.Values, "authenticated")
+	delete(session.Values, "userId")
+	session.Values["twoFactorUserId"] = userId
+	session.Values["twoFactorFirstFactor"] = firstFactor
+	session.Values["twoFactorStartedAt"] = time.Now().Unix()
+	session.Values["twoFactorAttempts"] = 0
+	return session.Save(r, w)
+}
+
+// pendingTwoFactorLogin returns the user waiting to enter a code in this session, if their password is still fresh
+func pendingTwoFactorLogin(session *sessions.Session) (int, bool) {
+	userId, ok := session.Values["twoFactorUserId"].(int)
+	if !ok {
+		return 0, false
+	}
+	startedAt, ok := session.Values["twoFactorStartedAt"].(int64)
+	if !ok || time.Since(time.Unix(startedAt, 0)) > twoFactorLoginTimeout {
+		return 0, false
+	}
+	return userId, true
+}
+
+func clearTwoFactorLogin(session *sessions.Session) {
+	delete(session.Values, "twoFactorUserId")
+	delete(session.Values, "twoFactorFirstFactor")
+	delete(session.Values, "twoFactorStartedAt")
+	delete(session.Values, "twoFactorAttempts")
+}
+
+// checkTwoFactorCode accepts either a code from the user's authenticator app or one of their
+// recovery codes, which is spent. It returns which of the two was used.
+func checkTwoFactorCode(ctx context.Context, userId int, code string) (string, bool, error) {
+	valid, err := repo.VerifyTwoFactorCode(ctx, userId, code)
+	if err != nil {
+		// A secret that can't be decrypted after LISTAWAY_AUTH_KEY changed still leaves the recovery codes
+		log.Print(err)
+	} else if valid {
+		return "authenticator app", true, nil
+	}
+	valid, err = repo.UseRecoveryCode(ctx, userId, code)
+	return "recovery code", valid, err
+}
+
+/* Two-factor code page, shown between the password and signing in */
+func twoFactorLoginGET(w http.ResponseWriter, r *http.Request) {
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	if _, ok := pendingTwoFactorLogin(session); !ok {
+		http.Redirect(w, r, "/auth", http.StatusSeeOther)
+		return
+	}
+	web.TwoFactorLoginPage(w)
+}
+
+/* Finish signing in with a two-factor code */
+func twoFactorLoginPOST(w http.ResponseWriter, r *http.Request) {
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	userId, ok := pendingTwoFactorLogin(session)
+	if !ok {
+		http.Error(w, "Sign in again", http.StatusUnauthorized)
+		return
+	}
+	method, valid, err := checkTwoFactorCode(r.Context(), userId, r.FormValue("code"))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !valid {
+		auditAs(r, -1, constants.AUDIT_LOGIN_FAILED, auditUser(r, userId), "Wrong two-factor code")
+		attempts, _ := session.Values["twoFactorAttempts"].(int)
+		attempts++
+		session.Values["twoFactorAttempts"] = attempts
+		if attempts >= twoFactorLoginMaxAttempts {
+			clearTwoFactorLogin(session)
+			session.Save(r, w)
+			http.Error(w, "Too many wrong codes; sign in again", http.StatusTooManyRequests)
+			return
+		}
+		session.Save(r, w)
+		http.Error(w, "Code is not recognized", http.StatusBadRequest)
+		return
+	}
+	firstFactor, ok := session.Values["twoFactorFirstFactor"].(string)
+	if !ok {
+		firstFactor = "Password"
+	}
+	clearTwoFactorLogin(session)
+	auditAs(r, userId, constants.AUDIT_LOGIN, nil, firstFactor+" and "+method)
+	if err := signIn(w, r, session, userId); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	w.Header().Add("Location", "/list")
+	w.WriteHeader(http.StatusOK)
+}
+
+/* Two-factor authentication settings page */
+func twoFactorGET(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	status, err := repo.GetTwoFactorStatus(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	admin := helper.IsUserAdmin(r, repo)
+	instanceAdmin := helper.IsUserInstanceAdmin(r, repo)
+	web.TwoFactorPage(w, web.TwoFactorPageParams(r, status, admin, instanceAdmin))
+}
+
+/* Start setting up an authenticator app; nothing changes until the first code is confirmed */
+func twoFactorSetupPOST(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	status, err := repo.GetTwoFactorStatus(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if status.Enabled {
+		http.Error(w, "Two-factor authentication is already on", http.StatusConflict)
+		return
+	}
+	user, err := repo.GetUser(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	key, err := repo.BeginTwoFactorSetup(r.Context(), userId, user.Email)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	img, err := key.Image(twoFactorQRCodeSize, twoFactorQRCodeSize)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	var qrCode bytes.Buffer
+	if err := png.Encode(&qrCode, img); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	writeJSON(w, http.StatusOK, constants.TwoFactorSetup{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode.Bytes()),
+	})
+}
+
+/* Confirm the authenticator app with its first code; the recovery codes are returned once */
+func twoFactorPUT(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	codes, ok, err := repo.ConfirmTwoFactorSetup(r.Context(), userId, r.FormValue("code"))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !ok {
+		http.Error(w, "Code is not recognized", http.StatusBadRequest)
+		return
+	}
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	if _, required := session.Values["twoFactorSetupRequired"]; required {
+		delete(session.Values, "twoFactorSetupRequired")
+		if err := session.Save(r, w); err != nil {
+			log.Print(err)
+		}
+	}
+	audit(r, constants.AUDIT_TWO_FACTOR_ENABLE, nil, "")
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(strings.Join(codes, "\n")))
+}
+
+/* Replace the recovery codes; the new ones are returned once */
+func recoveryCodesPOST(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	valid, err := repo.VerifyTwoFactorCode(r.Context(), userId, r.FormValue("code"))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !valid {
+		http.Error(w, "Code is not recognized", http.StatusBadRequest)
+		return
+	}
+	codes, err := repo.ResetRecoveryCodes(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	audit(r, constants.AUDIT_RECOVERY_CODES_RESET, nil, "")
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(strings.Join(codes, "\n")))
+}
+
+/* Turn two-factor authentication off, confirmed with a current code or a recovery code */
+func twoFactorDisablePOST(w http.ResponseWriter, r *http.Request) {
+	userId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	status, err := repo.GetTwoFactorStatus(r.Context(), userId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if status.Required {
+		http.Error(w, "Forbidden - your group requires two-factor authentication", http.StatusForbidden)
+		return
+	}
+	_, valid, err := checkTwoFactorCode(r.Context(), userId, r.FormValue("code"))
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if !valid {
+		http.Error(w, "Code is not recognized", http.StatusBadRequest)
+		return
+	}
+	if err := repo.DisableTwoFactor(r.Context(), userId); err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	audit(r, constants.AUDIT_TWO_FACTOR_DISABLE, nil, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getGroupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	selfId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	groupId, err := repo.GetUserGroupId(r.Context(), selfId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	required, err := repo.GetGroupTwoFactorRequired(r.Context(), groupId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if required {
+		w.Write([]byte("true"))
+	} else {
+		w.Write([]byte("false"))
+	}
+}
+
+// Members already signed in are held to the policy from their next sign-in
+func toggleGroupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	selfId, err := helper.GetUserId(r)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	groupId, err := repo.GetUserGroupId(r.Context(), selfId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	required, err := repo.GetGroupTwoFactorRequired(r.Context(), groupId)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	err = repo.SetGroupTwoFactorRequired(r.Context(), groupId, !required)
+	if err != nil {
+		http.Error(w, "Unexpected error occurred", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	if required {
+		audit(r, constants.AUDIT_GROUP_TWO_FACTOR_OFF, nil, "")
+		w.Write([]byte("false"))
+	} else {
+		audit(r, constants.AUDIT_GROUP_TWO_FACTOR_ON, nil, "")
+		w.Write([]byte("true"))
+	}
+}
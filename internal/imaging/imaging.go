@@ -0,0 +1,111 @@
+// Package imaging checks uploaded images and makes their thumbnails
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// MaxPixels refuses images that would take too much memory to decode, however small the file
+	MaxPixels = 40_000_000
+	// ThumbnailSize is the longest side of a thumbnail, in pixels
+	ThumbnailSize = 320
+	// ThumbnailContentType is what Thumbnail encodes to
+	ThumbnailContentType = "image/jpeg"
+	thumbnailQuality     = 80
+)
+
+// ErrUnsupported is returned for files that aren't a JPEG, PNG or GIF image
+var ErrUnsupported = errors.New("only JPEG, PNG and GIF images are supported")
+
+// Info describes an uploaded image from its header alone
+type Info struct {
+	ContentType string
+	Extension   string // for storage keys, e.g. "jpg"
+	Width       int
+	Height      int
+}
+
+var formats = map[string]Info{
+	"jpeg": {ContentType: "image/jpeg", Extension: "jpg"},
+	"png":  {ContentType: "image/png", Extension: "png"},
+	"gif":  {ContentType: "image/gif", Extension: "gif"},
+}
+
+// Inspect reads the format and size of an image without decoding it, rejecting anything that
+// isn't a supported format or is larger than MaxPixels. The content type comes from the data,
+// never from what the uploader claimed.
+func Inspect(data []byte) (Info, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Info{}, ErrUnsupported
+	}
+	info, ok := formats[format]
+	if !ok {
+		return Info{}, ErrUnsupported
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > MaxPixels {
+		return Info{}, fmt.Errorf("images can be at most %d megapixels", MaxPixels/1_000_000)
+	}
+	info.Width, info.Height = config.Width, config.Height
+	return info, nil
+}
+
+// Thumbnail scales an image to fit within ThumbnailSize × ThumbnailSize, never enlarging it, and
+// encodes it as a JPEG. Transparent areas become white. Check the image with Inspect first.
+func Thumbnail(data []byte) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	bounds := src.Bounds()
+	width, height := fit(bounds.Dx(), bounds.Dy(), ThumbnailSize)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// Average the block of source pixels behind each thumbnail pixel
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					// Composite over white; the values are alpha-premultiplied
+					white := 0xffff - uint64(pa)
+					r += uint64(pr) + white
+					g += uint64(pg) + white
+					b += uint64(pb) + white
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: 0xff})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// fit scales width × height down to fit within size × size, keeping the aspect ratio
+func fit(width int, height int, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(height*size/width, 1)
+	}
+	return max(width*size/height, 1), size
+}
@@ -0,0 +1,157 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func solid(width int, height int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// pngHeader is the start of a PNG claiming to be width × height, enough for DecodeConfig; the
+// pixels that would follow are never read
+func pngHeader(width uint32, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8], ihdr[9] = 8, 6 // 8-bit RGBA
+	chunk := append([]byte("IHDR"), ihdr...)
+
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&b, binary.BigEndian, uint32(len(ihdr)))
+	b.Write(chunk)
+	binary.Write(&b, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return b.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	var jpg, gifData bytes.Buffer
+	if err := jpeg.Encode(&jpg, solid(40, 30, color.White), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gifData, solid(12, 34, color.Black), nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want Info
+	}{
+		{"png", encodePNG(t, solid(64, 48, color.White)), Info{ContentType: "image/png", Extension: "png", Width: 64, Height: 48}},
+		{"jpeg", jpg.Bytes(), Info{ContentType: "image/jpeg", Extension: "jpg", Width: 40, Height: 30}},
+		{"gif", gifData.Bytes(), Info{ContentType: "image/gif", Extension: "gif", Width: 12, Height: 34}},
+		{"at the pixel limit", pngHeader(8000, MaxPixels/8000), Info{ContentType: "image/png", Extension: "png", Width: 8000, Height: MaxPixels / 8000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Inspect(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspectRejectsNonImages(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":      nil,
+		"text":       []byte("just some text"),
+		"html":       []byte("<html><body><img src=x></body></html>"),
+		"svg":        []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"></svg>`),
+		"truncated":  encodePNG(t, solid(8, 8, color.White))[:20],
+		"bad header": append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...),
+	} {
+		if info, err := Inspect(data); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: got %+v, %v, want ErrUnsupported", name, info, err)
+		}
+	}
+}
+
+func TestInspectRejectsHugeImages(t *testing.T) {
+	for _, size := range [][2]uint32{{8000, MaxPixels/8000 + 1}, {65535, 65535}, {1, MaxPixels + 1}} {
+		if info, err := Inspect(pngHeader(size[0], size[1])); err == nil {
+			t.Errorf("%d×%d: accepted as %+v", size[0], size[1], info)
+		}
+	}
+}
+
+func TestThumbnailSize(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height         int
+		wantWidth, wantHeight int
+	}{
+		{"landscape", 1000, 500, ThumbnailSize, ThumbnailSize / 2},
+		{"portrait", 200, 1000, ThumbnailSize / 5, ThumbnailSize},
+		{"square", 640, 640, ThumbnailSize, ThumbnailSize},
+		{"small images aren't enlarged", 100, 50, 100, 50},
+		{"very thin", 3200, 2, ThumbnailSize, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumbnail, err := Thumbnail(encodePNG(t, solid(tt.width, tt.height, color.Black)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := Inspect(thumbnail)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.ContentType != ThumbnailContentType {
+				t.Errorf("content type %s", info.ContentType)
+			}
+			if info.Width != tt.wantWidth || info.Height != tt.wantHeight {
+				t.Errorf("thumbnail is %d×%d, want %d×%d", info.Width, info.Height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestThumbnailFlattensTransparencyOntoWhite(t *testing.T) {
+	thumbnail, err := Thumbnail(encodePNG(t, solid(16, 16, color.NRGBA{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, g, b, _ := img.At(8, 8).RGBA()
+	if r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Errorf("transparent pixel became %d,%d,%d, want white", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestThumbnailRejectsNonImages(t *testing.T) {
+	if _, err := Thumbnail([]byte("not an image")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("got %v, want ErrUnsupported", err)
+	}
+}
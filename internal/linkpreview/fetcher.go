@@ -0,0 +1,137 @@
+// Package linkpreview reads the title, image, price and availability a product page publishes
+// about itself as OpenGraph tags or schema.org JSON-LD.
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// Limits used by New
+const (
+	DefaultTimeout  = 10 * time.Second
+	DefaultMaxBytes = 2 << 20 // anything past this is not read; product data usually sits near the top of the page
+	maxRedirects    = 5
+)
+
+// Fetcher downloads a page and reads its preview. Tests can point Client at a local stub server;
+// the client New builds refuses to connect to loopback and private addresses.
+type Fetcher struct {
+	Client    *http.Client
+	Timeout   time.Duration // for the whole fetch, including reading the body; 0 for none
+	MaxBytes  int64         // of the body to read; 0 for DefaultMaxBytes
+	UserAgent string
+}
+
+// New returns a Fetcher for untrusted URLs: it only connects to public addresses, follows a few
+// redirects, and gives up after DefaultTimeout
+func New() *Fetcher {
+	dialer := &net.Dialer{Timeout: DefaultTimeout, Control: publicOnly}
+	transport := &http.Transport{
+		// A proxy would make the connection on our behalf, out of reach of the address check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   DefaultTimeout,
+		ResponseHeaderTimeout: DefaultTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &Fetcher{
+		Client:    &http.Client{Transport: transport, CheckRedirect: checkRedirect},
+		Timeout:   DefaultTimeout,
+		MaxBytes:  DefaultMaxBytes,
+		UserAgent: "Listaway link preview (+https://github.com/jeffrpowell/listaway)",
+	}
+}
+
+// Fetch downloads rawURL and reads its preview. Pages that are not HTML, or that don't answer
+// with 200 OK, are errors; a page without any product data gives an empty preview.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (constants.LinkPreview, error) {
+	page, err := url.Parse(rawURL)
+	if err != nil || (page.Scheme != "http" && page.Scheme != "https") || page.Host == "" {
+		return constants.LinkPreview{}, fmt.Errorf("%q is not an http or https URL", rawURL)
+	}
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", page.String(), nil)
+	if err != nil {
+		return constants.LinkPreview{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return constants.LinkPreview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return constants.LinkPreview{}, fmt.Errorf("%s answered %s", page.Host, resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return constants.LinkPreview{}, fmt.Errorf("%s is not an HTML page", page.Host)
+	}
+
+	maxBytes := f.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	preview := Parse(io.LimitReader(resp.Body, maxBytes), resp.Request.URL)
+	if err := ctx.Err(); err != nil {
+		return constants.LinkPreview{}, err
+	}
+	preview.URL = rawURL
+	return preview, nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	// via holds every request so far, so the first redirect arrives with one
+	if len(via) > maxRedirects {
+		return errors.New("too many redirects")
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirected to unsupported scheme %q", req.URL.Scheme)
+	}
+	return nil
+}
+
+// sharedAddressSpace is carrier-grade NAT space, which netip doesn't count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicOnly refuses connections to addresses outside the public internet, so item URLs can't be
+// used to probe the network the server runs in. It sees the resolved address, which also covers
+// host names that resolve to private addresses.
+func publicOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("refusing to connect to non-public address %s", ip)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package linkpreview
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const openGraphPage = `<!doctype html>
+<html><head>
+<title>Ignored when OpenGraph has one</title>
+<meta property="og:title" content="Espresso Grinder &amp; Scale">
+<meta property="og:image" content="/images/grinder.jpg">
+<meta property="product:price:amount" content="129.50">
+<meta property="product:price:currency" content="usd">
+<meta property="product:availability" content="in stock">
+</head><body></body></html>`
+
+const jsonLDPage = `<!doctype html>
+<html><head>
+<title>Shop</title>
+<meta property="og:title" content="Loses to JSON-LD">
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "BreadcrumbList"},
+  {"@type": ["Thing", "Product"], "name": "Trail Running Shoes",
+   "image": {"@type": "ImageObject", "url": "https://cdn.example.com/shoes.png"},
+   "offers": [{"@type": "Offer", "price": "89.9900", "priceCurrency": "EUR",
+               "availability": "https://schema.org/OutOfStock"}]}
+]}
+</script>
+</head><body></body></html>`
+
+// stubFetcher fetches from a local test server: it has the redirect cap of New but none of its
+// address checks
+func stubFetcher(server *httptest.Server) *Fetcher {
+	client := server.Client()
+	client.CheckRedirect = checkRedirect
+	return &Fetcher{Client: client, Timeout: 5 * time.Second, MaxBytes: DefaultMaxBytes}
+}
+
+func servePage(contentType string, status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestFetchOpenGraph(t *testing.T) {
+	server := httptest.NewServer(servePage("text/html; charset=utf-8", http.StatusOK, openGraphPage))
+	defer server.Close()
+
+	preview, err := stubFetcher(server).Fetch(context.Background(), server.URL+"/products/grinder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.URL != server.URL+"/products/grinder" {
+		t.Errorf("URL = %q", preview.URL)
+	}
+	if preview.Title.String != "Espresso Grinder & Scale" {
+		t.Errorf("Title = %q", preview.Title.String)
+	}
+	if preview.ImageURL.String != server.URL+"/images/grinder.jpg" {
+		t.Errorf("ImageURL = %q, want it resolved against the page", preview.ImageURL.String)
+	}
+	if !preview.Price.Valid || preview.Price.Int64 != 12950 || preview.Currency.String != "USD" {
+		t.Errorf("Price = %v %v, want 12950 USD", preview.Price, preview.Currency)
+	}
+	if preview.Availability.String != "InStock" {
+		t.Errorf("Availability = %q", preview.Availability.String)
+	}
+}
+
+func TestFetchJSONLD(t *testing.T) {
+	server := httptest.NewServer(servePage("text/html", http.StatusOK, jsonLDPage))
+	defer server.Close()
+
+	preview, err := stubFetcher(server).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Title.String != "Trail Running Shoes" {
+		t.Errorf("Title = %q", preview.Title.String)
+	}
+	if preview.ImageURL.String != "https://cdn.example.com/shoes.png" {
+		t.Errorf("ImageURL = %q", preview.ImageURL.String)
+	}
+	if !preview.Price.Valid || preview.Price.Int64 != 8999 || preview.Currency.String != "EUR" {
+		t.Errorf("Price = %v %v, want 8999 EUR", preview.Price, preview.Currency)
+	}
+	if preview.Availability.String != "OutOfStock" {
+		t.Errorf("Availability = %q", preview.Availability.String)
+	}
+}
+
+func TestFetchRejectsUnusablePages(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"not HTML", servePage("application/json", http.StatusOK, `{"title": "x"}`)},
+		{"no content type", servePage("", http.StatusOK, "")},
+		{"not found", servePage("text/html", http.StatusNotFound, openGraphPage)},
+		{"server error", servePage("text/html", http.StatusInternalServerError, openGraphPage)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			if preview, err := stubFetcher(server).Fetch(context.Background(), server.URL); err == nil {
+				t.Errorf("got %+v, want an error", preview)
+			}
+		})
+	}
+}
+
+func TestFetchRejectsNonHTTPURLs(t *testing.T) {
+	for _, rawURL := range []string{"ftp://example.com/x", "file:///etc/passwd", "javascript:alert(1)", "/relative"} {
+		if _, err := New().Fetch(context.Background(), rawURL); err == nil {
+			t.Errorf("fetched %q", rawURL)
+		}
+	}
+}
+
+func TestFetchStopsAtMaxBytes(t *testing.T) {
+	// The product data sits past the first kilobyte, out of reach of a 1 KiB limit
+	page := "<html><head><!--" + strings.Repeat("x", 2048) + "-->" + strings.TrimPrefix(openGraphPage, "<!doctype html>\n<html><head>")
+	server := httptest.NewServer(servePage("text/html", http.StatusOK, page))
+	defer server.Close()
+	fetcher := stubFetcher(server)
+
+	full, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !full.Title.Valid {
+		t.Fatal("the whole page gave no title")
+	}
+	fetcher.MaxBytes = 1024
+	truncated, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !truncated.Empty() {
+		t.Errorf("read past MaxBytes: %+v", truncated)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "<html><head>")
+		w.(http.Flusher).Flush()
+		// Hold the body open until the client gives up
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	fetcher := stubFetcher(server)
+	fetcher.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	if _, err := fetcher.Fetch(context.Background(), server.URL); err == nil {
+		t.Error("a page that never finished gave no error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("gave up after %v", elapsed)
+	}
+}
+
+func TestFetchRedirects(t *testing.T) {
+	// /hop/n redirects n more times before serving the page
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if n > 0 {
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+			return
+		}
+		servePage("text/html", http.StatusOK, openGraphPage)(w, r)
+	}))
+	defer server.Close()
+	fetcher := stubFetcher(server)
+
+	preview, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/hop/%d", server.URL, maxRedirects))
+	if err != nil {
+		t.Fatalf("%d redirects: %v", maxRedirects, err)
+	}
+	if preview.ImageURL.String != server.URL+"/images/grinder.jpg" {
+		t.Errorf("ImageURL = %q, want it resolved against the final page", preview.ImageURL.String)
+	}
+	if _, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/hop/%d", server.URL, maxRedirects+1)); err == nil {
+		t.Errorf("followed %d redirects", maxRedirects+1)
+	}
+}
+
+func TestNewRefusesNonPublicAddresses(t *testing.T) {
+	server := httptest.NewServer(servePage("text/html", http.StatusOK, openGraphPage))
+	defer server.Close()
+	fetcher := New()
+	fetcher.Timeout = 2 * time.Second
+
+	for _, rawURL := range []string{
+		server.URL,
+		"http://localhost:" + server.URL[strings.LastIndex(server.URL, ":")+1:],
+		"http://10.0.0.1/",
+		"http://192.168.1.1/",
+		"http://100.64.0.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"http://[::ffff:127.0.0.1]/",
+		"http://0.0.0.0/",
+	} {
+		_, err := fetcher.Fetch(context.Background(), rawURL)
+		if err == nil || !strings.Contains(err.Error(), "non-public address") {
+			t.Errorf("%s: got %v, want a refusal", rawURL, err)
+		}
+	}
+}
+
+func TestPublicOnlyAllowsPublicAddresses(t *testing.T) {
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:80"} {
+		if err := publicOnly("tcp", address, nil); err != nil {
+			t.Errorf("%s: %v", address, err)
+		}
+	}
+}
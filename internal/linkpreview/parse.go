@@ -0,0 +1,262 @@
+package linkpreview
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+	parsehtml "github.com/tdewolff/parse/html"
+)
+
+// maxTitleLength keeps a page's title to what fits next to an item
+const maxTitleLength = 300
+
+// Parse reads a preview out of an HTML page. base resolves relative image URLs.
+// Product data in JSON-LD wins over OpenGraph tags, which win over the page's <title>.
+func Parse(r io.Reader, base *url.URL) constants.LinkPreview {
+	page := scan(r)
+
+	product := map[string]any{}
+	for _, block := range page.jsonLD {
+		var v any
+		if json.Unmarshal(block, &v) != nil {
+			continue
+		}
+		if p := ldProduct(v); p != nil {
+			product = p
+			break
+		}
+	}
+	offer := ldFirst(product["offers"])
+	if spec := ldFirst(offer["priceSpecification"]); offer["price"] == nil && spec["price"] != nil {
+		offer = spec
+	}
+
+	var preview constants.LinkPreview
+	preview.Title = cleanText(firstNonEmpty(ldString(product["name"]), page.meta["og:title"], page.meta["twitter:title"], page.title), maxTitleLength)
+	preview.ImageURL = imageURL(base, firstNonEmpty(page.meta["og:image:secure_url"], page.meta["og:image"], ldString(product["image"]), page.meta["twitter:image"]))
+
+	amount := firstNonEmpty(ldString(offer["price"]), ldString(offer["lowPrice"]))
+	currency := ldString(offer["priceCurrency"])
+	if amount == "" {
+		amount = firstNonEmpty(page.meta["product:price:amount"], page.meta["og:price:amount"], page.meta["price"])
+		currency = firstNonEmpty(page.meta["product:price:currency"], page.meta["og:price:currency"], page.meta["pricecurrency"])
+	}
+	if code, ok := constants.NormalizeCurrency(currency); ok {
+		if minor, ok := parsePrice(amount, code); ok {
+			preview.Price = sql.NullInt64{Int64: minor, Valid: true}
+			preview.Currency = sql.NullString{String: code, Valid: true}
+		}
+	}
+
+	if term := availabilityTerm(firstNonEmpty(ldString(offer["availability"]), page.meta["product:availability"], page.meta["og:availability"], page.meta["availability"])); term != "" {
+		preview.Availability = sql.NullString{String: term, Valid: true}
+	}
+	return preview
+}
+
+// scannedPage is the raw metadata found while lexing a page
+type scannedPage struct {
+	meta   map[string]string // <meta> content by lower-cased property, name or itemprop; the first one wins
+	jsonLD [][]byte
+	title  string
+}
+
+func scan(r io.Reader) scannedPage {
+	page := scannedPage{meta: map[string]string{}}
+	lexer := parsehtml.NewLexer(r)
+	var tag string
+	var attrs map[string]string
+	inTitle := false
+	for {
+		tt, data := lexer.Next()
+		switch tt {
+		case parsehtml.ErrorToken:
+			return page
+		case parsehtml.StartTagToken:
+			tag = strings.ToLower(string(lexer.Text()))
+			attrs = map[string]string{}
+		case parsehtml.AttributeToken:
+			attrs[strings.ToLower(string(lexer.Text()))] = html.UnescapeString(unquote(string(lexer.AttrVal())))
+		case parsehtml.StartTagCloseToken, parsehtml.StartTagVoidToken:
+			switch tag {
+			case "meta":
+				key := strings.ToLower(firstNonEmpty(attrs["property"], attrs["name"], attrs["itemprop"]))
+				if _, seen := page.meta[key]; key != "" && !seen && attrs["content"] != "" {
+					page.meta[key] = attrs["content"]
+				}
+			case "link":
+				// Microdata puts availability in <link itemprop="availability" href="https://schema.org/InStock">
+				key := strings.ToLower(attrs["itemprop"])
+				if _, seen := page.meta[key]; key != "" && !seen && attrs["href"] != "" {
+					page.meta[key] = attrs["href"]
+				}
+			case "script":
+				if strings.EqualFold(strings.TrimSpace(attrs["type"]), "application/ld+json") {
+					if next, text := lexer.Next(); next == parsehtml.TextToken {
+						page.jsonLD = append(page.jsonLD, append([]byte(nil), text...))
+					}
+				}
+			case "title":
+				inTitle = page.title == ""
+			}
+		case parsehtml.TextToken:
+			if inTitle {
+				page.title += html.UnescapeString(string(data))
+			}
+		case parsehtml.EndTagToken:
+			if strings.EqualFold(string(lexer.Text()), "title") {
+				inTitle = false
+			}
+		}
+	}
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// cleanText collapses whitespace, drops invalid UTF-8 and cuts text to at most maxLength characters
+func cleanText(text string, maxLength int) sql.NullString {
+	text = strings.Join(strings.Fields(strings.ToValidUTF8(text, "")), " ")
+	if text == "" {
+		return sql.NullString{}
+	}
+	if utf8.RuneCountInString(text) > maxLength {
+		text = string([]rune(text)[:maxLength-1]) + "…"
+	}
+	return sql.NullString{String: text, Valid: true}
+}
+
+// imageURL resolves an image reference against the page, keeping only http and https images
+func imageURL(base *url.URL, ref string) sql.NullString {
+	if ref == "" || !utf8.ValidString(ref) {
+		return sql.NullString{}
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return sql.NullString{}
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: u.String(), Valid: true}
+}
+
+// parsePrice reads a published price as minor units. Schema.org and OpenGraph both use a point
+// for decimals, so a comma without one is too ambiguous to guess at and gives no price.
+func parsePrice(amount string, currency string) (int64, bool) {
+	amount = strings.TrimSpace(amount)
+	if amount == "" || (strings.Contains(amount, ",") && !strings.Contains(amount, ".")) {
+		return 0, false
+	}
+	// Feeds often pad prices, as in 19.9900
+	if whole, fraction, found := strings.Cut(amount, "."); found {
+		exponent := constants.CurrencyExponent(currency)
+		for len(fraction) > exponent && strings.HasSuffix(fraction, "0") {
+			fraction = fraction[:len(fraction)-1]
+		}
+		amount = whole + "." + fraction
+	}
+	minor, err := constants.ParseAmount(amount, currency)
+	return minor, err == nil
+}
+
+// availabilityTerms maps schema.org ItemAvailability terms, and the OpenGraph product:availability
+// values, lower-cased without spaces, to the schema.org term
+var availabilityTerms = map[string]string{
+	"backorder": "BackOrder", "discontinued": "Discontinued", "instock": "InStock", "instoreonly": "InStoreOnly",
+	"limitedavailability": "LimitedAvailability", "madetoorder": "MadeToOrder", "onlineonly": "OnlineOnly",
+	"outofstock": "OutOfStock", "preorder": "PreOrder", "presale": "PreSale", "reserved": "Reserved", "soldout": "SoldOut",
+	"availablefororder": "BackOrder", "pending": "PreOrder",
+}
+
+// availabilityTerm turns "https://schema.org/InStock", "InStock" or "in stock" into InStock; unknown values give ""
+func availabilityTerm(value string) string {
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		value = value[i+1:]
+	}
+	value = strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(value))
+	return availabilityTerms[value]
+}
+
+// productTypes are the schema.org types read as a product
+var productTypes = map[string]bool{"Product": true, "ProductGroup": true, "ProductModel": true, "IndividualProduct": true}
+
+// ldProduct finds the first product in a JSON-LD document, looking through arrays and @graph
+func ldProduct(v any) map[string]any {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			if p := ldProduct(e); p != nil {
+				return p
+			}
+		}
+	case map[string]any:
+		types, _ := v["@type"].([]any)
+		if t, ok := v["@type"].(string); ok {
+			types = []any{t}
+		}
+		for _, t := range types {
+			if name, ok := t.(string); ok && productTypes[strings.TrimPrefix(name, "schema:")] {
+				return v
+			}
+		}
+		return ldProduct(v["@graph"])
+	}
+	return nil
+}
+
+// ldFirst is v if it is an object, or the first object in it if it is an array
+func ldFirst(v any) map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		return v
+	case []any:
+		for _, e := range v {
+			if m, ok := e.(map[string]any); ok {
+				return m
+			}
+		}
+	}
+	return map[string]any{}
+}
+
+// ldString reads a JSON-LD value as text: strings as-is, numbers in decimal, objects by their url
+// (as ImageObject has) and arrays by their first element
+func ldString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any:
+		return firstNonEmpty(ldString(v["url"]), ldString(v["contentUrl"]), ldString(v["@id"]))
+	case []any:
+		if len(v) > 0 {
+			return ldString(v[0])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package linkpreview
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// Store keeps fetched previews; database.Repository is the real one
+type Store interface {
+	// LinkPreviewDue reports whether url has no preview yet, or one old enough to fetch again
+	LinkPreviewDue(ctx context.Context, url string) (bool, error)
+	SaveLinkPreview(ctx context.Context, preview constants.LinkPreview) error
+	SaveLinkPreviewError(ctx context.Context, url string, message string) error
+}
+
+// queueSize bounds how many URLs can wait for a fetch. URLs that arrive while it is full are
+// dropped and fetched the next time their item is saved.
+const queueSize = 256
+
+// Worker fetches previews in the background, a few at a time, so saving an item never waits on a slow site
+type Worker struct {
+	fetcher *Fetcher
+	store   Store
+	queue   chan string
+
+	mu      sync.Mutex
+	pending map[string]bool // queued or being fetched
+}
+
+func NewWorker(fetcher *Fetcher, store Store) *Worker {
+	return &Worker{fetcher: fetcher, store: store, queue: make(chan string, queueSize), pending: map[string]bool{}}
+}
+
+// Start runs n goroutines working through the queue until ctx is cancelled
+func (w *Worker) Start(ctx context.Context, n int) {
+	for range n {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case url := <-w.queue:
+					w.process(ctx, url)
+					w.mu.Lock()
+					delete(w.pending, url)
+					w.mu.Unlock()
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue asks for a preview of url unless it is already waiting. It never blocks.
+func (w *Worker) Enqueue(url string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending[url] {
+		return
+	}
+	select {
+	case w.queue <- url:
+		w.pending[url] = true
+	default:
+		log.Printf("link preview queue is full; skipping %s", url)
+	}
+}
+
+func (w *Worker) process(ctx context.Context, url string) {
+	due, err := w.store.LinkPreviewDue(ctx, url)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	if !due {
+		return
+	}
+	preview, err := w.fetcher.Fetch(ctx, url)
+	if err != nil {
+		err = w.store.SaveLinkPreviewError(ctx, url, err.Error())
+	} else {
+		err = w.store.SaveLinkPreview(ctx, preview)
+	}
+	if err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// invalidKeys would escape the storage directory or the bucket, or aren't keys the app makes
+var invalidKeys = []string{
+	"",
+	"..",
+	"../escape.png",
+	"a/../../escape.png",
+	"/etc/passwd",
+	"sub/dir.png",
+	`..\escape.png`,
+	".hidden",
+	"UPPER.png",
+	"space here.png",
+	string(bytes.Repeat([]byte("a"), maxKeyLength+1)),
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocal(filepath.Join(dir, "attachments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	data := []byte("GIF89a not really")
+
+	if err := s.Put(ctx, "0a1b2c.gif", data, "image/gif"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	body, err := s.Get(ctx, "0a1b2c.gif")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Get returned %q, %v", got, err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "attachments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "0a1b2c.gif" {
+		t.Errorf("directory holds %v, want just the file", entries)
+	}
+
+	if err := s.Delete(ctx, "0a1b2c.gif"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "0a1b2c.gif"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "0a1b2c.gif"); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocal(filepath.Join(dir, "attachments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// Something a traversal from the storage directory would reach
+	if err := os.WriteFile(filepath.Join(dir, "escape.png"), []byte("outside"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range invalidKeys {
+		if err := s.Put(ctx, key, []byte("x"), "image/png"); err == nil {
+			t.Errorf("Put accepted %q", key)
+		}
+		if body, err := s.Get(ctx, key); err == nil {
+			body.Close()
+			t.Errorf("Get accepted %q", key)
+		}
+		if err := s.Delete(ctx, key); err == nil {
+			t.Errorf("Delete accepted %q", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.png")); err != nil {
+		t.Errorf("file outside the storage directory was touched: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "attachments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("invalid keys left files behind: %v", entries)
+	}
+}
+
+func TestNewLocalRequiresDirectory(t *testing.T) {
+	if _, err := NewLocal(""); err == nil {
+		t.Error("NewLocal accepted an empty directory")
+	}
+}
@@ -0,0 +1,58 @@
+// Package storage keeps uploaded files, either on the local disk or in an S3-compatible bucket
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jeffrpowell/listaway/internal/constants"
+)
+
+// ErrNotFound is returned by Get when nothing is stored under a key
+var ErrNotFound = errors.New("file not found")
+
+// Store keeps files by key. Keys are chosen by the caller and must pass ValidKey.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns ErrNotFound if nothing is stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds if nothing is stored under key
+	Delete(ctx context.Context, key string) error
+}
+
+// Storage backends for ATTACHMENT_STORAGE
+const (
+	BackendLocal string = "local"
+	BackendS3    string = "s3"
+)
+
+const maxKeyLength = 128
+
+// ValidKey reports whether key is safe to use as a file name and an object name: lower-case
+// letters, digits, dots, dashes and underscores, not starting with a dot
+func ValidKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength || strings.HasPrefix(key, ".") {
+		return false
+	}
+	for _, c := range key {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '.' && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// FromEnv builds the Store chosen by the ATTACHMENT_STORAGE env var
+func FromEnv() (Store, error) {
+	switch constants.ATTACHMENT_STORAGE {
+	case BackendLocal:
+		return NewLocal(constants.ATTACHMENT_DIR)
+	case BackendS3:
+		return NewS3(constants.S3_ENDPOINT, constants.S3_REGION, constants.S3_BUCKET, constants.S3_ACCESS_KEY_ID, constants.S3_SECRET_ACCESS_KEY, constants.S3_PATH_STYLE == "true")
+	default:
+		return nil, fmt.Errorf("unknown %s %q; use %s or %s", constants.ENV_ATTACHMENT_STORAGE, constants.ATTACHMENT_STORAGE, BackendLocal, BackendS3)
+	}
+}
@@ -8,8 +8,10 @@
  * Initializes a pre-rendered HTML table with master/detail functionality
  * @param {string} gridSelector - CSS selector for the grid container
  * @param {Object} options - Configuration options
- * @param {string} [options.defaultSortColumn] - Column field to sort by default
+ * @param {string} [options.defaultSortColumn] - Column field to sort by default; rows otherwise keep the list's own order
  * @param {string} [options.defaultSortDirection] - Default sort direction ('asc' or 'desc')
+ * @param {Function} [options.onReorder] - Enables drag-and-drop reordering; called with every row id in the new order,
+ *   and the moved row's id with its new and previous section ids ('' when outside every section)
  */
 export function initMasterDetailGrid(gridSelector, options = {}) {
   const gridContainer = document.querySelector(gridSelector);
@@ -27,6 +29,7 @@ export function initMasterDetailGrid(gridSelector, options = {}) {
   const tbody = table.querySelector('tbody');
   const dataRows = tbody ? tbody.querySelectorAll('tr.data-row') : [];
   const detailRows = tbody ? tbody.querySelectorAll('tr.detail-row') : [];
+  const sectionRows = tbody ? tbody.querySelectorAll('tr.section-row') : [];
   
   // Initialize state
   const state = {
@@ -79,7 +82,11 @@ export function initMasterDetailGrid(gridSelector, options = {}) {
       // Get values based on field
       let valueA, valueB;
       
-      if (field === 'name') {
+      if (field === 'position') {
+        // The list's own order, as rendered by the server
+        return parseInt(rowA.dataset.position) - parseInt(rowB.dataset.position);
+      }
+      else if (field === 'name') {
         // For name, get the text content of the first cell
         valueA = rowA.querySelector('td').textContent.trim().toLowerCase();
         valueB = rowB.querySelector('td').textContent.trim().toLowerCase();
@@ -108,13 +115,21 @@ export function initMasterDetailGrid(gridSelector, options = {}) {
       return 0; // Default no sorting
     });
     
-    // Remove all rows and reinsert them in the sorted order
+    // Remove all rows and reinsert them in the sorted order, keeping each row under its section heading.
+    // Rows outside every section come first, as the server renders them.
     const fragment = document.createDocumentFragment();
-    rowPairs.forEach(([dataRow, detailRow]) => {
-      fragment.appendChild(dataRow);
-      if (detailRow) {
-        fragment.appendChild(detailRow);
-      }
+    const appendPairs = (sectionId) => {
+      rowPairs.filter(([dataRow]) => (dataRow.dataset.sectionId || '') === sectionId).forEach(([dataRow, detailRow]) => {
+        fragment.appendChild(dataRow);
+        if (detailRow) {
+          fragment.appendChild(detailRow);
+        }
+      });
+    };
+    appendPairs('');
+    sectionRows.forEach(sectionRow => {
+      fragment.appendChild(sectionRow);
+      appendPairs(sectionRow.dataset.sectionId);
     });
     
     // Clear and append
@@ -158,8 +173,8 @@ export function initMasterDetailGrid(gridSelector, options = {}) {
   // Add event listeners to data rows for toggle detail view
   dataRows.forEach(row => {
     row.addEventListener('click', (event) => {
-      // Don't expand if clicking on action buttons
-      if (event.target.closest('button') || event.target.closest('a')) {
+      // Don't expand if clicking on action buttons or checkboxes
+      if (event.target.closest('button') || event.target.closest('a') || event.target.closest('input')) {
         return;
       }
       const rowId = row.dataset.id;
@@ -172,22 +187,105 @@ export function initMasterDetailGrid(gridSelector, options = {}) {
     header.addEventListener('click', () => {
       const field = header.dataset.field;
       
-      // Toggle direction if clicking the same column again
-      if (state.sortColumn === field) {
-        state.sortDirection = state.sortDirection === 'asc' ? 'desc' : 'asc';
+      // Clicking the same column flips to descending, and a third click goes back to the list's own order
+      if (state.sortColumn === field && state.sortDirection === 'asc') {
+        state.sortDirection = 'desc';
+      } else if (state.sortColumn === field) {
+        state.sortColumn = null;
+        state.sortDirection = 'asc';
       } else {
         state.sortColumn = field;
         state.sortDirection = 'asc';
       }
       
       // Sort the table
-      sortTableByColumn(field, state.sortDirection);
+      sortTableByColumn(state.sortColumn || 'position', state.sortDirection);
       
       // Update indicators
       updateSortIndicators();
     });
   });
   
+  /**
+   * Moves a data row, along with its detail row, next to another data row or a section heading
+   * @param {HTMLElement} row - The data row being moved
+   * @param {HTMLElement} target - The row it was dropped on
+   * @param {boolean} after - Whether to place it after the target rather than before
+   */
+  function moveRow(row, target, after) {
+    const detailRow = tbody.querySelector(`tr.detail-row[data-parent-id="${row.dataset.id}"]`);
+    const targetDetailRow = tbody.querySelector(`tr.detail-row[data-parent-id="${target.dataset.id}"]`);
+    const anchor = after ? (targetDetailRow || target).nextSibling : target;
+    tbody.insertBefore(row, anchor);
+    if (detailRow) {
+      tbody.insertBefore(detailRow, row.nextSibling);
+    }
+  }
+  
+  /**
+   * Finds the section a data row sits in from the nearest section heading above it
+   * @param {HTMLElement} row - A data row
+   * @returns {string} The section id, or '' when the row is above every heading
+   */
+  function sectionOf(row) {
+    for (let sibling = row.previousElementSibling; sibling; sibling = sibling.previousElementSibling) {
+      if (sibling.classList.contains('section-row')) {
+        return sibling.dataset.sectionId;
+      }
+    }
+    return '';
+  }
+  
+  // Drag-and-drop reordering, only while the table shows the list's own order.
+  // Dropping onto a section heading, or next to a row in another section, moves the row into that section.
+  // Completed items archived under their own heading stay put.
+  if (options.onReorder) {
+    let draggedRow = null;
+    const archived = (row) => row.dataset.sectionId === 'archive';
+    const dropTarget = (row) => {
+      if (archived(row)) return;
+      row.addEventListener('dragover', (event) => {
+        if (draggedRow && draggedRow !== row) {
+          event.preventDefault();
+        }
+      });
+      row.addEventListener('drop', (event) => {
+        event.preventDefault();
+        if (!draggedRow || draggedRow === row) return;
+        const rect = row.getBoundingClientRect();
+        const previousSectionId = draggedRow.dataset.sectionId || '';
+        moveRow(draggedRow, row, event.clientY > rect.top + rect.height / 2);
+        draggedRow.dataset.sectionId = sectionOf(draggedRow);
+        const rowIds = [...tbody.querySelectorAll('tr.data-row')].map(dataRow => Number(dataRow.dataset.id));
+        options.onReorder(rowIds, {
+          id: Number(draggedRow.dataset.id),
+          sectionId: draggedRow.dataset.sectionId,
+          previousSectionId: previousSectionId
+        });
+      });
+    };
+    sectionRows.forEach(dropTarget);
+    dataRows.forEach(row => {
+      dropTarget(row);
+      if (archived(row)) return;
+      row.setAttribute('draggable', 'true');
+      row.addEventListener('dragstart', (event) => {
+        if (state.sortColumn) {
+          event.preventDefault();
+          return;
+        }
+        draggedRow = row;
+        row.classList.add('opacity-50');
+        event.dataTransfer.effectAllowed = 'move';
+        event.dataTransfer.setData('text/plain', row.dataset.id);
+      });
+      row.addEventListener('dragend', () => {
+        row.classList.remove('opacity-50');
+        draggedRow = null;
+      });
+    });
+  }
+  
   // Apply initial sort if default is provided
   if (state.sortColumn) {
     sortTableByColumn(state.sortColumn, state.sortDirection);
@@ -204,6 +302,13 @@ export function initMasterDetailGrid(gridSelector, options = {}) {
       updateSortIndicators();
     },
     getExpandedRows: () => [...state.expandedRows],
+    // Records a saved order so that returning to the list's own order shows it
+    setOrder: (rowIds) => {
+      rowIds.forEach((rowId, index) => {
+        const dataRow = tbody.querySelector(`tr.data-row[data-id="${rowId}"]`);
+        if (dataRow) dataRow.dataset.position = index + 1;
+      });
+    },
     destroy: () => {
       // Clean up code if needed
     }
@@ -0,0 +1,70 @@
+require('../index')
+require('../navbar')
+
+document.addEventListener('DOMContentLoaded', (event) => {
+    const tokenNameInputs = document.querySelectorAll('.token-name');
+    const tokenScopeSelects = document.querySelectorAll('.token-scope');
+    const createTokenButtons = document.querySelectorAll('.btn-create-token');
+    const tokenErrors = document.querySelectorAll('.token-error');
+    const newTokenBoxes = document.querySelectorAll('.new-token');
+    const newTokenValues = document.querySelectorAll('.new-token-value');
+    const revokeTokenButtons = document.querySelectorAll('.btn-revoke-token');
+
+    createTokenButtons.forEach(createTokenBtn => {
+        createTokenBtn.addEventListener('click', async (event) => {
+            let name;
+            for (const input of tokenNameInputs) {
+                name = input.value.trim();
+                break;
+            }
+            let scope;
+            for (const select of tokenScopeSelects) {
+                scope = select.value;
+                break;
+            }
+            tokenErrors.forEach(el => el.classList.add('hidden'));
+            if (!name) {
+                tokenErrors.forEach(el => {
+                    el.textContent = 'Please give the token a name.';
+                    el.classList.remove('hidden');
+                });
+                return;
+            }
+
+            const formData = new URLSearchParams();
+            formData.append('name', name);
+            formData.append('scope', scope);
+            const response = await fetch('/tokens', {
+                method: 'PUT',
+                headers: {
+                    'Content-Type': 'application/x-www-form-urlencoded'
+                },
+                body: formData
+            });
+            if (response.status === 200) {
+                const token = await response.text();
+                newTokenValues.forEach(el => el.textContent = token);
+                newTokenBoxes.forEach(el => el.classList.remove('hidden'));
+                tokenNameInputs.forEach(input => input.value = '');
+            }
+            else {
+                tokenErrors.forEach(el => {
+                    el.textContent = 'A problem came up and the token was not created. Please try again later.';
+                    el.classList.remove('hidden');
+                });
+            }
+        });
+    });
+
+    revokeTokenButtons.forEach(revokeTokenBtn => {
+        revokeTokenBtn.addEventListener('click', async (event) => {
+            const tokenId = revokeTokenBtn.dataset.tokenId;
+            const response = await fetch('/tokens/'+tokenId, {
+                method: 'DELETE',
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+        });
+    });
+});
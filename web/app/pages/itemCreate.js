@@ -4,7 +4,7 @@ require('../navbar')
 document.addEventListener('DOMContentLoaded', (event) => {
     const forms = document.querySelectorAll(".list-form");
     const nameInputs = document.getElementsByName('name');
-    const submitButtons = document.querySelectorAll('button');
+    const submitButtons = document.querySelectorAll('.list-form button');
     const errorSpans = document.querySelectorAll('.error-span');
     var formReadyToSubmit = nameInputs[0].value.trim() !== '';
 
@@ -87,6 +87,61 @@ document.addEventListener('DOMContentLoaded', (event) => {
         sendData(form);
     }));
 
+    // Images are uploaded and removed on their own, straight away, rather than with the rest of the form
+    const attachments = document.querySelector('.item-attachments');
+    if (attachments) {
+        const attachmentsUrl = "/list/"+attachments.dataset.listId+"/item/"+attachments.dataset.itemId+"/attachment";
+        const attachmentInput = attachments.querySelector('.attachment-input');
+        const attachmentError = attachments.querySelector('.attachment-error-span');
+
+        async function showAttachmentError(response) {
+            attachmentError.innerText = response.status < 500 ? await response.text() : "Unexpected error occurred. Please try again later.";
+            attachmentError.classList.remove("hidden");
+        }
+
+        attachmentInput.addEventListener('change', async () => {
+            if (attachmentInput.files.length === 0) {
+                return;
+            }
+            attachmentError.classList.add("hidden");
+            const formData = new FormData();
+            for (const file of attachmentInput.files) {
+                formData.append("images", file);
+            }
+            attachmentInput.disabled = true;
+            try {
+                const response = await fetch(attachmentsUrl, { method: "PUT", body: formData });
+                if (response.status === 201) {
+                    window.location.reload();
+                    return;
+                }
+                await showAttachmentError(response);
+            } catch (e) {
+                console.error(e);
+                showError(500);
+            }
+            attachmentInput.disabled = false;
+            attachmentInput.value = "";
+        });
+
+        attachments.querySelectorAll('.attachment-remove').forEach(button =>
+            button.addEventListener('click', async () => {
+                attachmentError.classList.add("hidden");
+                try {
+                    const response = await fetch(attachmentsUrl+"/"+button.dataset.attachmentId, { method: "DELETE" });
+                    if (response.status === 204) {
+                        button.closest('.attachment').remove();
+                    } else {
+                        await showAttachmentError(response);
+                    }
+                } catch (e) {
+                    console.error(e);
+                    showError(500);
+                }
+            })
+        );
+    }
+
     function showError(statusCode) {
         const errorSpans = document.querySelectorAll(".error-span");
         errorSpans.forEach(errorSpan => {
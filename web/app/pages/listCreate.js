@@ -23,6 +23,16 @@ document.addEventListener('DOMContentLoaded', (event) => {
         })
     );
 
+    // Show the chosen template's description as what a blank description will get
+    document.querySelectorAll('.template-select').forEach(select =>
+        select.addEventListener('change', (event) => {
+            const description = select.selectedOptions[0].dataset.description;
+            document.getElementsByName('description').forEach(input => {
+                input.placeholder = description ? description : 'Optional description';
+            });
+        })
+    );
+
     var debouncedCheckListName = debounce(checkListName, 500);
     async function checkListName(submitButtons, errorSpans, name) {
         try {
@@ -28,6 +28,12 @@ document.addEventListener('DOMContentLoaded', (event) => {
     const groupCanEditCheckboxes = document.querySelectorAll('.checkbox-group-can-edit');
     const groupSharingStatus = document.querySelectorAll('.group-sharing-status');
     const groupSharingError = document.querySelectorAll('.group-sharing-error');
+    const claimableCheckboxes = document.querySelectorAll('.checkbox-claimable');
+    const claimableStatus = document.querySelectorAll('.claimable-status');
+    const claimableError = document.querySelectorAll('.claimable-error');
+    const completedDisplaySelects = document.querySelectorAll('.select-completed-display');
+    const completedDisplayStatus = document.querySelectorAll('.completed-display-status');
+    const completedDisplayError = document.querySelectorAll('.completed-display-error');
     var formReadyToSubmit = false;
     var firstDeleteClickDone = false;
 
@@ -426,6 +432,182 @@ document.addEventListener('DOMContentLoaded', (event) => {
         }
     }
 
+    // Gift claiming checkbox
+    claimableCheckboxes.forEach(checkbox => {
+        checkbox.addEventListener('change', async (event) => {
+            claimableStatus.forEach(el => el.classList.add('hidden'));
+            claimableError.forEach(el => el.classList.add('hidden'));
+            const listId = checkbox.dataset.listId;
+            try {
+                const response = await fetch('/list/' + listId + '/claimable', {
+                    method: checkbox.checked ? 'PUT' : 'DELETE'
+                });
+
+                if (response.status !== 204 && response.status !== 200) {
+                    throw new Error(`HTTP error! status: ${response.status}`);
+                }
+
+                claimableStatus.forEach(el => el.classList.remove('hidden'));
+                setTimeout(() => claimableStatus.forEach(el => el.classList.add('hidden')), 3000);
+            } catch (error) {
+                claimableError.forEach(el => el.classList.remove('hidden'));
+            }
+        });
+    });
+
+    // Completed items display
+    completedDisplaySelects.forEach(select => {
+        select.addEventListener('change', async (event) => {
+            completedDisplayStatus.forEach(el => el.classList.add('hidden'));
+            completedDisplayError.forEach(el => el.classList.add('hidden'));
+            try {
+                const response = await fetch('/list/' + select.dataset.listId + '/completed-display', {
+                    method: 'PUT',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({ completedDisplay: select.value })
+                });
+
+                if (response.status !== 204) {
+                    throw new Error(`HTTP error! status: ${response.status}`);
+                }
+
+                completedDisplayStatus.forEach(el => el.classList.remove('hidden'));
+                setTimeout(() => completedDisplayStatus.forEach(el => el.classList.add('hidden')), 3000);
+            } catch (error) {
+                completedDisplayError.forEach(el => el.classList.remove('hidden'));
+            }
+        });
+    });
+
+    // Budget: saving sends the amount as typed; the server checks it against the currency's decimal places
+    async function sendBudget(listId, method, body) {
+        document.querySelectorAll('.budget-status').forEach(el => el.classList.add('hidden'));
+        document.querySelectorAll('.budget-error').forEach(el => el.classList.add('hidden'));
+        try {
+            const response = await fetch('/list/' + listId + '/budget', {
+                method: method,
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: body ? JSON.stringify(body) : undefined
+            });
+            if (response.status === 400) {
+                const message = await response.text();
+                document.querySelectorAll('.budget-error').forEach(el => {
+                    el.textContent = message;
+                    el.classList.remove('hidden');
+                });
+                return false;
+            }
+            if (response.status !== 204) {
+                throw new Error(`HTTP error! status: ${response.status}`);
+            }
+            document.querySelectorAll('.budget-status').forEach(el => el.classList.remove('hidden'));
+            setTimeout(() => document.querySelectorAll('.budget-status').forEach(el => el.classList.add('hidden')), 3000);
+            return true;
+        } catch (error) {
+            document.querySelectorAll('.budget-error').forEach(el => {
+                el.textContent = 'A problem came up and your change was not saved. Please try again later.';
+                el.classList.remove('hidden');
+            });
+            return false;
+        }
+    }
+
+    document.querySelectorAll('.btn-save-budget').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            await sendBudget(button.dataset.listId, 'PUT', {
+                amount: document.querySelector('.input-budget-amount').value,
+                currency: document.querySelector('.input-budget-currency').value
+            });
+        });
+    });
+
+    document.querySelectorAll('.btn-clear-budget').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            if (await sendBudget(button.dataset.listId, 'DELETE')) {
+                document.querySelector('.input-budget-amount').value = '';
+                document.querySelector('.input-budget-currency').value = '';
+            }
+        });
+    });
+
+    // Tags: the server lowercases them and drops repeats, so show back what it will store
+    document.querySelectorAll('.btn-save-tags').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            document.querySelectorAll('.tags-status').forEach(el => el.classList.add('hidden'));
+            document.querySelectorAll('.tags-error').forEach(el => el.classList.add('hidden'));
+            const input = document.querySelector('.input-list-tags');
+            const tags = input.value.split(',').map(tag => tag.trim()).filter(tag => tag !== '');
+            try {
+                const response = await fetch('/list/' + button.dataset.listId + '/tags', {
+                    method: 'PUT',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({ tags: tags })
+                });
+                if (response.status === 400) {
+                    const message = await response.text();
+                    document.querySelectorAll('.tags-error').forEach(el => {
+                        el.textContent = message;
+                        el.classList.remove('hidden');
+                    });
+                    return;
+                }
+                if (response.status !== 204) {
+                    throw new Error(`HTTP error! status: ${response.status}`);
+                }
+                input.value = [...new Set(tags.map(tag => tag.toLowerCase().split(/\s+/).join(' ')))].join(', ');
+                document.querySelectorAll('.tags-status').forEach(el => el.classList.remove('hidden'));
+                setTimeout(() => document.querySelectorAll('.tags-status').forEach(el => el.classList.add('hidden')), 3000);
+            } catch (error) {
+                document.querySelectorAll('.tags-error').forEach(el => {
+                    el.textContent = 'A problem came up and your change was not saved. Please try again later.';
+                    el.classList.remove('hidden');
+                });
+            }
+        });
+    });
+
+    // Copy: the new list opens once it is made
+    document.querySelectorAll('.btn-copy-list').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            document.querySelectorAll('.copy-error').forEach(el => el.classList.add('hidden'));
+            try {
+                const response = await fetch('/list/' + button.dataset.listId + '/copy', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({
+                        name: document.querySelector('.input-copy-name').value.trim(),
+                        copySharing: document.querySelector('.checkbox-copy-sharing').checked
+                    })
+                });
+                if (response.status === 409) {
+                    const message = await response.text();
+                    document.querySelectorAll('.copy-error').forEach(el => {
+                        el.textContent = message;
+                        el.classList.remove('hidden');
+                    });
+                    return;
+                }
+                if (response.status !== 201) {
+                    throw new Error(`HTTP error! status: ${response.status}`);
+                }
+                window.location.href = response.headers.get('Location');
+            } catch (error) {
+                document.querySelectorAll('.copy-error').forEach(el => {
+                    el.textContent = 'A problem came up and the list was not copied. Please try again later.';
+                    el.classList.remove('hidden');
+                });
+            }
+        });
+    });
+
     function debounce(func, delay) {
         let timeoutId;
         const debouncedFunc = function(...args) {
@@ -0,0 +1,31 @@
+require('../index')
+require('../navbar')
+
+document.addEventListener('DOMContentLoaded', (event) => {
+    const historyErrors = document.querySelectorAll('.history-error');
+    const revertButtons = document.querySelectorAll('.btn-revert');
+
+    function showError(message) {
+        historyErrors.forEach(el => {
+            el.textContent = message;
+            el.classList.remove('hidden');
+        });
+    }
+
+    revertButtons.forEach(revertBtn => {
+        revertBtn.addEventListener('click', async (event) => {
+            const response = await fetch('/list/'+revertBtn.dataset.listId+'/history/'+revertBtn.dataset.revisionId+'/revert', {
+                method: 'POST',
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else if (response.status === 409) {
+                showError('That change can no longer be reverted; the item has changed since.');
+            }
+            else {
+                showError('A problem came up and the change was not reverted. Please try again later.');
+            }
+        });
+    });
+});
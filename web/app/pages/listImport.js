@@ -0,0 +1,153 @@
+require('../index')
+require('../navbar')
+
+document.addEventListener('DOMContentLoaded', (event) => {
+    const importForm = document.querySelector('.import-form');
+    const listId = importForm.dataset.listId;
+    const formatSelect = importForm.querySelector('.import-format');
+    const fileInput = importForm.querySelector('.import-file');
+    const contentInput = importForm.querySelector('.import-content');
+    const columnsBox = importForm.querySelector('.import-columns');
+    const columnSelects = importForm.querySelectorAll('.import-column');
+    const previewButton = importForm.querySelector('.btn-preview');
+    const importButton = importForm.querySelector('.btn-import');
+    const errorText = importForm.querySelector('.import-error');
+    const previewBox = importForm.querySelector('.import-preview');
+    const summaryText = importForm.querySelector('.import-summary');
+    const rowsBody = importForm.querySelector('.import-rows');
+
+    // Column choices only apply to the CSV currently previewed; start over when the input changes
+    function resetPreview() {
+        previewBox.classList.add('hidden');
+        errorText.classList.add('hidden');
+        columnsBox.classList.add('hidden');
+        columnSelects.forEach(select => select.innerHTML = '');
+    }
+
+    function showError(message) {
+        errorText.textContent = message;
+        errorText.classList.remove('hidden');
+    }
+
+    function buildFormData() {
+        const formData = new URLSearchParams();
+        formData.append('format', formatSelect.value);
+        formData.append('content', contentInput.value);
+        if (formatSelect.value === 'csv' && !columnsBox.classList.contains('hidden')) {
+            columnSelects.forEach(select => formData.append(select.name, select.value));
+        }
+        return formData;
+    }
+
+    function fillColumnSelects(headers, columns) {
+        const chosen = {
+            nameColumn: columns.name,
+            urlColumn: columns.url,
+            priorityColumn: columns.priority,
+            notesColumn: columns.notes,
+        };
+        columnSelects.forEach(select => {
+            select.innerHTML = '';
+            const none = document.createElement('option');
+            none.value = '';
+            none.textContent = '(none)';
+            select.appendChild(none);
+            headers.forEach(header => {
+                const option = document.createElement('option');
+                option.value = header;
+                option.textContent = header;
+                option.selected = header === chosen[select.name];
+                select.appendChild(option);
+            });
+        });
+        columnsBox.classList.remove('hidden');
+    }
+
+    function cell(text) {
+        const td = document.createElement('td');
+        td.className = 'border px-4 py-2';
+        td.textContent = text;
+        return td;
+    }
+
+    function showPreview(preview) {
+        if (preview.headers) {
+            fillColumnSelects(preview.headers, preview.columns);
+        }
+        rowsBody.innerHTML = '';
+        preview.rows.forEach(row => {
+            const tr = document.createElement('tr');
+            let status = 'New';
+            if (row.error) {
+                status = row.error;
+                tr.classList.add('text-error-light');
+            } else if (row.duplicate) {
+                status = 'Already in list';
+                tr.classList.add('text-gray-500');
+            }
+            [row.line, row.name, row.url || '', row.priority ?? '', row.notes || '', status]
+                .forEach(value => tr.appendChild(cell(value)));
+            rowsBody.appendChild(tr);
+        });
+        const skipped = preview.rows.length - preview.new;
+        summaryText.textContent = `${preview.new} of ${preview.rows.length} items will be added` + (skipped > 0 ? `; ${skipped} will be skipped.` : '.');
+        importButton.classList.toggle('hidden', preview.new === 0);
+        previewBox.classList.remove('hidden');
+    }
+
+    formatSelect.addEventListener('change', resetPreview);
+    contentInput.addEventListener('input', resetPreview);
+
+    fileInput.addEventListener('change', async () => {
+        const file = fileInput.files[0];
+        if (!file) {
+            return;
+        }
+        contentInput.value = await file.text();
+        if (file.name.endsWith('.json')) {
+            formatSelect.value = 'json';
+        } else if (file.name.endsWith('.csv')) {
+            formatSelect.value = 'csv';
+        }
+        resetPreview();
+    });
+
+    // Changing a column mapping re-parses the CSV with the new choice
+    columnSelects.forEach(select => select.addEventListener('change', () => previewButton.click()));
+
+    previewButton.addEventListener('click', async () => {
+        errorText.classList.add('hidden');
+        if (contentInput.value.trim() === '') {
+            showError('Choose a file or paste something to import.');
+            return;
+        }
+        const response = await fetch('/list/' + listId + '/import/preview', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/x-www-form-urlencoded'
+            },
+            body: buildFormData().toString()
+        });
+        if (response.status === 200) {
+            showPreview(await response.json());
+        } else {
+            previewBox.classList.add('hidden');
+            showError(response.status === 500 ? 'A problem came up. Please try again later.' : await response.text());
+        }
+    });
+
+    importButton.addEventListener('click', async () => {
+        const response = await fetch('/list/' + listId + '/import', {
+            method: 'PUT',
+            headers: {
+                'Content-Type': 'application/x-www-form-urlencoded'
+            },
+            body: buildFormData().toString()
+        });
+        if (response.status === 200) {
+            window.location.href = response.headers.get('Location');
+        } else {
+            showError(response.status === 500 ? 'A problem came up and nothing was imported. Please try again later.' : await response.text());
+        }
+    });
+});
@@ -24,9 +24,135 @@ document.addEventListener('DOMContentLoaded', (event) => {
         });
     });
     
-    // Initialize the master/detail grid
+    // Initialize release claim functionality
+    const releaseClaimButtons = document.querySelectorAll('.btn-release-claim');
+    releaseClaimButtons.forEach(button => {
+        button.addEventListener('click', async (event) => {
+            event.stopPropagation();
+            const listId = button.dataset.listId;
+            const itemId = button.dataset.itemId;
+
+            const response = await fetch('/list/' + listId + '/item/' + itemId + '/claim', {
+                method: 'DELETE'
+            });
+
+            if (response.status === 204 || response.status === 200) {
+                window.location.reload();
+            }
+        });
+    });
+    
+    // Remove one visitor's contribution towards an item's quantity
+    document.querySelectorAll('.btn-remove-contribution').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            event.stopPropagation();
+            const response = await fetch('/list/' + button.dataset.listId + '/item/' + button.dataset.itemId + '/contribution/' + button.dataset.contributionId, {
+                method: 'DELETE'
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+        });
+    });
+    
+    // Checklist mode: ticking an item checks it off. The page reloads because the list may hide or archive completed items.
+    document.querySelectorAll('.checkbox-complete').forEach(checkbox => {
+        checkbox.addEventListener('change', async (event) => {
+            const response = await fetch('/list/' + checkbox.dataset.listId + '/item/' + checkbox.dataset.itemId + '/complete', {
+                method: checkbox.checked ? 'PUT' : 'DELETE'
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            } else {
+                checkbox.checked = !checkbox.checked;
+            }
+        });
+    });
+    
+    // Sections: add, rename and delete. Deleting a section keeps its items in the list.
+    document.querySelectorAll('.add-section-form').forEach(form => {
+        form.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            const name = form.querySelector('input[name="name"]').value.trim();
+            if (name === '') return;
+            const response = await fetch('/list/' + form.dataset.listId + '/section', {
+                method: 'PUT',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify({ name: name })
+            });
+            if (response.status === 201) {
+                window.location.reload();
+            }
+        });
+    });
+
+    document.querySelectorAll('.btn-rename-section').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            event.stopPropagation();
+            const name = prompt('Rename section', button.dataset.sectionName);
+            if (name === null || name.trim() === '') return;
+            const response = await fetch('/list/' + button.dataset.listId + '/section/' + button.dataset.sectionId, {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify({ name: name.trim() })
+            });
+            if (response.status === 200) {
+                window.location.reload();
+            }
+        });
+    });
+
+    document.querySelectorAll('.btn-delete-section').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            event.stopPropagation();
+            if (!confirm('Delete the section "' + button.dataset.sectionName + '"? Its items will stay in the list.')) return;
+            const response = await fetch('/list/' + button.dataset.listId + '/section/' + button.dataset.sectionId, {
+                method: 'DELETE'
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+        });
+    });
+
+    // Initialize the master/detail grid; editors can drag rows to reorder the list
+    const itemGrid = document.querySelector('.item-grid');
     const gridApi = initMasterDetailGrid('.item-grid', {
-        defaultSortColumn: 'priority',
-        defaultSortDirection: 'asc'
+        onReorder: itemGrid && itemGrid.dataset.canEdit === 'true' ? saveOrder : null
     });
+
+    async function saveOrder(itemIds, moved) {
+        const listId = itemGrid.dataset.listId;
+        if (moved.sectionId !== moved.previousSectionId) {
+            const sectionResponse = await fetch('/list/' + listId + '/item/' + moved.id + '/section', {
+                method: 'PUT',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify({ sectionId: moved.sectionId === '' ? null : Number(moved.sectionId) })
+            });
+            if (sectionResponse.status !== 204) {
+                window.location.reload();
+                return;
+            }
+        }
+        const response = await fetch('/list/' + listId + '/items/order', {
+            method: 'PUT',
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ itemIds: itemIds })
+        });
+        if (response.status === 200) {
+            const order = await response.json();
+            gridApi.setOrder(order.itemIds);
+        } else {
+            // Someone else changed the list meanwhile; show what was actually saved
+            window.location.reload();
+        }
+    }
 });
\ No newline at end of file
@@ -0,0 +1,60 @@
+require('../index')
+require('../navbar')
+
+document.addEventListener('DOMContentLoaded', (event) => {
+    const templateErrors = document.querySelectorAll('.template-error');
+
+    function showTemplateError(message) {
+        templateErrors.forEach(el => {
+            el.textContent = message;
+            el.classList.remove('hidden');
+        });
+    }
+
+    document.querySelectorAll('.btn-create-template').forEach(createTemplateBtn => {
+        createTemplateBtn.addEventListener('click', async (event) => {
+            const name = document.querySelector('.template-name').value.trim();
+            const listId = document.querySelector('.template-list').value;
+            templateErrors.forEach(el => el.classList.add('hidden'));
+            if (!name) {
+                showTemplateError('Please give the template a name.');
+                return;
+            }
+
+            const formData = new URLSearchParams();
+            formData.append('listId', listId);
+            formData.append('name', name);
+            try {
+                const response = await fetch('/admin/templates', {
+                    method: 'PUT',
+                    headers: {
+                        'Content-Type': 'application/x-www-form-urlencoded'
+                    },
+                    body: formData
+                });
+                if (response.status === 204) {
+                    window.location.reload();
+                }
+                else if (response.status === 409) {
+                    showTemplateError('Your group already has a template with that name.');
+                }
+                else {
+                    throw new Error(`HTTP error! status: ${response.status}`);
+                }
+            } catch (error) {
+                showTemplateError('A problem came up and the template was not created. Please try again later.');
+            }
+        });
+    });
+
+    document.querySelectorAll('.btn-delete-template').forEach(deleteTemplateBtn => {
+        deleteTemplateBtn.addEventListener('click', async (event) => {
+            const response = await fetch('/admin/templates/' + deleteTemplateBtn.dataset.templateId, {
+                method: 'DELETE',
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+        });
+    });
+});
@@ -1,4 +1,5 @@
 require("../index")
+import { getPasskey, passkeysSupported } from "../webauthn";
 
 const pwdGroup = document.getElementById("password-group");
 const pwdInput = document.getElementById("password");
@@ -8,6 +9,8 @@ const errorSpan = document.getElementById("error-span");
 const oidcSection = document.getElementById("oidc-section");
 const oidcLoginBtn = document.getElementById("oidc-login-btn");
 const oidcProviderText = document.getElementById("oidc-provider-text");
+const passkeySection = document.getElementById("passkey-section");
+const passkeyLoginBtn = document.getElementById("passkey-login-btn");
 
 async function sendData(form) {
     const formData = new FormData(form);
@@ -93,6 +96,48 @@ function showError(statusCode) {
     errorSpan.classList.remove("hidden");
 }
 
+// Passkey sign-in: the authenticator offers the passkeys it holds for this site, so no email is needed
+async function handlePasskeyLogin() {
+    try {
+        const optionsResponse = await fetch("/auth/passkey/options", { method: "POST" });
+        if (optionsResponse.status !== 200) {
+            showError(optionsResponse.status);
+            return;
+        }
+        let assertion;
+        try {
+            assertion = await getPasskey(await optionsResponse.json());
+        } catch (e) {
+            // Cancelled or timed out; nothing to report
+            console.log("Passkey sign-in cancelled:", e);
+            return;
+        }
+        const response = await fetch("/auth/passkey", {
+            method: "POST",
+            headers: {
+                "Content-Type": "application/json"
+            },
+            body: JSON.stringify(assertion)
+        });
+        if (response.status === 200) {
+            window.location.href = response.headers.get("Location");
+        } else if (response.status === 401) {
+            errorSpan.innerText = "That passkey is not recognized.";
+            errorSpan.classList.remove("hidden");
+        } else {
+            showError(response.status);
+        }
+    } catch (e) {
+        console.error(e);
+        showError(500);
+    }
+}
+
+if (passkeysSupported()) {
+    passkeySection.classList.remove("hidden");
+    passkeyLoginBtn.addEventListener("click", handlePasskeyLogin);
+}
+
 // OIDC functionality
 async function checkOIDCStatus() {
     try {
@@ -0,0 +1,107 @@
+require('../index')
+require('../navbar')
+import { createPasskey, passkeysSupported } from "../webauthn";
+
+document.addEventListener('DOMContentLoaded', (event) => {
+    const addPasskeyButtons = document.querySelectorAll('.btn-add-passkey');
+    const renamePasskeyButtons = document.querySelectorAll('.btn-rename-passkey');
+    const deletePasskeyButtons = document.querySelectorAll('.btn-delete-passkey');
+    const passkeyErrors = document.querySelectorAll('.passkey-error');
+
+    function showError(message) {
+        passkeyErrors.forEach(el => {
+            el.textContent = message;
+            el.classList.remove('hidden');
+        });
+    }
+
+    function hideError() {
+        passkeyErrors.forEach(el => el.classList.add('hidden'));
+    }
+
+    addPasskeyButtons.forEach(addPasskeyBtn => {
+        if (!passkeysSupported()) {
+            addPasskeyBtn.disabled = true;
+            showError('This browser does not support passkeys.');
+            return;
+        }
+        addPasskeyBtn.addEventListener('click', async (event) => {
+            hideError();
+            const optionsResponse = await fetch('/account/passkeys/options', {
+                method: 'POST',
+            });
+            if (optionsResponse.status !== 200) {
+                showError(optionsResponse.status === 409
+                    ? await optionsResponse.text()
+                    : 'A problem came up and the passkey was not added. Please try again later.');
+                return;
+            }
+            let credential;
+            try {
+                credential = await createPasskey(await optionsResponse.json());
+            } catch (e) {
+                // Cancelled, timed out, or this authenticator already holds one of your passkeys
+                console.log('Passkey creation failed:', e);
+                showError('No passkey was added.');
+                return;
+            }
+            const response = await fetch('/account/passkeys', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify(credential)
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else {
+                showError('The passkey could not be added. Please try again.');
+            }
+        });
+    });
+
+    renamePasskeyButtons.forEach(renamePasskeyBtn => {
+        renamePasskeyBtn.addEventListener('click', async (event) => {
+            hideError();
+            const name = prompt('Name this passkey', renamePasskeyBtn.dataset.name);
+            if (name === null || name.trim() === '') {
+                return;
+            }
+            const response = await fetch('/account/passkeys/' + renamePasskeyBtn.dataset.passkeyId, {
+                method: 'PUT',
+                headers: {
+                    'Content-Type': 'application/x-www-form-urlencoded'
+                },
+                body: new URLSearchParams({ name: name.trim() })
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else if (response.status === 400) {
+                showError(await response.text());
+            }
+            else {
+                showError('A problem came up and the passkey was not renamed. Please try again later.');
+            }
+        });
+    });
+
+    deletePasskeyButtons.forEach(deletePasskeyBtn => {
+        deletePasskeyBtn.addEventListener('click', async (event) => {
+            hideError();
+            if (!confirm('Remove the passkey "' + deletePasskeyBtn.dataset.name + '"? It will no longer sign you in.')) {
+                return;
+            }
+            const response = await fetch('/account/passkeys/' + deletePasskeyBtn.dataset.passkeyId, {
+                method: 'DELETE',
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else {
+                showError('A problem came up and the passkey was not removed. Please try again later.');
+            }
+        });
+    });
+});
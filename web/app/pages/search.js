@@ -0,0 +1,2 @@
+require('../index')
+require('../navbar')
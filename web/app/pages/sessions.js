@@ -0,0 +1,50 @@
+require('../index')
+require('../navbar')
+
+document.addEventListener('DOMContentLoaded', (event) => {
+    const revokeSessionButtons = document.querySelectorAll('.btn-revoke-session');
+    const revokeOtherSessionsButtons = document.querySelectorAll('.btn-revoke-other-sessions');
+    const sessionErrors = document.querySelectorAll('.session-error');
+
+    function showError() {
+        sessionErrors.forEach(el => {
+            el.textContent = 'A problem came up and the session was not signed out. Please try again later.';
+            el.classList.remove('hidden');
+        });
+    }
+
+    revokeSessionButtons.forEach(revokeSessionBtn => {
+        revokeSessionBtn.addEventListener('click', async (event) => {
+            const sessionId = revokeSessionBtn.dataset.sessionId;
+            const response = await fetch('/sessions/'+sessionId, {
+                method: 'DELETE',
+            });
+            if (response.status === 204) {
+                // Signing out this browser leaves nothing to show here
+                if (revokeSessionBtn.dataset.current === 'true') {
+                    window.location.href = '/auth';
+                }
+                else {
+                    window.location.reload();
+                }
+            }
+            else {
+                showError();
+            }
+        });
+    });
+
+    revokeOtherSessionsButtons.forEach(revokeOtherSessionsBtn => {
+        revokeOtherSessionsBtn.addEventListener('click', async (event) => {
+            const response = await fetch('/sessions', {
+                method: 'DELETE',
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else {
+                showError();
+            }
+        });
+    });
+});
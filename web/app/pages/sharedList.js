@@ -5,8 +5,177 @@ import { initMasterDetailGrid } from "../grids";
 
 document.addEventListener('DOMContentLoaded', (event) => {    
     // Initialize the master/detail grid
-    const gridApi = initMasterDetailGrid('.item-grid', {
-        defaultSortColumn: 'priority',
-        defaultSortDirection: 'asc'
+    const gridApi = initMasterDetailGrid('.item-grid');
+
+    // Signed-in visitors can keep their own copy of the list, opened once it is made
+    document.querySelectorAll('.btn-copy-shared-list').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            const errorSpan = button.parentElement.querySelector('.copy-error');
+            errorSpan.classList.add('hidden');
+            try {
+                const response = await fetch('/' + button.dataset.sharedListPath + '/' + button.dataset.shareCode + '/copy', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({})
+                });
+                if (response.status === 409) {
+                    errorSpan.innerText = 'You already have a list named like this copy. Rename it, then try again.';
+                    errorSpan.classList.remove('hidden');
+                    return;
+                }
+                if (response.status !== 201) {
+                    throw new Error(`HTTP error! status: ${response.status}`);
+                }
+                window.location.href = response.headers.get('Location');
+            } catch (error) {
+                errorSpan.innerText = 'A problem came up and the list was not copied. Please try again later.';
+                errorSpan.classList.remove('hidden');
+            }
+        });
+    });
+
+    const grid = document.querySelector('.item-grid');
+    if (!grid) return;
+    const claimBaseUrl = '/' + grid.dataset.sharedListPath + '/' + grid.dataset.shareCode + '/item/';
+
+    // Claim tokens are kept in this browser so the visitor can release their own claims later
+    function claimTokenKey(itemId) {
+        return 'listaway-claim-' + grid.dataset.shareCode + '-' + itemId;
+    }
+
+    function showClaimError(section, message) {
+        const errorSpan = section.querySelector('.claim-error');
+        errorSpan.innerText = message;
+        errorSpan.classList.remove('hidden');
+    }
+
+    document.querySelectorAll('.btn-claim').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            event.stopPropagation();
+            const section = button.closest('.claim-section');
+            const itemId = button.dataset.itemId;
+            const formData = new URLSearchParams();
+            formData.append('name', section.querySelector('.claim-name').value);
+            formData.append('email', section.querySelector('.claim-email').value);
+            try {
+                const response = await fetch(claimBaseUrl + itemId + '/claim', {
+                    method: 'PUT',
+                    headers: {
+                        'Accept': 'text/plain',
+                        'Content-Type': 'application/x-www-form-urlencoded'
+                    },
+                    body: formData.toString()
+                });
+                if (response.status === 200) {
+                    localStorage.setItem(claimTokenKey(itemId), await response.text());
+                    window.location.reload();
+                } else if (response.status === 409) {
+                    showClaimError(section, 'Someone else just claimed this item.');
+                } else {
+                    showClaimError(section, 'Unexpected error occurred. Please try again later.');
+                }
+            } catch (e) {
+                console.error(e);
+                showClaimError(section, 'Unexpected error occurred. Please try again later.');
+            }
+        });
+    });
+
+    // Contribution tokens are kept per item as a JSON array, since a visitor may contribute more than once
+    function contributionTokensKey(itemId) {
+        return 'listaway-contributions-' + grid.dataset.shareCode + '-' + itemId;
+    }
+
+    function contributionTokens(itemId) {
+        try {
+            return JSON.parse(localStorage.getItem(contributionTokensKey(itemId))) || [];
+        } catch (e) {
+            return [];
+        }
+    }
+
+    document.querySelectorAll('.btn-contribute').forEach(button => {
+        button.addEventListener('click', async (event) => {
+            event.stopPropagation();
+            const section = button.closest('.claim-section');
+            const itemId = button.dataset.itemId;
+            const formData = new URLSearchParams();
+            formData.append('name', section.querySelector('.contribute-name').value);
+            formData.append('quantity', section.querySelector('.contribute-quantity').value);
+            try {
+                const response = await fetch(claimBaseUrl + itemId + '/contribution', {
+                    method: 'PUT',
+                    headers: {
+                        'Accept': 'text/plain',
+                        'Content-Type': 'application/x-www-form-urlencoded'
+                    },
+                    body: formData.toString()
+                });
+                if (response.status === 200) {
+                    const tokens = contributionTokens(itemId);
+                    tokens.push(await response.text());
+                    localStorage.setItem(contributionTokensKey(itemId), JSON.stringify(tokens));
+                    window.location.reload();
+                } else if (response.status === 409) {
+                    showClaimError(section, 'Fewer than that are still needed. Someone else may have just contributed.');
+                } else if (response.status === 400) {
+                    showClaimError(section, 'Please enter how many you will bring.');
+                } else {
+                    showClaimError(section, 'Unexpected error occurred. Please try again later.');
+                }
+            } catch (e) {
+                console.error(e);
+                showClaimError(section, 'Unexpected error occurred. Please try again later.');
+            }
+        });
+    });
+
+    document.querySelectorAll('.btn-withdraw').forEach(button => {
+        const itemId = button.dataset.itemId;
+        if (contributionTokens(itemId).length === 0) return;
+        button.classList.remove('hidden');
+        button.addEventListener('click', async (event) => {
+            event.stopPropagation();
+            const section = button.closest('.claim-section');
+            const remaining = [];
+            for (const token of contributionTokens(itemId)) {
+                const response = await fetch(claimBaseUrl + itemId + '/contribution?token=' + encodeURIComponent(token), {
+                    method: 'DELETE'
+                });
+                // 403 means the owner already removed it, so there is nothing left to withdraw
+                if (response.status !== 204 && response.status !== 403) {
+                    remaining.push(token);
+                }
+            }
+            if (remaining.length === 0) {
+                localStorage.removeItem(contributionTokensKey(itemId));
+                window.location.reload();
+            } else {
+                localStorage.setItem(contributionTokensKey(itemId), JSON.stringify(remaining));
+                showClaimError(section, 'Unexpected error occurred. Please try again later.');
+            }
+        });
+    });
+
+    document.querySelectorAll('.btn-unclaim').forEach(button => {
+        const itemId = button.dataset.itemId;
+        const token = localStorage.getItem(claimTokenKey(itemId));
+        if (!token) return;
+        button.classList.remove('hidden');
+        button.addEventListener('click', async (event) => {
+            event.stopPropagation();
+            const section = button.closest('.claim-section');
+            const response = await fetch(claimBaseUrl + itemId + '/claim?token=' + encodeURIComponent(token), {
+                method: 'DELETE'
+            });
+            if (response.status === 204 || response.status === 403) {
+                localStorage.removeItem(claimTokenKey(itemId));
+                window.location.reload();
+            } else {
+                showClaimError(section, 'Unexpected error occurred. Please try again later.');
+            }
+        });
     });
-});
\ No newline at end of file
+});
@@ -0,0 +1,67 @@
+require('../index')
+require('../navbar')
+
+document.addEventListener('DOMContentLoaded', (event) => {
+    const trashErrors = document.querySelectorAll('.trash-error');
+    const restoreButtons = document.querySelectorAll('.btn-restore');
+    const purgeButtons = document.querySelectorAll('.btn-purge');
+    const emptyTrashButtons = document.querySelectorAll('.btn-empty-trash');
+
+    function showError(message) {
+        trashErrors.forEach(el => {
+            el.textContent = message;
+            el.classList.remove('hidden');
+        });
+    }
+
+    restoreButtons.forEach(restoreBtn => {
+        restoreBtn.addEventListener('click', async (event) => {
+            const response = await fetch('/trash/'+restoreBtn.dataset.path+'/restore', {
+                method: 'POST',
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else if (response.status === 409) {
+                showError('You already have one with that name. Rename it, then restore this again.');
+            }
+            else {
+                showError('A problem came up and nothing was restored. Please try again later.');
+            }
+        });
+    });
+
+    purgeButtons.forEach(purgeBtn => {
+        purgeBtn.addEventListener('click', async (event) => {
+            if (!confirm('Delete this forever? It cannot be restored.')) {
+                return;
+            }
+            const response = await fetch('/trash/'+purgeBtn.dataset.path, {
+                method: 'DELETE',
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else {
+                showError('A problem came up and nothing was deleted. Please try again later.');
+            }
+        });
+    });
+
+    emptyTrashButtons.forEach(emptyTrashBtn => {
+        emptyTrashBtn.addEventListener('click', async (event) => {
+            if (!confirm('Delete everything in the trash forever? None of it can be restored.')) {
+                return;
+            }
+            const response = await fetch('/trash', {
+                method: 'DELETE',
+            });
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else {
+                showError('A problem came up and the trash was not emptied. Please try again later.');
+            }
+        });
+    });
+});
@@ -0,0 +1,120 @@
+require('../index')
+require('../navbar')
+
+document.addEventListener('DOMContentLoaded', (event) => {
+    const startSetupButtons = document.querySelectorAll('.btn-start-setup');
+    const setupBoxes = document.querySelectorAll('.setup');
+    const setupQRCodes = document.querySelectorAll('.setup-qr-code');
+    const setupSecrets = document.querySelectorAll('.setup-secret');
+    const setupCodeInputs = document.querySelectorAll('.input-setup-code');
+    const confirmSetupButtons = document.querySelectorAll('.btn-confirm-setup');
+    const twoFactorCodeInputs = document.querySelectorAll('.input-two-factor-code');
+    const resetRecoveryCodesButtons = document.querySelectorAll('.btn-reset-recovery-codes');
+    const disableTwoFactorButtons = document.querySelectorAll('.btn-disable-two-factor');
+    const twoFactorErrors = document.querySelectorAll('.two-factor-error');
+    const recoveryCodesBoxes = document.querySelectorAll('.recovery-codes');
+    const recoveryCodesValues = document.querySelectorAll('.recovery-codes-value');
+
+    function showError(message) {
+        twoFactorErrors.forEach(el => {
+            el.textContent = message;
+            el.classList.remove('hidden');
+        });
+    }
+
+    function hideError() {
+        twoFactorErrors.forEach(el => el.classList.add('hidden'));
+    }
+
+    function firstValue(inputs) {
+        for (const input of inputs) {
+            return input.value.trim();
+        }
+        return '';
+    }
+
+    async function showRecoveryCodes(response) {
+        const codes = await response.text();
+        recoveryCodesValues.forEach(el => el.textContent = codes);
+        recoveryCodesBoxes.forEach(el => el.classList.remove('hidden'));
+    }
+
+    function sendCode(url, method, code) {
+        return fetch(url, {
+            method: method,
+            headers: {
+                'Content-Type': 'application/x-www-form-urlencoded'
+            },
+            body: new URLSearchParams({ code })
+        });
+    }
+
+    startSetupButtons.forEach(startSetupBtn => {
+        startSetupBtn.addEventListener('click', async (event) => {
+            hideError();
+            const response = await fetch('/account/2fa/setup', {
+                method: 'POST',
+            });
+            if (response.status === 200) {
+                const setup = await response.json();
+                setupQRCodes.forEach(el => el.src = setup.qrCode);
+                setupSecrets.forEach(el => el.textContent = setup.secret);
+                setupBoxes.forEach(el => el.classList.remove('hidden'));
+                startSetupButtons.forEach(el => el.classList.add('hidden'));
+            }
+            else {
+                showError('A problem came up and setup could not start. Please try again later.');
+            }
+        });
+    });
+
+    confirmSetupButtons.forEach(confirmSetupBtn => {
+        confirmSetupBtn.addEventListener('click', async (event) => {
+            hideError();
+            const response = await sendCode('/account/2fa', 'PUT', firstValue(setupCodeInputs));
+            if (response.status === 200) {
+                setupBoxes.forEach(el => el.classList.add('hidden'));
+                await showRecoveryCodes(response);
+            }
+            else if (response.status === 400) {
+                showError('That code is not recognized. Check the time on your device and try the next code.');
+            }
+            else {
+                showError('A problem came up and two-factor authentication was not turned on. Please try again later.');
+            }
+        });
+    });
+
+    resetRecoveryCodesButtons.forEach(resetRecoveryCodesBtn => {
+        resetRecoveryCodesBtn.addEventListener('click', async (event) => {
+            hideError();
+            const response = await sendCode('/account/2fa/recovery', 'POST', firstValue(twoFactorCodeInputs));
+            if (response.status === 200) {
+                twoFactorCodeInputs.forEach(input => input.value = '');
+                await showRecoveryCodes(response);
+            }
+            else if (response.status === 400) {
+                showError('That code is not recognized.');
+            }
+            else {
+                showError('A problem came up and the recovery codes were not replaced. Please try again later.');
+            }
+        });
+    });
+
+    disableTwoFactorButtons.forEach(disableTwoFactorBtn => {
+        disableTwoFactorBtn.addEventListener('click', async (event) => {
+            hideError();
+            const response = await sendCode('/account/2fa/disable', 'POST', firstValue(twoFactorCodeInputs));
+            if (response.status === 204) {
+                window.location.reload();
+            }
+            else if (response.status === 400) {
+                showError('That code is not recognized.');
+            }
+            else {
+                showError('A problem came up and two-factor authentication was not turned off. Please try again later.');
+            }
+        });
+    });
+});
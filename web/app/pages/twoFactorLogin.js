@@ -0,0 +1,46 @@
+require("../index")
+
+document.addEventListener('DOMContentLoaded', function () {
+    const form = document.querySelector('.two-factor-form');
+    if (!form) return;
+    const codeInput = form.querySelector('input[name="code"]');
+    const errorSpan = form.querySelector('.two-factor-error');
+
+    form.addEventListener('submit', async function (e) {
+        e.preventDefault();
+        errorSpan.classList.add('hidden');
+        const code = codeInput.value.trim();
+        if (!code) {
+            errorSpan.textContent = 'Please enter a code.';
+            errorSpan.classList.remove('hidden');
+            return;
+        }
+        try {
+            const response = await fetch('/auth/2fa', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/x-www-form-urlencoded' },
+                body: new URLSearchParams({ code }),
+            });
+            if (response.status === 200) {
+                window.location.href = response.headers.get('Location');
+                return;
+            }
+            if (response.status === 401 || response.status === 429) {
+                // The password step ran out or too many codes were wrong; start over
+                window.location.href = '/auth';
+                return;
+            }
+            if (response.status === 400) {
+                errorSpan.textContent = 'That code is not recognized.';
+            } else {
+                errorSpan.textContent = 'Unexpected error occurred. Please try again later.';
+            }
+            errorSpan.classList.remove('hidden');
+            codeInput.value = '';
+        } catch (err) {
+            console.error(err);
+            errorSpan.textContent = 'Unexpected error occurred. Please try again later.';
+            errorSpan.classList.remove('hidden');
+        }
+    });
+});
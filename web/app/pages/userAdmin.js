@@ -7,6 +7,9 @@ document.addEventListener('DOMContentLoaded', (event) => {
     const groupSharingToggle = document.getElementById('group-sharing-toggle');
     const groupSharingStatus = document.querySelectorAll('.group-sharing-status');
     const groupSharingError = document.querySelectorAll('.group-sharing-error');
+    const groupTwoFactorToggle = document.getElementById('group-two-factor-toggle');
+    const groupTwoFactorStatus = document.querySelectorAll('.group-two-factor-status');
+    const groupTwoFactorError = document.querySelectorAll('.group-two-factor-error');
 
     adminToggles.forEach(adminToggle => {
         adminToggle.addEventListener('click', async (event) => {
@@ -99,4 +102,43 @@ document.addEventListener('DOMContentLoaded', (event) => {
             }
         });
     }
+
+    // Initialize group two-factor toggle checkbox state
+    if (groupTwoFactorToggle) {
+        fetch('/admin/grouptwofactor', {
+            method: 'GET'
+        })
+        .then(response => response.text())
+        .then(data => {
+            groupTwoFactorToggle.checked = data === 'true';
+        })
+        .catch(error => {
+            console.error('Error fetching group two-factor policy:', error);
+        });
+
+        // Handle group two-factor toggle changes
+        groupTwoFactorToggle.addEventListener('change', async (event) => {
+            groupTwoFactorStatus.forEach(el => el.classList.add('hidden'));
+            groupTwoFactorError.forEach(el => el.classList.add('hidden'));
+
+            try {
+                const response = await fetch('/admin/grouptwofactor', {
+                    method: 'POST'
+                });
+
+                if (response.status === 200) {
+                    const newValue = await response.text();
+                    groupTwoFactorToggle.checked = newValue === 'true';
+                    groupTwoFactorStatus.forEach(el => el.classList.remove('hidden'));
+                    setTimeout(() => groupTwoFactorStatus.forEach(el => el.classList.add('hidden')), 3000);
+                } else {
+                    throw new Error('Failed to toggle group two-factor policy');
+                }
+            } catch (error) {
+                groupTwoFactorError.forEach(el => el.classList.remove('hidden'));
+                // Revert checkbox state on error
+                groupTwoFactorToggle.checked = !groupTwoFactorToggle.checked;
+            }
+        });
+    }
 });
\ No newline at end of file
@@ -0,0 +1,82 @@
+/**
+ * Passkey helpers: the server speaks WebAuthn as JSON with binary fields base64url-encoded,
+ * while navigator.credentials works in ArrayBuffers, so these convert in both directions.
+ */
+
+/**
+ * Whether the browser can create and use passkeys at all
+ */
+export function passkeysSupported() {
+  return window.PublicKeyCredential !== undefined && navigator.credentials !== undefined;
+}
+
+function fromBase64url(value) {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/');
+  const padded = base64 + '='.repeat((4 - base64.length % 4) % 4);
+  return Uint8Array.from(atob(padded), c => c.charCodeAt(0)).buffer;
+}
+
+function toBase64url(buffer) {
+  const bytes = new Uint8Array(buffer);
+  let binary = '';
+  bytes.forEach(b => binary += String.fromCharCode(b));
+  return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+function decodeDescriptors(descriptors) {
+  return (descriptors || []).map(d => ({ ...d, id: fromBase64url(d.id) }));
+}
+
+/**
+ * Asks the authenticator for a new passkey using the options from the server, returning the
+ * credential as the JSON body the server expects
+ * @param {Object} options - CredentialCreation from the server, with its publicKey options
+ */
+export async function createPasskey(options) {
+  const publicKey = {
+    ...options.publicKey,
+    challenge: fromBase64url(options.publicKey.challenge),
+    user: { ...options.publicKey.user, id: fromBase64url(options.publicKey.user.id) },
+    excludeCredentials: decodeDescriptors(options.publicKey.excludeCredentials),
+  };
+  const credential = await navigator.credentials.create({ publicKey });
+  return {
+    id: credential.id,
+    rawId: toBase64url(credential.rawId),
+    type: credential.type,
+    authenticatorAttachment: credential.authenticatorAttachment,
+    clientExtensionResults: credential.getClientExtensionResults(),
+    response: {
+      clientDataJSON: toBase64url(credential.response.clientDataJSON),
+      attestationObject: toBase64url(credential.response.attestationObject),
+      transports: credential.response.getTransports ? credential.response.getTransports() : [],
+    },
+  };
+}
+
+/**
+ * Asks the authenticator to sign the server's challenge with one of its passkeys for this site,
+ * returning the assertion as the JSON body the server expects
+ * @param {Object} options - CredentialAssertion from the server, with its publicKey options
+ */
+export async function getPasskey(options) {
+  const publicKey = {
+    ...options.publicKey,
+    challenge: fromBase64url(options.publicKey.challenge),
+    allowCredentials: decodeDescriptors(options.publicKey.allowCredentials),
+  };
+  const credential = await navigator.credentials.get({ publicKey });
+  return {
+    id: credential.id,
+    rawId: toBase64url(credential.rawId),
+    type: credential.type,
+    authenticatorAttachment: credential.authenticatorAttachment,
+    clientExtensionResults: credential.getClientExtensionResults(),
+    response: {
+      clientDataJSON: toBase64url(credential.response.clientDataJSON),
+      authenticatorData: toBase64url(credential.response.authenticatorData),
+      signature: toBase64url(credential.response.signature),
+      userHandle: credential.response.userHandle ? toBase64url(credential.response.userHandle) : null,
+    },
+  };
+}
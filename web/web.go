@@ -1,12 +1,15 @@
 package web
 
 import (
+	"database/sql"
 	"embed"
+	"errors"
 	"html/template"
 	"io"
 	"log"
 	"mime"
 	"net/http"
+	"net/url"
 	"path/filepath"
 
 	"slices"
@@ -14,6 +17,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jeffrpowell/listaway/internal/constants"
 	"github.com/jeffrpowell/listaway/internal/handlers/middleware"
+	"github.com/jeffrpowell/listaway/internal/storage"
 	"github.com/tdewolff/minify"
 	"github.com/tdewolff/minify/html"
 )
@@ -39,10 +43,38 @@ var (
 	userAdmin           = parseSingleLayout("dist/userAdmin.html")
 	allUsers            = parseSingleLayout("dist/allUsers.html")
 	userCreate          = parseSingleLayout("dist/userCreate.html")
+	apiTokens           = parseSingleLayout("dist/apiTokens.html")
+	userSessions        = parseSingleLayout("dist/sessions.html")
+	twoFactor           = parseSingleLayout("dist/twoFactor.html")
+	twoFactorLogin      = parseSingleLayout("dist/twoFactorLogin.html")
+	userPasskeys        = parseSingleLayout("dist/passkeys.html")
+	listImport          = parseSingleLayout("dist/listImport.html")
+	search              = parseSingleLayout("dist/search.html")
+	trash               = parseSingleLayout("dist/trash.html")
+	listHistory         = parseSingleLayout("dist/listHistory.html")
+	auditLog            = parseSingleLayout("dist/auditLog.html")
+	listTemplates       = parseSingleLayout("dist/listTemplates.html")
 )
 
+// attachmentStore holds uploaded item images; see SetAttachmentStore
+var attachmentStore storage.Store
+
+// trashRetentionDays is how long the trash keeps things before they're purged; see SetTrashRetention
+var trashRetentionDays int
+
 func init() {
 	constants.ROUTER.HandleFunc("/static/{pathname...}", middleware.DefaultPublicMiddlewareChain(staticHandler)).Methods("GET")
+	constants.ROUTER.HandleFunc("/"+constants.ATTACHMENT_PATH+"/{key}", middleware.DefaultPublicMiddlewareChain(attachmentHandler)).Methods("GET")
+}
+
+// SetAttachmentStore sets where uploaded item images are served from. It must be set before the server starts.
+func SetAttachmentStore(store storage.Store) {
+	attachmentStore = store
+}
+
+// SetTrashRetention sets the number of days the trash page says things are kept; 0 means until the trash is emptied
+func SetTrashRetention(days int) {
+	trashRetentionDays = days
 }
 
 func staticHandler(w http.ResponseWriter, r *http.Request) {
@@ -75,6 +107,40 @@ func staticHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// attachmentHandler serves an uploaded image or its thumbnail. Keys are random and never reused, so
+// the key itself is the permission to view the image, as a share code is, and responses never go stale.
+func attachmentHandler(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	if attachmentStore == nil || !storage.ValidKey(key) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	file, err := attachmentStore.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error serving file", http.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	defer file.Close()
+
+	// Only images are ever stored, under the extension of their detected format
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	_, err = io.Copy(w, file)
+	if err != nil {
+		log.Print(err)
+	}
+}
+
 func minifyTemplates(filenames ...string) (*template.Template, error) {
 	m := minify.New()
 	m.AddFunc("text/html", html.Minify)
@@ -87,6 +153,10 @@ func minifyTemplates(filenames ...string) (*template.Template, error) {
 		}
 		tmpl = tmpl.Funcs(template.FuncMap{
 			"containsUint64": slices.Contains[[]uint64],
+			"formatAmount":   constants.FormatAmount,
+			"formatPrice":    constants.FormatPrice,
+			"tagsText":       constants.TagsText,
+			"auditAction":    constants.AuditActionLabel,
 			"add": func(a, b int) int {
 				return a + b
 			},
@@ -118,7 +188,7 @@ func isAuthenticated(r *http.Request) bool {
 	if r == nil {
 		return false
 	}
-	session, _ := constants.COOKIE_STORE.Get(r, constants.COOKIE_NAME_SESSION)
+	session, _ := constants.SESSION_STORE.Get(r, constants.COOKIE_NAME_SESSION)
 	auth, ok := session.Values["authenticated"].(bool)
 	return ok && auth
 }
@@ -174,6 +244,13 @@ func LoginPage(w io.Writer) {
 	}
 }
 
+// Two-factor code page, between the password and signing in
+func TwoFactorLoginPage(w io.Writer) {
+	if err := twoFactorLogin.Execute(w, globalWebParams{ShowNavbar: false, ChunkName: "twoFactorLogin"}); err != nil {
+		log.Print(err)
+	}
+}
+
 // Password Reset page
 type resetFormPageParams struct {
 	globalWebParams
@@ -203,10 +280,13 @@ type listsPageParams struct {
 	GroupSharingEnabled  bool
 	SharedListPath       string
 	SharedCollectionPath string
+	Tags                 []constants.Tag // the user's tag index
+	Tag                  string          // when set, lists are filtered to this tag and TaggedItems are shown
+	TaggedItems          []constants.TaggedItem
 	globalWebParams
 }
 
-func ListsPageParams(r *http.Request, lists []constants.List, collections []constants.Collection, groupSharedLists []constants.ListSharedWithGroup, groupSharingEnabled bool, showAdmin bool, showInstanceAdmin bool) listsPageParams {
+func ListsPageParams(r *http.Request, lists []constants.List, collections []constants.Collection, groupSharedLists []constants.ListSharedWithGroup, groupSharingEnabled bool, tags []constants.Tag, tag string, taggedItems []constants.TaggedItem, showAdmin bool, showInstanceAdmin bool) listsPageParams {
 	return listsPageParams{
 		globalWebParams:      newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "lists"),
 		Lists:                lists,
@@ -215,6 +295,9 @@ func ListsPageParams(r *http.Request, lists []constants.List, collections []cons
 		GroupSharingEnabled:  groupSharingEnabled,
 		SharedListPath:       constants.SHARED_LIST_PATH,
 		SharedCollectionPath: constants.SHARED_COLLECTION_PATH,
+		Tags:                 tags,
+		Tag:                  tag,
+		TaggedItems:          taggedItems,
 	}
 }
 
@@ -226,11 +309,19 @@ func ListsPage(w io.Writer, params listsPageParams) {
 
 // Create List page
 
-func CreateListParams(r *http.Request, showAdmin bool, showInstanceAdmin bool) globalWebParams {
-	return newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "listCreate")
+type createListParams struct {
+	Templates []constants.ListTemplate // offered to the user's group
+	globalWebParams
 }
 
-func CreateListPage(w io.Writer, params globalWebParams) {
+func CreateListParams(r *http.Request, templates []constants.ListTemplate, showAdmin bool, showInstanceAdmin bool) createListParams {
+	return createListParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "listCreate"),
+		Templates:       templates,
+	}
+}
+
+func CreateListPage(w io.Writer, params createListParams) {
 	if err := createList.Execute(w, params); err != nil {
 		log.Print(err)
 	}
@@ -262,21 +353,137 @@ func EditListPage(w io.Writer, params editListParams) {
 	}
 }
 
+// itemGroup is a section heading and the items under it. Items outside every section come first, in a group without a Section.
+// Lists that archive completed items end with an Archive group holding them.
+type itemGroup struct {
+	Section *constants.ItemSection
+	Archive bool
+	Items   []constants.Item
+}
+
+// displayGroups groups items by section after applying the list's completed display. Completed items are
+// dropped when the list hides them, unless showHidden is set, and moved to a final Archive group when the
+// list archives them. It also returns the items left to show and how many were hidden.
+func displayGroups(list constants.List, sections []constants.ItemSection, items []constants.Item, keepEmpty bool, showHidden bool) ([]itemGroup, []constants.Item, int) {
+	var shown, archived []constants.Item
+	hidden := 0
+	for _, item := range items {
+		switch {
+		case !item.Completed():
+			shown = append(shown, item)
+		case list.CompletedDisplay == constants.COMPLETED_DISPLAY_HIDE && !showHidden:
+			hidden++
+		case list.CompletedDisplay == constants.COMPLETED_DISPLAY_ARCHIVE:
+			archived = append(archived, item)
+		default:
+			shown = append(shown, item)
+		}
+	}
+	groups := groupItems(sections, shown, keepEmpty)
+	if len(archived) > 0 {
+		groups = append(groups, itemGroup{Archive: true, Items: archived})
+		shown = append(shown, archived...)
+	}
+	return groups, shown, hidden
+}
+
+// groupItems splits items, already in list order, by section. Empty sections are kept only when keepEmpty is set,
+// so editors can see and fill them while share link visitors never see a bare heading.
+func groupItems(sections []constants.ItemSection, items []constants.Item, keepEmpty bool) []itemGroup {
+	bySection := make(map[int64][]constants.Item, len(sections))
+	for _, section := range sections {
+		bySection[int64(section.Id)] = nil
+	}
+	var unsectioned []constants.Item
+	for _, item := range items {
+		if _, known := bySection[item.SectionId.Int64]; item.SectionId.Valid && known {
+			bySection[item.SectionId.Int64] = append(bySection[item.SectionId.Int64], item)
+		} else {
+			unsectioned = append(unsectioned, item)
+		}
+	}
+	var groups []itemGroup
+	if len(unsectioned) > 0 {
+		groups = append(groups, itemGroup{Items: unsectioned})
+	}
+	for i := range sections {
+		sectionItems := bySection[int64(sections[i].Id)]
+		if len(sectionItems) > 0 || keepEmpty {
+			groups = append(groups, itemGroup{Section: &sections[i], Items: sectionItems})
+		}
+	}
+	return groups
+}
+
 // List Items page
 
 type listItemsPageParams struct {
-	List    constants.List
-	Items   []constants.Item
-	CanEdit bool
+	List            constants.List
+	Items           []constants.Item
+	Sections        []constants.ItemSection
+	Groups          []itemGroup
+	CanEdit         bool
+	ShowClaims      bool
+	Claims          map[uint64]constants.ItemClaim
+	Contributions   map[uint64][]constants.ItemContribution
+	ShowCompleted   bool
+	HiddenCompleted int // completed items left out because the list hides them
+	Totals          []constants.PriceTotal
+	Budget          *budgetStatus // nil when the list has no budget
+	Tag             string        // when set, only the items carrying this tag are shown
 	globalWebParams
 }
 
-func ListItemsPageParams(r *http.Request, list constants.List, items []constants.Item, canEdit bool, showAdmin bool, showInstanceAdmin bool) listItemsPageParams {
+// budgetStatus compares a list's budget with the total of its items priced in the budget's currency
+type budgetStatus struct {
+	Budget          string
+	Spent           string
+	Left            string // what remains of the budget, or how far over it the items are
+	OverBudget      bool
+	OtherCurrencies int // priced items in other currencies, which aren't counted against the budget
+}
+
+func newBudgetStatus(list constants.List, totals []constants.PriceTotal) *budgetStatus {
+	if !list.Budget.Valid {
+		return nil
+	}
+	currency := list.BudgetCurrency.String
+	var spent int64
+	others := 0
+	for _, t := range totals {
+		if t.Currency == currency {
+			spent = t.Total
+		} else {
+			others += t.Items
+		}
+	}
+	left := list.Budget.Int64 - spent
+	return &budgetStatus{
+		Budget:          constants.FormatPrice(list.Budget.Int64, currency),
+		Spent:           constants.FormatPrice(spent, currency),
+		Left:            constants.FormatPrice(max(left, -left), currency),
+		OverBudget:      left < 0,
+		OtherCurrencies: others,
+	}
+}
+
+func ListItemsPageParams(r *http.Request, list constants.List, items []constants.Item, sections []constants.ItemSection, canEdit bool, showClaims bool, claims map[uint64]constants.ItemClaim, contributions map[uint64][]constants.ItemContribution, showCompleted bool, totals []constants.PriceTotal, tag string, showAdmin bool, showInstanceAdmin bool) listItemsPageParams {
+	groups, _, hidden := displayGroups(list, sections, items, true, showCompleted)
 	return listItemsPageParams{
 		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "listItems"),
 		List:            list,
 		Items:           items,
+		Sections:        sections,
+		Groups:          groups,
 		CanEdit:         canEdit,
+		ShowClaims:      showClaims,
+		Claims:          claims,
+		Contributions:   contributions,
+		ShowCompleted:   showCompleted,
+		HiddenCompleted: hidden,
+		Totals:          totals,
+		Budget:          newBudgetStatus(list, totals),
+		Tag:             tag,
 	}
 }
 
@@ -292,23 +499,27 @@ type createEditItemParams struct {
 	globalWebParams
 	List     constants.List
 	Item     constants.Item
+	Sections []constants.ItemSection
 	EditMode bool
 }
 
-func CreateItemParams(r *http.Request, list constants.List, showAdmin bool, showInstanceAdmin bool) createEditItemParams {
+// CreateItemParams starts the new item in sectionId, which may be null for no section
+func CreateItemParams(r *http.Request, list constants.List, sections []constants.ItemSection, sectionId sql.NullInt64, showAdmin bool, showInstanceAdmin bool) createEditItemParams {
 	return createEditItemParams{
 		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "itemCreate"),
 		List:            list,
-		Item:            constants.Item{},
+		Item:            constants.Item{SectionId: sectionId},
+		Sections:        sections,
 		EditMode:        false,
 	}
 }
 
-func EditItemParams(r *http.Request, list constants.List, item constants.Item, showAdmin bool, showInstanceAdmin bool) createEditItemParams {
+func EditItemParams(r *http.Request, list constants.List, item constants.Item, sections []constants.ItemSection, showAdmin bool, showInstanceAdmin bool) createEditItemParams {
 	return createEditItemParams{
 		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "itemCreate"),
 		List:            list,
 		Item:            item,
+		Sections:        sections,
 		EditMode:        true,
 	}
 }
@@ -324,30 +535,41 @@ func CreateEditItemPage(w io.Writer, params createEditItemParams) {
 type sharedListItemsPageParams struct {
 	List                constants.List
 	Items               []constants.Item
+	Groups              []itemGroup
+	ClaimedItemIds      []uint64
 	ShareCode           string
+	SharedListPath      string
 	CollectionShareCode string
 	HasParentCollection bool
 	globalWebParams
 }
 
-func SharedListItemsPageParams(r *http.Request, shareCode string, list constants.List, items []constants.Item, showAdmin bool, showInstanceAdmin bool) sharedListItemsPageParams {
+func SharedListItemsPageParams(r *http.Request, shareCode string, list constants.List, items []constants.Item, sections []constants.ItemSection, claimedItemIds []uint64, showAdmin bool, showInstanceAdmin bool) sharedListItemsPageParams {
+	groups, shown, _ := displayGroups(list, sections, items, false, false)
 	return sharedListItemsPageParams{
 		globalWebParams:     newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "sharedList"),
 		List:                list,
-		Items:               items,
+		Items:               shown,
+		Groups:              groups,
+		ClaimedItemIds:      claimedItemIds,
 		ShareCode:           shareCode,
+		SharedListPath:      constants.SHARED_LIST_PATH,
 		HasParentCollection: false,
 		CollectionShareCode: "",
 	}
 }
 
 // NestedSharedListItemsPageParams creates parameters for a shared list that's being viewed from a parent collection
-func NestedSharedListItemsPageParams(r *http.Request, shareCode string, collectionShareCode string, list constants.List, items []constants.Item, showAdmin bool, showInstanceAdmin bool) sharedListItemsPageParams {
+func NestedSharedListItemsPageParams(r *http.Request, shareCode string, collectionShareCode string, list constants.List, items []constants.Item, sections []constants.ItemSection, claimedItemIds []uint64, showAdmin bool, showInstanceAdmin bool) sharedListItemsPageParams {
+	groups, shown, _ := displayGroups(list, sections, items, false, false)
 	return sharedListItemsPageParams{
 		globalWebParams:     newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "sharedList"),
 		List:                list,
-		Items:               items,
+		Items:               shown,
+		Groups:              groups,
+		ClaimedItemIds:      claimedItemIds,
 		ShareCode:           shareCode,
+		SharedListPath:      constants.SHARED_LIST_PATH,
 		HasParentCollection: true,
 		CollectionShareCode: collectionShareCode,
 	}
@@ -486,18 +708,24 @@ type collectionDetailPageParams struct {
 	ListIdsInCollection  []uint64
 	ListIdsWithShareCode []uint64
 	AllLists             []constants.ListWithAuthor
+	Totals               []constants.PriceTotal
 	SharedListPath       string
+	Tag                  string // when set, lists are filtered to this tag and TaggedItems are shown
+	TaggedItems          []constants.TaggedItem
 	globalWebParams
 }
 
-func CollectionDetailPageParams(r *http.Request, collection constants.Collection, listIdsInCollection []uint64, listIdsWithShareCode []uint64, allLists []constants.ListWithAuthor, showAdmin bool, showInstanceAdmin bool) collectionDetailPageParams {
+func CollectionDetailPageParams(r *http.Request, collection constants.Collection, listIdsInCollection []uint64, listIdsWithShareCode []uint64, allLists []constants.ListWithAuthor, totals []constants.PriceTotal, tag string, taggedItems []constants.TaggedItem, showAdmin bool, showInstanceAdmin bool) collectionDetailPageParams {
 	return collectionDetailPageParams{
 		globalWebParams:      newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "collectionDetail"),
 		Collection:           collection,
 		ListIdsInCollection:  listIdsInCollection,
 		ListIdsWithShareCode: listIdsWithShareCode,
 		AllLists:             allLists,
+		Totals:               totals,
 		SharedListPath:       constants.SHARED_LIST_PATH,
+		Tag:                  tag,
+		TaggedItems:          taggedItems,
 	}
 }
 
@@ -553,3 +781,242 @@ func SharedCollection404Page(w io.Writer, params sharedCollection404PageParams)
 		log.Print(err)
 	}
 }
+
+// Personal access tokens page
+
+type apiTokensPageParams struct {
+	Tokens    []constants.ApiToken
+	ApiV1Path string
+	globalWebParams
+}
+
+func ApiTokensPageParams(r *http.Request, tokens []constants.ApiToken, showAdmin bool, showInstanceAdmin bool) apiTokensPageParams {
+	return apiTokensPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "apiTokens"),
+		Tokens:          tokens,
+		ApiV1Path:       constants.API_V1_PATH,
+	}
+}
+
+func ApiTokensPage(w io.Writer, params apiTokensPageParams) {
+	if err := apiTokens.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// Your sessions page
+
+type sessionsPageParams struct {
+	Sessions []constants.Session
+	globalWebParams
+}
+
+func SessionsPageParams(r *http.Request, sessions []constants.Session, showAdmin bool, showInstanceAdmin bool) sessionsPageParams {
+	return sessionsPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "sessions"),
+		Sessions:        sessions,
+	}
+}
+
+func SessionsPage(w io.Writer, params sessionsPageParams) {
+	if err := userSessions.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// Two-factor authentication settings page
+
+type twoFactorPageParams struct {
+	Status            constants.TwoFactorStatus
+	RecoveryCodeCount int
+	globalWebParams
+}
+
+func TwoFactorPageParams(r *http.Request, status constants.TwoFactorStatus, showAdmin bool, showInstanceAdmin bool) twoFactorPageParams {
+	return twoFactorPageParams{
+		globalWebParams:   newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "twoFactor"),
+		Status:            status,
+		RecoveryCodeCount: constants.TWO_FACTOR_RECOVERY_CODES,
+	}
+}
+
+func TwoFactorPage(w io.Writer, params twoFactorPageParams) {
+	if err := twoFactor.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// Passkeys page
+
+type passkeysPageParams struct {
+	Passkeys  []constants.Passkey
+	Available bool // Whether passkeys can be added; false when APP_URL doesn't name the site
+	globalWebParams
+}
+
+func PasskeysPageParams(r *http.Request, passkeys []constants.Passkey, available bool, showAdmin bool, showInstanceAdmin bool) passkeysPageParams {
+	return passkeysPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "passkeys"),
+		Passkeys:        passkeys,
+		Available:       available,
+	}
+}
+
+func PasskeysPage(w io.Writer, params passkeysPageParams) {
+	if err := userPasskeys.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// Import items page
+
+type listImportPageParams struct {
+	List    constants.List
+	MaxRows int
+	globalWebParams
+}
+
+func ListImportPageParams(r *http.Request, list constants.List, maxRows int, showAdmin bool, showInstanceAdmin bool) listImportPageParams {
+	return listImportPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "listImport"),
+		List:            list,
+		MaxRows:         maxRows,
+	}
+}
+
+func ListImportPage(w io.Writer, params listImportPageParams) {
+	if err := listImport.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// Search page
+
+type searchPageParams struct {
+	Query   string // empty until the user has searched
+	Results constants.SearchResults
+	globalWebParams
+}
+
+func SearchPageParams(r *http.Request, query string, results constants.SearchResults, showAdmin bool, showInstanceAdmin bool) searchPageParams {
+	return searchPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "search"),
+		Query:           query,
+		Results:         results,
+	}
+}
+
+func SearchPage(w io.Writer, params searchPageParams) {
+	if err := search.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// Trash page
+
+type trashPageParams struct {
+	Trash         constants.Trash
+	RetentionDays int // 0 when nothing is purged automatically
+	globalWebParams
+}
+
+func TrashPageParams(r *http.Request, trash constants.Trash, showAdmin bool, showInstanceAdmin bool) trashPageParams {
+	return trashPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "trash"),
+		Trash:           trash,
+		RetentionDays:   trashRetentionDays,
+	}
+}
+
+func TrashPage(w io.Writer, params trashPageParams) {
+	if err := trash.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// List history page
+
+type listHistoryPageParams struct {
+	List      constants.List
+	Revisions []constants.Revision
+	CanEdit   bool // whether revert buttons are shown
+	globalWebParams
+}
+
+func ListHistoryPageParams(r *http.Request, list constants.List, revisions []constants.Revision, canEdit bool, showAdmin bool, showInstanceAdmin bool) listHistoryPageParams {
+	return listHistoryPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "listHistory"),
+		List:            list,
+		Revisions:       revisions,
+		CanEdit:         canEdit,
+	}
+}
+
+func ListHistoryPage(w io.Writer, params listHistoryPageParams) {
+	if err := listHistory.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// Audit log page
+
+type auditLogPageParams struct {
+	Events  []constants.AuditEvent
+	Filter  auditLogFilter
+	Actions []constants.AuditAction
+	Limited bool // whether there may be older matching events than those shown
+	globalWebParams
+}
+
+// auditLogFilter is the filters as submitted, to fill the form back in
+type auditLogFilter struct {
+	Action string
+	User   string
+	From   string
+	Until  string
+	Group  string
+}
+
+func AuditLogPageParams(r *http.Request, events []constants.AuditEvent, filter url.Values, showAdmin bool, showInstanceAdmin bool) auditLogPageParams {
+	return auditLogPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "auditLog"),
+		Events:          events,
+		Filter: auditLogFilter{
+			Action: filter.Get("action"),
+			User:   filter.Get("user"),
+			From:   filter.Get("from"),
+			Until:  filter.Get("until"),
+			Group:  filter.Get("group"),
+		},
+		Actions: constants.AuditActions,
+		Limited: len(events) >= constants.MaxAuditEvents,
+	}
+}
+
+func AuditLogPage(w io.Writer, params auditLogPageParams) {
+	if err := auditLog.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
+
+// List templates page
+
+type listTemplatesPageParams struct {
+	Templates []constants.ListTemplate
+	Lists     []constants.List // the admin's own lists, which templates are made from
+	globalWebParams
+}
+
+func TemplatesPageParams(r *http.Request, templates []constants.ListTemplate, lists []constants.List, showAdmin bool, showInstanceAdmin bool) listTemplatesPageParams {
+	return listTemplatesPageParams{
+		globalWebParams: newGlobalWebParams(r, true, showAdmin, showInstanceAdmin, "listTemplates"),
+		Templates:       templates,
+		Lists:           lists,
+	}
+}
+
+func TemplatesPage(w io.Writer, params listTemplatesPageParams) {
+	if err := listTemplates.Execute(w, params); err != nil {
+		log.Print(err)
+	}
+}
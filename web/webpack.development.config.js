@@ -22,6 +22,17 @@ module.exports = {
       collectionEdit: './app/pages/collectionEdit.js',
       sharedCollection: './app/pages/sharedCollection.js',
       sharedCollection404: './app/pages/sharedCollection404.js',
+      apiTokens: './app/pages/apiTokens.js',
+      listImport: './app/pages/listImport.js',
+      search: './app/pages/search.js',
+      trash: './app/pages/trash.js',
+      listHistory: './app/pages/listHistory.js',
+      auditLog: './app/pages/auditLog.js',
+      listTemplates: './app/pages/listTemplates.js',
+      sessions: './app/pages/sessions.js',
+      twoFactor: './app/pages/twoFactor.js',
+      twoFactorLogin: './app/pages/twoFactorLogin.js',
+      passkeys: './app/pages/passkeys.js',
     },
     output: {
         filename: '[name].js',